@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -16,7 +17,9 @@ import (
 	"github.com/zyvpn/backend/internal/config"
 	"github.com/zyvpn/backend/internal/handler"
 	"github.com/zyvpn/backend/internal/middleware"
+	"github.com/zyvpn/backend/internal/model"
 	"github.com/zyvpn/backend/internal/repository"
+	"github.com/zyvpn/backend/internal/secret"
 	"github.com/zyvpn/backend/internal/service"
 	"github.com/zyvpn/backend/internal/telegram"
 	"github.com/zyvpn/backend/internal/ton"
@@ -36,6 +39,29 @@ func main() {
 	}
 	defer repo.Close()
 
+	// Encrypt panel credentials at rest; plaintext rows and rows encrypted with
+	// an old key are re-encrypted with the current key
+	if cfg.Secrets.CredentialsKey != "" {
+		box, err := secret.NewBox(cfg.Secrets.CredentialsKey, cfg.Secrets.CredentialsOldKeys...)
+		if err != nil {
+			log.Fatalf("Invalid CREDENTIALS_KEY: %v", err)
+		}
+		repo.SetSecretBox(box)
+
+		updated, skipped, err := repo.ReencryptServerCredentials(context.Background())
+		if err != nil {
+			log.Fatalf("Failed to encrypt panel credentials: %v", err)
+		}
+		if updated > 0 {
+			log.Printf("Encrypted panel credentials of %d servers", updated)
+		}
+		if skipped > 0 {
+			log.Printf("WARNING: Panel credentials of %d servers cannot be decrypted, set their passwords again", skipped)
+		}
+	} else if cfg.Server.Environment == "production" {
+		log.Println("WARNING: CREDENTIALS_KEY is not set, panel credentials are stored in plaintext")
+	}
+
 	// Create services
 	userService := service.NewUserService(repo)
 	planService := service.NewPlanService(repo)
@@ -48,6 +74,11 @@ func main() {
 	balanceSvc := service.NewBalanceService(repo)
 	promoCodeSvc := service.NewPromoCodeService(repo)
 	adminSvc := service.NewAdminService(repo)
+	statusSvc := service.NewStatusService(repo)
+	alertSvc := service.NewAlertService(repo, cfg.Telegram.AdminChatID)
+	if cfg.Telegram.AdminChatID == 0 {
+		log.Println("Warning: TELEGRAM_ADMIN_CHAT_ID is not set, admin alerts are only logged")
+	}
 
 	// Set balance service on payment service (to avoid circular dependency)
 	paymentSvc.SetBalanceService(balanceSvc)
@@ -61,9 +92,12 @@ func main() {
 	adminSvc.SetSubscriptionService(subscriptionSvc)
 	adminSvc.SetPromoCodeService(promoCodeSvc)
 
+	// Exchange rates convert revenue and costs for the profitability report
+	serverSvc.SetRatesService(ratesSvc)
+
 	// Create TON verifier and worker
 	tonVerifier := ton.NewVerifier(cfg.TON.Testnet, cfg.TON.WalletAddress)
-	tonWorker := service.NewTonWorker(repo, tonVerifier, balanceSvc, paymentSvc)
+	tonWorker := service.NewTonWorker(repo, tonVerifier, balanceSvc, paymentSvc, alertSvc)
 
 	// Create Telegram bot
 	var bot *telegram.Bot
@@ -73,7 +107,12 @@ func main() {
 			log.Printf("Warning: Failed to create Telegram bot: %v", err)
 		} else {
 			bot.SetPaymentService(paymentSvc)
+			bot.SetStatusService(statusSvc)
+			bot.SetAlertService(alertSvc)
+			bot.SetShopServices(planService, serverSvc, balanceSvc, promoCodeSvc, service.NewChatStateService(repo))
+			alertSvc.SetNotifier(bot)
 			paymentSvc.SetNotifier(bot)
+			adminSvc.SetNotifier(bot)
 			log.Printf("Telegram bot @%s initialized", bot.GetBotUsername())
 		}
 	}
@@ -81,10 +120,22 @@ func main() {
 	// Create handlers
 	h := handler.New(cfg, userService, planService, subscriptionSvc, paymentSvc, referralSvc, ratesSvc, balanceSvc, promoCodeSvc, adminSvc, bot)
 	adminHandler := handler.NewAdminHandler(adminSvc)
-	serverHandler := handler.NewServerHandler(serverSvc)
+	serverHandler := handler.NewServerHandler(serverSvc, subscriptionSvc, bot)
+	statusHandler := handler.NewStatusHandler(statusSvc)
+	alertHandler := handler.NewAlertHandler(alertSvc)
+
+	if cfg.Server.ProxyHeader != "" && len(cfg.Server.TrustedProxies) == 0 {
+		log.Printf("WARNING: PROXY_HEADER is set but TRUSTED_PROXIES is empty, client IPs are taken from connections")
+	}
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
+		// Client IPs are used to group latency measurements by network, so the
+		// proxy header is only trusted from the configured reverse proxies
+		ProxyHeader:             cfg.Server.ProxyHeader,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          cfg.Server.TrustedProxies,
+		EnableIPValidation:      true,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
 			if e, ok := err.(*fiber.Error); ok {
@@ -110,23 +161,27 @@ func main() {
 
 	// Public API (no auth required)
 	app.Get("/api/rates", h.GetRates)
+	app.Get("/api/status", statusHandler.GetStatus)
 
-	// Webhooks (no auth required) - TON payment callbacks
+	// Webhooks (no auth required) - TON payment callbacks and bot updates
 	app.Post("/webhook/ton", h.TONWebhook)
 	app.Post("/webhook/stars", h.StarsWebhook)
+	app.Post("/webhook/telegram", h.TelegramWebhook)
 
 	// API routes with Telegram authentication
-	api := app.Group("/api", middleware.TelegramAuth(cfg))
+	api := app.Group("/api", middleware.TelegramAuth(cfg), middleware.Language(userService))
 
 	// Plans
 	api.Get("/plans", h.GetPlans)
 
 	// User
 	api.Get("/user/me", h.GetMe)
+	api.Put("/user/language", h.SetLanguage)
 
 	// Subscription
 	api.Post("/subscription/buy", h.BuySubscription)
 	api.Get("/subscription/key", h.GetSubscriptionKey)
+	api.Get("/subscription/key/qr", h.GetSubscriptionKeyQR)
 	api.Get("/subscription/status", h.GetSubscriptionStatus)
 	api.Post("/subscription/trial", h.ActivateTrial)
 	api.Get("/subscription/switch-server/info", h.GetSwitchServerInfo)
@@ -160,6 +215,7 @@ func main() {
 
 	// Servers (for users)
 	api.Get("/servers", serverHandler.GetServers)
+	api.Post("/servers/latency", serverHandler.ReportLatency)
 
 	// Admin panel routes (requires Telegram auth + admin check)
 	admin := app.Group("/api/admin", middleware.TelegramAuth(cfg), middleware.AdminAuth(adminSvc))
@@ -172,6 +228,10 @@ func main() {
 	admin.Post("/users/:user_id/balance/add", adminHandler.AddBalance)
 	admin.Post("/users/:user_id/subscription/extend", adminHandler.ExtendSubscription)
 	admin.Post("/users/:user_id/subscription/cancel", adminHandler.CancelSubscription)
+	admin.Get("/subscriptions/bulk-extend", adminHandler.ListBulkExtensions)
+	admin.Post("/subscriptions/bulk-extend", adminHandler.StartBulkExtension)
+	admin.Get("/subscriptions/bulk-extend/:job_id", adminHandler.GetBulkExtension)
+	admin.Post("/subscriptions/bulk-extend/:job_id/retry", adminHandler.RetryBulkExtension)
 
 	// Admin - Ban management
 	admin.Get("/bans", adminHandler.ListBans)
@@ -205,14 +265,43 @@ func main() {
 	admin.Post("/settings/referral-bonus-days", adminHandler.SetReferralBonusDays)
 	admin.Get("/settings/region-switch-price", adminHandler.GetRegionSwitchPrice)
 	admin.Post("/settings/region-switch-price", adminHandler.SetRegionSwitchPrice)
+	admin.Get("/settings/server-selection", adminHandler.GetServerSelection)
+	admin.Post("/settings/server-selection", adminHandler.SetServerSelection)
 
 	// Admin - Servers
 	admin.Get("/servers", serverHandler.GetAllServers)
+	admin.Get("/servers/health", serverHandler.GetServersHealth)
+	admin.Get("/servers/profitability", serverHandler.GetProfitability)
 	admin.Get("/servers/:server_id", serverHandler.GetServer)
 	admin.Post("/servers", serverHandler.CreateServer)
 	admin.Put("/servers/:server_id", serverHandler.UpdateServer)
 	admin.Delete("/servers/:server_id", serverHandler.DeleteServer)
 	admin.Post("/servers/:server_id/test", serverHandler.TestServerConnection)
+	admin.Get("/servers/:server_id/health", serverHandler.GetServerHealth)
+	admin.Get("/servers/:server_id/load", serverHandler.GetServerLoad)
+	admin.Post("/servers/:server_id/credentials/reveal", serverHandler.RevealServerCredentials)
+	admin.Get("/servers/:server_id/inbounds", serverHandler.GetServerInbounds)
+	admin.Post("/servers/:server_id/inbounds", serverHandler.CreateServerInbound)
+	admin.Put("/servers/:server_id/inbounds/:inbound_id", serverHandler.UpdateServerInbound)
+	admin.Delete("/servers/:server_id/inbounds/:inbound_id", serverHandler.DeleteServerInbound)
+	admin.Get("/servers/:server_id/endpoints", serverHandler.GetServerEndpoints)
+	admin.Post("/servers/:server_id/endpoints", serverHandler.CreateServerEndpoint)
+	admin.Put("/servers/:server_id/endpoints/:endpoint_id", serverHandler.UpdateServerEndpoint)
+	admin.Post("/servers/:server_id/endpoints/:endpoint_id/retire", serverHandler.RetireServerEndpoint)
+	admin.Post("/servers/:server_id/endpoints/:endpoint_id/restore", serverHandler.RestoreServerEndpoint)
+	admin.Delete("/servers/:server_id/endpoints/:endpoint_id", serverHandler.DeleteServerEndpoint)
+
+	// Admin - Status page notices
+	admin.Get("/status/notices", statusHandler.GetNotices)
+	admin.Post("/status/notices", statusHandler.CreateNotice)
+	admin.Put("/status/notices/:notice_id", statusHandler.UpdateNotice)
+	admin.Post("/status/notices/:notice_id/resolve", statusHandler.ResolveNotice)
+	admin.Delete("/status/notices/:notice_id", statusHandler.DeleteNotice)
+
+	// Admin - Alerts
+	admin.Get("/alerts", alertHandler.GetAlerts)
+	admin.Post("/alerts/:alert_id/ack", alertHandler.AckAlert)
+	admin.Post("/alerts/:alert_id/mute", alertHandler.MuteAlert)
 
 	// Internal endpoints (for cron jobs)
 	internal := app.Group("/internal")
@@ -249,20 +338,39 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Start Telegram bot long polling
+	// Start Telegram bot with the webhook when configured, long polling otherwise
 	if bot != nil {
-		go bot.StartPolling(ctx)
-		log.Println("Telegram bot started with long polling")
+		if bot.WebhookEnabled() {
+			if err := bot.StartWebhook(); err != nil {
+				log.Printf("Warning: %v", err)
+			} else {
+				log.Println("Telegram bot started with webhook")
+			}
+		} else {
+			go bot.StartPolling(ctx)
+			log.Println("Telegram bot started with long polling")
+		}
 	}
 
 	// Start TON transaction verification worker
 	go tonWorker.Start(ctx)
 
 	// Start server health checker
-	healthWorker := service.NewHealthWorker(repo, serverSvc)
+	healthWorker := service.NewHealthWorker(repo, serverSvc, alertSvc)
 	go healthWorker.Start(ctx)
 
-	go runSubscriptionChecker(ctx, subscriptionSvc, bot)
+	// Start server load sampler
+	loadWorker := service.NewLoadWorker(repo, serverSvc)
+	go loadWorker.Start(ctx)
+
+	// Continue bulk extensions interrupted by a restart
+	if err := adminSvc.ResumeBulkExtensions(ctx); err != nil {
+		log.Printf("Warning: Failed to resume bulk extensions: %v", err)
+	}
+
+	go runSubscriptionChecker(ctx, subscriptionSvc, alertSvc, bot)
+	go runTrafficSync(ctx, subscriptionSvc, alertSvc)
+	go runRealityChecker(ctx, serverSvc, subscriptionSvc, adminSvc, alertSvc, bot)
 
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
@@ -272,6 +380,9 @@ func main() {
 		<-quit
 		log.Println("Shutting down server...")
 		cancel()
+		if bot != nil && bot.WebhookEnabled() {
+			bot.StopWebhook()
+		}
 		_ = app.Shutdown()
 	}()
 
@@ -282,7 +393,92 @@ func main() {
 	}
 }
 
-func runSubscriptionChecker(ctx context.Context, subscriptionSvc *service.SubscriptionService, bot *telegram.Bot) {
+// runTrafficSync is the only caller of the traffic sync, so panel counters are read in one place
+func runTrafficSync(ctx context.Context, subscriptionSvc *service.SubscriptionService, alertSvc *service.AlertService) {
+	ticker := time.NewTicker(config.TrafficSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Clients of subscriptions expired during a panel outage
+			if err := subscriptionSvc.RetryPanelRemovals(ctx); err != nil {
+				log.Printf("Error retrying panel client removals: %v", err)
+			}
+			if err := subscriptionSvc.SyncAllTraffic(ctx); err != nil {
+				log.Printf("Error syncing subscription traffic: %v", err)
+				alertSvc.Fire(ctx, model.AlertKindReconciliation, "limits", model.AlertSeverityWarning,
+					fmt.Sprintf("Не удалось применить лимиты подписок: %v", err))
+				continue
+			}
+			alertSvc.Resolve(ctx, model.AlertKindReconciliation, "limits")
+		}
+	}
+}
+
+func runRealityChecker(ctx context.Context, serverSvc *service.ServerService, subscriptionSvc *service.SubscriptionService, adminSvc *service.AdminService, alertSvc *service.AlertService, bot *telegram.Bot) {
+	ticker := time.NewTicker(config.RealityCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changes, err := serverSvc.CheckRealityParams(ctx)
+			if err != nil {
+				log.Printf("Error checking inbound parameters: %v", err)
+				alertSvc.Fire(ctx, model.AlertKindReconciliation, "reality", model.AlertSeverityWarning,
+					fmt.Sprintf("Не удалось проверить параметры inbound: %v", err))
+				continue
+			}
+			alertSvc.Resolve(ctx, model.AlertKindReconciliation, "reality")
+			for _, change := range changes {
+				log.Printf("Inbound parameters of server %s changed: %v", change.Server.Name, change.Changes)
+
+				// Server settings followed the panel, so stored keys are stale
+				if len(change.Followed) > 0 {
+					subject := "keys:" + change.Server.ID.String()
+					subs, err := subscriptionSvc.RegenerateConnectionKeys(ctx, change.Server.ID)
+					if err != nil {
+						log.Printf("Error regenerating connection keys for server %s: %v", change.Server.Name, err)
+						alertSvc.Fire(ctx, model.AlertKindReconciliation, subject, model.AlertSeverityCritical,
+							fmt.Sprintf("Не удалось перевыпустить ключи сервера %s: %v", change.Server.Name, err))
+					} else {
+						alertSvc.Resolve(ctx, model.AlertKindReconciliation, subject)
+					}
+
+					// Old keys stopped working, send users their new one as after an admin update
+					if bot != nil {
+						for _, sub := range subs {
+							if err := bot.SendConnectionKeyChanged(sub.UserID, sub.ConnectionKey); err != nil {
+								log.Printf("Failed to send new key to user %d: %v", sub.UserID, err)
+							}
+						}
+					}
+				}
+			}
+			if len(changes) == 0 || bot == nil {
+				continue
+			}
+
+			adminIDs, err := adminSvc.ListAdminUserIDs(ctx)
+			if err != nil {
+				log.Printf("Error listing admins: %v", err)
+				continue
+			}
+			for _, change := range changes {
+				for _, adminID := range adminIDs {
+					_ = bot.SendRealityChanged(adminID, change)
+				}
+			}
+		}
+	}
+}
+
+func runSubscriptionChecker(ctx context.Context, subscriptionSvc *service.SubscriptionService, alertSvc *service.AlertService, bot *telegram.Bot) {
 	ticker := time.NewTicker(config.SubscriptionCheckInterval)
 	defer ticker.Stop()
 
@@ -294,6 +490,10 @@ func runSubscriptionChecker(ctx context.Context, subscriptionSvc *service.Subscr
 			// Process expired subscriptions
 			if err := subscriptionSvc.ProcessExpiredSubscriptions(ctx); err != nil {
 				log.Printf("Error processing expired subscriptions: %v", err)
+				alertSvc.Fire(ctx, model.AlertKindReconciliation, "expiry", model.AlertSeverityWarning,
+					fmt.Sprintf("Не удалось обработать истёкшие подписки: %v", err))
+			} else {
+				alertSvc.Resolve(ctx, model.AlertKindReconciliation, "expiry")
 			}
 
 			// Send expiration notifications
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -14,6 +15,7 @@ type Config struct {
 	Redis    RedisConfig
 	Telegram TelegramConfig
 	TON      TONConfig
+	Secrets  SecretsConfig
 }
 
 type ServerConfig struct {
@@ -21,6 +23,9 @@ type ServerConfig struct {
 	Environment  string
 	JWTSecret    string
 	AllowOrigins string
+	ProxyHeader  string // header with the client IP when behind a reverse proxy, e.g. X-Forwarded-For
+	// IPs or CIDR ranges of the reverse proxies; ProxyHeader is only read from them
+	TrustedProxies []string
 }
 
 type DatabaseConfig struct {
@@ -40,8 +45,15 @@ type RedisConfig struct {
 }
 
 type TelegramConfig struct {
-	BotToken  string
-	WebAppURL string
+	BotToken    string
+	WebAppURL   string
+	AdminChatID int64 // chat receiving admin alerts, 0 = alerts are only recorded
+
+	// WebhookURL switches the bot from long polling to a webhook: the public
+	// HTTPS URL routed to /webhook/telegram that Telegram posts updates to
+	WebhookURL    string
+	WebhookSecret string // secret_token sent with every update, empty = derived from the bot token
+	WebhookDelete bool   // delete the webhook on shutdown; off by default so other replicas keep receiving updates
 }
 
 type TONConfig struct {
@@ -49,6 +61,14 @@ type TONConfig struct {
 	WalletAddress string
 }
 
+// SecretsConfig holds keys encrypting panel credentials in the database.
+// To rotate, move the current key to CredentialsOldKeys and set a new one;
+// stored values are re-encrypted on startup.
+type SecretsConfig struct {
+	CredentialsKey     string   // base64-encoded 32-byte key, empty = store plaintext
+	CredentialsOldKeys []string // previous keys, used only for decryption
+}
+
 func (d DatabaseConfig) DSN() string {
 	return "postgres://" + d.User + ":" + d.Password + "@" + d.Host + ":" + d.Port + "/" + d.Name + "?sslmode=" + d.SSLMode
 }
@@ -58,13 +78,17 @@ func Load() (*Config, error) {
 
 	redisDB, _ := strconv.Atoi(getEnv("REDIS_DB", "0"))
 	tonTestnet, _ := strconv.ParseBool(getEnv("TON_TESTNET", "true"))
+	adminChatID, _ := strconv.ParseInt(getEnv("TELEGRAM_ADMIN_CHAT_ID", "0"), 10, 64)
+	webhookDelete, _ := strconv.ParseBool(getEnv("TELEGRAM_WEBHOOK_DELETE", "false"))
 
 	cfg := &Config{
 		Server: ServerConfig{
-			Port:         getEnv("SERVER_PORT", "8080"),
-			Environment:  getEnv("ENVIRONMENT", "development"),
-			JWTSecret:    getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
-			AllowOrigins: getEnv("ALLOW_ORIGINS", "*"),
+			Port:           getEnv("SERVER_PORT", "8080"),
+			Environment:    getEnv("ENVIRONMENT", "development"),
+			JWTSecret:      getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
+			AllowOrigins:   getEnv("ALLOW_ORIGINS", "*"),
+			ProxyHeader:    getEnv("PROXY_HEADER", ""),
+			TrustedProxies: getEnvList("TRUSTED_PROXIES"),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -81,13 +105,21 @@ func Load() (*Config, error) {
 			DB:       redisDB,
 		},
 		Telegram: TelegramConfig{
-			BotToken:  getEnv("TELEGRAM_BOT_TOKEN", ""),
-			WebAppURL: getEnv("TELEGRAM_WEBAPP_URL", ""),
+			BotToken:      getEnv("TELEGRAM_BOT_TOKEN", ""),
+			WebAppURL:     getEnv("TELEGRAM_WEBAPP_URL", ""),
+			AdminChatID:   adminChatID,
+			WebhookURL:    getEnv("TELEGRAM_WEBHOOK_URL", ""),
+			WebhookSecret: getEnv("TELEGRAM_WEBHOOK_SECRET", ""),
+			WebhookDelete: webhookDelete,
 		},
 		TON: TONConfig{
 			Testnet:       tonTestnet,
 			WalletAddress: getEnv("TON_WALLET_ADDRESS", ""),
 		},
+		Secrets: SecretsConfig{
+			CredentialsKey:     getEnv("CREDENTIALS_KEY", ""),
+			CredentialsOldKeys: strings.Split(getEnv("CREDENTIALS_OLD_KEYS", ""), ","),
+		},
 	}
 
 	return cfg, nil
@@ -100,9 +132,82 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList returns the non-empty items of a comma-separated variable
+func getEnvList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // Subscription durations
 const (
 	SubscriptionCheckInterval = 1 * time.Hour
 	NotifyBeforeExpiry3Days   = 3 * 24 * time.Hour
 	NotifyBeforeExpiry1Day    = 24 * time.Hour
+
+	// TrafficSyncInterval is how often the traffic of active subscriptions is synced
+	// from the panels and their expiry and quota enforced
+	TrafficSyncInterval = 5 * time.Minute
+
+	// RealityCheckInterval is how often inbound Reality parameters are re-read from the panels
+	RealityCheckInterval = 30 * time.Minute
+)
+
+// Panel circuit breaker
+const (
+	// PanelFailureThreshold is how many consecutive connection failures mark a panel unavailable
+	PanelFailureThreshold = 3
+	// PanelOpenTimeout is how long requests to an unavailable panel fail fast before a probe is sent
+	PanelOpenTimeout = 30 * time.Second
+	// PanelMaxInFlight caps concurrent requests to one server's panel
+	PanelMaxInFlight = 10
+)
+
+// Admin alerts
+const (
+	// AlertRepeatInterval is how often an unacknowledged firing alert is re-sent
+	AlertRepeatInterval = time.Hour
+	// AlertRateLimit caps messages sent to the admin chat per AlertRateWindow
+	AlertRateLimit  = 20
+	AlertRateWindow = time.Minute
+
+	// ServerDownAlertAfter is how many consecutive offline checks raise a server down alert
+	ServerDownAlertAfter = 3
+	// PanelErrorAlertAfter is how many consecutive failed panel checks raise an alert
+	PanelErrorAlertAfter = 3
+	// TonWorkerAlertAfter is how many consecutive failing TON worker runs raise an alert
+	TonWorkerAlertAfter = 5
+	// PaymentStuckAfter is how long a payment may stay in awaiting_tx before it is
+	// reported. It is shorter than the TON payment timeout, so admins hear about
+	// unmatched payments while they can still be completed.
+	PaymentStuckAfter = 7 * time.Minute
+)
+
+// Client latency measured by the mini app
+const (
+	// ClientLatencyTimeoutMs is recorded for a server the user could not reach
+	ClientLatencyTimeoutMs = 3000
+	// ClientLatencyMaxAge is how long a user's or network's measurements are used
+	ClientLatencyMaxAge = 7 * 24 * time.Hour
+	// ClientLatencySmoothing is the weight of a new measurement in the moving average
+	ClientLatencySmoothing = 0.3
+	// ClientLatencyMinNetworkUsers is how many users of a network must have measured
+	// a server before their latencies are used for users of that network without their own
+	ClientLatencyMinNetworkUsers = 3
+	// ClientLatencyReportInterval is the minimum time between reports of one user
+	ClientLatencyReportInterval = time.Minute
+	// ClientLatencyMaxReports caps the servers in one report
+	ClientLatencyMaxReports = 100
+)
+
+// Purchases and balance in the bot chat
+const (
+	// BotChatStateTTL is how long an unfinished purchase in a chat is kept
+	BotChatStateTTL = 30 * time.Minute
+	// BotTransactionsPageSize is how many balance transactions one page of /balance shows
+	BotTransactionsPageSize = 5
 )
@@ -511,16 +511,18 @@ func (h *AdminHandler) ListPlans(c *fiber.Ctx) error {
 }
 
 type UpdatePlanRequest struct {
-	Name         *string  `json:"name,omitempty"`
-	Description  *string  `json:"description,omitempty"`
-	DurationDays *int     `json:"duration_days,omitempty"`
-	TrafficGB    *int     `json:"traffic_gb,omitempty"`
-	MaxDevices   *int     `json:"max_devices,omitempty"`
-	PriceTON     *float64 `json:"price_ton,omitempty"`
-	PriceStars   *int     `json:"price_stars,omitempty"`
-	PriceUSD     *float64 `json:"price_usd,omitempty"`
-	IsActive     *bool    `json:"is_active,omitempty"`
-	SortOrder    *int     `json:"sort_order,omitempty"`
+	Name         *string   `json:"name,omitempty"`
+	Description  *string   `json:"description,omitempty"`
+	DurationDays *int      `json:"duration_days,omitempty"`
+	TrafficGB    *int      `json:"traffic_gb,omitempty"`
+	MaxDevices   *int      `json:"max_devices,omitempty"`
+	PriceTON     *float64  `json:"price_ton,omitempty"`
+	PriceStars   *int      `json:"price_stars,omitempty"`
+	PriceUSD     *float64  `json:"price_usd,omitempty"`
+	IsActive     *bool     `json:"is_active,omitempty"`
+	SortOrder    *int      `json:"sort_order,omitempty"`
+	InboundTags  *[]string `json:"inbound_tags,omitempty"`
+	ServerGroups *[]string `json:"server_groups,omitempty"`
 }
 
 // UpdatePlan updates a plan
@@ -546,6 +548,8 @@ func (h *AdminHandler) UpdatePlan(c *fiber.Ctx) error {
 		PriceUSD:     req.PriceUSD,
 		IsActive:     req.IsActive,
 		SortOrder:    req.SortOrder,
+		InboundTags:  req.InboundTags,
+		ServerGroups: req.ServerGroups,
 	})
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -557,15 +561,17 @@ func (h *AdminHandler) UpdatePlan(c *fiber.Ctx) error {
 }
 
 type CreatePlanRequest struct {
-	Name         string  `json:"name"`
-	Description  string  `json:"description"`
-	DurationDays int     `json:"duration_days"`
-	TrafficGB    int     `json:"traffic_gb"`
-	MaxDevices   int     `json:"max_devices"`
-	PriceTON     float64 `json:"price_ton"`
-	PriceStars   int     `json:"price_stars"`
-	PriceUSD     float64 `json:"price_usd"`
-	SortOrder    int     `json:"sort_order"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	DurationDays int      `json:"duration_days"`
+	TrafficGB    int      `json:"traffic_gb"`
+	MaxDevices   int      `json:"max_devices"`
+	PriceTON     float64  `json:"price_ton"`
+	PriceStars   int      `json:"price_stars"`
+	PriceUSD     float64  `json:"price_usd"`
+	SortOrder    int      `json:"sort_order"`
+	InboundTags  []string `json:"inbound_tags"`
+	ServerGroups []string `json:"server_groups"`
 }
 
 // CreatePlan creates a new plan
@@ -599,6 +605,8 @@ func (h *AdminHandler) CreatePlan(c *fiber.Ctx) error {
 		PriceStars:   req.PriceStars,
 		PriceUSD:     req.PriceUSD,
 		SortOrder:    req.SortOrder,
+		InboundTags:  req.InboundTags,
+		ServerGroups: req.ServerGroups,
 	})
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -782,3 +790,22 @@ func (h *AdminHandler) SetRegionSwitchPrice(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{"success": true, "region_switch_price": req.Price})
 }
+
+// GetServerSelection returns the server selection strategy and its weights
+func (h *AdminHandler) GetServerSelection(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"server_selection": h.adminSvc.GetServerSelection(c.Context())})
+}
+
+// SetServerSelection sets the server selection strategy and its weights; omitted fields get defaults
+func (h *AdminHandler) SetServerSelection(c *fiber.Ctx) error {
+	adminID := middleware.GetAdminID(c)
+
+	cfg, err := h.adminSvc.SetServerSelection(c.Context(), adminID, c.Body())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{"success": true, "server_selection": cfg})
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/middleware"
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/service"
+)
+
+// AlertHandler lets admins list, acknowledge and mute alerts
+type AlertHandler struct {
+	alertSvc *service.AlertService
+}
+
+// NewAlertHandler creates a new alert handler
+func NewAlertHandler(alertSvc *service.AlertService) *AlertHandler {
+	return &AlertHandler{alertSvc: alertSvc}
+}
+
+// GetAlerts returns alerts, optionally filtered by ?status=firing|resolved
+func (h *AlertHandler) GetAlerts(c *fiber.Ctx) error {
+	_ = middleware.GetAdminID(c)
+
+	status := c.Query("status")
+	if status != "" && status != model.AlertStatusFiring && status != model.AlertStatusResolved {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid status, expected firing or resolved",
+		})
+	}
+
+	alerts, err := h.alertSvc.GetAlerts(c.Context(), status)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if alerts == nil {
+		alerts = []model.Alert{}
+	}
+
+	return c.JSON(fiber.Map{"alerts": alerts})
+}
+
+// AckAlert acknowledges an alert, stopping reminders until it fires again
+func (h *AlertHandler) AckAlert(c *fiber.Ctx) error {
+	adminID := middleware.GetAdminID(c)
+	alertID, err := uuid.Parse(c.Params("alert_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid alert_id",
+		})
+	}
+
+	alert, err := h.alertSvc.Acknowledge(c.Context(), adminID, alertID)
+	if err != nil {
+		return alertError(c, err)
+	}
+
+	return c.JSON(alert)
+}
+
+type MuteAlertRequest struct {
+	Minutes int `json:"minutes"` // 0 unmutes
+}
+
+// MuteAlert suppresses notifications for an alert for the given number of minutes
+func (h *AlertHandler) MuteAlert(c *fiber.Ctx) error {
+	adminID := middleware.GetAdminID(c)
+	alertID, err := uuid.Parse(c.Params("alert_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid alert_id",
+		})
+	}
+
+	var req MuteAlertRequest
+	if err := c.BodyParser(&req); err != nil || req.Minutes < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	alert, err := h.alertSvc.Mute(c.Context(), adminID, alertID, time.Duration(req.Minutes)*time.Minute)
+	if err != nil {
+		return alertError(c, err)
+	}
+
+	return c.JSON(alert)
+}
+
+func alertError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, service.ErrAlertNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "alert not found",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
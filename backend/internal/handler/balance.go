@@ -1,13 +1,16 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/i18n"
 	"github.com/zyvpn/backend/internal/middleware"
 	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/service"
 )
 
 type TopUpRequest struct {
@@ -19,16 +22,12 @@ type TopUpRequest struct {
 func (h *Handler) GetBalance(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	balance, err := h.balanceSvc.GetBalance(c.Context(), userID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Не удалось получить баланс",
-		})
+		return errorResponse(c, fiber.StatusInternalServerError, "internal_error")
 	}
 
 	return c.JSON(fiber.Map{
@@ -41,19 +40,15 @@ func (h *Handler) GetBalance(c *fiber.Ctx) error {
 func (h *Handler) GetBalanceTransactions(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	limit, _ := strconv.Atoi(c.Query("limit", "20"))
 	offset, _ := strconv.Atoi(c.Query("offset", "0"))
 
-	transactions, err := h.balanceSvc.GetTransactions(c.Context(), userID, limit, offset)
+	transactions, err := h.balanceSvc.GetTransactions(c.Context(), userID, middleware.GetLang(c), limit, offset)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Не удалось получить историю операций",
-		})
+		return errorResponse(c, fiber.StatusInternalServerError, "internal_error")
 	}
 
 	return c.JSON(fiber.Map{
@@ -69,73 +64,38 @@ type PayFromBalanceRequest struct {
 func (h *Handler) PayFromBalance(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	var req PayFromBalanceRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный формат запроса",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_request")
 	}
 
 	planID, err := uuid.Parse(req.PlanID)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный ID тарифа",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_plan_id")
 	}
 
 	// Get plan
 	plan, err := h.planService.GetPlan(c.Context(), planID)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Тариф не найден",
-		})
+		return errorResponse(c, fiber.StatusNotFound, "plan_not_found")
 	}
 
-	// Check if user can afford
-	canAfford, err := h.balanceSvc.CanAfford(c.Context(), userID, plan.PriceTON)
+	// Debit balance and create/extend subscription
+	_, newBalance, err := h.paymentSvc.PayFromBalance(c.Context(), userID, plan.ID, nil)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Не удалось проверить баланс",
-		})
-	}
-
-	if !canAfford {
-		balance, _ := h.balanceSvc.GetBalance(c.Context(), userID)
-		return c.Status(fiber.StatusPaymentRequired).JSON(fiber.Map{
-			"error":    "Недостаточно средств",
-			"balance":  balance,
-			"required": plan.PriceTON,
-		})
-	}
-
-	// Create payment record
-	payment, err := h.paymentSvc.CreatePayment(c.Context(), userID, plan.ID, model.PaymentProviderBalance)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Не удалось создать платёж",
-		})
-	}
-
-	// Debit balance
-	newBalance, err := h.balanceSvc.DebitForSubscription(c.Context(), userID, plan.PriceTON, payment.ID)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to debit balance: " + err.Error(),
-		})
-	}
-
-	// Complete payment and create/extend subscription
-	if err := h.paymentSvc.CompletePayment(c.Context(), payment.ID); err != nil {
-		// Refund on failure
-		h.balanceSvc.CreditRefund(c.Context(), userID, plan.PriceTON, payment.ID)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to complete payment: " + err.Error(),
-		})
+		if errors.Is(err, service.ErrInsufficientBalance) {
+			balance, _ := h.balanceSvc.GetBalance(c.Context(), userID)
+			return c.Status(fiber.StatusPaymentRequired).JSON(fiber.Map{
+				"error":    i18n.T(middleware.GetLang(c), "insufficient_balance"),
+				"code":     "insufficient_balance",
+				"balance":  balance,
+				"required": plan.PriceTON,
+			})
+		}
+		return serviceError(c, fiber.StatusInternalServerError, err)
 	}
 
 	// Get subscription key
@@ -160,35 +120,25 @@ func (h *Handler) PayFromBalance(c *fiber.Ctx) error {
 func (h *Handler) InitTopUp(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	var req TopUpRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный формат запроса",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_request")
 	}
 
 	if req.Amount <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Сумма должна быть положительной",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_amount")
 	}
 
 	if req.Provider != model.PaymentProviderTON && req.Provider != model.PaymentProviderStars {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Способ оплаты должен быть 'ton' или 'stars'",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_payment_provider")
 	}
 
 	payment, err := h.paymentSvc.CreateTopUpPayment(c.Context(), userID, req.Amount, req.Provider)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to create payment: " + err.Error(),
-		})
+		return serviceError(c, fiber.StatusInternalServerError, fmt.Errorf("failed to create payment: %w", err))
 	}
 
 	return c.JSON(fiber.Map{
@@ -203,30 +153,22 @@ func (h *Handler) InitTopUp(c *fiber.Ctx) error {
 func (h *Handler) GetTopUpTONInfo(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	paymentIDStr := c.Query("payment_id")
 	if paymentIDStr == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Отсутствует ID платежа",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "missing_payment_id")
 	}
 
 	paymentID, err := uuid.Parse(paymentIDStr)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный ID платежа",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_payment_id")
 	}
 
 	tonInfo, err := h.paymentSvc.GetTONTopUpInfo(c.Context(), paymentID)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return serviceError(c, fiber.StatusNotFound, err)
 	}
 
 	return c.JSON(tonInfo)
@@ -241,42 +183,30 @@ type VerifyTopUpRequest struct {
 func (h *Handler) InitTopUpStars(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	paymentIDStr := c.Query("payment_id")
 	if paymentIDStr == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Отсутствует ID платежа",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "missing_payment_id")
 	}
 
 	paymentID, err := uuid.Parse(paymentIDStr)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный ID платежа",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_payment_id")
 	}
 
 	payment, err := h.paymentSvc.GetPayment(c.Context(), paymentID)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Платёж не найден",
-		})
+		return errorResponse(c, fiber.StatusNotFound, "payment_not_found")
 	}
 
 	if payment.PaymentType != model.PaymentTypeTopUp {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Платёж не является пополнением",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "payment_not_top_up")
 	}
 
 	if h.bot == nil {
-		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-			"error": "Сервис оплаты недоступен",
-		})
+		return errorResponse(c, fiber.StatusServiceUnavailable, "payment_unavailable")
 	}
 
 	// Calculate TON amount for description
@@ -288,15 +218,13 @@ func (h *Handler) InitTopUpStars(c *fiber.Ctx) error {
 	// Create invoice via bot
 	invoiceLink, err := h.bot.CreateStarsInvoice(
 		userID,
-		"Пополнение баланса",
-		fmt.Sprintf("Пополнение баланса на %.4f TON", tonAmount),
+		i18n.T(middleware.GetLang(c), "invoice_top_up_title"),
+		i18n.T(middleware.GetLang(c), "invoice_top_up_description", tonAmount),
 		int(payment.Amount),
 		payment.ID.String(),
 	)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to create invoice: " + err.Error(),
-		})
+		return serviceError(c, fiber.StatusInternalServerError, fmt.Errorf("failed to create invoice: %w", err))
 	}
 
 	return c.JSON(fiber.Map{
@@ -311,29 +239,21 @@ func (h *Handler) InitTopUpStars(c *fiber.Ctx) error {
 func (h *Handler) VerifyTopUp(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	var req VerifyTopUpRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный формат запроса",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_request")
 	}
 
 	paymentID, err := uuid.Parse(req.PaymentID)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный ID платежа",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_payment_id")
 	}
 
 	if err := h.paymentSvc.CompleteTopUpPayment(c.Context(), paymentID, req.TxHash); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return serviceError(c, fiber.StatusBadRequest, err)
 	}
 
 	// Get updated balance
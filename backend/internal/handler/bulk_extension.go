@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/middleware"
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/service"
+)
+
+type BulkExtendRequest struct {
+	Days          int        `json:"days"`
+	ServerID      *uuid.UUID `json:"server_id"`
+	PlanID        *uuid.UUID `json:"plan_id"`
+	CreatedBefore *time.Time `json:"created_before"`
+	Notify        bool       `json:"notify"`
+}
+
+// StartBulkExtension extends all active subscriptions matching a filter in the background
+func (h *AdminHandler) StartBulkExtension(c *fiber.Ctx) error {
+	adminID := middleware.GetAdminID(c)
+
+	var req BulkExtendRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	filter := model.BulkExtensionFilter{
+		ServerID:      req.ServerID,
+		PlanID:        req.PlanID,
+		CreatedBefore: req.CreatedBefore,
+	}
+	job, err := h.adminSvc.StartBulkExtension(c.Context(), adminID, filter, req.Days, req.Notify)
+	if err != nil {
+		return bulkExtensionError(c, err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// ListBulkExtensions returns recent bulk extension jobs
+func (h *AdminHandler) ListBulkExtensions(c *fiber.Ctx) error {
+	jobs, err := h.adminSvc.ListBulkExtensions(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if jobs == nil {
+		jobs = []model.BulkExtensionJob{}
+	}
+
+	return c.JSON(fiber.Map{"jobs": jobs})
+}
+
+// GetBulkExtension returns the progress of a bulk extension job
+func (h *AdminHandler) GetBulkExtension(c *fiber.Ctx) error {
+	jobID, err := uuid.Parse(c.Params("job_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid job_id",
+		})
+	}
+
+	report, err := h.adminSvc.GetBulkExtension(c.Context(), jobID)
+	if err != nil {
+		return bulkExtensionError(c, err)
+	}
+
+	return c.JSON(report)
+}
+
+// RetryBulkExtension retries the subscriptions a finished job failed to extend
+func (h *AdminHandler) RetryBulkExtension(c *fiber.Ctx) error {
+	adminID := middleware.GetAdminID(c)
+	jobID, err := uuid.Parse(c.Params("job_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid job_id",
+		})
+	}
+
+	job, err := h.adminSvc.RetryBulkExtension(c.Context(), adminID, jobID)
+	if err != nil {
+		return bulkExtensionError(c, err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+func bulkExtensionError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidBulkExtension):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, service.ErrBulkJobNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "bulk extension job not found",
+		})
+	case errors.Is(err, service.ErrBulkJobRunning):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
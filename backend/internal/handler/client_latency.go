@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/zyvpn/backend/internal/middleware"
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/service"
+)
+
+type ReportLatencyRequest struct {
+	Results []model.LatencyReport `json:"results"`
+}
+
+// ReportLatency records server latencies measured by the mini app on the user's device
+func (h *ServerHandler) ReportLatency(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	var req ReportLatencyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_request")
+	}
+
+	if err := h.serverSvc.ReportLatency(c.Context(), userID, c.IP(), req.Results); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidLatencyReport):
+			return serviceError(c, fiber.StatusBadRequest, err)
+		case errors.Is(err, service.ErrLatencyReportTooSoon):
+			return errorResponse(c, fiber.StatusTooManyRequests, "too_many_requests")
+		}
+		return serviceError(c, fiber.StatusInternalServerError, err)
+	}
+
+	return c.JSON(fiber.Map{"success": true})
+}
@@ -1,8 +1,15 @@
 package handler
 
 import (
+	"errors"
+	"math"
+	"strconv"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/zyvpn/backend/internal/config"
+	"github.com/zyvpn/backend/internal/i18n"
+	"github.com/zyvpn/backend/internal/middleware"
+	"github.com/zyvpn/backend/internal/panel"
 	"github.com/zyvpn/backend/internal/service"
 	"github.com/zyvpn/backend/internal/telegram"
 )
@@ -49,6 +56,68 @@ func New(
 	}
 }
 
+// panelUnavailable responds 503 with Retry-After for a panel.UnavailableError
+func panelUnavailable(c *fiber.Ctx, err error) error {
+	var unavailable *panel.UnavailableError
+	if errors.As(err, &unavailable) && unavailable.RetryAfter > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(unavailable.RetryAfter.Seconds()))))
+	}
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+		"error":             i18n.T(middleware.GetLang(c), "server_unavailable"),
+		"code":              "server_unavailable",
+		"panel_unavailable": true,
+	})
+}
+
+// serverUnavailable responds to a chosen server that cannot take new subscriptions:
+// its panel is down, it is full, the plan does not include it or it does not exist
+func serverUnavailable(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, panel.ErrUnavailable):
+		return panelUnavailable(c, err)
+	case errors.Is(err, service.ErrServerFull):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":       i18n.Message(middleware.GetLang(c), err),
+			"code":        i18n.Code(err),
+			"server_full": true,
+		})
+	case errors.Is(err, service.ErrServerLocked):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":            i18n.Message(middleware.GetLang(c), err),
+			"code":             i18n.Code(err),
+			"upgrade_required": true,
+		})
+	case errors.Is(err, service.ErrServerNotFound):
+		return errorResponse(c, fiber.StatusNotFound, "server_not_found")
+	}
+	return serviceError(c, fiber.StatusInternalServerError, err)
+}
+
+// errorResponse responds with the text of an error code in the user's language
+// and the code itself
+func errorResponse(c *fiber.Ctx, status int, code string) error {
+	return c.Status(status).JSON(fiber.Map{
+		"error": i18n.T(middleware.GetLang(c), code),
+		"code":  code,
+	})
+}
+
+// serviceError responds with an error returned by a service. User-facing errors
+// keep their code; any other error keeps its own text under a generic code.
+func serviceError(c *fiber.Ctx, status int, err error) error {
+	if code := i18n.Code(err); code != "" {
+		return errorResponse(c, status, code)
+	}
+	code := "invalid_request"
+	if status >= fiber.StatusInternalServerError {
+		code = "internal_error"
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+		"code":  code,
+	})
+}
+
 func (h *Handler) Health(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
 		"status": "ok",
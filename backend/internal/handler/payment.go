@@ -1,10 +1,12 @@
 package handler
 
 import (
+	"fmt"
 	"log"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/i18n"
 	"github.com/zyvpn/backend/internal/middleware"
 )
 
@@ -16,30 +18,22 @@ type VerifyTONPaymentRequest struct {
 func (h *Handler) InitTONPayment(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	paymentIDStr := c.Query("payment_id")
 	if paymentIDStr == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Отсутствует ID платежа",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "missing_payment_id")
 	}
 
 	paymentID, err := uuid.Parse(paymentIDStr)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный ID платежа",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_payment_id")
 	}
 
 	tonInfo, err := h.paymentSvc.GetTONPaymentInfo(c.Context(), paymentID)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Платёж не найден",
-		})
+		return errorResponse(c, fiber.StatusNotFound, "payment_not_found")
 	}
 
 	return c.JSON(tonInfo)
@@ -48,29 +42,21 @@ func (h *Handler) InitTONPayment(c *fiber.Ctx) error {
 func (h *Handler) VerifyTONPayment(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	var req VerifyTONPaymentRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный формат запроса",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_request")
 	}
 
 	paymentID, err := uuid.Parse(req.PaymentID)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный ID платежа",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_payment_id")
 	}
 
 	if err := h.paymentSvc.VerifyTONPayment(c.Context(), paymentID, req.TxHash); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return serviceError(c, fiber.StatusBadRequest, err)
 	}
 
 	// Get subscription key for response
@@ -93,52 +79,38 @@ func (h *Handler) VerifyTONPayment(c *fiber.Ctx) error {
 func (h *Handler) RefundStarsPayment(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	paymentIDStr := c.Query("payment_id")
 	if paymentIDStr == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Отсутствует ID платежа",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "missing_payment_id")
 	}
 
 	paymentID, err := uuid.Parse(paymentIDStr)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный ID платежа",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_payment_id")
 	}
 
 	// Get telegram charge ID
 	paymentUserID, chargeID, err := h.paymentSvc.GetTelegramChargeID(c.Context(), paymentID)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return serviceError(c, fiber.StatusNotFound, err)
 	}
 
 	// Verify user owns this payment
 	if paymentUserID != userID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Доступ запрещён",
-		})
+		return errorResponse(c, fiber.StatusForbidden, "access_denied")
 	}
 
 	if h.bot == nil {
-		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-			"error": "Сервис возврата недоступен",
-		})
+		return errorResponse(c, fiber.StatusServiceUnavailable, "refund_unavailable")
 	}
 
 	// Refund via Telegram API
 	if err := h.bot.RefundStarsPayment(userID, chargeID); err != nil {
 		log.Printf("Failed to refund Stars payment: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to refund: " + err.Error(),
-		})
+		return serviceError(c, fiber.StatusInternalServerError, fmt.Errorf("failed to refund: %w", err))
 	}
 
 	// Update payment status
@@ -148,7 +120,7 @@ func (h *Handler) RefundStarsPayment(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{
 		"success": true,
-		"message": "Возврат успешно обработан",
+		"message": i18n.T(middleware.GetLang(c), "refund_completed"),
 	})
 }
 
@@ -156,37 +128,27 @@ func (h *Handler) RefundStarsPayment(c *fiber.Ctx) error {
 func (h *Handler) GetPaymentStatus(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	paymentIDStr := c.Query("payment_id")
 	if paymentIDStr == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Отсутствует ID платежа",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "missing_payment_id")
 	}
 
 	paymentID, err := uuid.Parse(paymentIDStr)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный ID платежа",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_payment_id")
 	}
 
 	payment, err := h.paymentSvc.GetPaymentStatus(c.Context(), paymentID)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Платёж не найден",
-		})
+		return errorResponse(c, fiber.StatusNotFound, "payment_not_found")
 	}
 
 	// Verify user owns this payment
 	if payment.UserID != userID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Доступ запрещён",
-		})
+		return errorResponse(c, fiber.StatusForbidden, "access_denied")
 	}
 
 	response := fiber.Map{
@@ -214,50 +176,36 @@ func (h *Handler) GetPaymentStatus(c *fiber.Ctx) error {
 func (h *Handler) InitStarsPayment(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	paymentIDStr := c.Query("payment_id")
 	if paymentIDStr == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Отсутствует ID платежа",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "missing_payment_id")
 	}
 
 	paymentID, err := uuid.Parse(paymentIDStr)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный ID платежа",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_payment_id")
 	}
 
 	payment, err := h.paymentSvc.GetPayment(c.Context(), paymentID)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Платёж не найден",
-		})
+		return errorResponse(c, fiber.StatusNotFound, "payment_not_found")
 	}
 
 	if payment.PlanID == nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Платёж не привязан к тарифу",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "payment_without_plan")
 	}
 
 	// Get plan for title/description
 	plan, err := h.planService.GetPlan(c.Context(), *payment.PlanID)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Тариф не найден",
-		})
+		return errorResponse(c, fiber.StatusNotFound, "plan_not_found")
 	}
 
 	if h.bot == nil {
-		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-			"error": "Сервис оплаты недоступен",
-		})
+		return errorResponse(c, fiber.StatusServiceUnavailable, "payment_unavailable")
 	}
 
 	// Create invoice via bot
@@ -271,9 +219,7 @@ func (h *Handler) InitStarsPayment(c *fiber.Ctx) error {
 	)
 	if err != nil {
 		log.Printf("Failed to create Stars invoice: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to create invoice: " + err.Error(),
-		})
+		return serviceError(c, fiber.StatusInternalServerError, fmt.Errorf("failed to create invoice: %w", err))
 	}
 	log.Printf("Stars invoice created: %s", invoiceLink)
 
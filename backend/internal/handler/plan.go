@@ -7,9 +7,7 @@ import (
 func (h *Handler) GetPlans(c *fiber.Ctx) error {
 	plans, err := h.planService.GetActivePlans(c.Context())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to get plans",
-		})
+		return errorResponse(c, fiber.StatusInternalServerError, "internal_error")
 	}
 
 	return c.JSON(fiber.Map{
@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/zyvpn/backend/internal/i18n"
 	"github.com/zyvpn/backend/internal/middleware"
 	"github.com/zyvpn/backend/internal/service"
 )
@@ -16,22 +17,16 @@ type ApplyPromoCodeRequest struct {
 func (h *Handler) ApplyPromoCode(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	var req ApplyPromoCodeRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный формат запроса",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_request")
 	}
 
 	if req.Code == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Введите промокод",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "promo_code_required")
 	}
 
 	result, err := h.promoCodeSvc.ApplyPromoCode(c.Context(), req.Code, userID)
@@ -40,9 +35,7 @@ func (h *Handler) ApplyPromoCode(c *fiber.Ctx) error {
 		if errors.Is(err, service.ErrPromoCodeNotFound) {
 			status = fiber.StatusNotFound
 		}
-		return c.Status(status).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return serviceError(c, status, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -50,7 +43,7 @@ func (h *Handler) ApplyPromoCode(c *fiber.Ctx) error {
 		"type":        result.Type,
 		"value":       result.Value,
 		"new_balance": result.NewBalance,
-		"message":     result.Message,
+		"message":     result.LocalizedMessage(middleware.GetLang(c)),
 	})
 }
 
@@ -58,16 +51,12 @@ func (h *Handler) ApplyPromoCode(c *fiber.Ctx) error {
 func (h *Handler) ValidatePromoCode(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	code := c.Query("code")
 	if code == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Введите промокод",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "promo_code_required")
 	}
 
 	promo, err := h.promoCodeSvc.ValidatePromoCode(c.Context(), code, userID)
@@ -77,7 +66,8 @@ func (h *Handler) ValidatePromoCode(c *fiber.Ctx) error {
 			status = fiber.StatusNotFound
 		}
 		return c.Status(status).JSON(fiber.Map{
-			"error": err.Error(),
+			"error": i18n.Message(middleware.GetLang(c), err),
+			"code":  i18n.Code(err),
 			"valid": false,
 		})
 	}
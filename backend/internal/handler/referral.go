@@ -2,6 +2,7 @@ package handler
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/zyvpn/backend/internal/i18n"
 	"github.com/zyvpn/backend/internal/middleware"
 )
 
@@ -12,16 +13,12 @@ type ApplyReferralRequest struct {
 func (h *Handler) GetReferralStats(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	stats, err := h.referralSvc.GetReferralStats(c.Context(), userID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Не удалось получить статистику",
-		})
+		return errorResponse(c, fiber.StatusInternalServerError, "internal_error")
 	}
 
 	return c.JSON(stats)
@@ -30,9 +27,7 @@ func (h *Handler) GetReferralStats(c *fiber.Ctx) error {
 func (h *Handler) GetReferralLink(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	// Get bot username from config or hardcode
@@ -40,17 +35,13 @@ func (h *Handler) GetReferralLink(c *fiber.Ctx) error {
 
 	link, err := h.referralSvc.GetReferralLink(c.Context(), userID, botUsername)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Не удалось получить ссылку",
-		})
+		return errorResponse(c, fiber.StatusInternalServerError, "internal_error")
 	}
 
 	// Get user's referral code
 	user, err := h.userService.GetUser(c.Context(), userID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Не удалось получить пользователя",
-		})
+		return errorResponse(c, fiber.StatusInternalServerError, "internal_error")
 	}
 
 	return c.JSON(fiber.Map{
@@ -62,49 +53,37 @@ func (h *Handler) GetReferralLink(c *fiber.Ctx) error {
 func (h *Handler) ApplyReferralCode(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	var req ApplyReferralRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный формат запроса",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_request")
 	}
 
 	if req.Code == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Введите код",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "referral_code_required")
 	}
 
 	if err := h.referralSvc.ApplyReferralCode(c.Context(), userID, req.Code); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return serviceError(c, fiber.StatusBadRequest, err)
 	}
 
 	return c.JSON(fiber.Map{
 		"success": true,
-		"message": "Реферальный код применён! Бонусные дни начислятся после первой оплаты.",
+		"message": i18n.T(middleware.GetLang(c), "referral_applied"),
 	})
 }
 
 func (h *Handler) GetReferredUsers(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	users, err := h.referralSvc.GetReferredUsers(c.Context(), userID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Не удалось получить список пользователей",
-		})
+		return errorResponse(c, fiber.StatusInternalServerError, "internal_error")
 	}
 
 	return c.JSON(fiber.Map{
@@ -1,32 +1,58 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/zyvpn/backend/internal/middleware"
 	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/panel"
 	"github.com/zyvpn/backend/internal/service"
+	"github.com/zyvpn/backend/internal/telegram"
 )
 
 // ServerHandler handles server-related requests
 type ServerHandler struct {
-	serverSvc *service.ServerService
+	serverSvc       *service.ServerService
+	subscriptionSvc *service.SubscriptionService
+	bot             *telegram.Bot
 }
 
 // NewServerHandler creates a new server handler
-func NewServerHandler(serverSvc *service.ServerService) *ServerHandler {
-	return &ServerHandler{serverSvc: serverSvc}
+func NewServerHandler(serverSvc *service.ServerService, subscriptionSvc *service.SubscriptionService, bot *telegram.Bot) *ServerHandler {
+	return &ServerHandler{
+		serverSvc:       serverSvc,
+		subscriptionSvc: subscriptionSvc,
+		bot:             bot,
+	}
 }
 
 // --- User Endpoints ---
 
-// GetServers returns list of available servers for users
+// GetServers returns list of available servers for users. Servers outside the
+// groups of the user's plan, or of the plan given by ?plan_id, are marked locked.
 func (h *ServerHandler) GetServers(c *fiber.Ctx) error {
-	servers, err := h.serverSvc.GetActiveServers(c.Context())
+	userID := middleware.GetUserID(c)
+
+	var planID *uuid.UUID
+	if q := c.Query("plan_id"); q != "" {
+		id, err := uuid.Parse(q)
+		if err != nil {
+			return errorResponse(c, fiber.StatusBadRequest, "invalid_plan_id")
+		}
+		planID = &id
+	}
+
+	servers, err := h.serverSvc.GetServersForUser(c.Context(), userID, planID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		if errors.Is(err, service.ErrPlanNotFound) {
+			return errorResponse(c, fiber.StatusNotFound, "plan_not_found")
+		}
+		return serviceError(c, fiber.StatusInternalServerError, err)
 	}
 	return c.JSON(fiber.Map{"servers": servers})
 }
@@ -62,7 +88,7 @@ func (h *ServerHandler) GetServer(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(server.ToAdmin())
+	return c.JSON(h.serverSvc.ToAdmin(server))
 }
 
 type CreateServerRequest struct {
@@ -70,10 +96,12 @@ type CreateServerRequest struct {
 	Country       string  `json:"country"`
 	City          *string `json:"city,omitempty"`
 	FlagEmoji     string  `json:"flag_emoji"`
+	PanelType     string  `json:"panel_type"`
 	XUIBaseURL    string  `json:"xui_base_url"`
 	XUIUsername   string  `json:"xui_username"`
 	XUIPassword   string  `json:"xui_password"`
 	XUIInboundID  int     `json:"xui_inbound_id"`
+	InboundTag    string  `json:"inbound_tag"`
 	ServerAddress string  `json:"server_address"`
 	ServerPort    int     `json:"server_port"`
 	PublicKey     string  `json:"public_key"`
@@ -82,6 +110,11 @@ type CreateServerRequest struct {
 	IsActive      bool    `json:"is_active"`
 	SortOrder     int     `json:"sort_order"`
 	Capacity      int     `json:"capacity"`
+	MaxOnline     int     `json:"max_online"`
+	BandwidthMbps int     `json:"bandwidth_mbps"`
+	Group         string  `json:"group"`
+	MonthlyCost   float64 `json:"monthly_cost"`
+	CostCurrency  string  `json:"cost_currency"`
 }
 
 // CreateServer creates a new server
@@ -113,8 +146,19 @@ func (h *ServerHandler) CreateServer(c *fiber.Ctx) error {
 		})
 	}
 
-	if req.ServerPort <= 0 {
-		req.ServerPort = 443
+	if req.PanelType == "" {
+		req.PanelType = model.PanelTypeXUI
+	}
+	if !validPanelType(req.PanelType) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "panel_type must be 'xui', 'marzban', 'outline' or 'xray'",
+		})
+	}
+
+	if req.PanelType == model.PanelTypeXray && req.InboundTag == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "inbound_tag is required for xray servers",
+		})
 	}
 
 	if req.XUIInboundID <= 0 {
@@ -125,15 +169,32 @@ func (h *ServerHandler) CreateServer(c *fiber.Ctx) error {
 		req.Capacity = 100
 	}
 
+	req.Group = strings.ToLower(strings.TrimSpace(req.Group))
+	if req.Group == "" {
+		req.Group = model.ServerGroupStandard
+	}
+
+	req.CostCurrency = strings.ToUpper(strings.TrimSpace(req.CostCurrency))
+	if req.CostCurrency == "" {
+		req.CostCurrency = model.CostCurrencyUSD
+	}
+	if req.MonthlyCost < 0 || !model.IsCostCurrency(req.CostCurrency) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "monthly_cost must not be negative and cost_currency must be 'USD', 'RUB' or 'TON'",
+		})
+	}
+
 	server := &model.Server{
 		Name:          req.Name,
 		Country:       req.Country,
 		City:          req.City,
 		FlagEmoji:     req.FlagEmoji,
+		PanelType:     req.PanelType,
 		XUIBaseURL:    req.XUIBaseURL,
 		XUIUsername:   req.XUIUsername,
 		XUIPassword:   req.XUIPassword,
 		XUIInboundID:  req.XUIInboundID,
+		InboundTag:    req.InboundTag,
 		ServerAddress: req.ServerAddress,
 		ServerPort:    req.ServerPort,
 		PublicKey:     req.PublicKey,
@@ -141,7 +202,19 @@ func (h *ServerHandler) CreateServer(c *fiber.Ctx) error {
 		ServerName:    req.ServerName,
 		IsActive:      req.IsActive,
 		SortOrder:     req.SortOrder,
+		Group:         req.Group,
 		Capacity:      req.Capacity,
+		MaxOnline:     req.MaxOnline,
+		BandwidthMbps: req.BandwidthMbps,
+		MonthlyCost:   req.MonthlyCost,
+		CostCurrency:  req.CostCurrency,
+	}
+
+	// Fill the port and Reality parameters from the panel inbound;
+	// an unreachable panel does not block adding the server
+	_, realityErr := h.serverSvc.DiscoverReality(c.Context(), server)
+	if server.ServerPort <= 0 {
+		server.ServerPort = 443
 	}
 
 	if err := h.serverSvc.CreateServer(c.Context(), server); err != nil {
@@ -150,29 +223,40 @@ func (h *ServerHandler) CreateServer(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(server.ToAdmin())
+	return c.JSON(serverAdminResponse(h.serverSvc.ToAdmin(server), realityErr))
 }
 
 type UpdateServerRequest struct {
-	Name          *string `json:"name,omitempty"`
-	Country       *string `json:"country,omitempty"`
-	City          *string `json:"city,omitempty"`
-	FlagEmoji     *string `json:"flag_emoji,omitempty"`
-	XUIBaseURL    *string `json:"xui_base_url,omitempty"`
-	XUIUsername   *string `json:"xui_username,omitempty"`
-	XUIPassword   *string `json:"xui_password,omitempty"`
-	XUIInboundID  *int    `json:"xui_inbound_id,omitempty"`
-	ServerAddress *string `json:"server_address,omitempty"`
-	ServerPort    *int    `json:"server_port,omitempty"`
-	PublicKey     *string `json:"public_key,omitempty"`
-	ShortID       *string `json:"short_id,omitempty"`
-	ServerName    *string `json:"server_name,omitempty"`
-	IsActive      *bool   `json:"is_active,omitempty"`
-	SortOrder     *int    `json:"sort_order,omitempty"`
-	Capacity      *int    `json:"capacity,omitempty"`
+	Name          *string  `json:"name,omitempty"`
+	Country       *string  `json:"country,omitempty"`
+	City          *string  `json:"city,omitempty"`
+	FlagEmoji     *string  `json:"flag_emoji,omitempty"`
+	PanelType     *string  `json:"panel_type,omitempty"`
+	XUIBaseURL    *string  `json:"xui_base_url,omitempty"`
+	XUIUsername   *string  `json:"xui_username,omitempty"`
+	XUIPassword   *string  `json:"xui_password,omitempty"`
+	XUIInboundID  *int     `json:"xui_inbound_id,omitempty"`
+	InboundTag    *string  `json:"inbound_tag,omitempty"`
+	ServerAddress *string  `json:"server_address,omitempty"`
+	ServerPort    *int     `json:"server_port,omitempty"`
+	PublicKey     *string  `json:"public_key,omitempty"`
+	ShortID       *string  `json:"short_id,omitempty"`
+	ServerName    *string  `json:"server_name,omitempty"`
+	IsActive      *bool    `json:"is_active,omitempty"`
+	SortOrder     *int     `json:"sort_order,omitempty"`
+	Capacity      *int     `json:"capacity,omitempty"`
+	MaxOnline     *int     `json:"max_online,omitempty"`
+	BandwidthMbps *int     `json:"bandwidth_mbps,omitempty"`
+	Group         *string  `json:"group,omitempty"`
+	MonthlyCost   *float64 `json:"monthly_cost,omitempty"`
+	CostCurrency  *string  `json:"cost_currency,omitempty"`
+
+	// NotifyUsers sends affected users their new key when connection parameters change
+	NotifyUsers bool `json:"notify_users,omitempty"`
 }
 
-// UpdateServer updates a server
+// UpdateServer updates a server. When the address, port or Reality parameters change,
+// the connection keys of all subscriptions on the server are regenerated in the background.
 func (h *ServerHandler) UpdateServer(c *fiber.Ctx) error {
 	_ = middleware.GetAdminID(c)
 	serverID, err := uuid.Parse(c.Params("server_id"))
@@ -182,7 +266,8 @@ func (h *ServerHandler) UpdateServer(c *fiber.Ctx) error {
 		})
 	}
 
-	server, err := h.serverSvc.GetServer(c.Context(), serverID)
+	// A server whose password cannot be decrypted is loaded too, so a new one can be set
+	server, err := h.serverSvc.GetServerForAdmin(c.Context(), serverID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "server not found",
@@ -209,18 +294,30 @@ func (h *ServerHandler) UpdateServer(c *fiber.Ctx) error {
 	if req.FlagEmoji != nil {
 		server.FlagEmoji = *req.FlagEmoji
 	}
+	if req.PanelType != nil {
+		if !validPanelType(*req.PanelType) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "panel_type must be 'xui', 'marzban', 'outline' or 'xray'",
+			})
+		}
+		server.PanelType = *req.PanelType
+	}
 	if req.XUIBaseURL != nil {
 		server.XUIBaseURL = *req.XUIBaseURL
 	}
 	if req.XUIUsername != nil {
 		server.XUIUsername = *req.XUIUsername
 	}
-	if req.XUIPassword != nil {
+	if req.XUIPassword != nil && *req.XUIPassword != model.MaskedSecret {
 		server.XUIPassword = *req.XUIPassword
+		server.XUIPasswordUnreadable = false
 	}
 	if req.XUIInboundID != nil {
 		server.XUIInboundID = *req.XUIInboundID
 	}
+	if req.InboundTag != nil {
+		server.InboundTag = *req.InboundTag
+	}
 	if req.ServerAddress != nil {
 		server.ServerAddress = *req.ServerAddress
 	}
@@ -245,14 +342,88 @@ func (h *ServerHandler) UpdateServer(c *fiber.Ctx) error {
 	if req.Capacity != nil {
 		server.Capacity = *req.Capacity
 	}
-
-	if err := h.serverSvc.UpdateServer(c.Context(), server); err != nil {
+	if req.MaxOnline != nil {
+		server.MaxOnline = *req.MaxOnline
+	}
+	if req.BandwidthMbps != nil {
+		server.BandwidthMbps = *req.BandwidthMbps
+	}
+	if req.Group != nil {
+		group := strings.ToLower(strings.TrimSpace(*req.Group))
+		if group == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "group must not be empty",
+			})
+		}
+		server.Group = group
+	}
+	if req.MonthlyCost != nil {
+		if *req.MonthlyCost < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "monthly_cost must not be negative",
+			})
+		}
+		server.MonthlyCost = *req.MonthlyCost
+	}
+	if req.CostCurrency != nil {
+		currency := strings.ToUpper(strings.TrimSpace(*req.CostCurrency))
+		if !model.IsCostCurrency(currency) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "cost_currency must be 'USD', 'RUB' or 'TON'",
+			})
+		}
+		server.CostCurrency = currency
+	}
+
+	// Re-read the inbound so cleared fields are filled again and mismatches are flagged
+	_, realityErr := h.serverSvc.DiscoverReality(c.Context(), server)
+
+	paramsChanged, err := h.serverSvc.UpdateServer(c.Context(), server)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
+	if paramsChanged {
+		go h.regenerateKeys(server.ID, req.NotifyUsers)
+	}
+
+	return c.JSON(serverAdminResponse(h.serverSvc.ToAdmin(server), realityErr))
+}
+
+// serverAdminResponse adds the error of reading the inbound from the panel, if any
+func serverAdminResponse(server model.ServerAdmin, realityErr error) model.ServerAdmin {
+	if realityErr != nil {
+		server.RealityError = realityErr.Error()
+	}
+	return server
+}
 
-	return c.JSON(server.ToAdmin())
+// regenerateKeys rebuilds the connection keys of a server's subscriptions and optionally sends users their new key
+func (h *ServerHandler) regenerateKeys(serverID uuid.UUID, notify bool) {
+	ctx := context.Background()
+
+	subs, err := h.subscriptionSvc.RegenerateConnectionKeys(ctx, serverID)
+	if err != nil {
+		log.Printf("Failed to regenerate connection keys for server %s: %v", serverID, err)
+	}
+	if !notify || h.bot == nil {
+		return
+	}
+
+	for _, sub := range subs {
+		if err := h.bot.SendConnectionKeyChanged(sub.UserID, sub.ConnectionKey); err != nil {
+			log.Printf("Failed to send new key to user %d: %v", sub.UserID, err)
+		}
+	}
+}
+
+func validPanelType(panelType string) bool {
+	switch panelType {
+	case model.PanelTypeXUI, model.PanelTypeMarzban, model.PanelTypeOutline, model.PanelTypeXray:
+		return true
+	}
+	return false
 }
 
 // DeleteServer deletes a server
@@ -274,7 +445,33 @@ func (h *ServerHandler) DeleteServer(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true})
 }
 
-// TestServerConnection tests connection to a server's XUI panel
+// RevealServerCredentials returns the panel credentials of a server (superadmin only, audited)
+func (h *ServerHandler) RevealServerCredentials(c *fiber.Ctx) error {
+	adminID := middleware.GetAdminID(c)
+	serverID, err := uuid.Parse(c.Params("server_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server_id",
+		})
+	}
+
+	creds, err := h.serverSvc.RevealCredentials(c.Context(), adminID, serverID)
+	if err != nil {
+		if errors.Is(err, service.ErrInsufficientPerms) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(creds)
+}
+
+// TestServerConnection tests connection to a server's panel
 func (h *ServerHandler) TestServerConnection(c *fiber.Ctx) error {
 	_ = middleware.GetAdminID(c)
 	serverID, err := uuid.Parse(c.Params("server_id"))
@@ -284,7 +481,10 @@ func (h *ServerHandler) TestServerConnection(c *fiber.Ctx) error {
 		})
 	}
 
-	client, _, err := h.serverSvc.GetXUIClient(c.Context(), serverID)
+	// An explicit test always reaches the panel, even while its breaker is open
+	h.serverSvc.ResetPanel(serverID)
+
+	client, server, err := h.serverSvc.GetPanel(c.Context(), serverID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -293,7 +493,7 @@ func (h *ServerHandler) TestServerConnection(c *fiber.Ctx) error {
 	}
 
 	// Try to get inbound info to verify connection
-	info, err := client.GetInboundInfo()
+	info, err := client.GetInboundInfo(c.Context())
 	if err != nil {
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
 			"connected": false,
@@ -303,9 +503,318 @@ func (h *ServerHandler) TestServerConnection(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{
 		"connected":   true,
+		"panel_type":  server.PanelType,
+		"protocol":    info.Protocol,
+		"network":     info.Network,
+		"security":    info.Security,
 		"port":        info.Port,
 		"public_key":  info.PublicKey,
 		"short_id":    info.ShortID,
 		"server_name": info.ServerName,
 	})
 }
+
+// --- Health ---
+
+// GetServersHealth returns uptime and latency of all servers over ?period=24h|7d|30d
+func (h *ServerHandler) GetServersHealth(c *fiber.Ctx) error {
+	_ = middleware.GetAdminID(c)
+
+	reports, err := h.serverSvc.GetHealthSummary(c.Context(), c.Query("period"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidHealthPeriod) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{"servers": reports})
+}
+
+// GetServerHealth returns uptime, latency percentiles, incidents and the latest checks of a server
+func (h *ServerHandler) GetServerHealth(c *fiber.Ctx) error {
+	_ = middleware.GetAdminID(c)
+	serverID, err := uuid.Parse(c.Params("server_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server_id",
+		})
+	}
+
+	report, err := h.serverSvc.GetServerHealth(c.Context(), serverID, c.Query("period"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidHealthPeriod) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, service.ErrServerNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "server not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(report)
+}
+
+// --- Additional inbounds ---
+
+type ServerInboundRequest struct {
+	XUIInboundID *int    `json:"xui_inbound_id,omitempty"`
+	Tag          *string `json:"tag,omitempty"`
+	Name         *string `json:"name,omitempty"`
+	IsActive     *bool   `json:"is_active,omitempty"`
+	SortOrder    *int    `json:"sort_order,omitempty"`
+}
+
+// GetServerInbounds returns additional inbounds of a server
+func (h *ServerHandler) GetServerInbounds(c *fiber.Ctx) error {
+	_ = middleware.GetAdminID(c)
+	serverID, err := uuid.Parse(c.Params("server_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server_id",
+		})
+	}
+
+	inbounds, err := h.serverSvc.GetServerInbounds(c.Context(), serverID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if inbounds == nil {
+		inbounds = []model.ServerInbound{}
+	}
+
+	return c.JSON(fiber.Map{"inbounds": inbounds})
+}
+
+// CreateServerInbound adds an additional inbound to a server
+func (h *ServerHandler) CreateServerInbound(c *fiber.Ctx) error {
+	_ = middleware.GetAdminID(c)
+	serverID, err := uuid.Parse(c.Params("server_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server_id",
+		})
+	}
+
+	server, err := h.serverSvc.GetServer(c.Context(), serverID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "server not found",
+		})
+	}
+
+	var req ServerInboundRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if req.XUIInboundID == nil || *req.XUIInboundID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "xui_inbound_id is required",
+		})
+	}
+	if server.PanelType != "" && server.PanelType != model.PanelTypeXUI {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "additional inbounds are only supported for xui servers",
+		})
+	}
+	if *req.XUIInboundID == server.XUIInboundID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "xui_inbound_id is already the primary inbound of this server",
+		})
+	}
+	if req.Tag == nil || *req.Tag == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "tag is required",
+		})
+	}
+
+	inbound := &model.ServerInbound{
+		ServerID:     server.ID,
+		XUIInboundID: *req.XUIInboundID,
+		Tag:          *req.Tag,
+		IsActive:     true,
+	}
+	if req.Name != nil {
+		inbound.Name = *req.Name
+	}
+	if req.IsActive != nil {
+		inbound.IsActive = *req.IsActive
+	}
+	if req.SortOrder != nil {
+		inbound.SortOrder = *req.SortOrder
+	}
+
+	// Verify the inbound exists on the panel before saving
+	client, err := h.serverSvc.GetPanelForInbound(server, inbound.XUIInboundID)
+	if err == nil {
+		_, err = client.GetInboundInfo(c.Context())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "failed to load inbound from panel: " + err.Error(),
+		})
+	}
+
+	if err := h.serverSvc.CreateServerInbound(c.Context(), inbound); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(inbound)
+}
+
+// UpdateServerInbound updates an additional inbound
+func (h *ServerHandler) UpdateServerInbound(c *fiber.Ctx) error {
+	_ = middleware.GetAdminID(c)
+	inbound, err := h.getServerInbound(c)
+	if err != nil {
+		return err
+	}
+
+	var req ServerInboundRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if req.XUIInboundID != nil {
+		inbound.XUIInboundID = *req.XUIInboundID
+	}
+	if req.Tag != nil && *req.Tag != "" {
+		inbound.Tag = *req.Tag
+	}
+	if req.Name != nil {
+		inbound.Name = *req.Name
+	}
+	if req.IsActive != nil {
+		inbound.IsActive = *req.IsActive
+	}
+	if req.SortOrder != nil {
+		inbound.SortOrder = *req.SortOrder
+	}
+
+	if err := h.serverSvc.UpdateServerInbound(c.Context(), inbound); err != nil {
+		if errors.Is(err, service.ErrInvalidInbound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, service.ErrInboundInUse) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(inbound)
+}
+
+// DeleteServerInbound deletes an additional inbound
+func (h *ServerHandler) DeleteServerInbound(c *fiber.Ctx) error {
+	_ = middleware.GetAdminID(c)
+	inbound, err := h.getServerInbound(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.serverSvc.DeleteServerInbound(c.Context(), inbound); err != nil {
+		if errors.Is(err, panel.ErrUnavailable) {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// getServerInbound loads the inbound referenced by route params
+func (h *ServerHandler) getServerInbound(c *fiber.Ctx) (*model.ServerInbound, error) {
+	serverID, err := uuid.Parse(c.Params("server_id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "invalid server_id")
+	}
+	inboundID, err := uuid.Parse(c.Params("inbound_id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "invalid inbound_id")
+	}
+
+	inbound, err := h.serverSvc.GetServerInbound(c.Context(), inboundID)
+	if err != nil || inbound.ServerID != serverID {
+		return nil, fiber.NewError(fiber.StatusNotFound, "inbound not found")
+	}
+	return inbound, nil
+}
+
+// GetServerLoad returns the live load and subscription count of a server with its load samples over ?period=24h|7d|30d
+func (h *ServerHandler) GetServerLoad(c *fiber.Ctx) error {
+	_ = middleware.GetAdminID(c)
+	serverID, err := uuid.Parse(c.Params("server_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server_id",
+		})
+	}
+
+	report, err := h.serverSvc.GetServerLoad(c.Context(), serverID, c.Query("period"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidHealthPeriod) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, service.ErrServerNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "server not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(report)
+}
+
+// GetProfitability returns revenue, cost, margin, users and traffic of every server
+// per month for ?from=YYYY-MM&to=YYYY-MM (default: the last three months)
+func (h *ServerHandler) GetProfitability(c *fiber.Ctx) error {
+	_ = middleware.GetAdminID(c)
+
+	report, err := h.serverSvc.GetProfitabilityReport(c.Context(), c.Query("from"), c.Query("to"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidReportPeriod) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(report)
+}
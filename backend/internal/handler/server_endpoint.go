@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/middleware"
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/service"
+)
+
+type ServerEndpointRequest struct {
+	Address   *string `json:"address,omitempty"`
+	Port      *int    `json:"port,omitempty"`
+	Label     *string `json:"label,omitempty"`
+	SortOrder *int    `json:"sort_order,omitempty"`
+}
+
+// GetServerEndpoints returns all endpoints of a server with their health
+func (h *ServerHandler) GetServerEndpoints(c *fiber.Ctx) error {
+	_ = middleware.GetAdminID(c)
+	serverID, err := uuid.Parse(c.Params("server_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server_id",
+		})
+	}
+
+	endpoints, err := h.serverSvc.GetServerEndpoints(c.Context(), serverID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if endpoints == nil {
+		endpoints = []model.ServerEndpoint{}
+	}
+
+	return c.JSON(fiber.Map{"endpoints": endpoints})
+}
+
+// CreateServerEndpoint adds an address the server is reachable at
+func (h *ServerHandler) CreateServerEndpoint(c *fiber.Ctx) error {
+	_ = middleware.GetAdminID(c)
+	serverID, err := uuid.Parse(c.Params("server_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server_id",
+		})
+	}
+
+	server, err := h.serverSvc.GetServer(c.Context(), serverID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "server not found",
+		})
+	}
+
+	var req ServerEndpointRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	endpoint := &model.ServerEndpoint{ServerID: server.ID}
+	applyEndpointRequest(endpoint, &req)
+
+	if err := h.serverSvc.CreateServerEndpoint(c.Context(), endpoint); err != nil {
+		return endpointError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(endpoint)
+}
+
+// UpdateServerEndpoint updates an endpoint's address, port, label or order
+func (h *ServerHandler) UpdateServerEndpoint(c *fiber.Ctx) error {
+	_ = middleware.GetAdminID(c)
+	endpoint, err := h.getServerEndpoint(c)
+	if err != nil {
+		return err
+	}
+
+	var req ServerEndpointRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+	applyEndpointRequest(endpoint, &req)
+
+	if err := h.serverSvc.UpdateServerEndpoint(c.Context(), endpoint); err != nil {
+		return endpointError(c, err)
+	}
+
+	return c.JSON(endpoint)
+}
+
+// RetireServerEndpoint removes an endpoint from subscription keys without touching subscriptions
+func (h *ServerHandler) RetireServerEndpoint(c *fiber.Ctx) error {
+	_ = middleware.GetAdminID(c)
+	endpoint, err := h.getServerEndpoint(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.serverSvc.RetireServerEndpoint(c.Context(), endpoint); err != nil {
+		return endpointError(c, err)
+	}
+
+	return c.JSON(endpoint)
+}
+
+// RestoreServerEndpoint offers a retired endpoint again
+func (h *ServerHandler) RestoreServerEndpoint(c *fiber.Ctx) error {
+	_ = middleware.GetAdminID(c)
+	endpoint, err := h.getServerEndpoint(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.serverSvc.RestoreServerEndpoint(c.Context(), endpoint); err != nil {
+		return endpointError(c, err)
+	}
+
+	return c.JSON(endpoint)
+}
+
+// DeleteServerEndpoint deletes an endpoint
+func (h *ServerHandler) DeleteServerEndpoint(c *fiber.Ctx) error {
+	_ = middleware.GetAdminID(c)
+	endpoint, err := h.getServerEndpoint(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.serverSvc.DeleteServerEndpoint(c.Context(), endpoint); err != nil {
+		return endpointError(c, err)
+	}
+
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// getServerEndpoint loads the endpoint referenced by route params
+func (h *ServerHandler) getServerEndpoint(c *fiber.Ctx) (*model.ServerEndpoint, error) {
+	serverID, err := uuid.Parse(c.Params("server_id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "invalid server_id")
+	}
+	endpointID, err := uuid.Parse(c.Params("endpoint_id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "invalid endpoint_id")
+	}
+
+	endpoint, err := h.serverSvc.GetServerEndpoint(c.Context(), endpointID)
+	if err != nil || endpoint.ServerID != serverID {
+		return nil, fiber.NewError(fiber.StatusNotFound, "endpoint not found")
+	}
+	return endpoint, nil
+}
+
+func applyEndpointRequest(endpoint *model.ServerEndpoint, req *ServerEndpointRequest) {
+	if req.Address != nil {
+		endpoint.Address = *req.Address
+	}
+	if req.Port != nil {
+		endpoint.Port = *req.Port
+	}
+	if req.Label != nil {
+		endpoint.Label = *req.Label
+	}
+	if req.SortOrder != nil {
+		endpoint.SortOrder = *req.SortOrder
+	}
+}
+
+func endpointError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidServerEndpoint):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, service.ErrServerEndpointExists):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "the server already has an endpoint with this address and port",
+		})
+	case errors.Is(err, service.ErrServerEndpointNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "endpoint not found",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/middleware"
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/service"
+)
+
+// StatusHandler serves the public status page and admin status notices
+type StatusHandler struct {
+	statusSvc *service.StatusService
+}
+
+// NewStatusHandler creates a new status handler
+func NewStatusHandler(statusSvc *service.StatusService) *StatusHandler {
+	return &StatusHandler{statusSvc: statusSvc}
+}
+
+// GetStatus returns the public service status (no auth required)
+func (h *StatusHandler) GetStatus(c *fiber.Ctx) error {
+	page, err := h.statusSvc.GetStatusPage(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to get status",
+		})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "public, max-age=30")
+	return c.JSON(page)
+}
+
+// --- Admin notices ---
+
+type StatusNoticeRequest struct {
+	Kind        *string    `json:"kind,omitempty"`
+	Title       *string    `json:"title,omitempty"`
+	Message     *string    `json:"message,omitempty"`
+	ServerID    *uuid.UUID `json:"server_id,omitempty"`
+	StartsAt    *time.Time `json:"starts_at,omitempty"`
+	EndsAt      *time.Time `json:"ends_at,omitempty"`
+	ClearServer bool       `json:"clear_server,omitempty"`
+}
+
+func (r *StatusNoticeRequest) params() service.StatusNoticeParams {
+	return service.StatusNoticeParams{
+		Kind:        r.Kind,
+		Title:       r.Title,
+		Message:     r.Message,
+		ServerID:    r.ServerID,
+		StartsAt:    r.StartsAt,
+		EndsAt:      r.EndsAt,
+		ClearServer: r.ClearServer,
+	}
+}
+
+// GetNotices returns the latest status notices
+func (h *StatusHandler) GetNotices(c *fiber.Ctx) error {
+	_ = middleware.GetAdminID(c)
+
+	notices, err := h.statusSvc.GetNotices(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if notices == nil {
+		notices = []model.StatusNotice{}
+	}
+
+	return c.JSON(fiber.Map{"notices": notices})
+}
+
+// CreateNotice posts an incident or maintenance notice on the status page
+func (h *StatusHandler) CreateNotice(c *fiber.Ctx) error {
+	adminID := middleware.GetAdminID(c)
+
+	var req StatusNoticeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	notice, err := h.statusSvc.CreateNotice(c.Context(), adminID, req.params())
+	if err != nil {
+		return noticeError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(notice)
+}
+
+// UpdateNotice changes a status notice
+func (h *StatusHandler) UpdateNotice(c *fiber.Ctx) error {
+	adminID := middleware.GetAdminID(c)
+	noticeID, err := uuid.Parse(c.Params("notice_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid notice_id",
+		})
+	}
+
+	var req StatusNoticeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	notice, err := h.statusSvc.UpdateNotice(c.Context(), adminID, noticeID, req.params())
+	if err != nil {
+		return noticeError(c, err)
+	}
+
+	return c.JSON(notice)
+}
+
+// ResolveNotice ends a status notice now
+func (h *StatusHandler) ResolveNotice(c *fiber.Ctx) error {
+	adminID := middleware.GetAdminID(c)
+	noticeID, err := uuid.Parse(c.Params("notice_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid notice_id",
+		})
+	}
+
+	notice, err := h.statusSvc.ResolveNotice(c.Context(), adminID, noticeID)
+	if err != nil {
+		return noticeError(c, err)
+	}
+
+	return c.JSON(notice)
+}
+
+// DeleteNotice removes a status notice
+func (h *StatusHandler) DeleteNotice(c *fiber.Ctx) error {
+	adminID := middleware.GetAdminID(c)
+	noticeID, err := uuid.Parse(c.Params("notice_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid notice_id",
+		})
+	}
+
+	if err := h.statusSvc.DeleteNotice(c.Context(), adminID, noticeID); err != nil {
+		return noticeError(c, err)
+	}
+
+	return c.JSON(fiber.Map{"success": true})
+}
+
+func noticeError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidStatusNotice):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, service.ErrStatusNoticeNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "notice not found",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
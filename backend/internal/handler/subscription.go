@@ -2,11 +2,16 @@ package handler
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/i18n"
 	"github.com/zyvpn/backend/internal/middleware"
 	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/panel"
+	"github.com/zyvpn/backend/internal/qr"
 	"github.com/zyvpn/backend/internal/service"
 )
 
@@ -19,23 +24,17 @@ type BuySubscriptionRequest struct {
 func (h *Handler) BuySubscription(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	var req BuySubscriptionRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный формат запроса",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_request")
 	}
 
 	planID, err := uuid.Parse(req.PlanID)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный ID тарифа",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_plan_id")
 	}
 
 	// Parse optional server ID
@@ -43,9 +42,7 @@ func (h *Handler) BuySubscription(c *fiber.Ctx) error {
 	if req.ServerID != nil && *req.ServerID != "" {
 		sid, err := uuid.Parse(*req.ServerID)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Неверный ID сервера",
-			})
+			return errorResponse(c, fiber.StatusBadRequest, "invalid_server_id")
 		}
 		serverID = &sid
 	}
@@ -57,25 +54,23 @@ func (h *Handler) BuySubscription(c *fiber.Ctx) error {
 	case "stars":
 		provider = model.PaymentProviderStars
 	default:
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный способ оплаты, выберите 'ton' или 'stars'",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_payment_provider")
 	}
 
 	payment, err := h.paymentSvc.CreatePaymentWithServer(c.Context(), userID, planID, serverID, provider)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to create payment: " + err.Error(),
-		})
+		if errors.Is(err, panel.ErrUnavailable) || errors.Is(err, service.ErrServerFull) ||
+			errors.Is(err, service.ErrServerLocked) || errors.Is(err, service.ErrServerNotFound) {
+			return serverUnavailable(c, err)
+		}
+		return serviceError(c, fiber.StatusInternalServerError, fmt.Errorf("failed to create payment: %w", err))
 	}
 
 	// Return payment info based on provider
 	if provider == model.PaymentProviderTON {
 		tonInfo, err := h.paymentSvc.GetTONPaymentInfo(c.Context(), payment.ID)
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Не удалось получить информацию о платеже",
-			})
+			return errorResponse(c, fiber.StatusInternalServerError, "internal_error")
 		}
 		return c.JSON(fiber.Map{
 			"payment":  payment,
@@ -92,29 +87,83 @@ func (h *Handler) BuySubscription(c *fiber.Ctx) error {
 func (h *Handler) GetSubscriptionKey(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	keys, err := h.subscriptionSvc.GetConnectionKeys(c.Context(), userID, middleware.GetLang(c))
+	if errors.Is(err, service.ErrNoActiveEndpoints) {
+		return serviceError(c, fiber.StatusServiceUnavailable, err)
+	}
+	if err != nil || len(keys) == 0 {
+		return errorResponse(c, fiber.StatusNotFound, "no_active_subscription")
+	}
+
+	result := fiber.Map{
+		"key":  keys[0].Key,
+		"keys": keys,
+	}
+
+	// Subscription URL, where the server's panel serves one
+	if url, err := h.subscriptionSvc.GetSubscriptionURL(c.Context(), userID); err == nil && url != "" {
+		result["subscription_url"] = url
+	}
+
+	return c.JSON(result)
+}
+
+// GetSubscriptionKeyQR returns a connection key rendered as a QR code PNG.
+// The optional index query parameter selects one of the keys returned by GetSubscriptionKey;
+// type=subscription renders the subscription URL instead.
+func (h *Handler) GetSubscriptionKeyQR(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
-	key, err := h.subscriptionSvc.GetConnectionKey(c.Context(), userID)
+	keys, err := h.subscriptionSvc.GetConnectionKeys(c.Context(), userID, middleware.GetLang(c))
+	if errors.Is(err, service.ErrNoActiveEndpoints) {
+		return serviceError(c, fiber.StatusServiceUnavailable, err)
+	}
+	if err != nil || len(keys) == 0 || keys[0].Key == "" {
+		return errorResponse(c, fiber.StatusNotFound, "no_active_subscription")
+	}
+
+	var content string
+	if c.Query("type") == "subscription" {
+		content, err = h.subscriptionSvc.GetSubscriptionURL(c.Context(), userID)
+		if err != nil {
+			if errors.Is(err, panel.ErrUnavailable) {
+				return panelUnavailable(c, err)
+			}
+			return serviceError(c, fiber.StatusInternalServerError, err)
+		}
+		if content == "" {
+			return errorResponse(c, fiber.StatusNotFound, "no_subscription_url")
+		}
+	} else {
+		index, _ := strconv.Atoi(c.Query("index", "0"))
+		if index < 0 || index >= len(keys) {
+			return errorResponse(c, fiber.StatusNotFound, "key_not_found")
+		}
+		content = keys[index].Key
+	}
+
+	size, _ := strconv.Atoi(c.Query("size", "0"))
+
+	png, err := qr.PNG(content, size)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Нет активной подписки",
-		})
+		return errorResponse(c, fiber.StatusInternalServerError, "internal_error")
 	}
 
-	return c.JSON(fiber.Map{
-		"key": key,
-	})
+	c.Set(fiber.HeaderContentType, "image/png")
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Send(png)
 }
 
 func (h *Handler) GetSubscriptionStatus(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	sub, err := h.subscriptionSvc.GetActiveSubscription(c.Context(), userID)
@@ -124,10 +173,7 @@ func (h *Handler) GetSubscriptionStatus(c *fiber.Ctx) error {
 		})
 	}
 
-	// Sync traffic
-	_ = h.subscriptionSvc.SyncTraffic(c.Context(), sub.ID)
-	sub, _ = h.subscriptionSvc.GetSubscription(c.Context(), sub.ID)
-
+	// Traffic is synced from the panel by the traffic worker
 	return c.JSON(fiber.Map{
 		"active":         sub.IsActive(),
 		"subscription":   sub,
@@ -143,26 +189,24 @@ func (h *Handler) GetSubscriptionStatus(c *fiber.Ctx) error {
 func (h *Handler) ActivateTrial(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	sub, err := h.subscriptionSvc.ActivateTrial(c.Context(), userID)
 	if err != nil {
 		if errors.Is(err, service.ErrTrialAlreadyUsed) {
-			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-				"error": "Пробный период уже использован",
-			})
+			return errorResponse(c, fiber.StatusConflict, "trial_already_used")
 		}
 		if errors.Is(err, service.ErrSubscriptionActive) {
-			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-				"error": "У вас уже есть активная подписка",
-			})
+			return errorResponse(c, fiber.StatusConflict, "subscription_active")
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		if errors.Is(err, panel.ErrUnavailable) {
+			return panelUnavailable(c, err)
+		}
+		if errors.Is(err, service.ErrServerFull) || errors.Is(err, service.ErrServerLocked) {
+			return serverUnavailable(c, err)
+		}
+		return serviceError(c, fiber.StatusInternalServerError, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -180,17 +224,13 @@ type SwitchServerRequest struct {
 func (h *Handler) GetSwitchServerInfo(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	// Get user for free switches count
 	user, err := h.userService.GetUser(c.Context(), userID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Не удалось получить данные пользователя",
-		})
+		return errorResponse(c, fiber.StatusInternalServerError, "internal_error")
 	}
 
 	// Get region switch price
@@ -209,31 +249,28 @@ func (h *Handler) GetSwitchServerInfo(c *fiber.Ctx) error {
 func (h *Handler) SwitchServer(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	var req SwitchServerRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный формат запроса",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_request")
 	}
 
 	serverID, err := uuid.Parse(req.ServerID)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Неверный ID сервера",
-		})
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_server_id")
+	}
+
+	// Refuse before charging if the target server's panel is known to be down or the server is full
+	if err := h.subscriptionSvc.CheckSwitchAvailable(c.Context(), userID, serverID); err != nil {
+		return serverUnavailable(c, err)
 	}
 
 	// Check if user has free switches
 	usedFree, err := h.userService.UseFreeRegionSwitch(c.Context(), userID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Ошибка при проверке бесплатных переключений",
-		})
+		return errorResponse(c, fiber.StatusInternalServerError, "internal_error")
 	}
 
 	// If no free switch available, charge from balance
@@ -249,27 +286,28 @@ func (h *Handler) SwitchServer(c *fiber.Ctx) error {
 		if err != nil {
 			if errors.Is(err, service.ErrInsufficientBalance) {
 				return c.Status(fiber.StatusPaymentRequired).JSON(fiber.Map{
-					"error":          "Недостаточно средств на балансе",
+					"error":          i18n.T(middleware.GetLang(c), "insufficient_balance"),
+					"code":           "insufficient_balance",
 					"price":          price,
 					"need_more":      true,
 				})
 			}
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": err.Error(),
-			})
+			return serviceError(c, fiber.StatusInternalServerError, err)
 		}
 	}
 
 	sub, err := h.subscriptionSvc.SwitchServer(c.Context(), userID, serverID)
 	if err != nil {
 		if errors.Is(err, service.ErrSubscriptionNotActive) {
-			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-				"error": "Нет активной подписки",
-			})
+			return errorResponse(c, fiber.StatusConflict, "no_active_subscription")
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		if errors.Is(err, panel.ErrUnavailable) {
+			return panelUnavailable(c, err)
+		}
+		if errors.Is(err, service.ErrServerFull) {
+			return serverUnavailable(c, err)
+		}
+		return serviceError(c, fiber.StatusInternalServerError, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"errors"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/zyvpn/backend/internal/middleware"
 	"github.com/zyvpn/backend/internal/service"
@@ -9,9 +11,7 @@ import (
 func (h *Handler) GetMe(c *fiber.Ctx) error {
 	telegramUser := middleware.GetTelegramUser(c)
 	if telegramUser == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Необходима авторизация",
-		})
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	// Get or create user
@@ -23,18 +23,52 @@ func (h *Handler) GetMe(c *fiber.Ctx) error {
 		LanguageCode: &telegramUser.LanguageCode,
 	})
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Не удалось получить пользователя",
-		})
+		return errorResponse(c, fiber.StatusInternalServerError, "internal_error")
 	}
 
 	// Get user with subscription
 	userWithSub, err := h.userService.GetUserWithSubscription(c.Context(), user.ID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Не удалось получить данные пользователя",
-		})
+		return errorResponse(c, fiber.StatusInternalServerError, "internal_error")
 	}
 
 	return c.JSON(userWithSub)
 }
+
+type SetLanguageRequest struct {
+	Language string `json:"language"`
+}
+
+// SetLanguage sets the language of bot messages and API errors for the current
+// user. An empty language goes back to the Telegram client language.
+func (h *Handler) SetLanguage(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return errorResponse(c, fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	var req SetLanguageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errorResponse(c, fiber.StatusBadRequest, "invalid_request")
+	}
+
+	if err := h.userService.SetLanguage(c.Context(), userID, req.Language); err != nil {
+		status := fiber.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrUnsupportedLanguage):
+			status = fiber.StatusBadRequest
+		case errors.Is(err, service.ErrUserNotFound):
+			status = fiber.StatusNotFound
+		}
+		return serviceError(c, status, err)
+	}
+
+	var languageCode string
+	if telegramUser := middleware.GetTelegramUser(c); telegramUser != nil {
+		languageCode = telegramUser.LanguageCode
+	}
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"language": h.userService.Language(c.Context(), userID, languageCode),
+	})
+}
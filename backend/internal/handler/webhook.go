@@ -1,12 +1,29 @@
 package handler
 
 import (
+	"errors"
+	"log"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/zyvpn/backend/internal/telegram"
 )
 
-// TelegramWebhook is deprecated - we use long polling instead
+// TelegramWebhook receives bot updates when the bot runs in webhook mode
 func (h *Handler) TelegramWebhook(c *fiber.Ctx) error {
-	// Long polling is used instead of webhooks
+	if h.bot == nil || !h.bot.WebhookEnabled() {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	if err := h.bot.HandleWebhookUpdate(c.Get(telegram.WebhookSecretHeader), c.Body()); err != nil {
+		if errors.Is(err, telegram.ErrInvalidWebhookSecret) {
+			log.Printf("Rejected Telegram webhook request from %s: %v", c.IP(), err)
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	return c.SendStatus(fiber.StatusOK)
 }
 
@@ -29,7 +46,7 @@ func (h *Handler) TONWebhook(c *fiber.Ctx) error {
 }
 
 func (h *Handler) StarsWebhook(c *fiber.Ctx) error {
-	// This is handled by the Telegram bot via long polling
+	// This is handled by the Telegram bot
 	// Successful payments come as regular Telegram updates
 
 	return c.SendStatus(fiber.StatusOK)
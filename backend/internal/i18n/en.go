@@ -0,0 +1,316 @@
+package i18n
+
+// en is the English catalog
+var en = map[string]string{
+	// Errors
+	"internal_error":            "Internal error, please try again later",
+	"unauthorized":              "Authorization required",
+	"invalid_init_data":         "Invalid Telegram authorization data",
+	"account_banned":            "Your account is blocked",
+	"ip_banned":                 "Access from this IP address is blocked",
+	"access_denied":             "Access denied",
+	"invalid_request":           "Invalid request format",
+	"too_many_requests":         "Too many requests, please try again later",
+	"unsupported_language":      "Language is not supported",
+	"invalid_plan_id":           "Invalid plan ID",
+	"invalid_server_id":         "Invalid server ID",
+	"invalid_payment_id":        "Invalid payment ID",
+	"missing_payment_id":        "Payment ID is missing",
+	"invalid_payment_provider":  "Invalid payment method, choose 'ton' or 'stars'",
+	"invalid_amount":            "Amount must be positive",
+	"plan_not_found":            "Plan not found",
+	"server_not_found":          "Server not found",
+	"payment_not_found":         "Payment not found",
+	"key_not_found":             "Key not found",
+	"no_subscription_url":       "Subscription URL not found",
+	"no_active_endpoints":       "The server has no working addresses right now. Switch to another server.",
+	"user_not_found":            "User not found",
+	"no_active_subscription":    "No active subscription",
+	"subscription_active":       "You already have an active subscription",
+	"subscription_not_active":   "Subscription is not active",
+	"trial_already_used":        "Free trial has already been used",
+	"no_servers_available":      "No servers available",
+	"server_full":               "Server is full, choose another one",
+	"server_locked":             "This location is not included in your plan",
+	"server_unavailable":        "Server is temporarily unavailable, please try again later",
+	"payment_already_complete":  "Payment is already completed",
+	"payment_not_pending":       "Payment is not awaiting payment",
+	"payment_not_top_up":        "Payment is not a top-up",
+	"payment_without_plan":      "Payment is not linked to a plan",
+	"payment_unavailable":       "Payments are unavailable",
+	"refund_unavailable":        "Refunds are unavailable",
+	"insufficient_balance":      "Insufficient balance",
+	"promo_code_required":       "Enter a promo code",
+	"promo_code_not_found":      "Promo code not found",
+	"promo_code_expired":        "Promo code has expired",
+	"promo_code_usage_limit":    "Promo code usage limit reached",
+	"promo_code_already_used":   "You have already used this promo code",
+	"promo_code_inactive":       "Promo code is inactive",
+	"no_subscription_to_extend": "No active subscription to extend",
+	"referral_code_required":    "Enter a code",
+	"referral_exists":           "Referral already exists",
+	"self_referral":             "You cannot invite yourself",
+	"not_admin":                 "User is not an administrator",
+	"already_banned":            "User is already blocked",
+	"not_banned":                "User is not blocked",
+	"insufficient_permissions":  "Insufficient permissions",
+
+	// API messages
+	"refund_completed":              "Refund completed",
+	"referral_applied":              "Referral code applied! Bonus days are credited after your first payment.",
+	"promo_applied_balance":         "%.4f TON has been added to your balance",
+	"promo_applied_extended":        "Your subscription has been extended by %d days",
+	"promo_applied_activated":       "A %d-day subscription has been activated for you",
+	"promo_applied_region_switch":   "You received 1 free region switch",
+	"promo_applied_region_switches": "You received %d free region switches",
+	"invoice_top_up_title":          "Balance top-up",
+	"invoice_top_up_description":    "Balance top-up of %.4f TON",
+
+	// Bot buttons
+	"btn_trial":        "🎁 Try for free (3 days)",
+	"btn_open_shop":    "📱 Open store",
+	"btn_status":       "📊 Subscription status",
+	"btn_get_key":      "🔑 Get key",
+	"btn_choose_plan":  "📱 Choose a plan",
+	"btn_extend":       "📱 Extend subscription",
+	"btn_open_miniapp": "📱 Open Mini App",
+	"btn_invite_more":  "👥 Invite more",
+
+	// Bot messages
+	"bot_start": `Hi, %s! 👋
+
+🔐 <b>ZyVPN</b> is a fast and secure VPN
+
+✅ VLESS + Reality protocol
+✅ High speed
+✅ Unlimited plans
+✅ Easy setup
+
+Tap the button below to choose a plan and pay for a subscription.`,
+	"bot_start_referred": "\n\n🎁 You were invited by a friend! Your friend gets a bonus after your first payment.",
+	"bot_status_none": `❌ <b>You have no active subscription</b>
+
+Tap the button below to choose a plan.`,
+	"bot_status_active": `✅ <b>Subscription is active</b>
+
+📅 Valid until: %s
+📊 Traffic: %s
+⏳ Days left: %d`,
+	"bot_traffic_limited":   "%.2f / %.0f GB",
+	"bot_traffic_unlimited": "%.2f GB (unlimited)",
+	"bot_key_none": `❌ <b>Key unavailable</b>
+
+You have no active subscription. Subscribe to get a connection key.`,
+	"bot_key": `🔑 <b>Your connection key:</b>
+
+<code>%s</code>
+
+📱 Copy the key and paste it into an app:
+• iOS: Streisand, V2Box
+• Android: V2rayNG, NekoBox
+• Windows/Mac: Nekoray, V2rayN
+
+📷 Or scan the QR code above from another device.`,
+	"bot_key_qr":               "📷 QR code of your connection key",
+	"bot_key_fallback":         "🛡 <b>Backup keys</b> — use them if the main key does not connect:\n",
+	"bot_key_subscription_url": "🔗 <b>Subscription URL</b> — add it to your app to receive all keys automatically:\n<code>%s</code>",
+	"bot_help": `📖 <b>ZyVPN help</b>
+
+<b>🔧 Setting up the VPN:</b>
+
+1️⃣ <b>Choose an app:</b>
+• iOS: Streisand, V2Box
+• Android: V2rayNG, NekoBox
+• Windows/Mac: Nekoray, V2rayN
+
+2️⃣ Install the app
+
+3️⃣ Get your key: /key or the Mini App
+
+4️⃣ Paste the key into the app
+
+5️⃣ Connect!
+
+<b>🎁 Promo codes:</b>
+Send /promo CODE or open the Mini App → Balance → Enter a promo code
+
+<b>📱 Commands:</b>
+/start — Main menu
+/status — Subscription status
+/status_servers — Server status
+/key — Get key
+/buy — Buy a subscription
+/balance — Balance and transactions
+/topup — Top up the balance
+/promo — Redeem a promo code
+/trial — Free trial
+/referral — Referral program
+/language — Bot language
+/support — Contact support
+
+❓ Questions? /support`,
+	"bot_support": `💬 <b>Support</b>
+
+If you have questions or problems, write to us:
+
+📧 support@zyvpn.com
+💬 @zyvpn_support
+
+We reply within 24 hours.`,
+	"bot_referral": `🎁 <b>Referral program</b>
+
+Invite friends and get TON on your balance!
+
+When a friend pays for their first subscription:
+• You get +0.1 TON on your balance
+
+📊 <b>Your stats:</b>
+👥 Invited: %d
+⏳ Awaiting payment: %d
+💎 TON earned: %.4f
+
+🔗 <b>Your link:</b>
+<code>%s</code>`,
+	"bot_servers_unavailable":    "Server status is temporarily unavailable",
+	"bot_servers_operational":    "✅ <b>All servers are operational</b>\n",
+	"bot_servers_degraded":       "🟡 <b>Delays are possible</b>\n",
+	"bot_servers_partial_outage": "🟠 <b>Some servers are unavailable</b>\n",
+	"bot_servers_outage":         "🔴 <b>Servers are unavailable</b>\n",
+	"bot_servers_uptime":         "\n<i>Uptime over 24h / 7d</i>\n",
+	"bot_servers_incidents":      "\n⚠️ <b>Incidents</b>\n",
+	"bot_servers_notices":        "\n📢 <b>Announcements</b>\n",
+	"bot_since":                  "since %s",
+	"bot_until":                  " until %s",
+	"bot_trial_used": `❌ <b>Trial already used</b>
+
+You have already used the free trial. Choose a plan to continue.`,
+	"bot_trial_subscription_active": `❌ <b>Subscription already active</b>
+
+You already have an active subscription. Use /status to check it.`,
+	"bot_trial_server_unavailable": `⏳ <b>Server is temporarily unavailable</b>
+
+Try activating the trial again in a few minutes.`,
+	"bot_trial_activated": `✅ <b>Trial activated!</b>
+
+🎁 You have free access for 3 days.
+
+📅 Valid until: %s
+📊 Traffic: 10 GB
+
+Use /key to get your connection key.`,
+	"bot_error":          "❌ Error: %s",
+	"bot_payment_failed": "Failed to process the payment. Please contact support.",
+	"bot_payment_done":   "✅ Payment successful! Use /key to get your key.",
+	"bot_language":       "🌐 Choose a language:",
+	"bot_language_set":   "✅ Bot language: English",
+
+	// Bot purchase flow
+	"btn_buy":             "🛒 Buy a subscription",
+	"btn_promo_code":      "🎁 Enter a promo code",
+	"btn_server_auto":     "⚡ Pick automatically",
+	"btn_pay_stars":       "⭐ Telegram Stars · %d ⭐",
+	"btn_pay_ton":         "💎 TON · %g TON",
+	"btn_pay_balance":     "💰 From balance · %g TON",
+	"btn_pay_invoice":     "⭐ Pay %d ⭐",
+	"btn_open_wallet":     "👛 Open wallet",
+	"btn_ton_paid":        "✅ I have paid",
+	"btn_cancel":          "✖️ Cancel",
+	"bot_buy_plans":       "🛒 <b>Choose a plan</b>",
+	"bot_buy_plan":        "%s · %g TON / %d ⭐",
+	"bot_buy_no_plans":    "No plans are available right now, please try again later.",
+	"bot_buy_servers":     "📦 Plan: <b>%s</b>\n\n🌍 Choose a server:",
+	"bot_buy_methods":     "📦 Plan: <b>%s</b>\n🌍 Server: %s\n\n💳 Choose a payment method:",
+	"bot_buy_server_auto": "automatic",
+	"bot_buy_expired":     "This purchase has expired, start again: /buy",
+	"bot_buy_cancelled":   "Purchase cancelled",
+	"bot_buy_stars":       "⭐ <b>%s</b>\n\nTo pay: <b>%d ⭐</b>\n\nTap the button below to pay.",
+	"bot_buy_ton": `💎 <b>Pay with TON</b>
+
+Send <b>%s TON</b> to the address:
+<code>%s</code>
+
+With the comment:
+<code>%s</code>
+
+Or open the link in your wallet:
+<code>%s</code>
+
+Tap "I have paid" after the transfer.`,
+	"bot_buy_insufficient_balance": `❌ <b>Insufficient balance</b>
+
+Balance: <b>%.4f TON</b>
+Price: <b>%g TON</b>`,
+	"bot_ton_checking":                 "⏳ Checking the payment, this can take a few minutes. We will notify you.",
+	"bot_ton_failed":                   "❌ The TON payment was not found. If you sent the transfer, contact /support.",
+	"bot_promo_enter":                  "🎁 Send the promo code as a message",
+	"bot_promo_applied":                "✅ %s",
+	"invoice_subscription_title":       "ZyVPN: %s",
+	"invoice_subscription_description": "VPN subscription for %d days",
+
+	// Bot balance
+	"btn_top_up":              "➕ Top up",
+	"btn_top_up_amount":       "%g TON",
+	"btn_method_stars":        "⭐ Telegram Stars",
+	"btn_method_ton":          "💎 TON",
+	"bot_balance":             "💰 <b>Balance: %.4f TON</b>",
+	"bot_balance_history":     "\n\n📜 <b>Transactions</b> · page %d\n",
+	"bot_balance_empty":       "\n\nNo transactions yet.",
+	"bot_top_up_amounts":      "➕ <b>Balance top-up</b>\n\nChoose an amount:",
+	"bot_top_up_methods":      "➕ Top-up of <b>%g TON</b>\n\nChoose a payment method:",
+	"bot_top_up_stars":        "⭐ Top-up of <b>%g TON</b>\n\nTo pay: <b>%d ⭐</b>\n\nTap the button below to pay.",
+	"tx_referral_bonus":       "Referral bonus",
+	"tx_giveaway":             "Gift",
+	"tx_subscription_payment": "Subscription payment",
+	"tx_refund":               "Refund",
+	"tx_manual":               "Adjustment",
+	"tx_top_up":               "Top-up",
+	"tx_promo_code":           "Promo code",
+	"tx_region_switch":        "Region switch",
+
+	// Balance history entries
+	"tx_desc_referral_bonus":       "Referral bonus: +%s TON",
+	"tx_desc_subscription_payment": "Subscription payment: -%s TON",
+	"tx_desc_refund":               "Refund: +%s TON",
+	"tx_desc_top_up":               "Balance top-up: +%s TON",
+	"tx_desc_top_up_bonus":         "Balance top-up: +%s TON (+%s%% bonus = %s)",
+	"tx_desc_promo_code":           "Promo code %s: +%s TON",
+	"tx_desc_region_switch":        "Region switch: -%s TON",
+
+	// Names of connection keys
+	"key_name_primary": "Main",
+
+	// Bot notifications
+	"notify_expiring": `⏰ <b>Your subscription ends soon!</b>
+
+Days left: %d
+
+Extend your subscription to keep your VPN access.`,
+	"notify_expired": `❌ <b>Subscription ended</b>
+
+Your VPN subscription has expired. Extend it to restore access.`,
+	"notify_activated": `✅ <b>Subscription activated!</b>
+
+Thank you for your purchase! Your subscription is active until %s.
+
+Use /key to get your connection key.`,
+	"notify_extended": `🎁 <b>Subscription extended</b>
+
+We added %d days to your subscription as compensation. It is active until %s.
+
+Thank you for staying with us!`,
+	"notify_referral_bonus":      "🎁 <b>Referral bonus!</b>\n\nYour friend paid for a subscription. You received:",
+	"notify_referral_bonus_ton":  "\n💎 <b>+%.4f TON</b> on your balance",
+	"notify_referral_bonus_days": "\n📅 <b>+%d days</b> of subscription",
+	"notify_referral_bonus_end":  "\n\nThank you for inviting friends!",
+	"notify_key_changed": `🔄 <b>Connection key updated</b>
+
+The server settings changed and the old key no longer works. Replace it in your app with the new one:
+
+<code>%s</code>
+
+The key is also available with /key.`,
+	"notify_top_up": `💰 <b>Balance topped up!</b>
+
+Credited: <b>+%.4f TON</b>
+Current balance: <b>%.4f TON</b>`,
+}
@@ -0,0 +1,106 @@
+// Package i18n holds the catalog of user-facing messages and picks the
+// language they are shown in.
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Supported languages
+const (
+	LangRU = "ru"
+	LangEN = "en"
+
+	// DefaultLang is used when the user's language is unknown
+	DefaultLang = LangRU
+)
+
+// Languages lists the supported languages
+var Languages = []string{LangRU, LangEN}
+
+var catalog = map[string]map[string]string{
+	LangRU: ru,
+	LangEN: en,
+}
+
+// russianSpeaking are Telegram language codes whose users get Russian rather than English
+var russianSpeaking = []string{"ru", "be", "kk", "ky", "tg", "uz"}
+
+// Supported reports whether lang is a supported language
+func Supported(lang string) bool {
+	return slices.Contains(Languages, lang)
+}
+
+// Lang picks the language for a user: the explicit preference when set and
+// supported, otherwise one derived from the Telegram language code
+func Lang(preference *string, languageCode string) string {
+	if preference != nil && Supported(*preference) {
+		return *preference
+	}
+
+	code := strings.ToLower(languageCode)
+	if base, _, ok := strings.Cut(code, "-"); ok {
+		code = base
+	}
+	switch {
+	case code == "":
+		return DefaultLang
+	case Supported(code):
+		return code
+	case slices.Contains(russianSpeaking, code):
+		return LangRU
+	}
+	return LangEN
+}
+
+// T returns the message for key in lang, formatted with args as fmt.Sprintf
+// does. Missing translations fall back to the default language, then to the key.
+func T(lang, key string, args ...any) string {
+	msg, ok := catalog[lang][key]
+	if !ok {
+		if msg, ok = catalog[DefaultLang][key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Error is a user-facing error. Its code is stable and machine-readable and
+// doubles as the catalog key of its text.
+type Error struct {
+	Code string
+}
+
+// NewError returns a user-facing error with the given code
+func NewError(code string) *Error {
+	return &Error{Code: code}
+}
+
+// Error returns the text in the default language
+func (e *Error) Error() string {
+	return T(DefaultLang, e.Code)
+}
+
+// Code returns the code of the user-facing error in err's chain, or "" without one
+func Code(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
+
+// Message returns err in lang: the catalog text of a user-facing error,
+// otherwise err's own text
+func Message(lang string, err error) string {
+	if code := Code(err); code != "" {
+		return T(lang, code)
+	}
+	return err.Error()
+}
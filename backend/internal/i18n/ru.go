@@ -0,0 +1,316 @@
+package i18n
+
+// ru is the Russian catalog. Keys of user-facing errors are their API error codes.
+var ru = map[string]string{
+	// Errors
+	"internal_error":            "Внутренняя ошибка, попробуйте позже",
+	"unauthorized":              "Необходима авторизация",
+	"invalid_init_data":         "Неверные данные авторизации Telegram",
+	"account_banned":            "Аккаунт заблокирован",
+	"ip_banned":                 "Доступ с этого IP-адреса заблокирован",
+	"access_denied":             "Доступ запрещён",
+	"invalid_request":           "Неверный формат запроса",
+	"too_many_requests":         "Слишком много запросов, попробуйте позже",
+	"unsupported_language":      "Язык не поддерживается",
+	"invalid_plan_id":           "Неверный ID тарифа",
+	"invalid_server_id":         "Неверный ID сервера",
+	"invalid_payment_id":        "Неверный ID платежа",
+	"missing_payment_id":        "Отсутствует ID платежа",
+	"invalid_payment_provider":  "Неверный способ оплаты, выберите 'ton' или 'stars'",
+	"invalid_amount":            "Сумма должна быть положительной",
+	"plan_not_found":            "Тариф не найден",
+	"server_not_found":          "Сервер не найден",
+	"payment_not_found":         "Платёж не найден",
+	"key_not_found":             "Ключ не найден",
+	"no_subscription_url":       "Ссылка подписки не найдена",
+	"no_active_endpoints":       "У сервера сейчас нет рабочих адресов. Выберите другой сервер.",
+	"user_not_found":            "Пользователь не найден",
+	"no_active_subscription":    "Нет активной подписки",
+	"subscription_active":       "У вас уже есть активная подписка",
+	"subscription_not_active":   "Подписка неактивна",
+	"trial_already_used":        "Пробный период уже использован",
+	"no_servers_available":      "Нет доступных серверов",
+	"server_full":               "Сервер заполнен, выберите другой",
+	"server_locked":             "Эта локация недоступна на вашем тарифе",
+	"server_unavailable":        "Сервер временно недоступен, попробуйте позже",
+	"payment_already_complete":  "Платёж уже завершён",
+	"payment_not_pending":       "Платёж не ожидает оплаты",
+	"payment_not_top_up":        "Платёж не является пополнением",
+	"payment_without_plan":      "Платёж не привязан к тарифу",
+	"payment_unavailable":       "Сервис оплаты недоступен",
+	"refund_unavailable":        "Сервис возврата недоступен",
+	"insufficient_balance":      "Недостаточно средств на балансе",
+	"promo_code_required":       "Введите промокод",
+	"promo_code_not_found":      "Промокод не найден",
+	"promo_code_expired":        "Срок действия промокода истёк",
+	"promo_code_usage_limit":    "Лимит использований промокода исчерпан",
+	"promo_code_already_used":   "Вы уже использовали этот промокод",
+	"promo_code_inactive":       "Промокод неактивен",
+	"no_subscription_to_extend": "Нет активной подписки для продления",
+	"referral_code_required":    "Введите код",
+	"referral_exists":           "Реферал уже существует",
+	"self_referral":             "Нельзя пригласить самого себя",
+	"not_admin":                 "Пользователь не является администратором",
+	"already_banned":            "Пользователь уже заблокирован",
+	"not_banned":                "Пользователь не заблокирован",
+	"insufficient_permissions":  "Недостаточно прав",
+
+	// API messages
+	"refund_completed":              "Возврат успешно обработан",
+	"referral_applied":              "Реферальный код применён! Бонусные дни начислятся после первой оплаты.",
+	"promo_applied_balance":         "На ваш баланс зачислено %.4f TON",
+	"promo_applied_extended":        "Ваша подписка продлена на %d дней",
+	"promo_applied_activated":       "Вам активирована подписка на %d дней",
+	"promo_applied_region_switch":   "Вам начислена 1 бесплатная смена региона",
+	"promo_applied_region_switches": "Вам начислено %d бесплатных смен региона",
+	"invoice_top_up_title":          "Пополнение баланса",
+	"invoice_top_up_description":    "Пополнение баланса на %.4f TON",
+
+	// Bot buttons
+	"btn_trial":        "🎁 Попробовать бесплатно (3 дня)",
+	"btn_open_shop":    "📱 Открыть магазин",
+	"btn_status":       "📊 Статус подписки",
+	"btn_get_key":      "🔑 Получить ключ",
+	"btn_choose_plan":  "📱 Выбрать тариф",
+	"btn_extend":       "📱 Продлить подписку",
+	"btn_open_miniapp": "📱 Открыть Mini App",
+	"btn_invite_more":  "👥 Пригласить ещё",
+
+	// Bot messages
+	"bot_start": `Привет, %s! 👋
+
+🔐 <b>ZyVPN</b> — быстрый и безопасный VPN
+
+✅ Протокол VLESS + Reality
+✅ Высокая скорость
+✅ Безлимитные тарифы
+✅ Простое подключение
+
+Нажми кнопку ниже, чтобы выбрать тариф и оплатить подписку.`,
+	"bot_start_referred": "\n\n🎁 Тебя пригласил друг! Твой друг получит бонус при твоей первой оплате.",
+	"bot_status_none": `❌ <b>У вас нет активной подписки</b>
+
+Нажмите кнопку ниже, чтобы выбрать тариф.`,
+	"bot_status_active": `✅ <b>Подписка активна</b>
+
+📅 Действует до: %s
+📊 Трафик: %s
+⏳ Осталось: %d дней`,
+	"bot_traffic_limited":   "%.2f / %.0f ГБ",
+	"bot_traffic_unlimited": "%.2f ГБ (безлимит)",
+	"bot_key_none": `❌ <b>Ключ недоступен</b>
+
+У вас нет активной подписки. Оформите подписку, чтобы получить ключ подключения.`,
+	"bot_key": `🔑 <b>Ваш ключ подключения:</b>
+
+<code>%s</code>
+
+📱 Скопируйте ключ и вставьте в приложение:
+• iOS: Streisand, V2Box
+• Android: V2rayNG, NekoBox
+• Windows/Mac: Nekoray, V2rayN
+
+📷 Или отсканируйте QR-код выше с другого устройства.`,
+	"bot_key_qr":               "📷 QR-код вашего ключа подключения",
+	"bot_key_fallback":         "🛡 <b>Резервные ключи</b> — используйте, если основной не подключается:\n",
+	"bot_key_subscription_url": "🔗 <b>Ссылка подписки</b> — добавьте её в приложение, чтобы получать все ключи автоматически:\n<code>%s</code>",
+	"bot_help": `📖 <b>Помощь по ZyVPN</b>
+
+<b>🔧 Настройка VPN:</b>
+
+1️⃣ <b>Выберите приложение:</b>
+• iOS: Streisand, V2Box
+• Android: V2rayNG, NekoBox
+• Windows/Mac: Nekoray, V2rayN
+
+2️⃣ Установите приложение
+
+3️⃣ Получите ключ: /key или Mini App
+
+4️⃣ Вставьте ключ в приложение
+
+5️⃣ Подключитесь!
+
+<b>🎁 Промокоды:</b>
+Отправьте /promo КОД или откройте Mini App → Баланс → Введите промокод
+
+<b>📱 Команды:</b>
+/start — Главное меню
+/status — Статус подписки
+/status_servers — Статус серверов
+/key — Получить ключ
+/buy — Купить подписку
+/balance — Баланс и история операций
+/topup — Пополнить баланс
+/promo — Активировать промокод
+/trial — Бесплатный период
+/referral — Реферальная программа
+/language — Язык бота
+/support — Связаться с поддержкой
+
+❓ Вопросы? /support`,
+	"bot_support": `💬 <b>Поддержка</b>
+
+Если у вас возникли вопросы или проблемы, напишите нам:
+
+📧 support@zyvpn.com
+💬 @zyvpn_support
+
+Мы ответим в течение 24 часов.`,
+	"bot_referral": `🎁 <b>Реферальная программа</b>
+
+Приглашай друзей и получай TON на баланс!
+
+Когда друг оплатит первую подписку:
+• Ты получишь +0.1 TON на баланс
+
+📊 <b>Твоя статистика:</b>
+👥 Приглашено: %d
+⏳ Ожидают оплаты: %d
+💎 Получено TON: %.4f
+
+🔗 <b>Твоя ссылка:</b>
+<code>%s</code>`,
+	"bot_servers_unavailable":    "Статус серверов временно недоступен",
+	"bot_servers_operational":    "✅ <b>Все серверы работают</b>\n",
+	"bot_servers_degraded":       "🟡 <b>Возможны задержки</b>\n",
+	"bot_servers_partial_outage": "🟠 <b>Часть серверов недоступна</b>\n",
+	"bot_servers_outage":         "🔴 <b>Серверы недоступны</b>\n",
+	"bot_servers_uptime":         "\n<i>Аптайм за 24ч / 7д</i>\n",
+	"bot_servers_incidents":      "\n⚠️ <b>Сбои</b>\n",
+	"bot_servers_notices":        "\n📢 <b>Объявления</b>\n",
+	"bot_since":                  "с %s",
+	"bot_until":                  " до %s",
+	"bot_trial_used": `❌ <b>Trial уже использован</b>
+
+Вы уже использовали бесплатный пробный период. Выберите тариф для продления.`,
+	"bot_trial_subscription_active": `❌ <b>Подписка уже активна</b>
+
+У вас уже есть активная подписка. Используйте /status для проверки статуса.`,
+	"bot_trial_server_unavailable": `⏳ <b>Сервер временно недоступен</b>
+
+Попробуйте активировать пробный период через несколько минут.`,
+	"bot_trial_activated": `✅ <b>Trial подписка активирована!</b>
+
+🎁 Вам предоставлен бесплатный доступ на 3 дня.
+
+📅 Действует до: %s
+📊 Трафик: 10 ГБ
+
+Используйте команду /key чтобы получить ключ подключения.`,
+	"bot_error":          "❌ Ошибка: %s",
+	"bot_payment_failed": "Ошибка при обработке платежа. Обратитесь в поддержку.",
+	"bot_payment_done":   "✅ Оплата прошла успешно! Используйте /key для получения ключа.",
+	"bot_language":       "🌐 Выберите язык:",
+	"bot_language_set":   "✅ Язык бота: русский",
+
+	// Bot purchase flow
+	"btn_buy":             "🛒 Купить подписку",
+	"btn_promo_code":      "🎁 Ввести промокод",
+	"btn_server_auto":     "⚡ Автовыбор",
+	"btn_pay_stars":       "⭐ Telegram Stars · %d ⭐",
+	"btn_pay_ton":         "💎 TON · %g TON",
+	"btn_pay_balance":     "💰 С баланса · %g TON",
+	"btn_pay_invoice":     "⭐ Оплатить %d ⭐",
+	"btn_open_wallet":     "👛 Открыть кошелёк",
+	"btn_ton_paid":        "✅ Я оплатил",
+	"btn_cancel":          "✖️ Отмена",
+	"bot_buy_plans":       "🛒 <b>Выберите тариф</b>",
+	"bot_buy_plan":        "%s · %g TON / %d ⭐",
+	"bot_buy_no_plans":    "Сейчас нет доступных тарифов, попробуйте позже.",
+	"bot_buy_servers":     "📦 Тариф: <b>%s</b>\n\n🌍 Выберите сервер:",
+	"bot_buy_methods":     "📦 Тариф: <b>%s</b>\n🌍 Сервер: %s\n\n💳 Выберите способ оплаты:",
+	"bot_buy_server_auto": "автовыбор",
+	"bot_buy_expired":     "Покупка устарела, начните заново: /buy",
+	"bot_buy_cancelled":   "Покупка отменена",
+	"bot_buy_stars":       "⭐ <b>%s</b>\n\nК оплате: <b>%d ⭐</b>\n\nНажмите кнопку ниже, чтобы оплатить.",
+	"bot_buy_ton": `💎 <b>Оплата в TON</b>
+
+Отправьте <b>%s TON</b> на адрес:
+<code>%s</code>
+
+С комментарием:
+<code>%s</code>
+
+Или откройте ссылку в кошельке:
+<code>%s</code>
+
+После перевода нажмите «Я оплатил».`,
+	"bot_buy_insufficient_balance": `❌ <b>Недостаточно средств на балансе</b>
+
+Баланс: <b>%.4f TON</b>
+Стоимость: <b>%g TON</b>`,
+	"bot_ton_checking":                 "⏳ Проверяем платёж, это может занять несколько минут. Мы пришлём уведомление.",
+	"bot_ton_failed":                   "❌ Платёж в TON не найден. Если вы отправили перевод, напишите в /support.",
+	"bot_promo_enter":                  "🎁 Отправьте промокод сообщением",
+	"bot_promo_applied":                "✅ %s",
+	"invoice_subscription_title":       "ZyVPN: %s",
+	"invoice_subscription_description": "Подписка на VPN на %d дн.",
+
+	// Bot balance
+	"btn_top_up":              "➕ Пополнить",
+	"btn_top_up_amount":       "%g TON",
+	"btn_method_stars":        "⭐ Telegram Stars",
+	"btn_method_ton":          "💎 TON",
+	"bot_balance":             "💰 <b>Баланс: %.4f TON</b>",
+	"bot_balance_history":     "\n\n📜 <b>История операций</b> · стр. %d\n",
+	"bot_balance_empty":       "\n\nОпераций пока нет.",
+	"bot_top_up_amounts":      "➕ <b>Пополнение баланса</b>\n\nВыберите сумму:",
+	"bot_top_up_methods":      "➕ Пополнение на <b>%g TON</b>\n\nВыберите способ оплаты:",
+	"bot_top_up_stars":        "⭐ Пополнение на <b>%g TON</b>\n\nК оплате: <b>%d ⭐</b>\n\nНажмите кнопку ниже, чтобы оплатить.",
+	"tx_referral_bonus":       "Реферальный бонус",
+	"tx_giveaway":             "Подарок",
+	"tx_subscription_payment": "Оплата подписки",
+	"tx_refund":               "Возврат",
+	"tx_manual":               "Начисление",
+	"tx_top_up":               "Пополнение",
+	"tx_promo_code":           "Промокод",
+	"tx_region_switch":        "Смена региона",
+
+	// Balance history entries
+	"tx_desc_referral_bonus":       "Реферальный бонус: +%s TON",
+	"tx_desc_subscription_payment": "Оплата подписки: -%s TON",
+	"tx_desc_refund":               "Возврат средств: +%s TON",
+	"tx_desc_top_up":               "Пополнение баланса: +%s TON",
+	"tx_desc_top_up_bonus":         "Пополнение баланса: +%s TON (+%s%% бонус = %s)",
+	"tx_desc_promo_code":           "Промокод %s: +%s TON",
+	"tx_desc_region_switch":        "Смена региона: -%s TON",
+
+	// Names of connection keys
+	"key_name_primary": "Основной",
+
+	// Bot notifications
+	"notify_expiring": `⏰ <b>Подписка скоро закончится!</b>
+
+Осталось дней: %d
+
+Продлите подписку, чтобы не потерять доступ к VPN.`,
+	"notify_expired": `❌ <b>Подписка закончилась</b>
+
+Ваша подписка на VPN истекла. Продлите подписку, чтобы восстановить доступ.`,
+	"notify_activated": `✅ <b>Подписка активирована!</b>
+
+Спасибо за покупку! Ваша подписка активна до %s.
+
+Используйте команду /key чтобы получить ключ подключения.`,
+	"notify_extended": `🎁 <b>Подписка продлена</b>
+
+Мы добавили %d дн. к вашей подписке в качестве компенсации. Подписка активна до %s.
+
+Спасибо, что остаётесь с нами!`,
+	"notify_referral_bonus":      "🎁 <b>Реферальный бонус!</b>\n\nВаш друг оплатил подписку. Вы получили:",
+	"notify_referral_bonus_ton":  "\n💎 <b>+%.4f TON</b> на баланс",
+	"notify_referral_bonus_days": "\n📅 <b>+%d дней</b> к подписке",
+	"notify_referral_bonus_end":  "\n\nСпасибо, что приглашаете друзей!",
+	"notify_key_changed": `🔄 <b>Ключ подключения обновлён</b>
+
+Настройки сервера изменились, старый ключ больше не работает. Замените его в приложении на новый:
+
+<code>%s</code>
+
+Ключ также доступен по команде /key.`,
+	"notify_top_up": `💰 <b>Баланс пополнен!</b>
+
+Зачислено: <b>+%.4f TON</b>
+Текущий баланс: <b>%.4f TON</b>`,
+}
@@ -0,0 +1,322 @@
+package marzban
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnauthorized is returned when the admin token is rejected
+var ErrUnauthorized = errors.New("marzban: unauthorized")
+
+// ErrUserNotFound is returned when the user does not exist on the panel
+var ErrUserNotFound = errors.New("marzban: user not found")
+
+// Client talks to the Marzban REST API (/api/...) using an admin bearer token
+type Client struct {
+	baseURL  string
+	username string
+	password string
+	client   *http.Client
+
+	mu    sync.Mutex
+	token string
+}
+
+// User is the user object returned by Marzban
+type User struct {
+	Username        string                    `json:"username"`
+	Status          string                    `json:"status"`
+	Expire          *int64                    `json:"expire"`     // unix seconds, null = never
+	DataLimit       *int64                    `json:"data_limit"` // bytes, null/0 = unlimited
+	UsedTraffic     int64                     `json:"used_traffic"`
+	Proxies         map[string]map[string]any `json:"proxies"`
+	Inbounds        map[string][]string       `json:"inbounds"`
+	Links           []string                  `json:"links"`
+	SubscriptionURL string                    `json:"subscription_url"`
+}
+
+// Inbound describes an inbound configured in the Marzban core
+type Inbound struct {
+	Tag      string `json:"tag"`
+	Protocol string `json:"protocol"`
+	Network  string `json:"network"`
+	TLS      string `json:"tls"`
+	Port     any    `json:"port"`
+}
+
+// PortNumber returns the inbound port; Marzban reports it as a number or a string
+func (i *Inbound) PortNumber() int {
+	switch v := i.Port.(type) {
+	case float64:
+		return int(v)
+	case string:
+		port, _ := strconv.Atoi(v)
+		return port
+	}
+	return 0
+}
+
+type userRequest struct {
+	Username               string                    `json:"username,omitempty"`
+	Proxies                map[string]map[string]any `json:"proxies,omitempty"`
+	Expire                 int64                     `json:"expire"`
+	DataLimit              int64                     `json:"data_limit"`
+	DataLimitResetStrategy string                    `json:"data_limit_reset_strategy,omitempty"`
+	Status                 string                    `json:"status,omitempty"`
+}
+
+func NewClient(baseURL, username, password string) *Client {
+	return &Client{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		username: username,
+		password: password,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Login obtains an admin access token
+func (c *Client) Login(ctx context.Context) error {
+	form := url.Values{}
+	form.Set("username", c.username)
+	form.Set("password", c.password)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/admin/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("login request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("login failed: status=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("failed to decode login response: %w", err)
+	}
+	if result.AccessToken == "" {
+		return fmt.Errorf("login failed: empty access token")
+	}
+
+	c.mu.Lock()
+	c.token = result.AccessToken
+	c.mu.Unlock()
+
+	log.Printf("[Marzban] Logged in to %s", c.baseURL)
+	return nil
+}
+
+// do performs an authenticated request, logging in first if needed and once more on 401
+func (c *Client) do(ctx context.Context, method, path string, payload any, out any) error {
+	var data []byte
+	if payload != nil {
+		var err error
+		data, err = json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		c.mu.Lock()
+		token := c.token
+		c.mu.Unlock()
+
+		if token == "" {
+			if err := c.Login(ctx); err != nil {
+				return err
+			}
+			c.mu.Lock()
+			token = c.token
+			c.mu.Unlock()
+		}
+
+		err := c.send(ctx, method, path, token, data, out)
+		if errors.Is(err, ErrUnauthorized) && attempt == 0 {
+			// Token expired: drop it and log in again once
+			c.mu.Lock()
+			c.token = ""
+			c.mu.Unlock()
+			continue
+		}
+		return err
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path, token string, data []byte, out any) error {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s request failed: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrUserNotFound
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("%s %s failed: status=%d, body=%s", method, path, resp.StatusCode, string(respBody))
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode response (status=%d, body=%s): %w", resp.StatusCode, string(respBody), err)
+	}
+	return nil
+}
+
+// SystemStats is the subset of GET /api/system used for health checks and load sampling
+type SystemStats struct {
+	CPUUsage          float64 `json:"cpu_usage"` // percent
+	MemTotal          int64   `json:"mem_total"`
+	MemUsed           int64   `json:"mem_used"`
+	OnlineUsers       int     `json:"online_users"`
+	IncomingBandwidth int64   `json:"incoming_bandwidth"` // bytes since the node started
+	OutgoingBandwidth int64   `json:"outgoing_bandwidth"`
+}
+
+// CoreStats is the Xray core state from GET /api/core
+type CoreStats struct {
+	Version string `json:"version"`
+	Started bool   `json:"started"`
+}
+
+// GetSystemStats returns CPU and memory usage of the Marzban host
+func (c *Client) GetSystemStats(ctx context.Context) (*SystemStats, error) {
+	var stats SystemStats
+	if err := c.do(ctx, http.MethodGet, "/api/system", nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetCoreStats returns whether the Xray core is running
+func (c *Client) GetCoreStats(ctx context.Context) (*CoreStats, error) {
+	var stats CoreStats
+	if err := c.do(ctx, http.MethodGet, "/api/core", nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetInbounds returns configured inbounds grouped by protocol
+func (c *Client) GetInbounds(ctx context.Context) (map[string][]Inbound, error) {
+	var inbounds map[string][]Inbound
+	if err := c.do(ctx, http.MethodGet, "/api/inbounds", nil, &inbounds); err != nil {
+		return nil, err
+	}
+	return inbounds, nil
+}
+
+// AddUser creates a user enabled for the given protocols on all their inbounds.
+// dataLimit is in bytes and expire in unix seconds; zero means unlimited.
+func (c *Client) AddUser(ctx context.Context, username string, protocols []string, dataLimit int64, expire int64) (*User, error) {
+	proxies := make(map[string]map[string]any, len(protocols))
+	for _, protocol := range protocols {
+		proxies[protocol] = map[string]any{}
+	}
+
+	req := userRequest{
+		Username:               username,
+		Proxies:                proxies,
+		Expire:                 expire,
+		DataLimit:              dataLimit,
+		DataLimitResetStrategy: "no_reset",
+		Status:                 "active",
+	}
+
+	var user User
+	if err := c.do(ctx, http.MethodPost, "/api/user", req, &user); err != nil {
+		return nil, fmt.Errorf("add user failed: %w", err)
+	}
+	return &user, nil
+}
+
+// GetUser returns a user by username
+func (c *Client) GetUser(ctx context.Context, username string) (*User, error) {
+	var user User
+	if err := c.do(ctx, http.MethodGet, "/api/user/"+url.PathEscape(username), nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SubscriptionURL returns the absolute subscription URL of a user. Marzban reports
+// it relative to the panel unless XRAY_SUBSCRIPTION_URL_PREFIX is set.
+func (c *Client) SubscriptionURL(ctx context.Context, username string) (string, error) {
+	user, err := c.GetUser(ctx, username)
+	if err != nil {
+		return "", err
+	}
+	if user.SubscriptionURL == "" || strings.Contains(user.SubscriptionURL, "://") {
+		return user.SubscriptionURL, nil
+	}
+	return c.baseURL + "/" + strings.TrimLeft(user.SubscriptionURL, "/"), nil
+}
+
+// UpdateUser sets the data limit (bytes) and expiry (unix seconds) and re-activates the user
+func (c *Client) UpdateUser(ctx context.Context, username string, dataLimit int64, expire int64) error {
+	req := userRequest{
+		Expire:    expire,
+		DataLimit: dataLimit,
+		Status:    "active",
+	}
+	if err := c.do(ctx, http.MethodPut, "/api/user/"+url.PathEscape(username), req, nil); err != nil {
+		return fmt.Errorf("update user failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteUser removes a user; a missing user is not an error
+func (c *Client) DeleteUser(ctx context.Context, username string) error {
+	err := c.do(ctx, http.MethodDelete, "/api/user/"+url.PathEscape(username), nil, nil)
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		return fmt.Errorf("delete user failed: %w", err)
+	}
+	return nil
+}
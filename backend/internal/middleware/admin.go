@@ -15,22 +15,16 @@ func AdminAuth(adminSvc *service.AdminService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		userID := GetUserID(c)
 		if userID == 0 {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "unauthorized",
-			})
+			return errorJSON(c, fiber.StatusUnauthorized, "unauthorized")
 		}
 
 		isAdmin, err := adminSvc.IsAdmin(c.Context(), userID)
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "failed to check admin status",
-			})
+			return errorJSON(c, fiber.StatusInternalServerError, "internal_error")
 		}
 
 		if !isAdmin {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "access denied",
-			})
+			return errorJSON(c, fiber.StatusForbidden, "access_denied")
 		}
 
 		c.Locals(AdminKey, true)
@@ -49,9 +43,7 @@ func BanCheck(adminSvc *service.AdminService) fiber.Handler {
 		if userID != 0 {
 			banned, err := adminSvc.IsUserBanned(c.Context(), userID)
 			if err == nil && banned {
-				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-					"error": "account banned",
-				})
+				return errorJSON(c, fiber.StatusForbidden, "account_banned")
 			}
 		}
 
@@ -60,9 +52,7 @@ func BanCheck(adminSvc *service.AdminService) fiber.Handler {
 		if ip != "" {
 			banned, err := adminSvc.IsIPBanned(c.Context(), ip)
 			if err == nil && banned {
-				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-					"error": "ip banned",
-				})
+				return errorJSON(c, fiber.StatusForbidden, "ip_banned")
 			}
 		}
 
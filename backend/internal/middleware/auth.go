@@ -12,6 +12,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/zyvpn/backend/internal/config"
+	"github.com/zyvpn/backend/internal/i18n"
 )
 
 const (
@@ -41,15 +42,15 @@ func TelegramAuth(cfg *config.Config) fiber.Handler {
 		}
 
 		if initData == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "missing telegram init data",
-			})
+			return errorJSON(c, fiber.StatusUnauthorized, "unauthorized")
 		}
 
 		userData, err := ValidateTelegramInitData(initData, cfg.Telegram.BotToken)
 		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "invalid telegram init data: " + err.Error(),
+				"error":   i18n.T(GetLang(c), "invalid_init_data"),
+				"code":    "invalid_init_data",
+				"details": err.Error(),
 			})
 		}
 
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/zyvpn/backend/internal/i18n"
+	"github.com/zyvpn/backend/internal/service"
+)
+
+const LangKey = "lang"
+
+// Language picks the language of API messages: the one the user chose, else
+// the language of their Telegram client
+func Language(userSvc *service.UserService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var languageCode string
+		if telegramUser := GetTelegramUser(c); telegramUser != nil {
+			languageCode = telegramUser.LanguageCode
+		}
+		c.Locals(LangKey, userSvc.Language(c.Context(), GetUserID(c), languageCode))
+		return c.Next()
+	}
+}
+
+// GetLang returns the language of API messages for the request. Before
+// Language has run it follows the Telegram init data, if any.
+func GetLang(c *fiber.Ctx) string {
+	if lang, ok := c.Locals(LangKey).(string); ok {
+		return lang
+	}
+	if telegramUser := GetTelegramUser(c); telegramUser != nil {
+		return i18n.Lang(nil, telegramUser.LanguageCode)
+	}
+	return i18n.DefaultLang
+}
+
+// errorJSON responds with a localized error message and its code
+func errorJSON(c *fiber.Ctx, status int, code string) error {
+	return c.Status(status).JSON(fiber.Map{
+		"error": i18n.T(GetLang(c), code),
+		"code":  code,
+	})
+}
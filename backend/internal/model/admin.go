@@ -63,4 +63,11 @@ const (
 	AdminActionCreatePlan       = "create_plan"
 	AdminActionUpdatePlan       = "update_plan"
 	AdminActionDeletePlan       = "delete_plan"
+	AdminActionRevealServerCred = "reveal_server_credentials"
+	AdminActionCreateNotice     = "create_status_notice"
+	AdminActionUpdateNotice     = "update_status_notice"
+	AdminActionDeleteNotice     = "delete_status_notice"
+	AdminActionAckAlert         = "ack_alert"
+	AdminActionMuteAlert        = "mute_alert"
+	AdminActionBulkExtend       = "bulk_extend_subscriptions"
 )
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Alert kinds
+const (
+	AlertKindServerDown     = "server_down"
+	AlertKindServerDegraded = "server_degraded"
+	AlertKindPanelErrors    = "panel_errors"
+	AlertKindEndpointDown   = "endpoint_down"
+	AlertKindTonWorker      = "ton_worker"
+	AlertKindPaymentsStuck  = "payments_stuck"
+	AlertKindReconciliation = "reconciliation"
+)
+
+// Alert severities
+const (
+	AlertSeverityWarning  = "warning"
+	AlertSeverityCritical = "critical"
+)
+
+// Alert statuses
+const (
+	AlertStatusFiring   = "firing"
+	AlertStatusResolved = "resolved"
+)
+
+// Alert is an admin alert. Repeated firings of the same key update one row,
+// so an alert is sent once and then at most every AlertRepeatInterval.
+type Alert struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	Key            string     `json:"key" db:"key"`
+	Kind           string     `json:"kind" db:"kind"`
+	Severity       string     `json:"severity" db:"severity"`
+	Message        string     `json:"message" db:"message"`
+	Status         string     `json:"status" db:"status"`
+	Occurrences    int        `json:"occurrences" db:"occurrences"`
+	FirstFiredAt   time.Time  `json:"first_fired_at" db:"first_fired_at"`
+	LastFiredAt    time.Time  `json:"last_fired_at" db:"last_fired_at"`
+	LastNotifiedAt *time.Time `json:"last_notified_at,omitempty" db:"last_notified_at"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty" db:"resolved_at"`
+	AckedBy        *int64     `json:"acked_by,omitempty" db:"acked_by"`
+	AckedAt        *time.Time `json:"acked_at,omitempty" db:"acked_at"`
+	MutedUntil     *time.Time `json:"muted_until,omitempty" db:"muted_until"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// IsMuted returns true if notifications for the alert are muted at the given time
+func (a *Alert) IsMuted(t time.Time) bool {
+	return a.MutedUntil != nil && a.MutedUntil.After(t)
+}
+
+// IsAcked returns true if an admin acknowledged the current firing
+func (a *Alert) IsAcked() bool {
+	return a.AckedAt != nil
+}
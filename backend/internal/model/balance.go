@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type TransactionType string
@@ -29,4 +30,9 @@ type BalanceTransaction struct {
 	BalanceBefore float64         `json:"balance_before" db:"balance_before"`
 	BalanceAfter  float64         `json:"balance_after" db:"balance_after"`
 	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+
+	// Catalog key and arguments Description is rendered from in the user's
+	// language; free text entered by admins has none
+	DescriptionKey  *string        `json:"-" db:"description_key"`
+	DescriptionArgs pq.StringArray `json:"-" db:"description_args"`
 }
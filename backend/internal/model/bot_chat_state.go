@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BotChatState is the progress of a purchase in a bot chat
+type BotChatState struct {
+	ChatID       int64      `db:"chat_id"`
+	PlanID       uuid.UUID  `db:"plan_id"`
+	ServerID     *uuid.UUID `db:"server_id"` // nil picks a server automatically
+	ServerChosen bool       `db:"server_chosen"`
+	Awaiting     string     `db:"awaiting"` // what the next text message is, if anything
+	UpdatedAt    time.Time  `db:"updated_at"`
+}
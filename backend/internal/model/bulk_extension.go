@@ -0,0 +1,82 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Bulk extension job statuses
+const (
+	BulkJobRunning   = "running"
+	BulkJobCompleted = "completed"
+	BulkJobFailed    = "failed" // stopped on an error, continued by a retry
+)
+
+// Bulk extension item statuses
+const (
+	BulkItemPending    = "pending"
+	BulkItemProcessing = "processing" // claimed, the subscription may already be extended
+	BulkItemDone       = "done"
+	BulkItemFailed     = "failed"
+	BulkItemSkipped    = "skipped" // subscription is no longer active
+)
+
+// BulkExtensionFilter selects the active subscriptions a bulk extension applies to.
+// Set fields are combined with AND.
+type BulkExtensionFilter struct {
+	ServerID      *uuid.UUID `json:"server_id,omitempty"`
+	PlanID        *uuid.UUID `json:"plan_id,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+}
+
+// IsEmpty reports whether the filter would match every active subscription
+func (f *BulkExtensionFilter) IsEmpty() bool {
+	return f.ServerID == nil && f.PlanID == nil && f.CreatedBefore == nil
+}
+
+// BulkExtensionJob extends all subscriptions matched by a filter by the same number of days.
+// A job is completed when no item is pending; Failed > 0 means a partial failure that can be retried.
+type BulkExtensionJob struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	AdminID       int64      `json:"admin_id" db:"admin_id"`
+	Days          int        `json:"days" db:"days"`
+	ServerID      *uuid.UUID `json:"server_id,omitempty" db:"server_id"`
+	PlanID        *uuid.UUID `json:"plan_id,omitempty" db:"plan_id"`
+	CreatedBefore *time.Time `json:"created_before,omitempty" db:"created_before"`
+	Notify        bool       `json:"notify" db:"notify"`
+	Status        string     `json:"status" db:"status"`
+	Total         int        `json:"total" db:"total"`
+	Succeeded     int        `json:"succeeded" db:"succeeded"`
+	Failed        int        `json:"failed" db:"failed"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// Filter returns the filter the job was created with
+func (j *BulkExtensionJob) Filter() BulkExtensionFilter {
+	return BulkExtensionFilter{ServerID: j.ServerID, PlanID: j.PlanID, CreatedBefore: j.CreatedBefore}
+}
+
+// BulkExtensionItem is one subscription of a bulk extension job
+type BulkExtensionItem struct {
+	JobID          uuid.UUID `json:"job_id" db:"job_id"`
+	SubscriptionID uuid.UUID `json:"subscription_id" db:"subscription_id"`
+	UserID         int64     `json:"user_id" db:"user_id"`
+	Status         string    `json:"status" db:"status"`
+	Attempts       int       `json:"attempts" db:"attempts"`
+	Error          *string   `json:"error,omitempty" db:"error"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+
+	// Expiry of the subscription when the item was first claimed; the job
+	// extends it to ExpiresFrom + Days however often the item is attempted
+	ExpiresFrom *time.Time `json:"expires_from,omitempty" db:"expires_from"`
+}
+
+// BulkExtensionReport is a job with the items that failed
+type BulkExtensionReport struct {
+	*BulkExtensionJob
+	Pending     int                 `json:"pending"`
+	Skipped     int                 `json:"skipped"`
+	FailedItems []BulkExtensionItem `json:"failed_items"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Where a client latency estimate comes from
+const (
+	LatencySourceUser    = "user"    // measured on the user's device
+	LatencySourceNetwork = "network" // measured by other users of the same network
+)
+
+// LatencyReport is the latency to a server measured by the mini app on the
+// user's device. A nil LatencyMs means the server could not be reached.
+type LatencyReport struct {
+	ServerID  uuid.UUID `json:"server_id"`
+	LatencyMs *int      `json:"latency_ms"`
+}
+
+// ClientLatency is the moving average of latencies to a server reported by a
+// user or from a network
+type ClientLatency struct {
+	ServerID  uuid.UUID `db:"server_id"`
+	LatencyMs float64   `db:"latency_ms"`
+	Samples   int       `db:"samples"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// LatencyEstimate is the latency a user can expect to a server
+type LatencyEstimate struct {
+	LatencyMs int
+	Source    string
+}
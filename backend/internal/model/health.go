@@ -0,0 +1,74 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Server health statuses. A degraded server still accepts VPN connections,
+// but its panel is unreachable or reports Xray as not running.
+const (
+	ServerStatusOnline   = "online"
+	ServerStatusDegraded = "degraded"
+	ServerStatusOffline  = "offline"
+	ServerStatusUnknown  = "unknown"
+)
+
+// HealthCheck is the result of one health check of a server. Panel fields are
+// only set on checks that probed the panel.
+type HealthCheck struct {
+	ID         int64     `json:"id" db:"id"`
+	ServerID   uuid.UUID `json:"server_id" db:"server_id"`
+	CheckedAt  time.Time `json:"checked_at" db:"checked_at"`
+	Status     string    `json:"status" db:"status"`
+	TCPMs      *int      `json:"tcp_ms,omitempty" db:"tcp_ms"`
+	TLSOK      *bool     `json:"tls_ok,omitempty" db:"tls_ok"`
+	TLSMs      *int      `json:"tls_ms,omitempty" db:"tls_ms"`
+	PanelOK    *bool     `json:"panel_ok,omitempty" db:"panel_ok"`
+	PanelMs    *int      `json:"panel_ms,omitempty" db:"panel_ms"`
+	CPUPercent *float64  `json:"cpu_percent,omitempty" db:"cpu_percent"`
+	MemPercent *float64  `json:"mem_percent,omitempty" db:"mem_percent"`
+	XrayState  *string   `json:"xray_state,omitempty" db:"xray_state"`
+	Error      string    `json:"error,omitempty" db:"error"`
+}
+
+// ServerIncident is a period during which a server was offline or degraded
+type ServerIncident struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	ServerID  uuid.UUID  `json:"server_id" db:"server_id"`
+	Status    string     `json:"status" db:"status"` // offline or degraded
+	Cause     string     `json:"cause" db:"cause"`
+	StartedAt time.Time  `json:"started_at" db:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty" db:"ended_at"`
+}
+
+// IsOpen returns true if the server has not recovered yet
+func (i *ServerIncident) IsOpen() bool {
+	return i.EndedAt == nil
+}
+
+// ServerHealthStats aggregates the health checks of a server over a period.
+// Degraded checks count as up: the server still served VPN connections.
+type ServerHealthStats struct {
+	ServerID      uuid.UUID `json:"server_id" db:"server_id"`
+	Checks        int       `json:"checks" db:"checks"`
+	UptimePercent *float64  `json:"uptime_percent" db:"uptime_percent"` // nil when there are no checks
+	LatencyP50    *float64  `json:"latency_p50_ms" db:"latency_p50"`    // TCP connect time
+	LatencyP95    *float64  `json:"latency_p95_ms" db:"latency_p95"`
+	TLSP50        *float64  `json:"tls_p50_ms,omitempty" db:"tls_p50"` // TLS/Reality handshake time
+	TLSP95        *float64  `json:"tls_p95_ms,omitempty" db:"tls_p95"`
+	Incidents     int       `json:"incidents" db:"incidents"`
+}
+
+// ServerHealthReport is the health of a server over a period for the admin API
+type ServerHealthReport struct {
+	ServerID     uuid.UUID         `json:"server_id"`
+	Name         string            `json:"name"`
+	Status       string            `json:"status"`
+	LastCheckAt  *time.Time        `json:"last_check_at,omitempty"`
+	Period       string            `json:"period"`
+	Stats        ServerHealthStats `json:"stats"`
+	Incidents    []ServerIncident  `json:"incidents,omitempty"`
+	RecentChecks []HealthCheck     `json:"recent_checks,omitempty"`
+}
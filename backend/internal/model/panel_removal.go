@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PanelClientRemoval is a panel client left behind by an expired subscription,
+// queued until its panel is reachable again
+type PanelClientRemoval struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	ServerID     uuid.UUID `json:"server_id" db:"server_id"`
+	XUIInboundID int       `json:"xui_inbound_id" db:"xui_inbound_id"`
+	XUIClientID  string    `json:"xui_client_id" db:"xui_client_id"`
+	XUIEmail     string    `json:"xui_email" db:"xui_email"`
+	Attempts     int       `json:"attempts" db:"attempts"`
+	LastError    *string   `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
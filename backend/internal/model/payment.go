@@ -46,6 +46,11 @@ type Payment struct {
 	Metadata       *string         `json:"metadata,omitempty" db:"metadata"`
 	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
 	CompletedAt    *time.Time      `json:"completed_at,omitempty" db:"completed_at"`
+
+	// The user reported paying in the bot chat and is told there how the payment ends
+	NotifyChat bool `json:"-" db:"notify_chat"`
+	// Only TON transfers carrying the payment comment pay it (payments made in the bot chat)
+	CommentRequired bool `json:"-" db:"comment_required"`
 }
 
 type CreatePaymentRequest struct {
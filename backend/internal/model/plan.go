@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type Plan struct {
@@ -19,9 +20,48 @@ type Plan struct {
 	IsActive     bool      `json:"is_active" db:"is_active"`
 	SortOrder    int       `json:"sort_order" db:"sort_order"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+
+	// InboundTags limits additional server inbounds provisioned for this plan (empty = all)
+	InboundTags pq.StringArray `json:"inbound_tags" db:"inbound_tags"`
+
+	// ServerGroups limits the server groups subscriptions of this plan may use (empty = all)
+	ServerGroups pq.StringArray `json:"server_groups" db:"server_groups"`
+}
+
+// PlanRef identifies a plan in responses about other objects
+type PlanRef struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
 }
 
 // TrafficBytes returns traffic limit in bytes
 func (p *Plan) TrafficBytes() int64 {
 	return int64(p.TrafficGB) * 1024 * 1024 * 1024
 }
+
+// AllowsInboundTag reports whether an additional inbound with this tag is provisioned for the plan
+func (p *Plan) AllowsInboundTag(tag string) bool {
+	if len(p.InboundTags) == 0 {
+		return true
+	}
+	for _, t := range p.InboundTags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsServerGroup reports whether subscriptions of the plan may use servers of the group.
+// A nil plan allows every group.
+func (p *Plan) AllowsServerGroup(group string) bool {
+	if p == nil || len(p.ServerGroups) == 0 {
+		return true
+	}
+	for _, g := range p.ServerGroups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
@@ -1,11 +1,28 @@
 package model
 
 import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+// Panel types selecting the provisioning implementation for a server
+const (
+	PanelTypeXUI     = "xui"
+	PanelTypeMarzban = "marzban"
+	PanelTypeOutline = "outline"
+	PanelTypeXray    = "xray"
+)
+
+// ServerGroupStandard is the group of servers available on every plan unless the plan restricts it
+const ServerGroupStandard = "standard"
+
 type Server struct {
 	ID        uuid.UUID `json:"id" db:"id"`
 	Name      string    `json:"name" db:"name"`
@@ -13,11 +30,17 @@ type Server struct {
 	City      *string   `json:"city,omitempty" db:"city"`
 	FlagEmoji string    `json:"flag_emoji" db:"flag_emoji"`
 
-	// XUI Panel connection (hidden from regular users)
+	// Panel connection (hidden from regular users); the xui_* columns are used by every panel type
+	PanelType    string `json:"-" db:"panel_type"`
 	XUIBaseURL   string `json:"-" db:"xui_base_url"`
 	XUIUsername  string `json:"-" db:"xui_username"`
 	XUIPassword  string `json:"-" db:"xui_password"`
 	XUIInboundID int    `json:"-" db:"xui_inbound_id"`
+	InboundTag   string `json:"-" db:"inbound_tag"` // xray inbound tag
+
+	// XUIPasswordUnreadable marks a server loaded for an admin whose stored password
+	// could not be decrypted; the stored value is kept on update until a new one is set
+	XUIPasswordUnreadable bool `json:"-" db:"xui_password_unreadable"`
 
 	// Server connection details
 	ServerAddress string `json:"server_address" db:"server_address"`
@@ -26,32 +49,132 @@ type Server struct {
 	ShortID       string `json:"-" db:"short_id"`
 	ServerName    string `json:"-" db:"server_name"`
 
+	// Reality parameters last read from the panel inbound (3x-ui only)
+	PanelPublicKey   string         `json:"-" db:"panel_public_key"`
+	PanelShortIDs    pq.StringArray `json:"-" db:"panel_short_ids"`
+	PanelServerNames pq.StringArray `json:"-" db:"panel_server_names"`
+	PanelPort        int            `json:"-" db:"panel_port"`
+	RealityCheckedAt *time.Time     `json:"-" db:"reality_checked_at"`
+
 	// Status
-	IsActive  bool `json:"is_active" db:"is_active"`
-	SortOrder int  `json:"sort_order" db:"sort_order"`
+	IsActive  bool   `json:"is_active" db:"is_active"`
+	SortOrder int    `json:"sort_order" db:"sort_order"`
+	Group     string `json:"group" db:"server_group"` // tier matched against plans.server_groups
 
-	// Capacity and health
+	// Capacity and health. Capacity limits active subscriptions (CurrentLoad,
+	// recounted by the load worker); MaxOnline is compared with live online clients.
 	Capacity    int        `json:"capacity" db:"capacity"`
-	CurrentLoad int        `json:"current_load" db:"current_load"`
+	CurrentLoad int        `json:"current_load" db:"current_load"` // active subscriptions
+	MaxOnline   int        `json:"max_online" db:"max_online"`     // 0 = unknown
 	PingMs      *int       `json:"ping_ms,omitempty" db:"ping_ms"`
-	Status      string     `json:"status" db:"status"` // online, offline, unknown
+	Status      string     `json:"status" db:"status"` // online, degraded, offline, unknown
 	LastCheckAt *time.Time `json:"last_check_at,omitempty" db:"last_check_at"`
 
+	// Live load from the latest panel sample
+	OnlineClients *int       `json:"online_clients,omitempty" db:"online_clients"`
+	Connections   *int       `json:"connections,omitempty" db:"connections"`
+	ThroughputBps *int64     `json:"throughput_bps,omitempty" db:"throughput_bps"`
+	LoadSampledAt *time.Time `json:"load_sampled_at,omitempty" db:"load_sampled_at"`
+	BandwidthMbps int        `json:"bandwidth_mbps" db:"bandwidth_mbps"` // 0 = unknown
+
+	// Hosting cost per month
+	MonthlyCost  float64 `json:"-" db:"monthly_cost"`
+	CostCurrency string  `json:"-" db:"cost_currency"`
+
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // LoadPercent returns server load as percentage
 func (s *Server) LoadPercent() float64 {
-	if s.Capacity <= 0 {
-		return 0
+	return s.LoadRatio() * 100
+}
+
+// LoadRatio returns the load of the server as the highest of active subscriptions
+// per capacity and, with a recent panel sample, online clients per max online and
+// throughput per bandwidth. A zero capacity puts no limit on subscriptions, so
+// only the live ratios count for it.
+func (s *Server) LoadRatio() float64 {
+	var ratio float64
+	if s.Capacity > 0 {
+		ratio = float64(s.CurrentLoad) / float64(s.Capacity)
+	}
+	if !s.HasLiveLoad() {
+		return ratio
+	}
+
+	if s.MaxOnline > 0 {
+		ratio = max(ratio, float64(*s.OnlineClients)/float64(s.MaxOnline))
+	}
+	if s.ThroughputBps != nil && s.BandwidthMbps > 0 {
+		bandwidthBps := float64(s.BandwidthMbps) * 1_000_000 / 8
+		ratio = max(ratio, float64(*s.ThroughputBps)/bandwidthBps)
 	}
-	return float64(s.CurrentLoad) / float64(s.Capacity) * 100
+	return ratio
 }
 
-// IsOnline returns true if server is online and active
+// HasLiveLoad reports whether the panel reported the server's load recently
+func (s *Server) HasLiveLoad() bool {
+	return s.OnlineClients != nil && s.LoadSampledAt != nil && time.Since(*s.LoadSampledAt) < LiveLoadMaxAge
+}
+
+// IsFull reports whether the active subscriptions reached the capacity; a zero capacity is unlimited
+func (s *Server) IsFull() bool {
+	return s.Capacity > 0 && s.CurrentLoad >= s.Capacity
+}
+
+// CountryCode returns the ISO 3166 country code encoded by the flag emoji, or
+// the lowercased country name when the flag is not set
+func (s *Server) CountryCode() string {
+	runes := []rune(s.FlagEmoji)
+	if len(runes) == 2 && isRegionalIndicator(runes[0]) && isRegionalIndicator(runes[1]) {
+		return string([]rune{'A' + runes[0] - 0x1F1E6, 'A' + runes[1] - 0x1F1E6})
+	}
+	return strings.ToLower(strings.TrimSpace(s.Country))
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// IsOnline returns true if server is active and serves VPN traffic. Degraded
+// servers count as online: their panel is down but existing keys still work.
 func (s *Server) IsOnline() bool {
-	return s.IsActive && s.Status == "online"
+	return s.IsActive && (s.Status == ServerStatusOnline || s.Status == ServerStatusDegraded)
+}
+
+// SameConnectionParams reports whether connection keys built for other are still valid for s
+func (s *Server) SameConnectionParams(other *Server) bool {
+	return s.ServerAddress == other.ServerAddress &&
+		s.ServerPort == other.ServerPort &&
+		s.PublicKey == other.PublicKey &&
+		s.ShortID == other.ShortID &&
+		s.ServerName == other.ServerName &&
+		s.PanelType == other.PanelType &&
+		s.XUIInboundID == other.XUIInboundID
+}
+
+// RealityMismatches lists configured connection parameters that the panel inbound
+// would reject. Empty values fall back to the panel's and never mismatch.
+func (s *Server) RealityMismatches() []string {
+	if s.RealityCheckedAt == nil {
+		return nil
+	}
+
+	var mismatches []string
+	if s.ServerPort != 0 && s.PanelPort != 0 && s.ServerPort != s.PanelPort {
+		mismatches = append(mismatches, fmt.Sprintf("server_port %d differs from inbound port %d", s.ServerPort, s.PanelPort))
+	}
+	if s.PublicKey != "" && s.PanelPublicKey != "" && s.PublicKey != s.PanelPublicKey {
+		mismatches = append(mismatches, "public_key differs from the inbound's Reality public key")
+	}
+	if s.ShortID != "" && len(s.PanelShortIDs) > 0 && !slices.Contains(s.PanelShortIDs, s.ShortID) {
+		mismatches = append(mismatches, fmt.Sprintf("short_id %q is not accepted by the inbound", s.ShortID))
+	}
+	if s.ServerName != "" && len(s.PanelServerNames) > 0 && !slices.Contains(s.PanelServerNames, s.ServerName) {
+		mismatches = append(mismatches, fmt.Sprintf("server_name %q is not accepted by the inbound", s.ServerName))
+	}
+	return mismatches
 }
 
 // ServerPublic is the public view of server for users (without sensitive data)
@@ -65,10 +188,30 @@ type ServerPublic struct {
 	PingMs      *int      `json:"ping_ms,omitempty"`
 	Status      string    `json:"status"`
 	LoadPercent float64   `json:"load_percent"`
+	IsFull      bool      `json:"is_full"`
+	Group       string    `json:"group"`
+
+	// PingHost is the address:port the mini app measures latency to
+	PingHost string `json:"ping_host,omitempty"`
+	// LatencyMs is the latency measured by the user, or by other users of the
+	// user's network when LatencySource is "network"
+	LatencyMs     *int   `json:"latency_ms,omitempty"`
+	LatencySource string `json:"latency_source,omitempty"`
+	// Recommended marks the server that would be picked for the user
+	Recommended bool `json:"recommended"`
+
+	// Locked is set when the user's plan does not include the server's group;
+	// UnlockPlans are the plans to upgrade to
+	Locked      bool      `json:"locked"`
+	UnlockPlans []PlanRef `json:"unlock_plans,omitempty"`
 }
 
 // ToPublic converts Server to ServerPublic
 func (s *Server) ToPublic() ServerPublic {
+	var pingHost string
+	if s.ServerAddress != "" && s.ServerPort != 0 {
+		pingHost = net.JoinHostPort(s.ServerAddress, strconv.Itoa(s.ServerPort))
+	}
 	return ServerPublic{
 		ID:          s.ID,
 		Name:        s.Name,
@@ -79,6 +222,9 @@ func (s *Server) ToPublic() ServerPublic {
 		PingMs:      s.PingMs,
 		Status:      s.Status,
 		LoadPercent: s.LoadPercent(),
+		IsFull:      s.IsFull(),
+		Group:       s.Group,
+		PingHost:    pingHost,
 	}
 }
 
@@ -89,10 +235,12 @@ type ServerAdmin struct {
 	Country       string     `json:"country"`
 	City          *string    `json:"city,omitempty"`
 	FlagEmoji     string     `json:"flag_emoji"`
+	PanelType     string     `json:"panel_type"`
 	XUIBaseURL    string     `json:"xui_base_url"`
 	XUIUsername   string     `json:"xui_username"`
-	XUIPassword   string     `json:"xui_password"`
+	XUIPassword   string     `json:"xui_password"` // MaskedSecret when set; revealed only by a superadmin
 	XUIInboundID  int        `json:"xui_inbound_id"`
+	InboundTag    string     `json:"inbound_tag"`
 	ServerAddress string     `json:"server_address"`
 	ServerPort    int        `json:"server_port"`
 	PublicKey     string     `json:"public_key"`
@@ -100,13 +248,40 @@ type ServerAdmin struct {
 	ServerName    string     `json:"server_name"`
 	IsActive      bool       `json:"is_active"`
 	SortOrder     int        `json:"sort_order"`
+	Group         string     `json:"group"`
 	Capacity      int        `json:"capacity"`
-	CurrentLoad   int        `json:"current_load"`
+	CurrentLoad   int        `json:"current_load"` // active subscriptions
+	MaxOnline     int        `json:"max_online"`
 	PingMs        *int       `json:"ping_ms,omitempty"`
 	Status        string     `json:"status"`
 	LastCheckAt   *time.Time `json:"last_check_at,omitempty"`
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// Reality parameters read from the panel and configured values that disagree with them
+	PanelPublicKey    string     `json:"panel_public_key,omitempty"`
+	PanelShortIDs     []string   `json:"panel_short_ids,omitempty"`
+	PanelServerNames  []string   `json:"panel_server_names,omitempty"`
+	PanelPort         int        `json:"panel_port,omitempty"`
+	RealityCheckedAt  *time.Time `json:"reality_checked_at,omitempty"`
+	RealityMismatches []string   `json:"reality_mismatches,omitempty"`
+	RealityError      string     `json:"reality_error,omitempty"` // set when create/update could not read the panel
+
+	// Live load reported by the panel
+	OnlineClients *int       `json:"online_clients,omitempty"`
+	Connections   *int       `json:"connections,omitempty"`
+	ThroughputBps *int64     `json:"throughput_bps,omitempty"`
+	LoadSampledAt *time.Time `json:"load_sampled_at,omitempty"`
+	BandwidthMbps int        `json:"bandwidth_mbps"`
+	LoadPercent   float64    `json:"load_percent"`
+
+	MonthlyCost  float64 `json:"monthly_cost"`
+	CostCurrency string  `json:"cost_currency"`
+
+	// Panel circuit breaker state: closed, open or half_open
+	PanelState   string     `json:"panel_state,omitempty"`
+	PanelError   string     `json:"panel_error,omitempty"`
+	PanelRetryAt *time.Time `json:"panel_retry_at,omitempty"`
 }
 
 // ToAdmin converts Server to ServerAdmin
@@ -117,10 +292,12 @@ func (s *Server) ToAdmin() ServerAdmin {
 		Country:       s.Country,
 		City:          s.City,
 		FlagEmoji:     s.FlagEmoji,
+		PanelType:     s.PanelType,
 		XUIBaseURL:    s.XUIBaseURL,
 		XUIUsername:   s.XUIUsername,
-		XUIPassword:   s.XUIPassword,
+		XUIPassword:   maskSecret(s.XUIPassword),
 		XUIInboundID:  s.XUIInboundID,
+		InboundTag:    s.InboundTag,
 		ServerAddress: s.ServerAddress,
 		ServerPort:    s.ServerPort,
 		PublicKey:     s.PublicKey,
@@ -128,12 +305,61 @@ func (s *Server) ToAdmin() ServerAdmin {
 		ServerName:    s.ServerName,
 		IsActive:      s.IsActive,
 		SortOrder:     s.SortOrder,
+		Group:         s.Group,
 		Capacity:      s.Capacity,
 		CurrentLoad:   s.CurrentLoad,
+		MaxOnline:     s.MaxOnline,
 		PingMs:        s.PingMs,
 		Status:        s.Status,
 		LastCheckAt:   s.LastCheckAt,
 		CreatedAt:     s.CreatedAt,
 		UpdatedAt:     s.UpdatedAt,
+
+		PanelPublicKey:    s.PanelPublicKey,
+		PanelShortIDs:     s.PanelShortIDs,
+		PanelServerNames:  s.PanelServerNames,
+		PanelPort:         s.PanelPort,
+		RealityCheckedAt:  s.RealityCheckedAt,
+		RealityMismatches: s.RealityMismatches(),
+
+		OnlineClients: s.OnlineClients,
+		Connections:   s.Connections,
+		ThroughputBps: s.ThroughputBps,
+		LoadSampledAt: s.LoadSampledAt,
+		BandwidthMbps: s.BandwidthMbps,
+		LoadPercent:   s.LoadPercent(),
+
+		MonthlyCost:  s.MonthlyCost,
+		CostCurrency: s.CostCurrency,
+	}
+}
+
+// MaskedSecret replaces stored secrets in admin responses. Sending it back
+// in an update keeps the stored value.
+const MaskedSecret = "********"
+
+func maskSecret(value string) string {
+	if value == "" {
+		return ""
 	}
+	return MaskedSecret
+}
+
+// ServerCredentials are the panel credentials of a server, returned by the audited reveal action
+type ServerCredentials struct {
+	ServerID    uuid.UUID `json:"server_id"`
+	XUIUsername string    `json:"xui_username"`
+	XUIPassword string    `json:"xui_password"`
+}
+
+// ServerInbound is an additional 3x-ui inbound on a server (e.g. a WS+TLS fallback)
+type ServerInbound struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	ServerID     uuid.UUID `json:"server_id" db:"server_id"`
+	XUIInboundID int       `json:"xui_inbound_id" db:"xui_inbound_id"`
+	Tag          string    `json:"tag" db:"tag"`
+	Name         string    `json:"name" db:"name"`
+	IsActive     bool      `json:"is_active" db:"is_active"`
+	SortOrder    int       `json:"sort_order" db:"sort_order"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 }
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ServerEndpoint is an address a server is reachable at. Subscription keys are
+// offered once per active endpoint, in order; retiring an endpoint (e.g. a
+// blocked IP) removes it from outputs without touching subscriptions.
+type ServerEndpoint struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	ServerID    uuid.UUID  `json:"server_id" db:"server_id"`
+	Address     string     `json:"address" db:"address"`
+	Port        int        `json:"port" db:"port"` // primary inbound port, 0 = the server's port
+	Label       string     `json:"label" db:"label"`
+	SortOrder   int        `json:"sort_order" db:"sort_order"`
+	RetiredAt   *time.Time `json:"retired_at,omitempty" db:"retired_at"`
+	Status      string     `json:"status" db:"status"` // online, offline, unknown
+	PingMs      *int       `json:"ping_ms,omitempty" db:"ping_ms"`
+	LastError   *string    `json:"last_error,omitempty" db:"last_error"`
+	LastCheckAt *time.Time `json:"last_check_at,omitempty" db:"last_check_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// IsRetired reports whether the endpoint is no longer offered to users
+func (e *ServerEndpoint) IsRetired() bool {
+	return e.RetiredAt != nil
+}
+
+// PortOr returns the endpoint's port, or the given port when the endpoint has none
+func (e *ServerEndpoint) PortOr(port int) int {
+	if e.Port > 0 {
+		return e.Port
+	}
+	return port
+}
+
+// HostPort returns the endpoint's address with its port, or the given port when it has none
+func (e *ServerEndpoint) HostPort(port int) string {
+	return net.JoinHostPort(e.Address, strconv.Itoa(e.PortOr(port)))
+}
+
+// OutputEndpoints returns the endpoints offered in a server's subscription keys:
+// those not retired, in order, with endpoints failing health checks moved last
+func OutputEndpoints(endpoints []ServerEndpoint) []ServerEndpoint {
+	var output []ServerEndpoint
+	for _, endpoint := range endpoints {
+		if !endpoint.IsRetired() {
+			output = append(output, endpoint)
+		}
+	}
+	sort.SliceStable(output, func(i, j int) bool {
+		return output[i].Status != ServerStatusOffline && output[j].Status == ServerStatusOffline
+	})
+	return output
+}
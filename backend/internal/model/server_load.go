@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LiveLoadMaxAge is how long a load sample reported by the panel is used for
+// load balancing before falling back to subscription counts
+const LiveLoadMaxAge = 5 * time.Minute
+
+// ServerLoadSample is the load of a server at one point in time. Throughput is
+// not set on the first sample after the panel host restarted.
+type ServerLoadSample struct {
+	ID            int64     `json:"id" db:"id"`
+	ServerID      uuid.UUID `json:"server_id" db:"server_id"`
+	SampledAt     time.Time `json:"sampled_at" db:"sampled_at"`
+	OnlineClients int       `json:"online_clients" db:"online_clients"`
+	Connections   *int      `json:"connections,omitempty" db:"connections"`
+	UploadBps     *int64    `json:"upload_bps,omitempty" db:"upload_bps"`
+	DownloadBps   *int64    `json:"download_bps,omitempty" db:"download_bps"`
+	Subscriptions int       `json:"subscriptions" db:"subscriptions"`
+}
+
+// ThroughputBps returns upload plus download throughput in bytes per second
+func (s *ServerLoadSample) ThroughputBps() *int64 {
+	if s.UploadBps == nil || s.DownloadBps == nil {
+		return nil
+	}
+	total := *s.UploadBps + *s.DownloadBps
+	return &total
+}
+
+// ServerLoadReport is the current load of a server and its samples over a period
+type ServerLoadReport struct {
+	ServerID      uuid.UUID          `json:"server_id"`
+	Name          string             `json:"name"`
+	Capacity      int                `json:"capacity"`
+	Subscriptions int                `json:"subscriptions"`
+	MaxOnline     int                `json:"max_online"`
+	OnlineClients *int               `json:"online_clients,omitempty"`
+	Connections   *int               `json:"connections,omitempty"`
+	ThroughputBps *int64             `json:"throughput_bps,omitempty"`
+	LoadPercent   float64            `json:"load_percent"`
+	SampledAt     *time.Time         `json:"sampled_at,omitempty"`
+	Period        string             `json:"period"`
+	Samples       []ServerLoadSample `json:"samples"`
+}
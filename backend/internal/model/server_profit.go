@@ -0,0 +1,132 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Currencies server costs can be recorded in
+const (
+	CostCurrencyUSD = "USD"
+	CostCurrencyRUB = "RUB"
+	CostCurrencyTON = "TON"
+)
+
+// IsCostCurrency reports whether server costs can be recorded in the currency
+func IsCostCurrency(currency string) bool {
+	switch currency {
+	case CostCurrencyUSD, CostCurrencyRUB, CostCurrencyTON:
+		return true
+	}
+	return false
+}
+
+// ServerCost is a period during which a server was charged a monthly cost. A
+// period is open (EffectiveTo nil) while the server is active. The server may
+// have been deleted since, so its name is kept with the period.
+type ServerCost struct {
+	ID            uuid.UUID  `db:"id"`
+	ServerID      uuid.UUID  `db:"server_id"`
+	Name          string     `db:"name"`
+	Country       string     `db:"country"`
+	FlagEmoji     string     `db:"flag_emoji"`
+	MonthlyCost   float64    `db:"monthly_cost"`
+	CostCurrency  string     `db:"cost_currency"`
+	EffectiveFrom time.Time  `db:"effective_from"`
+	EffectiveTo   *time.Time `db:"effective_to"`
+}
+
+// ServerRevenue is the revenue of completed subscription payments attributed to
+// a server in one month and currency. ServerID is nil for payments that could
+// not be attributed to a server.
+type ServerRevenue struct {
+	ServerID *uuid.UUID `db:"server_id"`
+	Month    time.Time  `db:"month"`
+	Currency string     `db:"currency"`
+	Amount   float64    `db:"amount"`
+	Payments int        `db:"payments"`
+}
+
+// ServerMonthlyUsers is the number of users with a subscription on a server during a month
+type ServerMonthlyUsers struct {
+	ServerID uuid.UUID `db:"server_id"`
+	Month    time.Time `db:"month"`
+	Users    int       `db:"users"`
+}
+
+// ServerMonthlyTraffic is the host traffic of a server during a month
+type ServerMonthlyTraffic struct {
+	ServerID      uuid.UUID `db:"server_id"`
+	Month         time.Time `db:"month"`
+	UploadBytes   int64     `db:"upload_bytes"`
+	DownloadBytes int64     `db:"download_bytes"`
+}
+
+// ServerProfit is the revenue, cost and usage of a server in one month.
+// Money fields ending in USD are converted at the rates of the report.
+type ServerProfit struct {
+	Month     string    `json:"month"` // YYYY-MM
+	ServerID  uuid.UUID `json:"server_id"`
+	Name      string    `json:"name"`
+	Country   string    `json:"country"`
+	FlagEmoji string    `json:"flag_emoji"`
+	IsActive  bool      `json:"is_active"`
+
+	Payments     int     `json:"payments"`
+	RevenueTON   float64 `json:"revenue_ton"`
+	RevenueStars float64 `json:"revenue_stars"`
+	RevenueUSD   float64 `json:"revenue_usd"`
+
+	// Cost is the monthly cost in effect at the end of the month; CostUSD is
+	// what the server was charged for the part of the month it was active
+	Cost         float64 `json:"cost"`
+	CostCurrency string  `json:"cost_currency"`
+	CostUSD      float64 `json:"cost_usd"`
+
+	MarginUSD     float64  `json:"margin_usd"`
+	MarginPercent *float64 `json:"margin_percent,omitempty"` // not set without revenue
+
+	Users         int   `json:"users"`
+	UploadBytes   int64 `json:"upload_bytes"`
+	DownloadBytes int64 `json:"download_bytes"`
+}
+
+// ProfitRates are the exchange rates a profitability report was converted at
+type ProfitRates struct {
+	TONUSD  float64 `json:"ton_usd"`
+	USDRUB  float64 `json:"usd_rub"`
+	StarUSD float64 `json:"star_usd"`
+}
+
+// ToUSD converts an amount in a payment or cost currency (XTR for Stars) to USD
+func (r ProfitRates) ToUSD(amount float64, currency string) (float64, bool) {
+	switch currency {
+	case CostCurrencyUSD:
+		return amount, true
+	case CostCurrencyRUB:
+		if r.USDRUB <= 0 {
+			return 0, false
+		}
+		return amount / r.USDRUB, true
+	case CostCurrencyTON:
+		return amount * r.TONUSD, true
+	case "XTR":
+		return amount * r.StarUSD, true
+	}
+	return 0, false
+}
+
+// ProfitabilityReport compares revenue and cost of every server month by month
+type ProfitabilityReport struct {
+	From  string      `json:"from"` // YYYY-MM, inclusive
+	To    string      `json:"to"`   // YYYY-MM, inclusive
+	Rates ProfitRates `json:"rates"`
+
+	Servers []ServerProfit `json:"servers"`
+
+	RevenueUSD      float64 `json:"revenue_usd"`
+	CostUSD         float64 `json:"cost_usd"`
+	MarginUSD       float64 `json:"margin_usd"`
+	UnattributedUSD float64 `json:"unattributed_usd"` // revenue of payments without a server, not in the totals above
+}
@@ -0,0 +1,65 @@
+package model
+
+import "strings"
+
+// Server selection strategies used to pick a server for new subscriptions
+const (
+	// SelectionLeastLoaded picks the server with the lowest load/capacity ratio
+	SelectionLeastLoaded = "least_loaded"
+	// SelectionWeighted scores servers by load, ping, health and country preference
+	SelectionWeighted = "weighted"
+)
+
+// ServerSelectionConfig tunes how a server is picked when the user does not
+// choose one. It is stored as JSON in the server_selection setting.
+type ServerSelectionConfig struct {
+	Strategy string `json:"strategy"`
+
+	// Weights of the weighted strategy; each score is in [0, 1]
+	LoadWeight    float64 `json:"load_weight"`
+	PingWeight    float64 `json:"ping_weight"`
+	HealthWeight  float64 `json:"health_weight"`
+	CountryWeight float64 `json:"country_weight"`
+
+	// Ping at or above which a server gets no ping score
+	MaxPingMs int `json:"max_ping_ms"`
+
+	// Preferred countries (ISO 3166 codes, most preferred first) by Telegram language code.
+	// The country of the user's last server always comes first.
+	CountryPreferences map[string][]string `json:"country_preferences"`
+}
+
+// DefaultServerSelectionConfig returns the configuration used until admins change it
+func DefaultServerSelectionConfig() *ServerSelectionConfig {
+	return &ServerSelectionConfig{
+		Strategy:      SelectionWeighted,
+		LoadWeight:    0.4,
+		PingWeight:    0.2,
+		HealthWeight:  0.2,
+		CountryWeight: 0.2,
+		MaxPingMs:     300,
+		CountryPreferences: map[string][]string{
+			"ru": {"FI", "NL", "DE", "EE", "LV", "SE"},
+			"uk": {"PL", "DE", "NL", "FI"},
+			"be": {"PL", "LT", "LV", "DE"},
+			"kk": {"KZ", "TR", "DE", "NL"},
+			"uz": {"KZ", "TR", "DE", "NL"},
+			"en": {"US", "GB", "NL", "DE"},
+			"de": {"DE", "NL", "AT", "CH"},
+			"tr": {"TR", "DE", "NL"},
+			"fa": {"TR", "DE", "NL", "AE"},
+		},
+	}
+}
+
+// PreferredCountries returns the preferred countries for a Telegram language code such as "ru" or "pt-br"
+func (c *ServerSelectionConfig) PreferredCountries(languageCode string) []string {
+	lang := strings.ToLower(languageCode)
+	if countries, ok := c.CountryPreferences[lang]; ok {
+		return countries
+	}
+	if base, _, found := strings.Cut(lang, "-"); found {
+		return c.CountryPreferences[base]
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status notice kinds
+const (
+	NoticeKindIncident    = "incident"
+	NoticeKindMaintenance = "maintenance"
+)
+
+// Overall states of the public status page
+const (
+	StatusOperational   = "operational"
+	StatusDegraded      = "degraded"
+	StatusPartialOutage = "partial_outage"
+	StatusMajorOutage   = "major_outage"
+)
+
+// LocationStatusMaintenance is shown instead of the health status of a server under announced maintenance
+const LocationStatusMaintenance = "maintenance"
+
+// StatusNotice is an incident or maintenance notice posted by an admin
+type StatusNotice struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	Kind      string     `json:"kind" db:"kind"` // incident, maintenance
+	Title     string     `json:"title" db:"title"`
+	Message   string     `json:"message" db:"message"`
+	ServerID  *uuid.UUID `json:"server_id,omitempty" db:"server_id"` // nil = whole service
+	StartsAt  time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at,omitempty" db:"ends_at"` // nil = until resolved
+	CreatedBy int64      `json:"created_by" db:"created_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// IsActiveAt returns true if the notice is in effect at the given time
+func (n *StatusNotice) IsActiveAt(t time.Time) bool {
+	return !n.StartsAt.After(t) && (n.EndsAt == nil || n.EndsAt.After(t))
+}
+
+// StatusPage is the public service status. It is built from health history and
+// must not expose server addresses, load or failure details.
+type StatusPage struct {
+	Status    string           `json:"status"` // operational, degraded, partial_outage, major_outage
+	Locations []LocationStatus `json:"locations"`
+	Incidents []StatusIncident `json:"incidents"`
+	Notices   []PublicNotice   `json:"notices"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// LocationStatus is the public status of one server
+type LocationStatus struct {
+	Name      string   `json:"name"`
+	Country   string   `json:"country"`
+	City      *string  `json:"city,omitempty"`
+	FlagEmoji string   `json:"flag_emoji"`
+	Status    string   `json:"status"` // online, degraded, offline, maintenance, unknown
+	Uptime24h *float64 `json:"uptime_24h,omitempty"`
+	Uptime7d  *float64 `json:"uptime_7d,omitempty"`
+}
+
+// StatusIncident is an ongoing outage detected by health checks
+type StatusIncident struct {
+	Location  string    `json:"location"`
+	Status    string    `json:"status"` // offline, degraded
+	StartedAt time.Time `json:"started_at"`
+}
+
+// PublicNotice is a status notice as shown on the public status page
+type PublicNotice struct {
+	Kind     string     `json:"kind"`
+	Title    string     `json:"title"`
+	Message  string     `json:"message,omitempty"`
+	Location string     `json:"location,omitempty"` // empty = whole service
+	StartsAt time.Time  `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+}
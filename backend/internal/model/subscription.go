@@ -29,6 +29,9 @@ type Subscription struct {
 	TrafficUsed   int64              `json:"traffic_used" db:"traffic_used"`
 	MaxDevices    int                `json:"max_devices" db:"max_devices"`
 	CreatedAt     time.Time          `json:"created_at" db:"created_at"`
+
+	// Raw traffic counter of the client at the last sync (stateless nodes only)
+	TrafficCounter int64 `json:"-" db:"traffic_counter"`
 }
 
 type SubscriptionWithPlan struct {
@@ -70,3 +73,25 @@ func (s *Subscription) DaysRemaining() int {
 	}
 	return int(duration.Hours() / 24)
 }
+
+// SubscriptionInbound is a client provisioned for a subscription on an additional server inbound
+type SubscriptionInbound struct {
+	ID              uuid.UUID `json:"id" db:"id"`
+	SubscriptionID  uuid.UUID `json:"subscription_id" db:"subscription_id"`
+	ServerInboundID uuid.UUID `json:"server_inbound_id" db:"server_inbound_id"`
+	XUIClientID     string    `json:"xui_client_id" db:"xui_client_id"`
+	XUIEmail        string    `json:"xui_email" db:"xui_email"`
+	ConnectionKey   string    `json:"connection_key" db:"connection_key"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+// PrimaryInboundTag labels the key of the server's primary inbound
+const PrimaryInboundTag = "primary"
+
+// ConnectionKey is one of the keys a subscription exposes, one per inbound and server endpoint
+type ConnectionKey struct {
+	Tag      string     `json:"tag"`
+	Name     string     `json:"name"`
+	Key      string     `json:"key"`
+	Endpoint *uuid.UUID `json:"endpoint_id,omitempty"`
+}
@@ -10,6 +10,7 @@ type User struct {
 	FirstName           *string   `json:"first_name,omitempty" db:"first_name"`
 	LastName            *string   `json:"last_name,omitempty" db:"last_name"`
 	LanguageCode        *string   `json:"language_code,omitempty" db:"language_code"`
+	Language            *string   `json:"language,omitempty" db:"language"`           // Chosen language, overrides LanguageCode
 	ReferralCode        string    `json:"referral_code" db:"referral_code"`
 	ReferredBy          *int64    `json:"referred_by,omitempty" db:"referred_by"`
 	Balance             float64   `json:"balance" db:"balance"`                            // Balance in TON
@@ -0,0 +1,236 @@
+// Package outline is a client for the Outline (Shadowsocks) server Management API.
+package outline
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrKeyNotFound is returned when the access key does not exist on the server
+var ErrKeyNotFound = errors.New("outline: access key not found")
+
+// Client talks to the Management API. The API URL embeds the secret prefix
+// (https://host:port/SECRET) as printed by the Outline installer.
+type Client struct {
+	apiURL string
+	client *http.Client
+}
+
+// AccessKey is an Outline access key
+type AccessKey struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Password  string `json:"password"`
+	Port      int    `json:"port"`
+	Method    string `json:"method"`
+	AccessURL string `json:"accessUrl"`
+}
+
+// ServerInfo is the subset of GET /server used by the backend
+type ServerInfo struct {
+	Name                  string `json:"name"`
+	ServerID              string `json:"serverId"`
+	HostnameForAccessKeys string `json:"hostnameForAccessKeys"`
+	PortForNewAccessKeys  int    `json:"portForNewAccessKeys"`
+}
+
+// NewClient creates a client for the API URL. The Management API uses a self-signed
+// certificate; when certSHA256 (hex, as printed by the installer) is set the
+// certificate is pinned to it, otherwise normal TLS verification applies.
+func NewClient(apiURL, certSHA256 string) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	fingerprint := strings.ToLower(strings.ReplaceAll(certSHA256, ":", ""))
+	if fingerprint != "" {
+		transport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true, // verified against the pinned fingerprint below
+			VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+				if len(rawCerts) == 0 {
+					return errors.New("outline: no server certificate")
+				}
+				sum := sha256.Sum256(rawCerts[0])
+				if hex.EncodeToString(sum[:]) != fingerprint {
+					return errors.New("outline: server certificate fingerprint mismatch")
+				}
+				return nil
+			},
+		}
+	}
+
+	return &Client{
+		apiURL: strings.TrimRight(apiURL, "/"),
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: transport,
+		},
+	}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, payload any, out any) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.apiURL+path, body)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s request failed: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrKeyNotFound
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s failed: status=%d, body=%s", method, path, resp.StatusCode, string(respBody))
+	}
+
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode response (status=%d, body=%s): %w", resp.StatusCode, string(respBody), err)
+	}
+	return nil
+}
+
+// GetServer returns server information
+func (c *Client) GetServer(ctx context.Context) (*ServerInfo, error) {
+	var info ServerInfo
+	if err := c.do(ctx, http.MethodGet, "/server", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// CreateAccessKey creates a new access key and names it
+func (c *Client) CreateAccessKey(ctx context.Context, name string) (*AccessKey, error) {
+	var key AccessKey
+	if err := c.do(ctx, http.MethodPost, "/access-keys", nil, &key); err != nil {
+		return nil, fmt.Errorf("create access key failed: %w", err)
+	}
+
+	if name != "" {
+		if err := c.do(ctx, http.MethodPut, "/access-keys/"+url.PathEscape(key.ID)+"/name", map[string]string{"name": name}, nil); err != nil {
+			_ = c.DeleteAccessKey(ctx, key.ID)
+			return nil, fmt.Errorf("rename access key failed: %w", err)
+		}
+		key.Name = name
+	}
+
+	return &key, nil
+}
+
+// GetAccessKeys returns all access keys
+func (c *Client) GetAccessKeys(ctx context.Context) ([]AccessKey, error) {
+	var result struct {
+		AccessKeys []AccessKey `json:"accessKeys"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/access-keys", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.AccessKeys, nil
+}
+
+// GetAccessKey finds an access key by ID
+func (c *Client) GetAccessKey(ctx context.Context, id string) (*AccessKey, error) {
+	keys, err := c.GetAccessKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range keys {
+		if keys[i].ID == id {
+			return &keys[i], nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+// SetDataLimit sets a per-key transfer limit in bytes; zero or less removes the limit
+func (c *Client) SetDataLimit(ctx context.Context, id string, bytes int64) error {
+	path := "/access-keys/" + url.PathEscape(id) + "/data-limit"
+	if bytes <= 0 {
+		err := c.do(ctx, http.MethodDelete, path, nil, nil)
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			return fmt.Errorf("remove data limit failed: %w", err)
+		}
+		return nil
+	}
+
+	payload := map[string]any{"limit": map[string]int64{"bytes": bytes}}
+	if err := c.do(ctx, http.MethodPut, path, payload, nil); err != nil {
+		return fmt.Errorf("set data limit failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteAccessKey deletes an access key; a missing key is not an error
+func (c *Client) DeleteAccessKey(ctx context.Context, id string) error {
+	err := c.do(ctx, http.MethodDelete, "/access-keys/"+url.PathEscape(id), nil, nil)
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return fmt.Errorf("delete access key failed: %w", err)
+	}
+	return nil
+}
+
+// GetTransferMetrics returns bytes transferred per access key ID
+func (c *Client) GetTransferMetrics(ctx context.Context) (map[string]int64, error) {
+	var result struct {
+		BytesTransferredByUserID map[string]int64 `json:"bytesTransferredByUserId"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/metrics/transfer", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.BytesTransferredByUserID, nil
+}
+
+// ShareLink returns an ss:// link for the key. A non-empty host replaces the
+// hostname Outline advertises; remark is set as the link name.
+func (k *AccessKey) ShareLink(host, remark string) (string, error) {
+	if host == "" {
+		u, err := url.Parse(k.AccessURL)
+		if err != nil {
+			return "", fmt.Errorf("invalid access url: %w", err)
+		}
+		host = u.Hostname()
+	}
+	if host == "" {
+		return "", errors.New("outline: access key has no host")
+	}
+
+	userInfo := base64.RawURLEncoding.EncodeToString([]byte(k.Method + ":" + k.Password))
+	hostPort := net.JoinHostPort(host, strconv.Itoa(k.Port))
+	return fmt.Sprintf("ss://%s@%s/?outline=1#%s", userInfo, hostPort, url.PathEscape(remark)), nil
+}
@@ -0,0 +1,337 @@
+package panel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrUnavailable matches every UnavailableError, so callers can use errors.Is
+// without inspecting the reason
+var ErrUnavailable = errors.New("panel unavailable")
+
+// UnavailableError is returned without contacting the panel when its circuit
+// breaker is open or too many requests to it are already in flight
+type UnavailableError struct {
+	Reason     string
+	RetryAfter time.Duration
+	Err        error // last failure that opened the breaker, if any
+}
+
+func (e *UnavailableError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("panel unavailable: %s: %v", e.Reason, e.Err)
+	}
+	return "panel unavailable: " + e.Reason
+}
+
+func (e *UnavailableError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// Breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// BreakerConfig configures a Breaker
+type BreakerConfig struct {
+	FailureThreshold int           // consecutive failures that open the breaker
+	OpenTimeout      time.Duration // how long the breaker stays open before a probe is let through
+	MaxInFlight      int           // concurrent requests allowed to the panel, 0 = unlimited
+}
+
+// BreakerStatus is a snapshot of a breaker for the admin API
+type BreakerStatus struct {
+	State     string
+	Failures  int
+	InFlight  int
+	LastError string
+	RetryAt   *time.Time
+}
+
+// Breaker is a circuit breaker with an in-flight limit, shared by all panel
+// clients of one server. After FailureThreshold consecutive connectivity
+// failures it rejects requests for OpenTimeout, then lets a single probe
+// through (half-open): success closes it, failure opens it again.
+type Breaker struct {
+	cfg BreakerConfig
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	lastErr  error
+	probing  bool
+	inFlight int
+}
+
+func NewBreaker(cfg BreakerConfig) *Breaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 1
+	}
+	return &Breaker{cfg: cfg, state: BreakerClosed}
+}
+
+// acquire reserves a request slot. The returned function must be called with
+// the request's result to release the slot and record the outcome.
+func (b *Breaker) acquire() (func(error), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	probe := false
+	switch b.state {
+	case BreakerOpen:
+		retryAt := b.openedAt.Add(b.cfg.OpenTimeout)
+		if time.Now().Before(retryAt) {
+			return nil, &UnavailableError{Reason: "circuit open", RetryAfter: time.Until(retryAt), Err: b.lastErr}
+		}
+		b.state = BreakerHalfOpen
+		fallthrough
+	case BreakerHalfOpen:
+		if b.probing {
+			return nil, &UnavailableError{Reason: "circuit half-open, probe in progress", RetryAfter: time.Second, Err: b.lastErr}
+		}
+		b.probing = true
+		probe = true
+	}
+
+	if b.cfg.MaxInFlight > 0 && b.inFlight >= b.cfg.MaxInFlight {
+		if probe {
+			b.probing = false
+		}
+		return nil, &UnavailableError{Reason: "too many requests in flight", RetryAfter: time.Second}
+	}
+	b.inFlight++
+
+	return func(err error) { b.release(probe, err) }, nil
+}
+
+func (b *Breaker) release(probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.inFlight--
+	if probe {
+		b.probing = false
+	}
+
+	if !isConnectivityError(err) {
+		// The panel answered, even if with an application error
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	b.lastErr = err
+	if probe || b.failures >= b.cfg.FailureThreshold {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// Reset closes the breaker, e.g. after the server's settings were changed
+func (b *Breaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = BreakerClosed
+	b.failures = 0
+	b.lastErr = nil
+}
+
+// Status returns a snapshot of the breaker
+func (b *Breaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	st := BreakerStatus{
+		State:    b.state,
+		Failures: b.failures,
+		InFlight: b.inFlight,
+	}
+	if b.lastErr != nil {
+		st.LastError = b.lastErr.Error()
+	}
+	if b.state == BreakerOpen {
+		retryAt := b.openedAt.Add(b.cfg.OpenTimeout)
+		st.RetryAt = &retryAt
+	}
+	return st
+}
+
+// Check returns an UnavailableError while the breaker is open, without reserving a slot
+func (b *Breaker) Check() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != BreakerOpen {
+		return nil
+	}
+	retryAt := b.openedAt.Add(b.cfg.OpenTimeout)
+	if time.Now().Before(retryAt) {
+		return &UnavailableError{Reason: "circuit open", RetryAfter: time.Until(retryAt), Err: b.lastErr}
+	}
+	return nil
+}
+
+// isConnectivityError reports whether err means the panel could not be reached
+// in time, as opposed to the panel rejecting the request
+func isConnectivityError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// Guard wraps a panel so that every call goes through the breaker.
+// Stateless panels stay Stateless, and Close is passed through.
+func Guard(p Panel, b *Breaker) Panel {
+	g := &guarded{panel: p, breaker: b}
+	if stateless, ok := p.(Stateless); ok {
+		return &guardedStateless{guarded: g, stateless: stateless}
+	}
+	return g
+}
+
+type guarded struct {
+	panel   Panel
+	breaker *Breaker
+}
+
+func (g *guarded) do(fn func() error) error {
+	done, err := g.breaker.acquire()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	done(err)
+	return err
+}
+
+func (g *guarded) AddClient(ctx context.Context, email string, trafficLimitGB int64, expiryDays int, maxDevices int) (account *Account, err error) {
+	err = g.do(func() error {
+		account, err = g.panel.AddClient(ctx, email, trafficLimitGB, expiryDays, maxDevices)
+		return err
+	})
+	return account, err
+}
+
+func (g *guarded) UpdateClient(ctx context.Context, clientID, email string, totalGB int64, expiryTime int64, maxDevices int) error {
+	return g.do(func() error {
+		return g.panel.UpdateClient(ctx, clientID, email, totalGB, expiryTime, maxDevices)
+	})
+}
+
+func (g *guarded) DeleteClient(ctx context.Context, clientID, email string) error {
+	return g.do(func() error {
+		return g.panel.DeleteClient(ctx, clientID, email)
+	})
+}
+
+func (g *guarded) GetClientTraffic(ctx context.Context, email string) (traffic *Traffic, err error) {
+	err = g.do(func() error {
+		traffic, err = g.panel.GetClientTraffic(ctx, email)
+		return err
+	})
+	return traffic, err
+}
+
+func (g *guarded) GetInboundInfo(ctx context.Context) (info *InboundInfo, err error) {
+	err = g.do(func() error {
+		info, err = g.panel.GetInboundInfo(ctx)
+		return err
+	})
+	return info, err
+}
+
+func (g *guarded) ConnectionKey(ctx context.Context, account *Account, endpoint Endpoint) (key string, err error) {
+	err = g.do(func() error {
+		key, err = g.panel.ConnectionKey(ctx, account, endpoint)
+		return err
+	})
+	return key, err
+}
+
+// SystemStatus returns ErrNotSupported when the wrapped panel does not report host status
+func (g *guarded) SystemStatus(ctx context.Context) (status *SystemStatus, err error) {
+	reporter, ok := g.panel.(StatusReporter)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	err = g.do(func() error {
+		status, err = reporter.SystemStatus(ctx)
+		return err
+	})
+	return status, err
+}
+
+// Load returns ErrNotSupported when the wrapped panel does not report load
+func (g *guarded) Load(ctx context.Context) (load *Load, err error) {
+	reporter, ok := g.panel.(LoadReporter)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	err = g.do(func() error {
+		load, err = reporter.Load(ctx)
+		return err
+	})
+	return load, err
+}
+
+// SubscriptionURL returns ErrNotSupported when the wrapped panel has no subscription URLs
+func (g *guarded) SubscriptionURL(ctx context.Context, account *Account) (url string, err error) {
+	linker, ok := g.panel.(SubscriptionLinker)
+	if !ok {
+		return "", ErrNotSupported
+	}
+	err = g.do(func() error {
+		url, err = linker.SubscriptionURL(ctx, account)
+		return err
+	})
+	return url, err
+}
+
+// Close releases the wrapped panel's resources if it holds any
+func (g *guarded) Close() error {
+	if closer, ok := g.panel.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+type guardedStateless struct {
+	*guarded
+	stateless Stateless
+}
+
+func (g *guardedStateless) EnsureClient(ctx context.Context, clientID, email string) error {
+	return g.do(func() error {
+		return g.stateless.EnsureClient(ctx, clientID, email)
+	})
+}
@@ -0,0 +1,145 @@
+package panel
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/zyvpn/backend/internal/marzban"
+)
+
+// marzbanProtocols is the order in which Marzban protocols are preferred
+var marzbanProtocols = []string{"vless", "vmess", "trojan", "shadowsocks"}
+
+// Marzban adapts the Marzban REST API. Users are enabled on every inbound
+// configured in the core; Marzban has no per-user device limit, so maxDevices is ignored.
+type Marzban struct {
+	client *marzban.Client
+}
+
+func NewMarzban(client *marzban.Client) *Marzban {
+	return &Marzban{client: client}
+}
+
+func (p *Marzban) AddClient(ctx context.Context, email string, trafficLimitGB int64, expiryDays int, maxDevices int) (*Account, error) {
+	inbounds, err := p.client.GetInbounds(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var protocols []string
+	for _, protocol := range marzbanProtocols {
+		if len(inbounds[protocol]) > 0 {
+			protocols = append(protocols, protocol)
+		}
+	}
+	if len(protocols) == 0 {
+		return nil, errors.New("no inbounds configured on Marzban")
+	}
+
+	var expire int64
+	if expiryDays > 0 {
+		expire = time.Now().Add(time.Duration(expiryDays) * 24 * time.Hour).Unix()
+	}
+
+	user, err := p.client.AddUser(ctx, email, protocols, trafficLimitGB*1024*1024*1024, expire)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Account{ID: user.Username, Email: user.Username, Links: user.Links}, nil
+}
+
+func (p *Marzban) UpdateClient(ctx context.Context, clientID, email string, totalGB int64, expiryTime int64, maxDevices int) error {
+	return p.client.UpdateUser(ctx, clientID, totalGB*1024*1024*1024, expiryTime/1000)
+}
+
+func (p *Marzban) DeleteClient(ctx context.Context, clientID, email string) error {
+	return p.client.DeleteUser(ctx, clientID)
+}
+
+// GetClientTraffic returns used traffic; Marzban does not split it by direction
+func (p *Marzban) GetClientTraffic(ctx context.Context, email string) (*Traffic, error) {
+	user, err := p.client.GetUser(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	return &Traffic{Down: user.UsedTraffic}, nil
+}
+
+// GetInboundInfo describes the preferred inbound users are given links for
+func (p *Marzban) GetInboundInfo(ctx context.Context) (*InboundInfo, error) {
+	inbounds, err := p.client.GetInbounds(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, protocol := range marzbanProtocols {
+		if len(inbounds[protocol]) == 0 {
+			continue
+		}
+		inbound := inbounds[protocol][0]
+		return &InboundInfo{
+			Protocol: inbound.Protocol,
+			Network:  inbound.Network,
+			Security: inbound.TLS,
+			Port:     inbound.PortNumber(),
+		}, nil
+	}
+
+	return nil, errors.New("no inbounds configured on Marzban")
+}
+
+func (p *Marzban) SystemStatus(ctx context.Context) (*SystemStatus, error) {
+	system, err := p.client.GetSystemStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	core, err := p.client.GetCoreStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SystemStatus{CPUPercent: system.CPUUsage, XrayState: "stopped"}
+	if core.Started {
+		result.XrayState = "running"
+	}
+	if system.MemTotal > 0 {
+		result.MemPercent = float64(system.MemUsed) / float64(system.MemTotal) * 100
+	}
+	return result, nil
+}
+
+func (p *Marzban) Load(ctx context.Context) (*Load, error) {
+	system, err := p.client.GetSystemStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Load{
+		OnlineClients: system.OnlineUsers,
+		TrafficUp:     system.OutgoingBandwidth,
+		TrafficDown:   system.IncomingBandwidth,
+	}, nil
+}
+
+// ConnectionKey returns the first link generated by Marzban. Addresses come from
+// Marzban host settings, so the endpoint is not applied.
+func (p *Marzban) ConnectionKey(ctx context.Context, account *Account, endpoint Endpoint) (string, error) {
+	links := account.Links
+	if len(links) == 0 {
+		user, err := p.client.GetUser(ctx, account.ID)
+		if err != nil {
+			return "", err
+		}
+		links = user.Links
+	}
+	if len(links) == 0 {
+		return "", errors.New("marzban returned no links for user")
+	}
+	return links[0], nil
+}
+
+// SubscriptionURL returns the Marzban subscription URL of the user
+func (p *Marzban) SubscriptionURL(ctx context.Context, account *Account) (string, error) {
+	return p.client.SubscriptionURL(ctx, account.ID)
+}
@@ -0,0 +1,100 @@
+package panel
+
+import (
+	"context"
+	"errors"
+
+	"github.com/zyvpn/backend/internal/outline"
+)
+
+// Outline adapts the Outline Management API. Outline keys have no expiry, so
+// expiryTime is ignored and keys are deleted by the subscription expiry worker;
+// device limits are not supported either.
+type Outline struct {
+	client *outline.Client
+}
+
+func NewOutline(client *outline.Client) *Outline {
+	return &Outline{client: client}
+}
+
+func (p *Outline) AddClient(ctx context.Context, email string, trafficLimitGB int64, expiryDays int, maxDevices int) (*Account, error) {
+	key, err := p.client.CreateAccessKey(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if trafficLimitGB > 0 {
+		if err := p.client.SetDataLimit(ctx, key.ID, trafficLimitGB*1024*1024*1024); err != nil {
+			_ = p.client.DeleteAccessKey(ctx, key.ID)
+			return nil, err
+		}
+	}
+
+	return &Account{ID: key.ID, Email: email, outline: key}, nil
+}
+
+func (p *Outline) UpdateClient(ctx context.Context, clientID, email string, totalGB int64, expiryTime int64, maxDevices int) error {
+	return p.client.SetDataLimit(ctx, clientID, totalGB*1024*1024*1024)
+}
+
+func (p *Outline) DeleteClient(ctx context.Context, clientID, email string) error {
+	return p.client.DeleteAccessKey(ctx, clientID)
+}
+
+// GetClientTraffic looks the key up by name, since metrics are reported per key ID.
+// Outline only reports the bytes transferred over the last 30 days, so the
+// traffic is marked Rolling.
+func (p *Outline) GetClientTraffic(ctx context.Context, email string) (*Traffic, error) {
+	keys, err := p.client.GetAccessKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyID string
+	for _, key := range keys {
+		if key.Name == email {
+			keyID = key.ID
+			break
+		}
+	}
+	if keyID == "" {
+		return nil, outline.ErrKeyNotFound
+	}
+
+	metrics, err := p.client.GetTransferMetrics(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Traffic{Down: metrics[keyID], Rolling: true}, nil
+}
+
+func (p *Outline) GetInboundInfo(ctx context.Context) (*InboundInfo, error) {
+	server, err := p.client.GetServer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &InboundInfo{
+		Protocol: "shadowsocks",
+		Network:  "tcp",
+		Security: "none",
+		Port:     server.PortForNewAccessKeys,
+	}, nil
+}
+
+// ConnectionKey returns an ss:// link pointing at the endpoint address.
+// The port is always the one Outline assigned to the key.
+func (p *Outline) ConnectionKey(ctx context.Context, account *Account, endpoint Endpoint) (string, error) {
+	key := account.outline
+	if key == nil {
+		var err error
+		key, err = p.client.GetAccessKey(ctx, account.ID)
+		if err != nil {
+			return "", err
+		}
+	}
+	if key.Password == "" {
+		return "", errors.New("outline: access key has no password")
+	}
+	return key.ShareLink(endpoint.Address, account.Email)
+}
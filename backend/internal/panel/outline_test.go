@@ -0,0 +1,264 @@
+package panel
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zyvpn/backend/internal/outline"
+)
+
+const fakeOutlineSecret = "/TestSecret"
+
+// fakeOutline is an in-memory implementation of the Outline Management API
+type fakeOutline struct {
+	mu      sync.Mutex
+	nextID  int
+	keys    map[string]*outline.AccessKey
+	limits  map[string]int64
+	traffic map[string]int64
+}
+
+func newFakeOutline(t *testing.T) (*httptest.Server, *fakeOutline) {
+	t.Helper()
+	fake := &fakeOutline{
+		keys:    make(map[string]*outline.AccessKey),
+		limits:  make(map[string]int64),
+		traffic: make(map[string]int64),
+	}
+	srv := httptest.NewTLSServer(fake)
+	t.Cleanup(srv.Close)
+	return srv, fake
+}
+
+func (f *fakeOutline) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path, ok := strings.CutPrefix(r.URL.Path, fakeOutlineSecret)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	switch {
+	case r.Method == http.MethodGet && path == "/server":
+		writeJSON(w, http.StatusOK, map[string]any{"name": "fake", "serverId": "srv", "portForNewAccessKeys": 8388})
+
+	case r.Method == http.MethodPost && path == "/access-keys":
+		f.nextID++
+		id := strconv.Itoa(f.nextID)
+		key := &outline.AccessKey{
+			ID:        id,
+			Password:  "secret" + id,
+			Port:      8388,
+			Method:    "chacha20-ietf-poly1305",
+			AccessURL: "ss://x@outline.example.com:8388/?outline=1",
+		}
+		f.keys[id] = key
+		writeJSON(w, http.StatusCreated, key)
+
+	case r.Method == http.MethodGet && path == "/access-keys":
+		keys := make([]*outline.AccessKey, 0, len(f.keys))
+		for _, key := range f.keys {
+			keys = append(keys, key)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"accessKeys": keys})
+
+	case r.Method == http.MethodGet && path == "/metrics/transfer":
+		writeJSON(w, http.StatusOK, map[string]any{"bytesTransferredByUserId": f.traffic})
+
+	case len(parts) >= 2 && parts[0] == "access-keys":
+		key, exists := f.keys[parts[1]]
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch {
+		case len(parts) == 2 && r.Method == http.MethodDelete:
+			delete(f.keys, key.ID)
+			delete(f.limits, key.ID)
+		case len(parts) == 3 && parts[2] == "name" && r.Method == http.MethodPut:
+			var body struct {
+				Name string `json:"name"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			key.Name = body.Name
+		case len(parts) == 3 && parts[2] == "data-limit" && r.Method == http.MethodPut:
+			var body struct {
+				Limit struct {
+					Bytes int64 `json:"bytes"`
+				} `json:"limit"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			f.limits[key.ID] = body.Limit.Bytes
+		case len(parts) == 3 && parts[2] == "data-limit" && r.Method == http.MethodDelete:
+			delete(f.limits, key.ID)
+		default:
+			http.Error(w, "unsupported", http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// key returns a stored access key; the handler goroutine writes the maps under mu
+func (f *fakeOutline) key(id string) (*outline.AccessKey, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key, exists := f.keys[id]
+	return key, exists
+}
+
+// limit returns the data limit of a key in bytes and whether one is set
+func (f *fakeOutline) limit(id string) (int64, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	limit, exists := f.limits[id]
+	return limit, exists
+}
+
+// limitBytes returns the data limit of a key in bytes, 0 when none is set
+func (f *fakeOutline) limitBytes(id string) int64 {
+	limit, _ := f.limit(id)
+	return limit
+}
+
+// setTraffic sets the bytes transferred by a key
+func (f *fakeOutline) setTraffic(id string, bytes int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.traffic[id] = bytes
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func certFingerprint(srv *httptest.Server) string {
+	sum := sha256.Sum256(srv.Certificate().Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestOutlineProvisioning(t *testing.T) {
+	srv, fake := newFakeOutline(t)
+	p := NewOutline(outline.NewClient(srv.URL+fakeOutlineSecret, certFingerprint(srv)))
+	ctx := context.Background()
+
+	account, err := p.AddClient(ctx, "user_1_100", 50, 30, 3)
+	if err != nil {
+		t.Fatalf("AddClient: %v", err)
+	}
+	if account.ID == "" || account.Email != "user_1_100" {
+		t.Fatalf("unexpected account: %+v", account)
+	}
+	if key, exists := fake.key(account.ID); !exists || key.Name != "user_1_100" {
+		t.Errorf("key = %+v, want one named user_1_100", key)
+	}
+	if got, want := fake.limitBytes(account.ID), int64(50)*1024*1024*1024; got != want {
+		t.Errorf("data limit = %d, want %d", got, want)
+	}
+
+	link, err := p.ConnectionKey(ctx, account, Endpoint{Address: "vpn.example.com"})
+	if err != nil {
+		t.Fatalf("ConnectionKey: %v", err)
+	}
+	userInfo := base64.RawURLEncoding.EncodeToString([]byte("chacha20-ietf-poly1305:secret" + account.ID))
+	if want := "ss://" + userInfo + "@vpn.example.com:8388/?outline=1#user_1_100"; link != want {
+		t.Errorf("link = %q, want %q", link, want)
+	}
+
+	// Stored subscriptions only keep the key ID, so the link must be reproducible from it
+	relink, err := p.ConnectionKey(ctx, &Account{ID: account.ID, Email: account.Email}, Endpoint{Address: "vpn.example.com"})
+	if err != nil || relink != link {
+		t.Errorf("ConnectionKey from ID = %q, %v; want %q", relink, err, link)
+	}
+
+	fake.setTraffic(account.ID, 12345)
+	traffic, err := p.GetClientTraffic(ctx, "user_1_100")
+	if err != nil {
+		t.Fatalf("GetClientTraffic: %v", err)
+	}
+	if traffic.Total() != 12345 || !traffic.Rolling {
+		t.Errorf("traffic = %+v, want 12345 rolling", traffic)
+	}
+
+	if err := p.UpdateClient(ctx, account.ID, account.Email, 100, 0, 3); err != nil {
+		t.Fatalf("UpdateClient: %v", err)
+	}
+	if got, want := fake.limitBytes(account.ID), int64(100)*1024*1024*1024; got != want {
+		t.Errorf("data limit after update = %d, want %d", got, want)
+	}
+
+	if err := p.UpdateClient(ctx, account.ID, account.Email, 0, 0, 3); err != nil {
+		t.Fatalf("UpdateClient unlimited: %v", err)
+	}
+	if _, exists := fake.limit(account.ID); exists {
+		t.Error("data limit should be removed for unlimited traffic")
+	}
+
+	if err := p.DeleteClient(ctx, account.ID, account.Email); err != nil {
+		t.Fatalf("DeleteClient: %v", err)
+	}
+	if _, exists := fake.key(account.ID); exists {
+		t.Error("key should be deleted")
+	}
+
+	// Deleting an already removed key is not an error (expiry may run twice)
+	if err := p.DeleteClient(ctx, account.ID, account.Email); err != nil {
+		t.Errorf("DeleteClient of missing key: %v", err)
+	}
+}
+
+func TestOutlineUnlimitedTrafficSetsNoLimit(t *testing.T) {
+	srv, fake := newFakeOutline(t)
+	p := NewOutline(outline.NewClient(srv.URL+fakeOutlineSecret, certFingerprint(srv)))
+	ctx := context.Background()
+
+	account, err := p.AddClient(ctx, "user_2_100", 0, 30, 3)
+	if err != nil {
+		t.Fatalf("AddClient: %v", err)
+	}
+	if _, exists := fake.limit(account.ID); exists {
+		t.Error("no data limit expected for unlimited plans")
+	}
+}
+
+func TestOutlineInboundInfo(t *testing.T) {
+	srv, _ := newFakeOutline(t)
+	p := NewOutline(outline.NewClient(srv.URL+fakeOutlineSecret, certFingerprint(srv)))
+	ctx := context.Background()
+
+	info, err := p.GetInboundInfo(ctx)
+	if err != nil {
+		t.Fatalf("GetInboundInfo: %v", err)
+	}
+	if info.Protocol != "shadowsocks" || info.Port != 8388 {
+		t.Errorf("unexpected inbound info: %+v", info)
+	}
+}
+
+func TestOutlineRejectsWrongCertificate(t *testing.T) {
+	srv, _ := newFakeOutline(t)
+	p := NewOutline(outline.NewClient(srv.URL+fakeOutlineSecret, strings.Repeat("00", sha256.Size)))
+	ctx := context.Background()
+
+	if _, err := p.AddClient(ctx, "user_3_100", 10, 30, 3); err == nil {
+		t.Fatal("expected certificate fingerprint mismatch")
+	}
+}
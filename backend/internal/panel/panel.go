@@ -0,0 +1,155 @@
+// Package panel abstracts the control panels (3x-ui, Marzban, Outline, plain Xray) used to provision VPN clients.
+package panel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/zyvpn/backend/internal/marzban"
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/outline"
+	"github.com/zyvpn/backend/internal/xray"
+	"github.com/zyvpn/backend/internal/xui"
+)
+
+// Panel provisions and manages VPN clients on a server's control panel
+type Panel interface {
+	// AddClient creates a client with a traffic limit in GB and a lifetime in days (0 = unlimited)
+	AddClient(ctx context.Context, email string, trafficLimitGB int64, expiryDays int, maxDevices int) (*Account, error)
+	// UpdateClient sets the total traffic limit in GB and the expiry time (unix milliseconds)
+	UpdateClient(ctx context.Context, clientID, email string, totalGB int64, expiryTime int64, maxDevices int) error
+	// DeleteClient removes a client by the ID and email returned from AddClient
+	DeleteClient(ctx context.Context, clientID, email string) error
+	// GetClientTraffic returns traffic used by a client
+	GetClientTraffic(ctx context.Context, email string) (*Traffic, error)
+	// GetInboundInfo fetches protocol and transport details of the inbound clients are created on
+	GetInboundInfo(ctx context.Context) (*InboundInfo, error)
+	// ConnectionKey returns the share link for a client created by AddClient
+	ConnectionKey(ctx context.Context, account *Account, endpoint Endpoint) (string, error)
+}
+
+// Stateless is implemented by backends that keep no expiry, quota or persistent
+// users themselves (plain Xray). Their GetClientTraffic returns raw counters that
+// start from zero when the node restarts; SubscriptionService stores the difference,
+// enforces limits by expiring subscriptions and restores valid clients with
+// EnsureClient after the node restarts.
+type Stateless interface {
+	Panel
+	// EnsureClient adds the client back if it is missing; an existing client is left as is
+	EnsureClient(ctx context.Context, clientID, email string) error
+}
+
+// StatusReporter is implemented by panels that report the status of their host
+type StatusReporter interface {
+	SystemStatus(ctx context.Context) (*SystemStatus, error)
+}
+
+// LoadReporter is implemented by panels that report how busy their host is
+type LoadReporter interface {
+	Load(ctx context.Context) (*Load, error)
+}
+
+// SubscriptionLinker is implemented by panels that serve a subscription URL per client
+type SubscriptionLinker interface {
+	// SubscriptionURL returns the client's subscription URL, or "" if the panel has none
+	SubscriptionURL(ctx context.Context, account *Account) (string, error)
+}
+
+// ErrNotSupported is returned by optional operations the panel does not implement
+var ErrNotSupported = errors.New("panel: operation not supported")
+
+// SystemStatus is the host status reported by a panel
+type SystemStatus struct {
+	CPUPercent float64
+	MemPercent float64
+	XrayState  string // running, stopped or error
+	XrayError  string
+}
+
+// Load is the live usage reported by a panel. Traffic counters are cumulative
+// for the host and reset when it restarts; throughput is derived from their deltas.
+type Load struct {
+	OnlineClients int
+	Connections   *int // open TCP connections, where the panel reports them
+	TrafficUp     int64
+	TrafficDown   int64
+}
+
+// Account is a client provisioned on a panel
+type Account struct {
+	ID    string   // identifier stored in subscriptions.xui_client_id
+	Email string   // name of the client on the panel
+	Links []string // share links generated by the panel itself, if it provides them
+
+	xui     *xui.ClientConfig
+	outline *outline.AccessKey
+}
+
+// Traffic is the traffic used by a client in bytes
+type Traffic struct {
+	Up   int64
+	Down int64
+	// Rolling is set when the counters only cover a recent window instead of the
+	// client's lifetime (Outline reports the last 30 days). SubscriptionService
+	// then adds up their increases; a decrease means old usage left the window.
+	Rolling bool
+}
+
+// Total returns uploaded plus downloaded bytes
+func (t *Traffic) Total() int64 {
+	return t.Up + t.Down
+}
+
+// InboundInfo describes the inbound clients are created on
+type InboundInfo struct {
+	Protocol   string `json:"protocol"`
+	Network    string `json:"network"`
+	Security   string `json:"security"`
+	Port       int    `json:"port"`
+	PublicKey  string `json:"public_key,omitempty"`
+	ShortID    string `json:"short_id,omitempty"`
+	ServerName string `json:"server_name,omitempty"`
+
+	// Every Reality short ID and server name the inbound accepts, where the panel reports them
+	ShortIDs    []string `json:"short_ids,omitempty"`
+	ServerNames []string `json:"server_names,omitempty"`
+}
+
+// Endpoint is the address and Reality parameters advertised in share links.
+// Empty fields fall back to the values configured on the panel.
+type Endpoint struct {
+	Address    string
+	Port       int
+	PublicKey  string
+	ShortID    string
+	ServerName string
+}
+
+// New creates a panel client for the server's panel type, bound to the given inbound
+// where the panel supports selecting one
+func New(server *model.Server, inboundID int) (Panel, error) {
+	switch server.PanelType {
+	case model.PanelTypeXUI, "":
+		client, err := xui.NewClient(server.XUIBaseURL, server.XUIUsername, server.XUIPassword, inboundID)
+		if err != nil {
+			return nil, err
+		}
+		return NewXUI(client), nil
+	case model.PanelTypeMarzban:
+		return NewMarzban(marzban.NewClient(server.XUIBaseURL, server.XUIUsername, server.XUIPassword)), nil
+	case model.PanelTypeXray:
+		if server.InboundTag == "" {
+			return nil, fmt.Errorf("inbound_tag is required for xray servers")
+		}
+		client, err := xray.NewClient(server.XUIBaseURL)
+		if err != nil {
+			return nil, err
+		}
+		return NewXray(client, server), nil
+	case model.PanelTypeOutline:
+		// xui_base_url holds the API URL and xui_password the optional certificate SHA-256
+		return NewOutline(outline.NewClient(server.XUIBaseURL, server.XUIPassword)), nil
+	}
+	return nil, fmt.Errorf("unsupported panel type: %s", server.PanelType)
+}
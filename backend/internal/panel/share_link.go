@@ -0,0 +1,89 @@
+package panel
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// RewriteShareLink points a share link (vless, trojan, ss, vmess, ...) at another
+// address. A positive port replaces the link's port. A non-empty label is added
+// to the remark so clients can tell the endpoints of one server apart. Everything
+// else in the link is kept as is, so no panel is needed.
+func RewriteShareLink(link, address string, port int, label string) (string, error) {
+	scheme, rest, ok := strings.Cut(link, "://")
+	if !ok {
+		return "", fmt.Errorf("invalid share link")
+	}
+	if scheme == "vmess" {
+		return rewriteVmessLink(rest, address, port, label)
+	}
+
+	var fragment string
+	if i := strings.IndexByte(rest, '#'); i >= 0 {
+		rest, fragment = rest[:i], rest[i+1:]
+	}
+
+	authorityEnd := strings.IndexAny(rest, "/?")
+	if authorityEnd < 0 {
+		authorityEnd = len(rest)
+	}
+	authority := rest[:authorityEnd]
+	at := strings.LastIndexByte(authority, '@')
+
+	_, oldPort, err := net.SplitHostPort(authority[at+1:])
+	if err != nil {
+		return "", fmt.Errorf("invalid share link address: %w", err)
+	}
+	if port > 0 {
+		oldPort = strconv.Itoa(port)
+	}
+
+	result := scheme + "://" + authority[:at+1] + net.JoinHostPort(address, oldPort) + rest[authorityEnd:]
+	if label != "" {
+		remark, err := url.PathUnescape(fragment)
+		if err != nil {
+			remark = fragment
+		}
+		fragment = url.PathEscape(strings.TrimSpace(remark + " " + label))
+	}
+	if fragment != "" {
+		result += "#" + fragment
+	}
+	return result, nil
+}
+
+// rewriteVmessLink rewrites the base64 JSON vmess format
+func rewriteVmessLink(encoded, address string, port int, label string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		data, err = base64.RawStdEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", fmt.Errorf("invalid vmess link: %w", err)
+		}
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return "", fmt.Errorf("invalid vmess link: %w", err)
+	}
+
+	payload["add"] = address
+	if port > 0 {
+		payload["port"] = strconv.Itoa(port)
+	}
+	if label != "" {
+		remark, _ := payload["ps"].(string)
+		payload["ps"] = strings.TrimSpace(remark + " " + label)
+	}
+
+	data, err = json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return "vmess://" + base64.StdEncoding.EncodeToString(data), nil
+}
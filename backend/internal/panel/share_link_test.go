@@ -0,0 +1,83 @@
+package panel
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRewriteShareLink(t *testing.T) {
+	tests := []struct {
+		link    string
+		address string
+		port    int
+		label   string
+		want    string
+	}{
+		{
+			link:    "vless://uuid@1.2.3.4:443?type=tcp&security=reality&sni=a.com#user_1",
+			address: "example.com",
+			want:    "vless://uuid@example.com:443?type=tcp&security=reality&sni=a.com#user_1",
+		},
+		{
+			link:    "vless://uuid@1.2.3.4:443?type=tcp#user_1",
+			address: "2001:db8::1",
+			port:    8443,
+			label:   "IPv6",
+			want:    "vless://uuid@[2001:db8::1]:8443?type=tcp#user_1%20IPv6",
+		},
+		{
+			link:    "ss://Y2hhY2hhMjA6cGFzcw@1.2.3.4:8388/?outline=1#key",
+			address: "cdn.example.com",
+			want:    "ss://Y2hhY2hhMjA6cGFzcw@cdn.example.com:8388/?outline=1#key",
+		},
+		{
+			link:    "trojan://p%40ss@1.2.3.4:443?security=tls",
+			address: "5.6.7.8",
+			label:   "backup",
+			want:    "trojan://p%40ss@5.6.7.8:443?security=tls#backup",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := RewriteShareLink(tt.link, tt.address, tt.port, tt.label)
+		if err != nil {
+			t.Errorf("RewriteShareLink(%q): %v", tt.link, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("RewriteShareLink(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestRewriteVmessLink(t *testing.T) {
+	data, _ := json.Marshal(map[string]string{"v": "2", "ps": "user_1", "add": "1.2.3.4", "port": "443", "id": "uuid"})
+	link := "vmess://" + base64.StdEncoding.EncodeToString(data)
+
+	got, err := RewriteShareLink(link, "example.com", 8443, "CDN")
+	if err != nil {
+		t.Fatalf("RewriteShareLink: %v", err)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(got, "vmess://"))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(decoded, &payload); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if payload["add"] != "example.com" || payload["port"] != "8443" || payload["ps"] != "user_1 CDN" || payload["id"] != "uuid" {
+		t.Errorf("payload = %v", payload)
+	}
+}
+
+func TestRewriteShareLinkInvalid(t *testing.T) {
+	for _, link := range []string{"", "not a link", "vless://uuid@host-without-port?x=1"} {
+		if _, err := RewriteShareLink(link, "example.com", 0, ""); err == nil {
+			t.Errorf("RewriteShareLink(%q) succeeded, want error", link)
+		}
+	}
+}
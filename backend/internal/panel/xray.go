@@ -0,0 +1,135 @@
+package panel
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/xray"
+	"github.com/zyvpn/backend/internal/xui"
+)
+
+// Xray provisions VLESS users directly through the Xray gRPC API on the inbound
+// with the server's inbound tag. Links are built from the server record: VLESS over
+// TCP with Reality when a public key is configured. Xray does not keep users across
+// restarts nor enforce expiry or quota, so this backend is Stateless.
+type Xray struct {
+	client *xray.Client
+	tag    string
+	info   xui.InboundInfo
+}
+
+func NewXray(client *xray.Client, server *model.Server) *Xray {
+	info := xui.InboundInfo{
+		Protocol: xui.ProtocolVLESS,
+		Network:  xui.NetworkTCP,
+		Security: xui.SecurityNone,
+		Port:     server.ServerPort,
+	}
+	if server.PublicKey != "" {
+		info.Security = xui.SecurityReality
+		info.PublicKey = server.PublicKey
+		info.ShortID = server.ShortID
+		info.ServerName = server.ServerName
+	}
+
+	return &Xray{client: client, tag: server.InboundTag, info: info}
+}
+
+// flow returns the VLESS flow for users on the inbound
+func (p *Xray) flow() string {
+	if p.info.Security == xui.SecurityReality {
+		return "xtls-rprx-vision"
+	}
+	return ""
+}
+
+func (p *Xray) AddClient(ctx context.Context, email string, trafficLimitGB int64, expiryDays int, maxDevices int) (*Account, error) {
+	id := uuid.New().String()
+	if err := p.client.AddVLESSUser(ctx, p.tag, email, id, p.flow()); err != nil {
+		return nil, err
+	}
+
+	return &Account{
+		ID:    id,
+		Email: email,
+		xui:   &xui.ClientConfig{ID: id, Email: email, Flow: p.flow()},
+	}, nil
+}
+
+// UpdateClient re-adds the user, since limits are enforced by the backend and a
+// user may have been removed after running out of traffic
+func (p *Xray) UpdateClient(ctx context.Context, clientID, email string, totalGB int64, expiryTime int64, maxDevices int) error {
+	return p.EnsureClient(ctx, clientID, email)
+}
+
+func (p *Xray) DeleteClient(ctx context.Context, clientID, email string) error {
+	err := p.client.RemoveUser(ctx, p.tag, email)
+	if err != nil && !errors.Is(err, xray.ErrUserNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (p *Xray) EnsureClient(ctx context.Context, clientID, email string) error {
+	err := p.client.AddVLESSUser(ctx, p.tag, email, clientID, p.flow())
+	if err != nil && !errors.Is(err, xray.ErrUserExists) {
+		return err
+	}
+	return nil
+}
+
+// GetClientTraffic returns the client's raw counters since Xray started. They are
+// not reset on read, so a failed sync loses nothing; the backend stores the difference.
+func (p *Xray) GetClientTraffic(ctx context.Context, email string) (*Traffic, error) {
+	up, down, err := p.client.GetUserTraffic(ctx, email, false)
+	if err != nil {
+		return nil, err
+	}
+	return &Traffic{Up: up, Down: down}, nil
+}
+
+func (p *Xray) GetInboundInfo(ctx context.Context) (*InboundInfo, error) {
+	if _, err := p.client.Ping(ctx); err != nil {
+		return nil, err
+	}
+	return &InboundInfo{
+		Protocol:   p.info.Protocol,
+		Network:    p.info.Network,
+		Security:   p.info.Security,
+		Port:       p.info.Port,
+		PublicKey:  p.info.PublicKey,
+		ShortID:    p.info.ShortID,
+		ServerName: p.info.ServerName,
+	}, nil
+}
+
+// ConnectionKey builds the VLESS link; the UUID is the account ID, so stored
+// subscriptions can be re-linked without querying Xray
+func (p *Xray) ConnectionKey(ctx context.Context, account *Account, endpoint Endpoint) (string, error) {
+	client := account.xui
+	if client == nil {
+		client = &xui.ClientConfig{ID: account.ID, Email: account.Email, Flow: p.flow()}
+	}
+
+	info := p.info
+	if info.Security == xui.SecurityReality {
+		if endpoint.PublicKey != "" {
+			info.PublicKey = endpoint.PublicKey
+		}
+		if endpoint.ShortID != "" {
+			info.ShortID = endpoint.ShortID
+		}
+		if endpoint.ServerName != "" {
+			info.ServerName = endpoint.ServerName
+		}
+	}
+
+	return xui.ShareLink(&info, client, endpoint.Address, endpoint.Port, account.Email)
+}
+
+// Close releases the gRPC connection
+func (p *Xray) Close() error {
+	return p.client.Close()
+}
@@ -0,0 +1,133 @@
+package panel
+
+import (
+	"context"
+	"errors"
+
+	"github.com/zyvpn/backend/internal/xui"
+)
+
+// XUI adapts a 3x-ui client bound to a single inbound
+type XUI struct {
+	client *xui.Client
+}
+
+func NewXUI(client *xui.Client) *XUI {
+	return &XUI{client: client}
+}
+
+func (p *XUI) AddClient(ctx context.Context, email string, trafficLimitGB int64, expiryDays int, maxDevices int) (*Account, error) {
+	cfg, err := p.client.AddClient(ctx, email, trafficLimitGB, expiryDays, maxDevices)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{ID: cfg.Credential(), Email: cfg.Email, xui: cfg}, nil
+}
+
+func (p *XUI) UpdateClient(ctx context.Context, clientID, email string, totalGB int64, expiryTime int64, maxDevices int) error {
+	return p.client.UpdateClientTraffic(ctx, clientID, email, totalGB, expiryTime, maxDevices)
+}
+
+func (p *XUI) DeleteClient(ctx context.Context, clientID, email string) error {
+	return p.client.DeleteClient(ctx, clientID)
+}
+
+func (p *XUI) GetClientTraffic(ctx context.Context, email string) (*Traffic, error) {
+	traffic, err := p.client.GetClientTraffic(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if traffic == nil {
+		return &Traffic{}, nil
+	}
+	return &Traffic{Up: traffic.Up, Down: traffic.Down}, nil
+}
+
+func (p *XUI) GetInboundInfo(ctx context.Context) (*InboundInfo, error) {
+	info, err := p.client.GetInboundInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &InboundInfo{
+		Protocol:   info.Protocol,
+		Network:    info.Network,
+		Security:   info.Security,
+		Port:       info.Port,
+		PublicKey:  info.PublicKey,
+		ShortID:    info.ShortID,
+		ServerName: info.ServerName,
+
+		ShortIDs:    info.ShortIDs,
+		ServerNames: info.ServerNames,
+	}, nil
+}
+
+func (p *XUI) SystemStatus(ctx context.Context) (*SystemStatus, error) {
+	status, err := p.client.GetServerStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SystemStatus{
+		CPUPercent: status.CPU,
+		XrayState:  status.Xray.State,
+		XrayError:  status.Xray.ErrorMsg,
+	}
+	if result.XrayState == "stop" {
+		result.XrayState = "stopped"
+	}
+	if status.Mem.Total > 0 {
+		result.MemPercent = float64(status.Mem.Current) / float64(status.Mem.Total) * 100
+	}
+	return result, nil
+}
+
+func (p *XUI) Load(ctx context.Context) (*Load, error) {
+	online, err := p.client.GetOnlineClients(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status, err := p.client.GetServerStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Load{
+		OnlineClients: len(online),
+		Connections:   &status.TCPCount,
+		TrafficUp:     status.NetTraffic.Sent,
+		TrafficDown:   status.NetTraffic.Recv,
+	}, nil
+}
+
+// ConnectionKey builds the share link locally from the cached inbound settings.
+// Reality overrides in the endpoint take precedence over the panel's values.
+// Accounts without a client config (stored subscriptions) are rebuilt from their ID.
+func (p *XUI) ConnectionKey(ctx context.Context, account *Account, endpoint Endpoint) (string, error) {
+	inbound, err := p.client.CachedInboundInfo(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	client := account.xui
+	if client == nil {
+		if account.ID == "" {
+			return "", errors.New("client config not available")
+		}
+		client = xui.ExistingClientConfig(inbound, account.ID, account.Email)
+	}
+
+	info := *inbound
+	if info.Security == xui.SecurityReality {
+		if endpoint.PublicKey != "" {
+			info.PublicKey = endpoint.PublicKey
+		}
+		if endpoint.ShortID != "" {
+			info.ShortID = endpoint.ShortID
+		}
+		if endpoint.ServerName != "" {
+			info.ServerName = endpoint.ServerName
+		}
+	}
+
+	return xui.ShareLink(&info, client, endpoint.Address, endpoint.Port, account.Email)
+}
@@ -0,0 +1,35 @@
+package qr
+
+import (
+	"errors"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	DefaultSize = 512
+	MinSize     = 128
+	MaxSize     = 1024
+)
+
+var ErrEmptyContent = errors.New("qr content is empty")
+
+// PNG renders content as a QR code PNG image of the given size in pixels.
+// Size is clamped to [MinSize, MaxSize]; zero means DefaultSize.
+func PNG(content string, size int) ([]byte, error) {
+	if content == "" {
+		return nil, ErrEmptyContent
+	}
+
+	switch {
+	case size == 0:
+		size = DefaultSize
+	case size < MinSize:
+		size = MinSize
+	case size > MaxSize:
+		size = MaxSize
+	}
+
+	// Medium recovery keeps long VLESS links scannable from a TV screen
+	return qrcode.Encode(content, qrcode.Medium, size)
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/model"
+)
+
+var ErrAlertNotFound = errors.New("alert not found")
+
+// FireAlert records a firing of an alert. A firing alert counts another occurrence;
+// a resolved or new one starts a new firing with the acknowledgement cleared.
+func (r *Repository) FireAlert(ctx context.Context, key, kind, severity, message string) (*model.Alert, error) {
+	var alert model.Alert
+	err := r.db.GetContext(ctx, &alert, `
+		INSERT INTO alerts (key, kind, severity, message, status)
+		VALUES ($1, $2, $3, $4, 'firing')
+		ON CONFLICT (key) DO UPDATE SET
+			kind = EXCLUDED.kind,
+			severity = EXCLUDED.severity,
+			message = EXCLUDED.message,
+			occurrences = CASE WHEN alerts.status = 'firing' THEN alerts.occurrences + 1 ELSE 1 END,
+			first_fired_at = CASE WHEN alerts.status = 'firing' THEN alerts.first_fired_at ELSE NOW() END,
+			last_notified_at = CASE WHEN alerts.status = 'firing' THEN alerts.last_notified_at END,
+			acked_by = CASE WHEN alerts.status = 'firing' THEN alerts.acked_by END,
+			acked_at = CASE WHEN alerts.status = 'firing' THEN alerts.acked_at END,
+			status = 'firing',
+			resolved_at = NULL,
+			last_fired_at = NOW(),
+			updated_at = NOW()
+		RETURNING *
+	`, key, kind, severity, message)
+	if err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// ResolveAlert marks a firing alert as resolved. It returns nil if the alert was not firing.
+func (r *Repository) ResolveAlert(ctx context.Context, key string) (*model.Alert, error) {
+	var alert model.Alert
+	err := r.db.GetContext(ctx, &alert, `
+		UPDATE alerts
+		SET status = 'resolved', resolved_at = NOW(), updated_at = NOW()
+		WHERE key = $1 AND status = 'firing'
+		RETURNING *
+	`, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// MarkAlertNotified records that the alert was sent to the admin chat
+func (r *Repository) MarkAlertNotified(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE alerts SET last_notified_at = NOW() WHERE id = $1
+	`, id)
+	return err
+}
+
+// AckAlert acknowledges the current firing of an alert
+func (r *Repository) AckAlert(ctx context.Context, id uuid.UUID, adminID int64) (*model.Alert, error) {
+	var alert model.Alert
+	err := r.db.GetContext(ctx, &alert, `
+		UPDATE alerts
+		SET acked_by = $2, acked_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+		RETURNING *
+	`, id, adminID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAlertNotFound
+		}
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// MuteAlert suppresses notifications for an alert until the given time, nil unmutes
+func (r *Repository) MuteAlert(ctx context.Context, id uuid.UUID, until *time.Time) (*model.Alert, error) {
+	var alert model.Alert
+	err := r.db.GetContext(ctx, &alert, `
+		UPDATE alerts
+		SET muted_until = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING *
+	`, id, until)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAlertNotFound
+		}
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// GetAlerts returns alerts with the given status (all when empty), most recently fired first
+func (r *Repository) GetAlerts(ctx context.Context, status string, limit int) ([]model.Alert, error) {
+	var alerts []model.Alert
+	err := r.db.SelectContext(ctx, &alerts, `
+		SELECT * FROM alerts
+		WHERE $1 = '' OR status = $1
+		ORDER BY last_fired_at DESC
+		LIMIT $2
+	`, status, limit)
+	return alerts, err
+}
+
+// GetStuckPaymentsCount returns the number of payments in awaiting_tx created before the given time
+func (r *Repository) GetStuckPaymentsCount(ctx context.Context, before time.Time) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `
+		SELECT COUNT(*) FROM payments
+		WHERE status = 'awaiting_tx' AND created_at < $1
+	`, before)
+	return count, err
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/zyvpn/backend/internal/model"
 )
 
@@ -16,8 +17,9 @@ func (r *Repository) GetUserBalance(ctx context.Context, userID int64) (float64,
 }
 
 // UpdateBalance updates user balance atomically and creates a transaction record
+// described by free text or by a catalog key with its arguments.
 // Returns the new balance and error
-func (r *Repository) UpdateBalance(ctx context.Context, userID int64, amount float64, txType model.TransactionType, description string, referenceID *uuid.UUID) (float64, error) {
+func (r *Repository) UpdateBalance(ctx context.Context, userID int64, amount float64, txType model.TransactionType, description, descriptionKey string, descriptionArgs []string, referenceID *uuid.UUID) (float64, error) {
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return 0, err
@@ -45,15 +47,18 @@ func (r *Repository) UpdateBalance(ctx context.Context, userID int64, amount flo
 	}
 
 	// Create transaction record
-	var desc *string
+	var desc, descKey *string
 	if description != "" {
 		desc = &description
 	}
+	if descriptionKey != "" {
+		descKey = &descriptionKey
+	}
 
 	_, err = tx.ExecContext(ctx, `
-		INSERT INTO balance_transactions (user_id, amount, type, description, reference_id, balance_before, balance_after)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
-		userID, amount, txType, desc, referenceID, balanceBefore, balanceAfter)
+		INSERT INTO balance_transactions (user_id, amount, type, description, description_key, description_args, reference_id, balance_before, balance_after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		userID, amount, txType, desc, descKey, pq.StringArray(descriptionArgs), referenceID, balanceBefore, balanceAfter)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create transaction record: %w", err)
 	}
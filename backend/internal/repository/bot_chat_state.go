@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/model"
+)
+
+// GetBotChatState returns the purchase in progress in a chat if it was updated
+// since the given time, or nil without one
+func (r *Repository) GetBotChatState(ctx context.Context, chatID int64, since time.Time) (*model.BotChatState, error) {
+	var state model.BotChatState
+	err := r.db.GetContext(ctx, &state, `
+		SELECT chat_id, plan_id, server_id, server_chosen, awaiting, updated_at
+		FROM bot_chat_states WHERE chat_id = $1 AND updated_at >= $2
+	`, chatID, since)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// SaveBotChatState stores the purchase in progress in a chat
+func (r *Repository) SaveBotChatState(ctx context.Context, state *model.BotChatState) error {
+	// No plan is chosen yet when a promo code is entered first
+	var planID *uuid.UUID
+	if state.PlanID != uuid.Nil {
+		planID = &state.PlanID
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO bot_chat_states (chat_id, plan_id, server_id, server_chosen, awaiting, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (chat_id) DO UPDATE SET
+			plan_id = EXCLUDED.plan_id,
+			server_id = EXCLUDED.server_id,
+			server_chosen = EXCLUDED.server_chosen,
+			awaiting = EXCLUDED.awaiting,
+			updated_at = NOW()
+	`, state.ChatID, planID, state.ServerID, state.ServerChosen, state.Awaiting)
+	return err
+}
+
+// DeleteBotChatState ends the purchase in progress in a chat
+func (r *Repository) DeleteBotChatState(ctx context.Context, chatID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM bot_chat_states WHERE chat_id = $1`, chatID)
+	return err
+}
+
+// DeleteBotChatStatesBefore removes purchases abandoned before the given time
+func (r *Repository) DeleteBotChatStatesBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM bot_chat_states WHERE updated_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
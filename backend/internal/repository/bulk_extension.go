@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/model"
+)
+
+var ErrBulkJobNotFound = errors.New("bulk extension job not found")
+
+// CreateBulkExtensionJob creates a job and snapshots the active subscriptions matching its filter as items
+func (r *Repository) CreateBulkExtensionJob(ctx context.Context, job *model.BulkExtensionJob) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowxContext(ctx, `
+		INSERT INTO bulk_extension_jobs (admin_id, days, server_id, plan_id, created_before, notify, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, job.AdminID, job.Days, job.ServerID, job.PlanID, job.CreatedBefore, job.Notify, job.Status,
+	).Scan(&job.ID, &job.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO bulk_extension_items (job_id, subscription_id, user_id)
+		SELECT $1, id, user_id FROM subscriptions
+		WHERE status = 'active' AND expires_at > NOW()
+			AND ($2::uuid IS NULL OR server_id = $2)
+			AND ($3::uuid IS NULL OR plan_id = $3)
+			AND ($4::timestamptz IS NULL OR created_at < $4)
+	`, job.ID, job.ServerID, job.PlanID, job.CreatedBefore)
+	if err != nil {
+		return err
+	}
+	total, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	job.Total = int(total)
+
+	if _, err := tx.ExecContext(ctx, `UPDATE bulk_extension_jobs SET total = $2 WHERE id = $1`, job.ID, job.Total); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetBulkExtensionJob gets a bulk extension job by ID
+func (r *Repository) GetBulkExtensionJob(ctx context.Context, id uuid.UUID) (*model.BulkExtensionJob, error) {
+	var job model.BulkExtensionJob
+	err := r.db.GetContext(ctx, &job, `SELECT * FROM bulk_extension_jobs WHERE id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrBulkJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetBulkExtensionJobs returns bulk extension jobs, newest first
+func (r *Repository) GetBulkExtensionJobs(ctx context.Context, limit int) ([]model.BulkExtensionJob, error) {
+	var jobs []model.BulkExtensionJob
+	err := r.db.SelectContext(ctx, &jobs, `
+		SELECT * FROM bulk_extension_jobs ORDER BY created_at DESC LIMIT $1
+	`, limit)
+	return jobs, err
+}
+
+// GetRunningBulkExtensionJobs returns jobs that were not finished, e.g. because the server restarted
+func (r *Repository) GetRunningBulkExtensionJobs(ctx context.Context) ([]model.BulkExtensionJob, error) {
+	var jobs []model.BulkExtensionJob
+	err := r.db.SelectContext(ctx, &jobs, `
+		SELECT * FROM bulk_extension_jobs WHERE status = $1 ORDER BY created_at
+	`, model.BulkJobRunning)
+	return jobs, err
+}
+
+// GetBulkExtensionItems returns the items of a job with the given status
+func (r *Repository) GetBulkExtensionItems(ctx context.Context, jobID uuid.UUID, status string) ([]model.BulkExtensionItem, error) {
+	var items []model.BulkExtensionItem
+	err := r.db.SelectContext(ctx, &items, `
+		SELECT * FROM bulk_extension_items WHERE job_id = $1 AND status = $2 ORDER BY subscription_id
+	`, jobID, status)
+	return items, err
+}
+
+// CountBulkExtensionItems returns the number of items of a job by status
+func (r *Repository) CountBulkExtensionItems(ctx context.Context, jobID uuid.UUID) (map[string]int, error) {
+	var rows []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	err := r.db.SelectContext(ctx, &rows, `
+		SELECT status, COUNT(*) AS count FROM bulk_extension_items WHERE job_id = $1 GROUP BY status
+	`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// UpdateBulkExtensionItem records the outcome of an attempt to extend an item's subscription
+func (r *Repository) UpdateBulkExtensionItem(ctx context.Context, item *model.BulkExtensionItem) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE bulk_extension_items
+		SET status = $3, attempts = $4, error = $5, updated_at = NOW()
+		WHERE job_id = $1 AND subscription_id = $2
+	`, item.JobID, item.SubscriptionID, item.Status, item.Attempts, item.Error)
+	return err
+}
+
+// ClaimBulkExtensionItem marks an item as processing and records the expiry its
+// subscription is extended from, keeping the one recorded by an earlier attempt
+func (r *Repository) ClaimBulkExtensionItem(ctx context.Context, item *model.BulkExtensionItem, expiresFrom *time.Time) error {
+	return r.db.QueryRowxContext(ctx, `
+		UPDATE bulk_extension_items
+		SET status = $3, expires_from = COALESCE(expires_from, $4), updated_at = NOW()
+		WHERE job_id = $1 AND subscription_id = $2
+		RETURNING expires_from
+	`, item.JobID, item.SubscriptionID, model.BulkItemProcessing, expiresFrom,
+	).Scan(&item.ExpiresFrom)
+}
+
+// ReleaseBulkExtensionItems moves the items of a job left processing by an
+// interrupted run back to pending
+func (r *Repository) ReleaseBulkExtensionItems(ctx context.Context, jobID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE bulk_extension_items SET status = $2, updated_at = NOW()
+		WHERE job_id = $1 AND status = $3
+	`, jobID, model.BulkItemPending, model.BulkItemProcessing)
+	return err
+}
+
+// RequeueBulkExtensionItems moves failed items with fewer than maxAttempts attempts
+// back to pending and returns how many were requeued. maxAttempts <= 0 requeues all
+// failed items and resets their attempts.
+func (r *Repository) RequeueBulkExtensionItems(ctx context.Context, jobID uuid.UUID, maxAttempts int) (int64, error) {
+	var result sql.Result
+	var err error
+	if maxAttempts <= 0 {
+		result, err = r.db.ExecContext(ctx, `
+			UPDATE bulk_extension_items SET status = $2, attempts = 0, updated_at = NOW()
+			WHERE job_id = $1 AND status = $3
+		`, jobID, model.BulkItemPending, model.BulkItemFailed)
+	} else {
+		result, err = r.db.ExecContext(ctx, `
+			UPDATE bulk_extension_items SET status = $2, updated_at = NOW()
+			WHERE job_id = $1 AND status = $3 AND attempts < $4
+		`, jobID, model.BulkItemPending, model.BulkItemFailed, maxAttempts)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// UpdateBulkExtensionJobProgress recounts a job's succeeded and failed items
+func (r *Repository) UpdateBulkExtensionJobProgress(ctx context.Context, jobID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE bulk_extension_jobs SET
+			succeeded = (SELECT COUNT(*) FROM bulk_extension_items WHERE job_id = $1 AND status = $2),
+			failed = (SELECT COUNT(*) FROM bulk_extension_items WHERE job_id = $1 AND status = $3)
+		WHERE id = $1
+	`, jobID, model.BulkItemDone, model.BulkItemFailed)
+	return err
+}
+
+// SetBulkExtensionJobStatus sets a job's status; finished_at is set when it completes
+// or fails and cleared when it runs again
+func (r *Repository) SetBulkExtensionJobStatus(ctx context.Context, jobID uuid.UUID, status string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE bulk_extension_jobs
+		SET status = $2, finished_at = CASE WHEN $2 = $3 THEN NULL ELSE NOW() END
+		WHERE id = $1
+	`, jobID, status, model.BulkJobRunning)
+	return err
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/model"
+)
+
+// RecordClientLatency folds latencies reported by a user into the user's moving
+// averages and, when asn is not 0, records the network the user reported from.
+// Averages not updated since staleBefore start over from the new measurement.
+func (r *Repository) RecordClientLatency(ctx context.Context, userID, asn int64, latencies map[uuid.UUID]float64, smoothing float64, staleBefore time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for serverID, latency := range latencies {
+		if _, err := tx.ExecContext(ctx, latencyUpsert("user_server_latency", "user_id"),
+			userID, serverID, latency, smoothing, staleBefore); err != nil {
+			return err
+		}
+	}
+
+	if asn != 0 {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO user_networks (user_id, asn, updated_at)
+			VALUES ($1, $2, NOW())
+			ON CONFLICT (user_id) DO UPDATE SET asn = EXCLUDED.asn, updated_at = NOW()
+		`, userID, asn)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// latencyUpsert builds the moving average upsert for a latency table keyed by column and server_id
+func latencyUpsert(table, column string) string {
+	return fmt.Sprintf(`
+		INSERT INTO %[1]s (%[2]s, server_id, latency_ms, samples, updated_at)
+		VALUES ($1, $2, $3, 1, NOW())
+		ON CONFLICT (%[2]s, server_id) DO UPDATE SET
+			latency_ms = CASE WHEN %[1]s.updated_at < $5 THEN EXCLUDED.latency_ms
+				ELSE %[1]s.latency_ms + $4 * (EXCLUDED.latency_ms - %[1]s.latency_ms) END,
+			samples = CASE WHEN %[1]s.updated_at < $5 THEN 1 ELSE %[1]s.samples + 1 END,
+			updated_at = NOW()
+	`, table, column)
+}
+
+// GetUserLatencies returns the user's latency averages updated since the given time
+func (r *Repository) GetUserLatencies(ctx context.Context, userID int64, since time.Time) ([]model.ClientLatency, error) {
+	var latencies []model.ClientLatency
+	err := r.db.SelectContext(ctx, &latencies, `
+		SELECT server_id, latency_ms, samples, updated_at FROM user_server_latency
+		WHERE user_id = $1 AND updated_at >= $2
+	`, userID, since)
+	return latencies, err
+}
+
+// GetNetworkLatencies returns, per server, the median of the latency averages of
+// users who last reported from the network, updated since the given time. Each
+// user counts once however often they report, and servers measured by fewer than
+// minUsers users are left out; Samples is the number of users.
+func (r *Repository) GetNetworkLatencies(ctx context.Context, asn int64, since time.Time, minUsers int) ([]model.ClientLatency, error) {
+	var latencies []model.ClientLatency
+	err := r.db.SelectContext(ctx, &latencies, `
+		SELECT l.server_id,
+			PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY l.latency_ms) AS latency_ms,
+			COUNT(*) AS samples,
+			MAX(l.updated_at) AS updated_at
+		FROM user_server_latency l
+		JOIN user_networks n ON n.user_id = l.user_id
+		WHERE n.asn = $1 AND l.updated_at >= $2
+		GROUP BY l.server_id
+		HAVING COUNT(*) >= $3
+	`, asn, since, minUsers)
+	return latencies, err
+}
+
+// GetUserASN returns the network the user last reported latencies from, or 0 when unknown
+func (r *Repository) GetUserASN(ctx context.Context, userID int64) (int64, error) {
+	var asn int64
+	err := r.db.GetContext(ctx, &asn, `SELECT asn FROM user_networks WHERE user_id = $1`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return asn, err
+}
+
+// DeleteClientLatenciesBefore removes latency averages not updated since the given time
+func (r *Repository) DeleteClientLatenciesBefore(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	for _, table := range []string{"user_server_latency", "user_networks"} {
+		result, err := r.db.ExecContext(ctx, `DELETE FROM `+table+` WHERE updated_at < $1`, before)
+		if err != nil {
+			return deleted, err
+		}
+		rows, _ := result.RowsAffected()
+		deleted += rows
+	}
+	return deleted, nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/model"
+)
+
+// healthStatsColumns aggregates server_health_checks rows into model.ServerHealthStats
+const healthStatsColumns = `
+	COUNT(*) AS checks,
+	100.0 * COUNT(*) FILTER (WHERE status <> 'offline') / NULLIF(COUNT(*), 0) AS uptime_percent,
+	percentile_cont(0.5) WITHIN GROUP (ORDER BY tcp_ms) AS latency_p50,
+	percentile_cont(0.95) WITHIN GROUP (ORDER BY tcp_ms) AS latency_p95,
+	percentile_cont(0.5) WITHIN GROUP (ORDER BY tls_ms) AS tls_p50,
+	percentile_cont(0.95) WITHIN GROUP (ORDER BY tls_ms) AS tls_p95`
+
+// CreateHealthCheck stores the result of a health check
+func (r *Repository) CreateHealthCheck(ctx context.Context, check *model.HealthCheck) error {
+	return r.db.QueryRowxContext(ctx, `
+		INSERT INTO server_health_checks (server_id, status, tcp_ms, tls_ok, tls_ms, panel_ok, panel_ms,
+			cpu_percent, mem_percent, xray_state, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, checked_at
+	`, check.ServerID, check.Status, check.TCPMs, check.TLSOK, check.TLSMs, check.PanelOK, check.PanelMs,
+		check.CPUPercent, check.MemPercent, check.XrayState, check.Error,
+	).Scan(&check.ID, &check.CheckedAt)
+}
+
+// GetRecentHealthChecks returns the latest health checks of a server, newest first
+func (r *Repository) GetRecentHealthChecks(ctx context.Context, serverID uuid.UUID, limit int) ([]model.HealthCheck, error) {
+	var checks []model.HealthCheck
+	err := r.db.SelectContext(ctx, &checks, `
+		SELECT * FROM server_health_checks
+		WHERE server_id = $1
+		ORDER BY checked_at DESC
+		LIMIT $2
+	`, serverID, limit)
+	return checks, err
+}
+
+// DeleteHealthChecksBefore removes health checks older than the given time
+func (r *Repository) DeleteHealthChecksBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM server_health_checks WHERE checked_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetServerHealthStats returns uptime and latency percentiles of a server since the given time
+func (r *Repository) GetServerHealthStats(ctx context.Context, serverID uuid.UUID, since time.Time) (*model.ServerHealthStats, error) {
+	stats := model.ServerHealthStats{ServerID: serverID}
+	err := r.db.GetContext(ctx, &stats, `
+		SELECT $1::uuid AS server_id, `+healthStatsColumns+`,
+			(SELECT COUNT(*) FROM server_incidents
+			 WHERE server_id = $1 AND (ended_at IS NULL OR ended_at >= $2)) AS incidents
+		FROM server_health_checks
+		WHERE server_id = $1 AND checked_at >= $2
+	`, serverID, since)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetAllServerHealthStats returns uptime and latency percentiles of every server with checks since the given time
+func (r *Repository) GetAllServerHealthStats(ctx context.Context, since time.Time) ([]model.ServerHealthStats, error) {
+	var stats []model.ServerHealthStats
+	err := r.db.SelectContext(ctx, &stats, `
+		SELECT c.server_id, `+healthStatsColumns+`,
+			(SELECT COUNT(*) FROM server_incidents i
+			 WHERE i.server_id = c.server_id AND (i.ended_at IS NULL OR i.ended_at >= $1)) AS incidents
+		FROM server_health_checks c
+		WHERE c.checked_at >= $1
+		GROUP BY c.server_id
+	`, since)
+	return stats, err
+}
+
+// CreateServerIncident opens an incident for a server
+func (r *Repository) CreateServerIncident(ctx context.Context, incident *model.ServerIncident) error {
+	return r.db.QueryRowxContext(ctx, `
+		INSERT INTO server_incidents (server_id, status, cause)
+		VALUES ($1, $2, $3)
+		RETURNING id, started_at
+	`, incident.ServerID, incident.Status, incident.Cause,
+	).Scan(&incident.ID, &incident.StartedAt)
+}
+
+// CloseServerIncident marks an incident as resolved
+func (r *Repository) CloseServerIncident(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE server_incidents SET ended_at = NOW() WHERE id = $1 AND ended_at IS NULL
+	`, id)
+	return err
+}
+
+// GetOpenServerIncident returns the unresolved incident of a server, or nil if there is none
+func (r *Repository) GetOpenServerIncident(ctx context.Context, serverID uuid.UUID) (*model.ServerIncident, error) {
+	var incident model.ServerIncident
+	err := r.db.GetContext(ctx, &incident, `
+		SELECT * FROM server_incidents
+		WHERE server_id = $1 AND ended_at IS NULL
+		ORDER BY started_at DESC
+		LIMIT 1
+	`, serverID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &incident, nil
+}
+
+// GetServerIncidents returns incidents of a server that were open at any time since the given time, newest first
+func (r *Repository) GetServerIncidents(ctx context.Context, serverID uuid.UUID, since time.Time) ([]model.ServerIncident, error) {
+	var incidents []model.ServerIncident
+	err := r.db.SelectContext(ctx, &incidents, `
+		SELECT * FROM server_incidents
+		WHERE server_id = $1 AND (ended_at IS NULL OR ended_at >= $2)
+		ORDER BY started_at DESC
+	`, serverID, since)
+	return incidents, err
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/model"
+)
+
+// QueuePanelClientRemoval queues a panel client for removal; a client already queued is kept once
+func (r *Repository) QueuePanelClientRemoval(ctx context.Context, removal *model.PanelClientRemoval) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO panel_client_removals (server_id, xui_inbound_id, xui_client_id, xui_email, last_error)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (server_id, xui_inbound_id, xui_email) DO NOTHING
+	`, removal.ServerID, removal.XUIInboundID, removal.XUIClientID, removal.XUIEmail, removal.LastError)
+	return err
+}
+
+// GetPanelClientRemovals returns queued removals, oldest first
+func (r *Repository) GetPanelClientRemovals(ctx context.Context, limit int) ([]model.PanelClientRemoval, error) {
+	var removals []model.PanelClientRemoval
+	err := r.db.SelectContext(ctx, &removals, `
+		SELECT * FROM panel_client_removals ORDER BY created_at LIMIT $1
+	`, limit)
+	return removals, err
+}
+
+// DeletePanelClientRemoval removes a completed removal from the queue
+func (r *Repository) DeletePanelClientRemoval(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM panel_client_removals WHERE id = $1`, id)
+	return err
+}
+
+// FailPanelClientRemoval records a failed attempt of a queued removal
+func (r *Repository) FailPanelClientRemoval(ctx context.Context, id uuid.UUID, lastError string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE panel_client_removals SET attempts = attempts + 1, last_error = $2 WHERE id = $1
+	`, id, lastError)
+	return err
+}
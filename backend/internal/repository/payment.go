@@ -125,6 +125,26 @@ func (r *Repository) HasCompletedPayment(ctx context.Context, userID int64) (boo
 	return count > 0, err
 }
 
+// SetPaymentCommentRequired makes a TON payment match only transfers carrying its comment
+func (r *Repository) SetPaymentCommentRequired(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE payments SET comment_required = TRUE WHERE id = $1", id)
+	return err
+}
+
+// AwaitPaymentTx moves a pending TON payment to awaiting_tx and marks it to be
+// reported in the bot chat. It returns false when the payment is not waiting for a transfer.
+func (r *Repository) AwaitPaymentTx(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE payments SET status = 'awaiting_tx', notify_chat = TRUE
+		WHERE id = $1 AND provider = 'ton' AND status IN ('pending', 'awaiting_tx')`,
+		id)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
 // GetAwaitingTxPayments returns TON payments waiting for blockchain confirmation
 func (r *Repository) GetAwaitingTxPayments(ctx context.Context) ([]model.Payment, error) {
 	var payments []model.Payment
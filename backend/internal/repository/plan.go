@@ -6,6 +6,7 @@ import (
 	"errors"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/zyvpn/backend/internal/model"
 )
 
@@ -56,14 +57,21 @@ func (r *Repository) GetTrialPlan(ctx context.Context) (*model.Plan, error) {
 }
 
 // CreatePlan creates a new plan with parameters
-func (r *Repository) CreatePlan(ctx context.Context, name, description string, durationDays, trafficGB, maxDevices int, priceTON float64, priceStars int, priceUSD float64, sortOrder int) (*model.Plan, error) {
+func (r *Repository) CreatePlan(ctx context.Context, name, description string, durationDays, trafficGB, maxDevices int, priceTON float64, priceStars int, priceUSD float64, sortOrder int, inboundTags, serverGroups []string) (*model.Plan, error) {
 	var plan model.Plan
 	query := `
-		INSERT INTO plans (name, description, duration_days, traffic_gb, max_devices, price_ton, price_stars, price_usd, is_active, sort_order)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, true, $9)
+		INSERT INTO plans (name, description, duration_days, traffic_gb, max_devices, price_ton, price_stars, price_usd, is_active, sort_order, inbound_tags, server_groups)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, true, $9, $10, $11)
 		RETURNING *`
 
-	err := r.db.QueryRowxContext(ctx, query, name, description, durationDays, trafficGB, maxDevices, priceTON, priceStars, priceUSD, sortOrder).StructScan(&plan)
+	if inboundTags == nil {
+		inboundTags = []string{}
+	}
+	if serverGroups == nil {
+		serverGroups = []string{}
+	}
+
+	err := r.db.QueryRowxContext(ctx, query, name, description, durationDays, trafficGB, maxDevices, priceTON, priceStars, priceUSD, sortOrder, pq.StringArray(inboundTags), pq.StringArray(serverGroups)).StructScan(&plan)
 	if err != nil {
 		return nil, err
 	}
@@ -71,7 +79,7 @@ func (r *Repository) CreatePlan(ctx context.Context, name, description string, d
 }
 
 // UpdatePlan updates a plan with optional parameters
-func (r *Repository) UpdatePlan(ctx context.Context, planID string, name, description *string, durationDays, trafficGB, maxDevices *int, priceTON *float64, priceStars *int, priceUSD *float64, isActive *bool, sortOrder *int) (*model.Plan, error) {
+func (r *Repository) UpdatePlan(ctx context.Context, planID string, name, description *string, durationDays, trafficGB, maxDevices *int, priceTON *float64, priceStars *int, priceUSD *float64, isActive *bool, sortOrder *int, inboundTags, serverGroups *[]string) (*model.Plan, error) {
 	id, err := uuid.Parse(planID)
 	if err != nil {
 		return nil, err
@@ -114,6 +122,18 @@ func (r *Repository) UpdatePlan(ctx context.Context, planID string, name, descri
 	if sortOrder != nil {
 		plan.SortOrder = *sortOrder
 	}
+	if inboundTags != nil {
+		plan.InboundTags = *inboundTags
+	}
+	if plan.InboundTags == nil {
+		plan.InboundTags = pq.StringArray{}
+	}
+	if serverGroups != nil {
+		plan.ServerGroups = *serverGroups
+	}
+	if plan.ServerGroups == nil {
+		plan.ServerGroups = pq.StringArray{}
+	}
 
 	query := `
 		UPDATE plans SET
@@ -126,7 +146,9 @@ func (r *Repository) UpdatePlan(ctx context.Context, planID string, name, descri
 			price_stars = $8,
 			price_usd = $9,
 			is_active = $10,
-			sort_order = $11
+			sort_order = $11,
+			inbound_tags = $12,
+			server_groups = $13
 		WHERE id = $1
 		RETURNING *`
 
@@ -142,6 +164,8 @@ func (r *Repository) UpdatePlan(ctx context.Context, planID string, name, descri
 		plan.PriceUSD,
 		plan.IsActive,
 		plan.SortOrder,
+		plan.InboundTags,
+		plan.ServerGroups,
 	).StructScan(plan)
 
 	return plan, err
@@ -5,11 +5,13 @@ import (
 	"fmt"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/zyvpn/backend/internal/secret"
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
 type Repository struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	secrets *secret.Box // encrypts panel credentials, nil = plaintext
 }
 
 func New(dsn string) (*Repository, error) {
@@ -24,6 +26,11 @@ func New(dsn string) (*Repository, error) {
 	return &Repository{db: db}, nil
 }
 
+// SetSecretBox enables encryption of panel credentials
+func (r *Repository) SetSecretBox(box *secret.Box) {
+	r.secrets = box
+}
+
 func (r *Repository) Close() error {
 	return r.db.Close()
 }
@@ -4,9 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"log"
 
 	"github.com/google/uuid"
 	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/secret"
 )
 
 var ErrServerNotFound = errors.New("server not found")
@@ -23,6 +26,31 @@ func (r *Repository) GetServer(ctx context.Context, id uuid.UUID) (*model.Server
 		}
 		return nil, err
 	}
+	if err := r.decryptServer(&server); err != nil {
+		return nil, err
+	}
+	return &server, nil
+}
+
+// GetServerForAdmin returns a server by ID like GetServer, but a server whose
+// password cannot be decrypted is returned with the password cleared and
+// XUIPasswordUnreadable set, so an admin can set a new one
+func (r *Repository) GetServerForAdmin(ctx context.Context, id uuid.UUID) (*model.Server, error) {
+	var server model.Server
+	err := r.db.GetContext(ctx, &server, `
+		SELECT * FROM servers WHERE id = $1
+	`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrServerNotFound
+		}
+		return nil, err
+	}
+	if err := r.decryptServer(&server); err != nil {
+		log.Printf("WARNING: %v", err)
+		server.XUIPassword = ""
+		server.XUIPasswordUnreadable = true
+	}
 	return &server, nil
 }
 
@@ -37,7 +65,7 @@ func (r *Repository) GetActiveServers(ctx context.Context) ([]model.Server, erro
 	if err != nil {
 		return nil, err
 	}
-	return servers, nil
+	return r.decryptServers(servers), nil
 }
 
 // GetAllServers returns all servers (for admin)
@@ -50,47 +78,79 @@ func (r *Repository) GetAllServers(ctx context.Context) ([]model.Server, error)
 	if err != nil {
 		return nil, err
 	}
+	// Admins must still see and fix a server whose password cannot be decrypted,
+	// so it is kept with the password cleared and its panel calls fail on their own
+	for i := range servers {
+		if err := r.decryptServer(&servers[i]); err != nil {
+			log.Printf("WARNING: %v", err)
+			servers[i].XUIPassword = ""
+			servers[i].XUIPasswordUnreadable = true
+		}
+	}
 	return servers, nil
 }
 
 // CreateServer creates a new server
 func (r *Repository) CreateServer(ctx context.Context, server *model.Server) error {
 	server.ID = uuid.New()
-	_, err := r.db.NamedExecContext(ctx, `
-		INSERT INTO servers (id, name, country, city, flag_emoji,
-			xui_base_url, xui_username, xui_password, xui_inbound_id,
+	stored, err := r.encryptServer(server)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.NamedExecContext(ctx, `
+		INSERT INTO servers (id, name, country, city, flag_emoji, panel_type,
+			xui_base_url, xui_username, xui_password, xui_inbound_id, inbound_tag,
 			server_address, server_port, public_key, short_id, server_name,
-			is_active, sort_order)
-		VALUES (:id, :name, :country, :city, :flag_emoji,
-			:xui_base_url, :xui_username, :xui_password, :xui_inbound_id,
+			panel_public_key, panel_short_ids, panel_server_names, panel_port, reality_checked_at,
+			is_active, sort_order, server_group, capacity, max_online, bandwidth_mbps, monthly_cost, cost_currency)
+		VALUES (:id, :name, :country, :city, :flag_emoji, :panel_type,
+			:xui_base_url, :xui_username, :xui_password, :xui_inbound_id, :inbound_tag,
 			:server_address, :server_port, :public_key, :short_id, :server_name,
-			:is_active, :sort_order)
-	`, server)
+			:panel_public_key, :panel_short_ids, :panel_server_names, :panel_port, :reality_checked_at,
+			:is_active, :sort_order, :server_group, :capacity, :max_online, :bandwidth_mbps, :monthly_cost, :cost_currency)
+	`, stored)
 	return err
 }
 
 // UpdateServer updates a server
 func (r *Repository) UpdateServer(ctx context.Context, server *model.Server) error {
+	stored, err := r.encryptServer(server)
+	if err != nil {
+		return err
+	}
 	result, err := r.db.NamedExecContext(ctx, `
 		UPDATE servers SET
 			name = :name,
 			country = :country,
 			city = :city,
 			flag_emoji = :flag_emoji,
+			panel_type = :panel_type,
 			xui_base_url = :xui_base_url,
 			xui_username = :xui_username,
-			xui_password = :xui_password,
+			xui_password = CASE WHEN :xui_password_unreadable THEN xui_password ELSE :xui_password END,
 			xui_inbound_id = :xui_inbound_id,
+			inbound_tag = :inbound_tag,
 			server_address = :server_address,
 			server_port = :server_port,
 			public_key = :public_key,
 			short_id = :short_id,
 			server_name = :server_name,
+			panel_public_key = :panel_public_key,
+			panel_short_ids = :panel_short_ids,
+			panel_server_names = :panel_server_names,
+			panel_port = :panel_port,
+			reality_checked_at = :reality_checked_at,
 			is_active = :is_active,
 			sort_order = :sort_order,
+			server_group = :server_group,
+			capacity = :capacity,
+			max_online = :max_online,
+			bandwidth_mbps = :bandwidth_mbps,
+			monthly_cost = :monthly_cost,
+			cost_currency = :cost_currency,
 			updated_at = NOW()
 		WHERE id = :id
-	`, server)
+	`, stored)
 	if err != nil {
 		return err
 	}
@@ -102,6 +162,25 @@ func (r *Repository) UpdateServer(ctx context.Context, server *model.Server) err
 	return nil
 }
 
+// UpdateServerReality stores the connection parameters and the values last read from the panel
+func (r *Repository) UpdateServerReality(ctx context.Context, server *model.Server) error {
+	_, err := r.db.NamedExecContext(ctx, `
+		UPDATE servers SET
+			server_port = :server_port,
+			public_key = :public_key,
+			short_id = :short_id,
+			server_name = :server_name,
+			panel_public_key = :panel_public_key,
+			panel_short_ids = :panel_short_ids,
+			panel_server_names = :panel_server_names,
+			panel_port = :panel_port,
+			reality_checked_at = :reality_checked_at,
+			updated_at = NOW()
+		WHERE id = :id
+	`, server)
+	return err
+}
+
 // DeleteServer deletes a server
 func (r *Repository) DeleteServer(ctx context.Context, id uuid.UUID) error {
 	result, err := r.db.ExecContext(ctx, `DELETE FROM servers WHERE id = $1`, id)
@@ -131,44 +210,24 @@ func (r *Repository) GetDefaultServer(ctx context.Context) (*model.Server, error
 		}
 		return nil, err
 	}
-	return &server, nil
-}
-
-// GetBestServer returns the best available server based on load and capacity
-// Prioritizes: online status, lower load percentage, higher capacity
-func (r *Repository) GetBestServer(ctx context.Context) (*model.Server, error) {
-	var server model.Server
-	err := r.db.GetContext(ctx, &server, `
-		SELECT * FROM servers
-		WHERE is_active = true AND status = 'online'
-		ORDER BY
-			(CAST(current_load AS FLOAT) / NULLIF(capacity, 0)) ASC,
-			capacity DESC,
-			sort_order ASC
-		LIMIT 1
-	`)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			// Fall back to any active server
-			return r.GetDefaultServer(ctx)
-		}
+	if err := r.decryptServer(&server); err != nil {
 		return nil, err
 	}
 	return &server, nil
 }
 
-// GetOnlineServers returns all online active servers
+// GetOnlineServers returns all active servers that serve traffic, including degraded ones
 func (r *Repository) GetOnlineServers(ctx context.Context) ([]model.Server, error) {
 	var servers []model.Server
 	err := r.db.SelectContext(ctx, &servers, `
 		SELECT * FROM servers
-		WHERE is_active = true AND status = 'online'
+		WHERE is_active = true AND status IN ('online', 'degraded')
 		ORDER BY sort_order, name
 	`)
 	if err != nil {
 		return nil, err
 	}
-	return servers, nil
+	return r.decryptServers(servers), nil
 }
 
 // UpdateServerHealth updates server ping and status
@@ -181,30 +240,6 @@ func (r *Repository) UpdateServerHealth(ctx context.Context, id uuid.UUID, pingM
 	return err
 }
 
-// UpdateServerLoad updates server current load
-func (r *Repository) UpdateServerLoad(ctx context.Context, id uuid.UUID, currentLoad int) error {
-	_, err := r.db.ExecContext(ctx, `
-		UPDATE servers SET current_load = $2 WHERE id = $1
-	`, id, currentLoad)
-	return err
-}
-
-// IncrementServerLoad increments server current load by 1
-func (r *Repository) IncrementServerLoad(ctx context.Context, id uuid.UUID) error {
-	_, err := r.db.ExecContext(ctx, `
-		UPDATE servers SET current_load = current_load + 1 WHERE id = $1
-	`, id)
-	return err
-}
-
-// DecrementServerLoad decrements server current load by 1
-func (r *Repository) DecrementServerLoad(ctx context.Context, id uuid.UUID) error {
-	_, err := r.db.ExecContext(ctx, `
-		UPDATE servers SET current_load = GREATEST(current_load - 1, 0) WHERE id = $1
-	`, id)
-	return err
-}
-
 // CountActiveSubscriptionsByServer counts active subscriptions for a server
 func (r *Repository) CountActiveSubscriptionsByServer(ctx context.Context, serverID uuid.UUID) (int, error) {
 	var count int
@@ -226,3 +261,97 @@ func (r *Repository) SyncAllServerLoads(ctx context.Context) error {
 	`)
 	return err
 }
+
+// encryptServer returns a copy of the server with credentials encrypted for storage
+func (r *Repository) encryptServer(server *model.Server) (*model.Server, error) {
+	stored := *server
+	if r.secrets == nil {
+		return &stored, nil
+	}
+
+	password, err := r.secrets.Encrypt(server.XUIPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt panel password: %w", err)
+	}
+	stored.XUIPassword = password
+	return &stored, nil
+}
+
+// decryptServer decrypts credentials of a server read from the database
+func (r *Repository) decryptServer(server *model.Server) error {
+	if !secret.IsEncrypted(server.XUIPassword) {
+		return nil
+	}
+	if r.secrets == nil {
+		return fmt.Errorf("panel password of server %s is encrypted but no credentials key is configured", server.Name)
+	}
+
+	password, err := r.secrets.Decrypt(server.XUIPassword)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt panel password of server %s: %w", server.Name, err)
+	}
+	server.XUIPassword = password
+	return nil
+}
+
+// decryptServers decrypts credentials of servers read from the database. A server
+// whose password cannot be decrypted is skipped and logged, so it does not take
+// every other server down with it.
+func (r *Repository) decryptServers(servers []model.Server) []model.Server {
+	result := servers[:0]
+	for _, server := range servers {
+		if err := r.decryptServer(&server); err != nil {
+			log.Printf("WARNING: Skipping server %s: %v", server.ID, err)
+			continue
+		}
+		result = append(result, server)
+	}
+	return result
+}
+
+// ReencryptServerCredentials encrypts plaintext credentials and those encrypted
+// with an old key using the current key. Servers whose password cannot be
+// decrypted are logged and left as they are. It returns the number of updated
+// and of skipped servers.
+func (r *Repository) ReencryptServerCredentials(ctx context.Context) (updated, skipped int, err error) {
+	if r.secrets == nil {
+		return 0, 0, nil
+	}
+
+	var rows []struct {
+		ID          uuid.UUID `db:"id"`
+		XUIPassword string    `db:"xui_password"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, `SELECT id, xui_password FROM servers`); err != nil {
+		return 0, 0, err
+	}
+
+	for _, row := range rows {
+		if !r.secrets.NeedsRotation(row.XUIPassword) {
+			continue
+		}
+
+		plaintext, err := r.secrets.Decrypt(row.XUIPassword)
+		if err != nil {
+			log.Printf("WARNING: Skipping re-encryption of server %s: %v", row.ID, err)
+			skipped++
+			continue
+		}
+		encrypted, err := r.secrets.Encrypt(plaintext)
+		if err != nil {
+			return updated, skipped, err
+		}
+
+		// Compare-and-set so a concurrent update is not overwritten
+		result, err := r.db.ExecContext(ctx, `
+			UPDATE servers SET xui_password = $3 WHERE id = $1 AND xui_password = $2
+		`, row.ID, row.XUIPassword, encrypted)
+		if err != nil {
+			return updated, skipped, err
+		}
+		if rows, err := result.RowsAffected(); err == nil && rows > 0 {
+			updated++
+		}
+	}
+	return updated, skipped, nil
+}
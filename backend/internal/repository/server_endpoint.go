@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/zyvpn/backend/internal/model"
+)
+
+var (
+	ErrServerEndpointNotFound = errors.New("server endpoint not found")
+	ErrServerEndpointExists   = errors.New("server endpoint already exists")
+)
+
+// GetServerEndpoint returns an endpoint by ID
+func (r *Repository) GetServerEndpoint(ctx context.Context, id uuid.UUID) (*model.ServerEndpoint, error) {
+	var endpoint model.ServerEndpoint
+	err := r.db.GetContext(ctx, &endpoint, `SELECT * FROM server_endpoints WHERE id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrServerEndpointNotFound
+		}
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+// GetServerEndpoints returns all endpoints of a server, retired ones included, in order
+func (r *Repository) GetServerEndpoints(ctx context.Context, serverID uuid.UUID) ([]model.ServerEndpoint, error) {
+	var endpoints []model.ServerEndpoint
+	err := r.db.SelectContext(ctx, &endpoints, `
+		SELECT * FROM server_endpoints
+		WHERE server_id = $1
+		ORDER BY sort_order, created_at
+	`, serverID)
+	return endpoints, err
+}
+
+// GetAllServerEndpoints returns the endpoints of all servers, retired ones included, in order
+func (r *Repository) GetAllServerEndpoints(ctx context.Context) ([]model.ServerEndpoint, error) {
+	var endpoints []model.ServerEndpoint
+	err := r.db.SelectContext(ctx, &endpoints, `
+		SELECT * FROM server_endpoints ORDER BY server_id, sort_order, created_at
+	`)
+	return endpoints, err
+}
+
+// GetActiveServerEndpoints returns the endpoints of a server that are not retired, in order
+func (r *Repository) GetActiveServerEndpoints(ctx context.Context, serverID uuid.UUID) ([]model.ServerEndpoint, error) {
+	var endpoints []model.ServerEndpoint
+	err := r.db.SelectContext(ctx, &endpoints, `
+		SELECT * FROM server_endpoints
+		WHERE server_id = $1 AND retired_at IS NULL
+		ORDER BY sort_order, created_at
+	`, serverID)
+	return endpoints, err
+}
+
+// CreateServerEndpoint adds an endpoint to a server
+func (r *Repository) CreateServerEndpoint(ctx context.Context, endpoint *model.ServerEndpoint) error {
+	if endpoint.Status == "" {
+		endpoint.Status = model.ServerStatusUnknown
+	}
+	err := r.db.QueryRowxContext(ctx, `
+		INSERT INTO server_endpoints (server_id, address, port, label, sort_order, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, endpoint.ServerID, endpoint.Address, endpoint.Port, endpoint.Label, endpoint.SortOrder, endpoint.Status,
+	).Scan(&endpoint.ID, &endpoint.CreatedAt)
+	return endpointError(err)
+}
+
+// UpdateServerEndpoint updates the address, port, label, order and retirement of an endpoint
+func (r *Repository) UpdateServerEndpoint(ctx context.Context, endpoint *model.ServerEndpoint) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE server_endpoints SET
+			address = $2,
+			port = $3,
+			label = $4,
+			sort_order = $5,
+			retired_at = $6
+		WHERE id = $1
+	`, endpoint.ID, endpoint.Address, endpoint.Port, endpoint.Label, endpoint.SortOrder, endpoint.RetiredAt)
+	if err != nil {
+		return endpointError(err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrServerEndpointNotFound
+	}
+	return nil
+}
+
+// UpdateServerEndpointAddress moves the endpoints at one address of a server to another,
+// keeping their order, label and health history
+func (r *Repository) UpdateServerEndpointAddress(ctx context.Context, serverID uuid.UUID, oldAddress, newAddress string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE server_endpoints SET address = $3, status = $4, ping_ms = NULL, last_error = NULL, last_check_at = NULL
+		WHERE server_id = $1 AND address = $2
+	`, serverID, oldAddress, newAddress, model.ServerStatusUnknown)
+	return endpointError(err)
+}
+
+// UpdateServerEndpointHealth records the result of an endpoint health check
+func (r *Repository) UpdateServerEndpointHealth(ctx context.Context, id uuid.UUID, status string, pingMs *int, lastError *string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE server_endpoints SET status = $2, ping_ms = $3, last_error = $4, last_check_at = NOW()
+		WHERE id = $1
+	`, id, status, pingMs, lastError)
+	return err
+}
+
+// DeleteServerEndpoint deletes an endpoint
+func (r *Repository) DeleteServerEndpoint(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM server_endpoints WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrServerEndpointNotFound
+	}
+	return nil
+}
+
+// endpointError maps a duplicate address and port on the same server to ErrServerEndpointExists
+func endpointError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrServerEndpointExists
+	}
+	return err
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/model"
+)
+
+var ErrServerInboundNotFound = errors.New("server inbound not found")
+
+// GetServerInbound returns an additional inbound by ID
+func (r *Repository) GetServerInbound(ctx context.Context, id uuid.UUID) (*model.ServerInbound, error) {
+	var inbound model.ServerInbound
+	err := r.db.GetContext(ctx, &inbound, `SELECT * FROM server_inbounds WHERE id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrServerInboundNotFound
+		}
+		return nil, err
+	}
+	return &inbound, nil
+}
+
+// GetServerInbounds returns all additional inbounds of a server
+func (r *Repository) GetServerInbounds(ctx context.Context, serverID uuid.UUID) ([]model.ServerInbound, error) {
+	var inbounds []model.ServerInbound
+	err := r.db.SelectContext(ctx, &inbounds, `
+		SELECT * FROM server_inbounds
+		WHERE server_id = $1
+		ORDER BY sort_order, xui_inbound_id
+	`, serverID)
+	return inbounds, err
+}
+
+// GetActiveServerInbounds returns active additional inbounds of a server
+func (r *Repository) GetActiveServerInbounds(ctx context.Context, serverID uuid.UUID) ([]model.ServerInbound, error) {
+	var inbounds []model.ServerInbound
+	err := r.db.SelectContext(ctx, &inbounds, `
+		SELECT * FROM server_inbounds
+		WHERE server_id = $1 AND is_active = true
+		ORDER BY sort_order, xui_inbound_id
+	`, serverID)
+	return inbounds, err
+}
+
+// CreateServerInbound adds an additional inbound to a server
+func (r *Repository) CreateServerInbound(ctx context.Context, inbound *model.ServerInbound) error {
+	return r.db.QueryRowxContext(ctx, `
+		INSERT INTO server_inbounds (server_id, xui_inbound_id, tag, name, is_active, sort_order)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, inbound.ServerID, inbound.XUIInboundID, inbound.Tag, inbound.Name, inbound.IsActive, inbound.SortOrder,
+	).Scan(&inbound.ID, &inbound.CreatedAt)
+}
+
+// UpdateServerInbound updates an additional inbound
+func (r *Repository) UpdateServerInbound(ctx context.Context, inbound *model.ServerInbound) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE server_inbounds SET
+			xui_inbound_id = $2,
+			tag = $3,
+			name = $4,
+			is_active = $5,
+			sort_order = $6
+		WHERE id = $1
+	`, inbound.ID, inbound.XUIInboundID, inbound.Tag, inbound.Name, inbound.IsActive, inbound.SortOrder)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrServerInboundNotFound
+	}
+	return nil
+}
+
+// DeleteServerInbound deletes an additional inbound
+func (r *Repository) DeleteServerInbound(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM server_inbounds WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrServerInboundNotFound
+	}
+	return nil
+}
+
+// CreateSubscriptionInbound records a client provisioned on an additional inbound
+func (r *Repository) CreateSubscriptionInbound(ctx context.Context, si *model.SubscriptionInbound) error {
+	return r.db.QueryRowxContext(ctx, `
+		INSERT INTO subscription_inbounds (subscription_id, server_inbound_id, xui_client_id, xui_email, connection_key)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, si.SubscriptionID, si.ServerInboundID, si.XUIClientID, si.XUIEmail, si.ConnectionKey,
+	).Scan(&si.ID, &si.CreatedAt)
+}
+
+// GetSubscriptionInbounds returns clients provisioned on additional inbounds for a subscription
+func (r *Repository) GetSubscriptionInbounds(ctx context.Context, subscriptionID uuid.UUID) ([]model.SubscriptionInbound, error) {
+	var inbounds []model.SubscriptionInbound
+	err := r.db.SelectContext(ctx, &inbounds, `
+		SELECT si.* FROM subscription_inbounds si
+		JOIN server_inbounds i ON i.id = si.server_inbound_id
+		WHERE si.subscription_id = $1
+		ORDER BY i.sort_order, i.xui_inbound_id
+	`, subscriptionID)
+	return inbounds, err
+}
+
+// GetSubscriptionInboundsByServerInbound returns the clients provisioned on an additional inbound
+func (r *Repository) GetSubscriptionInboundsByServerInbound(ctx context.Context, serverInboundID uuid.UUID) ([]model.SubscriptionInbound, error) {
+	var inbounds []model.SubscriptionInbound
+	err := r.db.SelectContext(ctx, &inbounds, `
+		SELECT * FROM subscription_inbounds WHERE server_inbound_id = $1
+	`, serverInboundID)
+	return inbounds, err
+}
+
+// UpdateSubscriptionInboundKey replaces the stored connection key of an additional inbound client
+func (r *Repository) UpdateSubscriptionInboundKey(ctx context.Context, id uuid.UUID, connectionKey string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE subscription_inbounds SET connection_key = $2 WHERE id = $1`, id, connectionKey)
+	return err
+}
+
+// DeleteSubscriptionInbound removes one additional inbound client of a subscription
+func (r *Repository) DeleteSubscriptionInbound(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM subscription_inbounds WHERE id = $1`, id)
+	return err
+}
+
+// DeleteSubscriptionInbounds removes all additional inbound clients of a subscription
+func (r *Repository) DeleteSubscriptionInbounds(ctx context.Context, subscriptionID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM subscription_inbounds WHERE subscription_id = $1`, subscriptionID)
+	return err
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/model"
+)
+
+// CreateServerLoadSample stores a load sample and makes it the server's current live load
+func (r *Repository) CreateServerLoadSample(ctx context.Context, sample *model.ServerLoadSample) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowxContext(ctx, `
+		INSERT INTO server_load_samples (server_id, online_clients, connections, upload_bps, download_bps, subscriptions)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, sampled_at
+	`, sample.ServerID, sample.OnlineClients, sample.Connections, sample.UploadBps, sample.DownloadBps, sample.Subscriptions,
+	).Scan(&sample.ID, &sample.SampledAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE servers SET online_clients = $2, connections = $3, throughput_bps = $4, load_sampled_at = $5
+		WHERE id = $1
+	`, sample.ServerID, sample.OnlineClients, sample.Connections, sample.ThroughputBps(), sample.SampledAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetServerLoadSamples returns the load samples of a server since the given time, oldest first
+func (r *Repository) GetServerLoadSamples(ctx context.Context, serverID uuid.UUID, since time.Time) ([]model.ServerLoadSample, error) {
+	var samples []model.ServerLoadSample
+	err := r.db.SelectContext(ctx, &samples, `
+		SELECT * FROM server_load_samples
+		WHERE server_id = $1 AND sampled_at >= $2
+		ORDER BY sampled_at
+	`, serverID, since)
+	return samples, err
+}
+
+// DeleteServerLoadSamplesBefore removes load samples older than the given time
+func (r *Repository) DeleteServerLoadSamplesBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM server_load_samples WHERE sampled_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/model"
+)
+
+// GetServerRevenue sums completed subscription payments in [from, to) by server,
+// calendar month (UTC) and currency. A payment is attributed to the server chosen
+// at purchase, or else to the server its subscription is placed on.
+func (r *Repository) GetServerRevenue(ctx context.Context, from, to time.Time) ([]model.ServerRevenue, error) {
+	var revenue []model.ServerRevenue
+	err := r.db.SelectContext(ctx, &revenue, `
+		SELECT COALESCE(p.server_id, s.server_id) AS server_id,
+			date_trunc('month', p.completed_at AT TIME ZONE 'UTC') AS month,
+			p.currency,
+			SUM(p.amount) AS amount,
+			COUNT(*) AS payments
+		FROM payments p
+		LEFT JOIN subscriptions s ON s.id = p.subscription_id
+		WHERE p.status = $1 AND p.payment_type = $2
+			AND p.completed_at >= $3 AND p.completed_at < $4
+		GROUP BY 1, 2, 3
+	`, model.PaymentStatusCompleted, model.PaymentTypeSubscription, from, to)
+	return revenue, err
+}
+
+// GetServerMonthlyUsers counts distinct users whose subscription on a server was
+// active at some point of each calendar month in [from, to)
+func (r *Repository) GetServerMonthlyUsers(ctx context.Context, from, to time.Time) ([]model.ServerMonthlyUsers, error) {
+	var users []model.ServerMonthlyUsers
+	err := r.db.SelectContext(ctx, &users, `
+		SELECT s.server_id, m.month, COUNT(DISTINCT s.user_id) AS users
+		FROM generate_series($1::timestamp, $2::timestamp - INTERVAL '1 month', INTERVAL '1 month') AS m(month)
+		JOIN subscriptions s ON s.server_id IS NOT NULL
+			AND s.started_at < (m.month + INTERVAL '1 month') AT TIME ZONE 'UTC'
+			AND (s.expires_at IS NULL OR s.expires_at >= m.month AT TIME ZONE 'UTC')
+		GROUP BY 1, 2
+	`, from.UTC().Format(time.DateTime), to.UTC().Format(time.DateTime))
+	return users, err
+}
+
+// GetServerCosts returns the cost periods of all servers, including deleted ones,
+// that overlap [from, to), oldest first
+func (r *Repository) GetServerCosts(ctx context.Context, from, to time.Time) ([]model.ServerCost, error) {
+	var costs []model.ServerCost
+	err := r.db.SelectContext(ctx, &costs, `
+		SELECT * FROM server_costs
+		WHERE effective_from < $2::timestamp AND (effective_to IS NULL OR effective_to > $1::timestamp)
+		ORDER BY effective_from
+	`, from.UTC().Format(time.DateTime), to.UTC().Format(time.DateTime))
+	return costs, err
+}
+
+// OpenServerCost starts charging a server its current monthly cost
+func (r *Repository) OpenServerCost(ctx context.Context, server *model.Server) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO server_costs (server_id, name, country, flag_emoji, monthly_cost, cost_currency)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, server.ID, server.Name, server.Country, server.FlagEmoji, server.MonthlyCost, server.CostCurrency)
+	return err
+}
+
+// CloseServerCost stops charging a server, e.g. when it is deactivated or its cost changes
+func (r *Repository) CloseServerCost(ctx context.Context, serverID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE server_costs SET effective_to = NOW() WHERE server_id = $1 AND effective_to IS NULL
+	`, serverID)
+	return err
+}
+
+// GetServerMonthlyTraffic returns the traffic of all servers for the months in [from, to)
+func (r *Repository) GetServerMonthlyTraffic(ctx context.Context, from, to time.Time) ([]model.ServerMonthlyTraffic, error) {
+	var traffic []model.ServerMonthlyTraffic
+	err := r.db.SelectContext(ctx, &traffic, `
+		SELECT * FROM server_monthly_traffic WHERE month >= $1::date AND month < $2::date
+	`, from.UTC().Format(time.DateOnly), to.UTC().Format(time.DateOnly))
+	return traffic, err
+}
+
+// AddServerMonthlyTraffic adds traffic to a server's total for the month of at (UTC)
+func (r *Repository) AddServerMonthlyTraffic(ctx context.Context, serverID uuid.UUID, at time.Time, upload, download int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO server_monthly_traffic (server_id, month, upload_bytes, download_bytes)
+		VALUES ($1, date_trunc('month', $2::date), $3, $4)
+		ON CONFLICT (server_id, month) DO UPDATE SET
+			upload_bytes = server_monthly_traffic.upload_bytes + EXCLUDED.upload_bytes,
+			download_bytes = server_monthly_traffic.download_bytes + EXCLUDED.download_bytes
+	`, serverID, at.UTC().Format(time.DateOnly), upload, download)
+	return err
+}
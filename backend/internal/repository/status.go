@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/model"
+)
+
+var ErrStatusNoticeNotFound = errors.New("status notice not found")
+
+// GetStatusNotice returns a status notice by ID
+func (r *Repository) GetStatusNotice(ctx context.Context, id uuid.UUID) (*model.StatusNotice, error) {
+	var notice model.StatusNotice
+	err := r.db.GetContext(ctx, &notice, `SELECT * FROM status_notices WHERE id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrStatusNoticeNotFound
+		}
+		return nil, err
+	}
+	return &notice, nil
+}
+
+// GetStatusNotices returns the latest status notices, newest first
+func (r *Repository) GetStatusNotices(ctx context.Context, limit int) ([]model.StatusNotice, error) {
+	var notices []model.StatusNotice
+	err := r.db.SelectContext(ctx, &notices, `
+		SELECT * FROM status_notices
+		ORDER BY starts_at DESC
+		LIMIT $1
+	`, limit)
+	return notices, err
+}
+
+// GetCurrentStatusNotices returns notices that are in effect or scheduled, soonest first
+func (r *Repository) GetCurrentStatusNotices(ctx context.Context) ([]model.StatusNotice, error) {
+	var notices []model.StatusNotice
+	err := r.db.SelectContext(ctx, &notices, `
+		SELECT * FROM status_notices
+		WHERE ends_at IS NULL OR ends_at > NOW()
+		ORDER BY starts_at
+	`)
+	return notices, err
+}
+
+// CreateStatusNotice saves a new status notice
+func (r *Repository) CreateStatusNotice(ctx context.Context, notice *model.StatusNotice) error {
+	return r.db.QueryRowxContext(ctx, `
+		INSERT INTO status_notices (kind, title, message, server_id, starts_at, ends_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at
+	`, notice.Kind, notice.Title, notice.Message, notice.ServerID, notice.StartsAt, notice.EndsAt, notice.CreatedBy,
+	).Scan(&notice.ID, &notice.CreatedAt, &notice.UpdatedAt)
+}
+
+// UpdateStatusNotice saves changes to a status notice
+func (r *Repository) UpdateStatusNotice(ctx context.Context, notice *model.StatusNotice) error {
+	err := r.db.QueryRowxContext(ctx, `
+		UPDATE status_notices
+		SET kind = $2, title = $3, message = $4, server_id = $5, starts_at = $6, ends_at = $7, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`, notice.ID, notice.Kind, notice.Title, notice.Message, notice.ServerID, notice.StartsAt, notice.EndsAt,
+	).Scan(&notice.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrStatusNoticeNotFound
+	}
+	return err
+}
+
+// DeleteStatusNotice deletes a status notice
+func (r *Repository) DeleteStatusNotice(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM status_notices WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrStatusNoticeNotFound
+	}
+	return nil
+}
+
+// GetOpenServerIncidents returns the unresolved incidents of all servers, oldest first
+func (r *Repository) GetOpenServerIncidents(ctx context.Context) ([]model.ServerIncident, error) {
+	var incidents []model.ServerIncident
+	err := r.db.SelectContext(ctx, &incidents, `
+		SELECT * FROM server_incidents
+		WHERE ended_at IS NULL
+		ORDER BY started_at
+	`)
+	return incidents, err
+}
@@ -108,6 +108,15 @@ func (r *Repository) UpdateSubscriptionStatus(ctx context.Context, id uuid.UUID,
 	return err
 }
 
+// UpdateSubscriptionPlan moves a subscription to another plan, e.g. after an upgrade
+func (r *Repository) UpdateSubscriptionPlan(ctx context.Context, id, planID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE subscriptions SET plan_id = $2 WHERE id = $1",
+		id, planID,
+	)
+	return err
+}
+
 func (r *Repository) UpdateSubscriptionTraffic(ctx context.Context, id uuid.UUID, trafficUsed int64) error {
 	_, err := r.db.ExecContext(ctx,
 		"UPDATE subscriptions SET traffic_used = $2 WHERE id = $1",
@@ -116,6 +125,49 @@ func (r *Repository) UpdateSubscriptionTraffic(ctx context.Context, id uuid.UUID
 	return err
 }
 
+// AddSubscriptionTrafficCounter adds the usage between the stored raw counter of a
+// stateless node or a rolling window and its current value. A counter below the stored
+// one means the node restarted and counts from zero, or for a rolling window that old
+// usage left it, which adds nothing. The update only applies while the stored counter
+// is still previous, so concurrent syncs never add the same usage twice.
+func (r *Repository) AddSubscriptionTrafficCounter(ctx context.Context, id uuid.UUID, previous, counter int64, rolling bool) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE subscriptions SET
+			traffic_used = traffic_used + CASE WHEN $3 >= $2 THEN $3 - $2 WHEN $4 THEN 0 ELSE $3 END,
+			traffic_counter = $3
+		WHERE id = $1 AND traffic_counter = $2`,
+		id, previous, counter, rolling,
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// GetActiveSubscriptions returns all active subscriptions
+func (r *Repository) GetActiveSubscriptions(ctx context.Context) ([]model.Subscription, error) {
+	var subs []model.Subscription
+	err := r.db.SelectContext(ctx, &subs, `SELECT * FROM subscriptions WHERE status = 'active'`)
+	return subs, err
+}
+
+// GetActiveSubscriptionsByServer returns active subscriptions on a server
+func (r *Repository) GetActiveSubscriptionsByServer(ctx context.Context, serverID uuid.UUID) ([]model.Subscription, error) {
+	var subs []model.Subscription
+	query := `
+		SELECT * FROM subscriptions
+		WHERE status = 'active' AND server_id = $1`
+	err := r.db.SelectContext(ctx, &subs, query, serverID)
+	return subs, err
+}
+
+// UpdateSubscriptionConnectionKey replaces the stored connection key of a subscription
+func (r *Repository) UpdateSubscriptionConnectionKey(ctx context.Context, id uuid.UUID, connectionKey string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE subscriptions SET connection_key = $2 WHERE id = $1`, id, connectionKey)
+	return err
+}
+
 func (r *Repository) GetExpiredSubscriptions(ctx context.Context) ([]model.Subscription, error) {
 	var subs []model.Subscription
 	query := `
@@ -146,6 +198,14 @@ func (r *Repository) ExtendSubscription(ctx context.Context, id uuid.UUID, days
 	return err
 }
 
+// ExtendSubscriptionTo moves the expiry of a subscription to expiresAt unless it is already later
+func (r *Repository) ExtendSubscriptionTo(ctx context.Context, id uuid.UUID, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE subscriptions SET expires_at = GREATEST(expires_at, $2) WHERE id = $1
+	`, id, expiresAt)
+	return err
+}
+
 func (r *Repository) HasUsedTrial(ctx context.Context, userID int64) (bool, error) {
 	var count int
 	query := `
@@ -166,7 +226,8 @@ func (r *Repository) UpdateSubscriptionServer(ctx context.Context, id uuid.UUID,
 			server_id = $2,
 			xui_client_id = $3,
 			xui_email = $4,
-			connection_key = $5
+			connection_key = $5,
+			traffic_counter = 0
 		WHERE id = $1`
 	_, err := r.db.ExecContext(ctx, query, id, serverID, xuiClientID, xuiEmail, connectionKey)
 	return err
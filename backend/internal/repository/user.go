@@ -119,3 +119,19 @@ func (r *Repository) UseFreeRegionSwitch(ctx context.Context, userID int64) (boo
 	}
 	return rows > 0, nil
 }
+
+// SetUserLanguage stores the language chosen by the user, nil = follow the Telegram client
+func (r *Repository) SetUserLanguage(ctx context.Context, userID int64, language *string) error {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE users SET language = $2, updated_at = NOW() WHERE id = $1",
+		userID, language,
+	)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+// Package secret encrypts credentials stored in the database (panel passwords)
+// with AES-256-GCM. Values are tagged with the ID of the key that encrypted
+// them, so keys can be rotated: old keys stay configured for decryption until
+// every value has been re-encrypted with the current key.
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// prefix marks encrypted values: enc:v1:<key id>:<base64 nonce+ciphertext>
+const prefix = "enc:v1:"
+
+// ErrUnknownKey is returned when a value was encrypted with a key that is not configured
+var ErrUnknownKey = errors.New("secret: value encrypted with an unknown key")
+
+type key struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// Box encrypts with the current key and decrypts with the current or any old key
+type Box struct {
+	current key
+	keys    map[string]key
+}
+
+// NewBox creates a box from base64-encoded 32-byte keys (e.g. `openssl rand -base64 32`).
+// oldKeys are only used to decrypt values written before a rotation.
+func NewBox(currentKey string, oldKeys ...string) (*Box, error) {
+	current, err := parseKey(currentKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid current key: %w", err)
+	}
+
+	b := &Box{current: current, keys: map[string]key{current.id: current}}
+	for _, raw := range oldKeys {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		old, err := parseKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid old key: %w", err)
+		}
+		b.keys[old.id] = old
+	}
+	return b, nil
+}
+
+func parseKey(encoded string) (key, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return key{}, err
+	}
+	if len(raw) != 32 {
+		return key{}, fmt.Errorf("key must be 32 bytes, got %d", len(raw))
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return key{}, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return key{}, err
+	}
+
+	sum := sha256.Sum256(raw)
+	return key{id: hex.EncodeToString(sum[:4]), aead: aead}, nil
+}
+
+// IsEncrypted reports whether a stored value is encrypted rather than legacy plaintext
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt encrypts a value with the current key. Empty values stay empty.
+func (b *Box) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	nonce := make([]byte, b.current.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.current.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return prefix + b.current.id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a stored value. Values without the encryption prefix are
+// legacy plaintext and returned as is.
+func (b *Box) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	keyID, payload, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !ok {
+		return "", errors.New("secret: malformed value")
+	}
+	k, ok := b.keys[keyID]
+	if !ok {
+		return "", ErrUnknownKey
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("secret: malformed value: %w", err)
+	}
+	nonceSize := k.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", errors.New("secret: malformed value")
+	}
+
+	plaintext, err := k.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("secret: decryption failed: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether a stored value is plaintext or encrypted with an old key
+func (b *Box) NeedsRotation(value string) bool {
+	if value == "" {
+		return false
+	}
+	return !strings.HasPrefix(value, prefix+b.current.id+":")
+}
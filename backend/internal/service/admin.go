@@ -3,22 +3,26 @@ package service
 import (
 	"context"
 	"crypto/rand"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/i18n"
 	"github.com/zyvpn/backend/internal/model"
 	"github.com/zyvpn/backend/internal/repository"
 )
 
 var (
-	ErrNotAdmin          = errors.New("Пользователь не является администратором")
-	ErrUserNotFound      = errors.New("Пользователь не найден")
-	ErrAlreadyBanned     = errors.New("Пользователь уже заблокирован")
-	ErrNotBanned         = errors.New("Пользователь не заблокирован")
-	ErrInsufficientPerms = errors.New("Недостаточно прав")
+	ErrNotAdmin          = i18n.NewError("not_admin")
+	ErrUserNotFound      = i18n.NewError("user_not_found")
+	ErrAlreadyBanned     = i18n.NewError("already_banned")
+	ErrNotBanned         = i18n.NewError("not_banned")
+	ErrInsufficientPerms = i18n.NewError("insufficient_permissions")
 )
 
 type AdminService struct {
@@ -26,10 +30,20 @@ type AdminService struct {
 	balanceSvc      *BalanceService
 	subscriptionSvc *SubscriptionService
 	promoCodeSvc    *PromoCodeService
+	notifier        AdminNotifier
+
+	bulkMu      sync.Mutex
+	bulkRunning map[uuid.UUID]bool // bulk extension jobs being processed
+	bulkCtx     context.Context    // stops bulk extension jobs on shutdown
 }
 
 func NewAdminService(repo *repository.Repository) *AdminService {
-	return &AdminService{repo: repo}
+	return &AdminService{repo: repo, bulkRunning: make(map[uuid.UUID]bool), bulkCtx: context.Background()}
+}
+
+// SetNotifier sets the notifier for messages to users about admin actions
+func (s *AdminService) SetNotifier(notifier AdminNotifier) {
+	s.notifier = notifier
 }
 
 // SetBalanceService sets the balance service (to avoid circular deps)
@@ -57,6 +71,20 @@ func (s *AdminService) GetAdmin(ctx context.Context, userID int64) (*model.Admin
 	return s.repo.GetAdmin(ctx, userID)
 }
 
+// ListAdminUserIDs returns Telegram user IDs of all admins, e.g. to send them alerts
+func (s *AdminService) ListAdminUserIDs(ctx context.Context) ([]int64, error) {
+	admins, err := s.repo.ListAdmins(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(admins))
+	for i, admin := range admins {
+		ids[i] = admin.UserID
+	}
+	return ids, nil
+}
+
 // --- User Management ---
 
 // ListUsers lists users with pagination
@@ -516,6 +544,8 @@ type UpdatePlanParams struct {
 	PriceUSD     *float64
 	IsActive     *bool
 	SortOrder    *int
+	InboundTags  *[]string
+	ServerGroups *[]string
 }
 
 // CreatePlanParams holds parameters for creating a plan
@@ -529,6 +559,8 @@ type CreatePlanParams struct {
 	PriceStars   int
 	PriceUSD     float64
 	SortOrder    int
+	InboundTags  []string
+	ServerGroups []string
 }
 
 // ListAllPlans lists all plans including inactive
@@ -542,7 +574,7 @@ func (s *AdminService) UpdatePlan(ctx context.Context, adminID int64, planID str
 		return nil, ErrNotAdmin
 	}
 
-	plan, err := s.repo.UpdatePlan(ctx, planID, params.Name, params.Description, params.DurationDays, params.TrafficGB, params.MaxDevices, params.PriceTON, params.PriceStars, params.PriceUSD, params.IsActive, params.SortOrder)
+	plan, err := s.repo.UpdatePlan(ctx, planID, params.Name, params.Description, params.DurationDays, params.TrafficGB, params.MaxDevices, params.PriceTON, params.PriceStars, params.PriceUSD, params.IsActive, params.SortOrder, params.InboundTags, params.ServerGroups)
 	if err != nil {
 		return nil, err
 	}
@@ -562,7 +594,7 @@ func (s *AdminService) CreatePlan(ctx context.Context, adminID int64, params Cre
 		return nil, ErrNotAdmin
 	}
 
-	plan, err := s.repo.CreatePlan(ctx, params.Name, params.Description, params.DurationDays, params.TrafficGB, params.MaxDevices, params.PriceTON, params.PriceStars, params.PriceUSD, params.SortOrder)
+	plan, err := s.repo.CreatePlan(ctx, params.Name, params.Description, params.DurationDays, params.TrafficGB, params.MaxDevices, params.PriceTON, params.PriceStars, params.PriceUSD, params.SortOrder, params.InboundTags, params.ServerGroups)
 	if err != nil {
 		return nil, err
 	}
@@ -583,7 +615,7 @@ func (s *AdminService) DeletePlan(ctx context.Context, adminID int64, planID str
 	}
 
 	isActive := false
-	if _, err := s.repo.UpdatePlan(ctx, planID, nil, nil, nil, nil, nil, nil, nil, nil, &isActive, nil); err != nil {
+	if _, err := s.repo.UpdatePlan(ctx, planID, nil, nil, nil, nil, nil, nil, nil, nil, &isActive, nil, nil, nil); err != nil {
 		return err
 	}
 
@@ -692,3 +724,27 @@ func (s *AdminService) SetRegionSwitchPrice(ctx context.Context, adminID int64,
 	}
 	return s.repo.SetSetting(ctx, "region_switch_price", fmt.Sprintf("%.4f", price))
 }
+
+// GetServerSelection returns the strategy and weights used to pick servers for new subscriptions
+func (s *AdminService) GetServerSelection(ctx context.Context) *model.ServerSelectionConfig {
+	return loadSelectionConfig(ctx, s.repo)
+}
+
+// SetServerSelection validates and stores the server selection settings
+func (s *AdminService) SetServerSelection(ctx context.Context, adminID int64, data []byte) (*model.ServerSelectionConfig, error) {
+	if ok, _ := s.IsAdmin(ctx, adminID); !ok {
+		return nil, ErrNotAdmin
+	}
+	cfg, err := ParseSelectionConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	value, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetSetting(ctx, serverSelectionSetting, string(value)); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/config"
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/repository"
+)
+
+// adminAlertsLimit is the number of alerts returned to admins
+const adminAlertsLimit = 100
+
+var ErrAlertNotFound = repository.ErrAlertNotFound
+
+// AlertNotifier sends alerts to the admin chat (implemented by telegram.Bot)
+type AlertNotifier interface {
+	SendAlert(chatID int64, alert *model.Alert) error
+}
+
+// AlertService records alerts raised by background workers and sends them to the
+// admin chat. Alerts are deduplicated by key: a firing alert is sent once, then
+// re-sent every AlertRepeatInterval until it is acknowledged or resolved.
+// Muted alerts are only recorded. All messages share a global rate limit.
+type AlertService struct {
+	repo     *repository.Repository
+	chatID   int64
+	notifier AlertNotifier
+
+	mu   sync.Mutex
+	sent []time.Time // send times within the current rate window
+}
+
+func NewAlertService(repo *repository.Repository, chatID int64) *AlertService {
+	return &AlertService{repo: repo, chatID: chatID}
+}
+
+// SetNotifier sets the notifier for sending alerts
+func (s *AlertService) SetNotifier(notifier AlertNotifier) {
+	s.notifier = notifier
+}
+
+// alertKey builds the deduplication key of an alert about a subject
+func alertKey(kind, subject string) string {
+	if subject == "" {
+		return kind
+	}
+	return kind + ":" + subject
+}
+
+// Fire records that the condition of an alert holds and notifies admins if due.
+// subject distinguishes alerts of the same kind, e.g. a server ID.
+func (s *AlertService) Fire(ctx context.Context, kind, subject, severity, message string) {
+	alert, err := s.repo.FireAlert(ctx, alertKey(kind, subject), kind, severity, message)
+	if err != nil {
+		log.Printf("[Alerts] Failed to record alert %s: %v", alertKey(kind, subject), err)
+		return
+	}
+
+	now := time.Now()
+	if alert.IsMuted(now) {
+		return
+	}
+	if alert.LastNotifiedAt != nil && (alert.IsAcked() || now.Sub(*alert.LastNotifiedAt) < config.AlertRepeatInterval) {
+		return
+	}
+	if s.send(alert) {
+		if err := s.repo.MarkAlertNotified(ctx, alert.ID); err != nil {
+			log.Printf("[Alerts] Failed to mark alert %s notified: %v", alert.Key, err)
+		}
+	}
+}
+
+// Resolve marks an alert as resolved and sends a recovery message if admins were notified of it
+func (s *AlertService) Resolve(ctx context.Context, kind, subject string) {
+	alert, err := s.repo.ResolveAlert(ctx, alertKey(kind, subject))
+	if err != nil {
+		log.Printf("[Alerts] Failed to resolve alert %s: %v", alertKey(kind, subject), err)
+		return
+	}
+	if alert == nil || alert.LastNotifiedAt == nil || alert.IsMuted(time.Now()) {
+		return
+	}
+	s.send(alert)
+}
+
+// send delivers an alert to the admin chat unless the rate limit is exhausted
+func (s *AlertService) send(alert *model.Alert) bool {
+	if s.notifier == nil || s.chatID == 0 {
+		log.Printf("[Alerts] %s %s: %s", alert.Status, alert.Key, alert.Message)
+		return false
+	}
+	if !s.allow() {
+		log.Printf("[Alerts] Rate limited, not sending %s %s: %s", alert.Status, alert.Key, alert.Message)
+		return false
+	}
+	if err := s.notifier.SendAlert(s.chatID, alert); err != nil {
+		log.Printf("[Alerts] Failed to send alert %s: %v", alert.Key, err)
+		return false
+	}
+	return true
+}
+
+// allow reserves a message in the rate window
+func (s *AlertService) allow() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-config.AlertRateWindow)
+	i := 0
+	for i < len(s.sent) && s.sent[i].Before(cutoff) {
+		i++
+	}
+	s.sent = s.sent[i:]
+
+	if len(s.sent) >= config.AlertRateLimit {
+		return false
+	}
+	s.sent = append(s.sent, time.Now())
+	return true
+}
+
+// GetAlerts returns alerts with the given status (firing or resolved), all when empty
+func (s *AlertService) GetAlerts(ctx context.Context, status string) ([]model.Alert, error) {
+	return s.repo.GetAlerts(ctx, status, adminAlertsLimit)
+}
+
+// Acknowledge stops reminders for the current firing of an alert
+func (s *AlertService) Acknowledge(ctx context.Context, adminID int64, id uuid.UUID) (*model.Alert, error) {
+	if ok, _ := s.repo.IsAdmin(ctx, adminID); !ok {
+		return nil, ErrNotAdmin
+	}
+
+	alert, err := s.repo.AckAlert(ctx, id, adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = s.repo.LogAdminAction(ctx, adminID, model.AdminActionAckAlert, nil, map[string]interface{}{
+		"alert_id": alert.ID,
+		"key":      alert.Key,
+	})
+
+	return alert, nil
+}
+
+// Mute suppresses notifications for an alert for the given duration; zero unmutes
+func (s *AlertService) Mute(ctx context.Context, adminID int64, id uuid.UUID, d time.Duration) (*model.Alert, error) {
+	if ok, _ := s.repo.IsAdmin(ctx, adminID); !ok {
+		return nil, ErrNotAdmin
+	}
+	if d < 0 {
+		return nil, fmt.Errorf("invalid mute duration %v", d)
+	}
+
+	var until *time.Time
+	if d > 0 {
+		t := time.Now().Add(d)
+		until = &t
+	}
+	alert, err := s.repo.MuteAlert(ctx, id, until)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = s.repo.LogAdminAction(ctx, adminID, model.AdminActionMuteAlert, nil, map[string]interface{}{
+		"alert_id": alert.ID,
+		"key":      alert.Key,
+		"until":    until,
+	})
+
+	return alert, nil
+}
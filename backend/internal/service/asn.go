@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	asnLookupTimeout = 2 * time.Second
+	asnCacheTTL      = 24 * time.Hour
+	asnCacheSize     = 10000
+)
+
+// asnResolver maps client IPs to the autonomous system (network) they belong to
+// through the Team Cymru IP to ASN DNS service. Results, failures included, are
+// cached so a user reporting latencies does not cost a lookup every time.
+type asnResolver struct {
+	resolver *net.Resolver
+	mu       sync.Mutex
+	cache    map[string]asnCacheEntry
+}
+
+type asnCacheEntry struct {
+	asn     int64
+	expires time.Time
+}
+
+func newASNResolver() *asnResolver {
+	return &asnResolver{
+		resolver: net.DefaultResolver,
+		cache:    make(map[string]asnCacheEntry),
+	}
+}
+
+// Lookup returns the ASN announcing the IP, or 0 when it is unknown or the IP
+// is not public
+func (r *asnResolver) Lookup(ctx context.Context, ip string) int64 {
+	addr := net.ParseIP(ip)
+	if addr == nil || addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsUnspecified() {
+		return 0
+	}
+	key := addr.String()
+
+	r.mu.Lock()
+	entry, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.asn
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, asnLookupTimeout)
+	defer cancel()
+
+	var asn int64
+	records, err := r.resolver.LookupTXT(ctx, asnQueryName(addr))
+	if err == nil && len(records) > 0 {
+		asn = parseASNRecord(records[0])
+	}
+
+	r.mu.Lock()
+	if len(r.cache) >= asnCacheSize {
+		r.evictExpired()
+	}
+	if len(r.cache) < asnCacheSize {
+		r.cache[key] = asnCacheEntry{asn: asn, expires: time.Now().Add(asnCacheTTL)}
+	}
+	r.mu.Unlock()
+
+	return asn
+}
+
+// evictExpired drops expired cache entries; the caller holds r.mu
+func (r *asnResolver) evictExpired() {
+	now := time.Now()
+	for key, entry := range r.cache {
+		if now.After(entry.expires) {
+			delete(r.cache, key)
+		}
+	}
+}
+
+// asnQueryName returns the reversed lookup name, e.g. 4.3.2.1.origin.asn.cymru.com
+// for 1.2.3.4 and the reversed nibbles under origin6.asn.cymru.com for IPv6
+func asnQueryName(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.origin.asn.cymru.com", v4[3], v4[2], v4[1], v4[0])
+	}
+
+	const hex = "0123456789abcdef"
+	v6 := ip.To16()
+	var b strings.Builder
+	for i := len(v6) - 1; i >= 0; i-- {
+		b.WriteByte(hex[v6[i]&0x0f])
+		b.WriteByte('.')
+		b.WriteByte(hex[v6[i]>>4])
+		b.WriteByte('.')
+	}
+	b.WriteString("origin6.asn.cymru.com")
+	return b.String()
+}
+
+// parseASNRecord reads the first ASN of a record like "13335 | 1.1.1.0/24 | US | arin | 2010-07-14"
+func parseASNRecord(record string) int64 {
+	fields := strings.Fields(strings.SplitN(record, "|", 2)[0])
+	if len(fields) == 0 {
+		return 0
+	}
+	asn, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return asn
+}
@@ -2,15 +2,15 @@ package service
 
 import (
 	"context"
-	"errors"
-	"fmt"
+	"strconv"
 
 	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/i18n"
 	"github.com/zyvpn/backend/internal/model"
 	"github.com/zyvpn/backend/internal/repository"
 )
 
-var ErrInsufficientBalance = errors.New("Недостаточно средств на балансе")
+var ErrInsufficientBalance = i18n.NewError("insufficient_balance")
 
 type BalanceService struct {
 	repo *repository.Repository
@@ -27,30 +27,30 @@ func (s *BalanceService) GetBalance(ctx context.Context, userID int64) (float64,
 
 // CreditReferralBonus adds referral bonus to user balance
 func (s *BalanceService) CreditReferralBonus(ctx context.Context, userID int64, amount float64, referralID uuid.UUID) (float64, error) {
-	description := fmt.Sprintf("Реферальный бонус: +%.4f TON", amount)
-	return s.repo.UpdateBalance(ctx, userID, amount, model.TransactionTypeReferralBonus, description, &referralID)
+	return s.repo.UpdateBalance(ctx, userID, amount, model.TransactionTypeReferralBonus,
+		"", "tx_desc_referral_bonus", []string{formatTON(amount)}, &referralID)
 }
 
 // CreditGiveaway adds giveaway prize to user balance
 func (s *BalanceService) CreditGiveaway(ctx context.Context, userID int64, amount float64, description string) (float64, error) {
-	return s.repo.UpdateBalance(ctx, userID, amount, model.TransactionTypeGiveaway, description, nil)
+	return s.repo.UpdateBalance(ctx, userID, amount, model.TransactionTypeGiveaway, description, "", nil, nil)
 }
 
 // DebitForSubscription deducts amount for subscription payment
 func (s *BalanceService) DebitForSubscription(ctx context.Context, userID int64, amount float64, paymentID uuid.UUID) (float64, error) {
-	description := fmt.Sprintf("Оплата подписки: -%.4f TON", amount)
-	return s.repo.UpdateBalance(ctx, userID, -amount, model.TransactionTypeSubscriptionPayment, description, &paymentID)
+	return s.repo.UpdateBalance(ctx, userID, -amount, model.TransactionTypeSubscriptionPayment,
+		"", "tx_desc_subscription_payment", []string{formatTON(amount)}, &paymentID)
 }
 
 // CreditRefund adds refund to user balance
 func (s *BalanceService) CreditRefund(ctx context.Context, userID int64, amount float64, paymentID uuid.UUID) (float64, error) {
-	description := fmt.Sprintf("Возврат средств: +%.4f TON", amount)
-	return s.repo.UpdateBalance(ctx, userID, amount, model.TransactionTypeRefund, description, &paymentID)
+	return s.repo.UpdateBalance(ctx, userID, amount, model.TransactionTypeRefund,
+		"", "tx_desc_refund", []string{formatTON(amount)}, &paymentID)
 }
 
 // CreditManual adds manual adjustment (admin operation)
 func (s *BalanceService) CreditManual(ctx context.Context, userID int64, amount float64, description string) (float64, error) {
-	return s.repo.UpdateBalance(ctx, userID, amount, model.TransactionTypeManual, description, nil)
+	return s.repo.UpdateBalance(ctx, userID, amount, model.TransactionTypeManual, description, "", nil, nil)
 }
 
 // CreditTopUp adds balance from top-up with optional bonus
@@ -65,31 +65,46 @@ func (s *BalanceService) CreditTopUp(ctx context.Context, userID int64, amount f
 	bonusAmount := amount * bonusPercent / 100
 	totalAmount := amount + bonusAmount
 
-	var description string
+	key, args := "tx_desc_top_up", []string{formatTON(amount)}
 	if bonusAmount > 0 {
-		description = fmt.Sprintf("Пополнение баланса: +%.4f TON (+%.1f%% бонус = %.4f)", amount, bonusPercent, totalAmount)
-	} else {
-		description = fmt.Sprintf("Пополнение баланса: +%.4f TON", amount)
+		key = "tx_desc_top_up_bonus"
+		args = append(args, strconv.FormatFloat(bonusPercent, 'f', 1, 64), formatTON(totalAmount))
 	}
 
-	return s.repo.UpdateBalance(ctx, userID, totalAmount, model.TransactionTypeTopUp, description, &paymentID)
+	return s.repo.UpdateBalance(ctx, userID, totalAmount, model.TransactionTypeTopUp, "", key, args, &paymentID)
 }
 
 // CreditPromoCode adds balance from promo code
 func (s *BalanceService) CreditPromoCode(ctx context.Context, userID int64, amount float64, promoCodeID uuid.UUID, code string) (float64, error) {
-	description := fmt.Sprintf("Промокод %s: +%.4f TON", code, amount)
-	return s.repo.UpdateBalance(ctx, userID, amount, model.TransactionTypePromoCode, description, &promoCodeID)
+	return s.repo.UpdateBalance(ctx, userID, amount, model.TransactionTypePromoCode,
+		"", "tx_desc_promo_code", []string{code, formatTON(amount)}, &promoCodeID)
 }
 
-// GetTransactions returns balance transaction history
-func (s *BalanceService) GetTransactions(ctx context.Context, userID int64, limit, offset int) ([]model.BalanceTransaction, error) {
+// GetTransactions returns balance transaction history with descriptions in lang
+func (s *BalanceService) GetTransactions(ctx context.Context, userID int64, lang string, limit, offset int) ([]model.BalanceTransaction, error) {
 	if limit <= 0 {
 		limit = 20
 	}
 	if limit > 100 {
 		limit = 100
 	}
-	return s.repo.GetBalanceTransactions(ctx, userID, limit, offset)
+	transactions, err := s.repo.GetBalanceTransactions(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	for i := range transactions {
+		tx := &transactions[i]
+		if tx.DescriptionKey == nil {
+			continue
+		}
+		args := make([]any, len(tx.DescriptionArgs))
+		for j, arg := range tx.DescriptionArgs {
+			args[j] = arg
+		}
+		description := i18n.T(lang, *tx.DescriptionKey, args...)
+		tx.Description = &description
+	}
+	return transactions, nil
 }
 
 // CanAfford checks if user has enough balance
@@ -112,6 +127,11 @@ func (s *BalanceService) ChargeRegionSwitch(ctx context.Context, userID int64, a
 		return 0, ErrInsufficientBalance
 	}
 
-	description := fmt.Sprintf("Смена региона: -%.4f TON", amount)
-	return s.repo.UpdateBalance(ctx, userID, -amount, model.TransactionTypeRegionSwitch, description, nil)
+	return s.repo.UpdateBalance(ctx, userID, -amount, model.TransactionTypeRegionSwitch,
+		"", "tx_desc_region_switch", []string{formatTON(amount)}, nil)
+}
+
+// formatTON formats a TON amount for a transaction description
+func formatTON(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 4, 64)
 }
@@ -0,0 +1,343 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/repository"
+)
+
+const (
+	// bulkExtendMaxDays is the longest extension a bulk job may give
+	bulkExtendMaxDays = 365
+	// bulkExtendMaxAttempts is how many times a job tries each subscription before
+	// leaving it failed for a manual retry
+	bulkExtendMaxAttempts = 3
+	// bulkExtendRetryDelay is the pause before failed subscriptions are tried again,
+	// giving an unavailable panel time to recover
+	bulkExtendRetryDelay = 30 * time.Second
+	// bulkExtendProgressEvery is how often job counters are updated, in subscriptions
+	bulkExtendProgressEvery = 20
+	// bulkExtendJobsLimit is the number of jobs returned to admins
+	bulkExtendJobsLimit = 50
+)
+
+var (
+	ErrBulkJobNotFound      = repository.ErrBulkJobNotFound
+	ErrBulkJobRunning       = errors.New("bulk extension job is still running")
+	ErrInvalidBulkExtension = errors.New("invalid bulk extension")
+)
+
+// AdminNotifier sends users messages about admin actions (implemented by telegram.Bot)
+type AdminNotifier interface {
+	SendSubscriptionExtended(chatID int64, days int, expiresAt string) error
+}
+
+// StartBulkExtension extends all active subscriptions matching the filter by the
+// given number of days. The subscriptions are fixed when the job is created and
+// extended in the background; progress is read with GetBulkExtension.
+func (s *AdminService) StartBulkExtension(ctx context.Context, adminID int64, filter model.BulkExtensionFilter, days int, notify bool) (*model.BulkExtensionJob, error) {
+	if ok, _ := s.IsAdmin(ctx, adminID); !ok {
+		return nil, ErrNotAdmin
+	}
+
+	if s.subscriptionSvc == nil {
+		return nil, errors.New("subscription service not configured")
+	}
+
+	if days <= 0 || days > bulkExtendMaxDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidBulkExtension, bulkExtendMaxDays)
+	}
+	if filter.IsEmpty() {
+		return nil, fmt.Errorf("%w: set server_id, plan_id or created_before", ErrInvalidBulkExtension)
+	}
+
+	job := &model.BulkExtensionJob{
+		AdminID:       adminID,
+		Days:          days,
+		ServerID:      filter.ServerID,
+		PlanID:        filter.PlanID,
+		CreatedBefore: filter.CreatedBefore,
+		Notify:        notify,
+		Status:        model.BulkJobRunning,
+	}
+	if err := s.repo.CreateBulkExtensionJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	s.runBulkExtension(job, false)
+	return job, nil
+}
+
+// RetryBulkExtension extends the subscriptions a completed job failed to extend,
+// or continues a job that stopped on an error
+func (s *AdminService) RetryBulkExtension(ctx context.Context, adminID int64, jobID uuid.UUID) (*model.BulkExtensionJob, error) {
+	if ok, _ := s.IsAdmin(ctx, adminID); !ok {
+		return nil, ErrNotAdmin
+	}
+
+	if s.subscriptionSvc == nil {
+		return nil, errors.New("subscription service not configured")
+	}
+
+	job, err := s.repo.GetBulkExtensionJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == model.BulkJobRunning {
+		return nil, ErrBulkJobRunning
+	}
+
+	requeued, err := s.repo.RequeueBulkExtensionItems(ctx, jobID, 0)
+	if err != nil {
+		return nil, err
+	}
+	if requeued == 0 && job.Status == model.BulkJobCompleted {
+		return job, nil
+	}
+
+	if err := s.repo.SetBulkExtensionJobStatus(ctx, jobID, model.BulkJobRunning); err != nil {
+		return nil, err
+	}
+	job.Status = model.BulkJobRunning
+	job.FinishedAt = nil
+
+	s.runBulkExtension(job, true)
+	return job, nil
+}
+
+// GetBulkExtension returns a job's progress and the subscriptions that failed
+func (s *AdminService) GetBulkExtension(ctx context.Context, jobID uuid.UUID) (*model.BulkExtensionReport, error) {
+	job, err := s.repo.GetBulkExtensionJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.repo.CountBulkExtensionItems(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	// Counters on the job are updated in batches, the item counts are current
+	job.Succeeded = counts[model.BulkItemDone]
+	job.Failed = counts[model.BulkItemFailed]
+
+	failed, err := s.repo.GetBulkExtensionItems(ctx, jobID, model.BulkItemFailed)
+	if err != nil {
+		return nil, err
+	}
+	if failed == nil {
+		failed = []model.BulkExtensionItem{}
+	}
+
+	return &model.BulkExtensionReport{
+		BulkExtensionJob: job,
+		Pending:          counts[model.BulkItemPending],
+		Skipped:          counts[model.BulkItemSkipped],
+		FailedItems:      failed,
+	}, nil
+}
+
+// ListBulkExtensions returns recent bulk extension jobs
+func (s *AdminService) ListBulkExtensions(ctx context.Context) ([]model.BulkExtensionJob, error) {
+	return s.repo.GetBulkExtensionJobs(ctx, bulkExtendJobsLimit)
+}
+
+// ResumeBulkExtensions continues jobs interrupted by a restart. Jobs run until
+// ctx is done, including ones started later.
+func (s *AdminService) ResumeBulkExtensions(ctx context.Context) error {
+	s.bulkMu.Lock()
+	s.bulkCtx = ctx
+	s.bulkMu.Unlock()
+
+	jobs, err := s.repo.GetRunningBulkExtensionJobs(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range jobs {
+		log.Printf("[Bulk Extend] Resuming job %s", jobs[i].ID)
+		s.runBulkExtension(&jobs[i], false)
+	}
+	return nil
+}
+
+// runBulkExtension processes the job's pending subscriptions in the background
+// unless it is already being processed
+func (s *AdminService) runBulkExtension(job *model.BulkExtensionJob, retry bool) {
+	s.bulkMu.Lock()
+	if s.bulkRunning[job.ID] {
+		s.bulkMu.Unlock()
+		return
+	}
+	s.bulkRunning[job.ID] = true
+	ctx := s.bulkCtx
+	s.bulkMu.Unlock()
+
+	go func() {
+		defer func() {
+			s.bulkMu.Lock()
+			delete(s.bulkRunning, job.ID)
+			s.bulkMu.Unlock()
+		}()
+		s.processBulkExtension(ctx, job, retry)
+	}()
+}
+
+// processBulkExtension extends pending subscriptions, retries failed ones up to
+// bulkExtendMaxAttempts times and logs the run as one admin action. Items left
+// processing by an interrupted run are attempted again; their recorded expiry
+// keeps them from being extended twice.
+func (s *AdminService) processBulkExtension(ctx context.Context, job *model.BulkExtensionJob, retry bool) {
+	var extended, skipped []uuid.UUID
+
+	if err := s.repo.ReleaseBulkExtensionItems(ctx, job.ID); err != nil {
+		s.failBulkExtension(ctx, job, fmt.Errorf("failed to release interrupted subscriptions: %w", err))
+		return
+	}
+
+	for {
+		items, err := s.repo.GetBulkExtensionItems(ctx, job.ID, model.BulkItemPending)
+		if err != nil {
+			s.failBulkExtension(ctx, job, fmt.Errorf("failed to get subscriptions: %w", err))
+			return
+		}
+		if len(items) > 0 {
+			log.Printf("[Bulk Extend] Job %s: extending %d subscriptions by %d days", job.ID, len(items), job.Days)
+		}
+
+		for i := range items {
+			item := &items[i]
+			s.extendBulkItem(ctx, job, item)
+			if err := s.repo.UpdateBulkExtensionItem(ctx, item); err != nil {
+				log.Printf("[Bulk Extend] Job %s: failed to save result for subscription %s: %v", job.ID, item.SubscriptionID, err)
+			}
+
+			switch item.Status {
+			case model.BulkItemDone:
+				extended = append(extended, item.SubscriptionID)
+			case model.BulkItemSkipped:
+				skipped = append(skipped, item.SubscriptionID)
+			}
+
+			if (i+1)%bulkExtendProgressEvery == 0 {
+				_ = s.repo.UpdateBulkExtensionJobProgress(ctx, job.ID)
+			}
+		}
+		_ = s.repo.UpdateBulkExtensionJobProgress(ctx, job.ID)
+
+		requeued, err := s.repo.RequeueBulkExtensionItems(ctx, job.ID, bulkExtendMaxAttempts)
+		if err != nil {
+			s.failBulkExtension(ctx, job, fmt.Errorf("failed to requeue failed subscriptions: %w", err))
+			return
+		}
+		if requeued == 0 {
+			break
+		}
+		log.Printf("[Bulk Extend] Job %s: retrying %d failed subscriptions in %s", job.ID, requeued, bulkExtendRetryDelay)
+		select {
+		case <-ctx.Done():
+			// The job stays running and is resumed on the next start
+			log.Printf("[Bulk Extend] Job %s: stopped", job.ID)
+			return
+		case <-time.After(bulkExtendRetryDelay):
+		}
+	}
+
+	if err := s.repo.SetBulkExtensionJobStatus(ctx, job.ID, model.BulkJobCompleted); err != nil {
+		log.Printf("[Bulk Extend] Job %s: failed to complete: %v", job.ID, err)
+		return
+	}
+
+	failed, err := s.repo.GetBulkExtensionItems(ctx, job.ID, model.BulkItemFailed)
+	if err != nil {
+		log.Printf("[Bulk Extend] Job %s: failed to get failed subscriptions: %v", job.ID, err)
+	}
+	failedIDs := make([]uuid.UUID, len(failed))
+	for i, item := range failed {
+		failedIDs[i] = item.SubscriptionID
+	}
+
+	log.Printf("[Bulk Extend] Job %s completed: %d extended, %d skipped, %d failed", job.ID, len(extended), len(skipped), len(failedIDs))
+
+	_ = s.repo.LogAdminAction(ctx, job.AdminID, model.AdminActionBulkExtend, nil, map[string]interface{}{
+		"job_id":                   job.ID,
+		"days":                     job.Days,
+		"filter":                   job.Filter(),
+		"retry":                    retry,
+		"subscription_ids":         extended,
+		"skipped_subscription_ids": skipped,
+		"failed_subscription_ids":  failedIDs,
+	})
+}
+
+// failBulkExtension stops a job on an error; RetryBulkExtension continues it
+func (s *AdminService) failBulkExtension(ctx context.Context, job *model.BulkExtensionJob, err error) {
+	log.Printf("[Bulk Extend] Job %s failed: %v", job.ID, err)
+	if err := s.repo.UpdateBulkExtensionJobProgress(ctx, job.ID); err != nil {
+		log.Printf("[Bulk Extend] Job %s: failed to update progress: %v", job.ID, err)
+	}
+	if err := s.repo.SetBulkExtensionJobStatus(ctx, job.ID, model.BulkJobFailed); err != nil {
+		log.Printf("[Bulk Extend] Job %s: failed to mark failed: %v", job.ID, err)
+	}
+}
+
+// extendBulkItem extends one subscription of the job and sets the item's outcome.
+// The item is claimed with the subscription's expiry first and the subscription is
+// extended to that expiry plus the job's days, so attempting it again is harmless.
+// Subscriptions that expired or were cancelled since the job was created are skipped.
+func (s *AdminService) extendBulkItem(ctx context.Context, job *model.BulkExtensionJob, item *model.BulkExtensionItem) {
+	item.Attempts++
+
+	err := s.claimBulkItem(ctx, item)
+	if err == nil {
+		expiresAt := item.ExpiresFrom.Add(time.Duration(job.Days) * 24 * time.Hour)
+		err = s.subscriptionSvc.ExtendSubscriptionTo(ctx, item.SubscriptionID, expiresAt)
+	}
+	switch {
+	case err == nil:
+		item.Status = model.BulkItemDone
+		item.Error = nil
+	case errors.Is(err, ErrSubscriptionNotActive):
+		item.Status = model.BulkItemSkipped
+		msg := err.Error()
+		item.Error = &msg
+		return
+	default:
+		item.Status = model.BulkItemFailed
+		msg := err.Error()
+		item.Error = &msg
+		return
+	}
+
+	if !job.Notify || s.notifier == nil {
+		return
+	}
+	sub, err := s.repo.GetSubscription(ctx, item.SubscriptionID)
+	if err != nil || sub.ExpiresAt == nil {
+		return
+	}
+	if err := s.notifier.SendSubscriptionExtended(item.UserID, job.Days, sub.ExpiresAt.Format("02.01.2006")); err != nil {
+		log.Printf("[Bulk Extend] Job %s: failed to notify user %d: %v", job.ID, item.UserID, err)
+	}
+}
+
+// claimBulkItem marks an item as processing. The first claim records the expiry
+// its subscription is extended from.
+func (s *AdminService) claimBulkItem(ctx context.Context, item *model.BulkExtensionItem) error {
+	var expiresFrom *time.Time
+	if item.ExpiresFrom == nil {
+		sub, err := s.repo.GetSubscription(ctx, item.SubscriptionID)
+		if err != nil {
+			return err
+		}
+		if sub.Status != model.SubscriptionStatusActive || sub.ExpiresAt == nil {
+			return ErrSubscriptionNotActive
+		}
+		expiresFrom = sub.ExpiresAt
+	}
+	return s.repo.ClaimBulkExtensionItem(ctx, item, expiresFrom)
+}
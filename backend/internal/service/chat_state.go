@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/zyvpn/backend/internal/config"
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/repository"
+)
+
+// ChatStateService keeps the purchases in progress in bot chats in the database,
+// so a purchase started on one replica continues on any other
+type ChatStateService struct {
+	repo *repository.Repository
+
+	mu        sync.Mutex
+	cleanedAt time.Time
+}
+
+func NewChatStateService(repo *repository.Repository) *ChatStateService {
+	return &ChatStateService{repo: repo}
+}
+
+// Get returns the purchase in progress in a chat, or nil without one or when it
+// was abandoned longer than BotChatStateTTL ago
+func (s *ChatStateService) Get(ctx context.Context, chatID int64) (*model.BotChatState, error) {
+	return s.repo.GetBotChatState(ctx, chatID, time.Now().Add(-config.BotChatStateTTL))
+}
+
+// Save stores the purchase in progress in a chat
+func (s *ChatStateService) Save(ctx context.Context, state *model.BotChatState) error {
+	if err := s.repo.SaveBotChatState(ctx, state); err != nil {
+		return err
+	}
+	s.cleanup(ctx)
+	return nil
+}
+
+// Delete ends the purchase in progress in a chat
+func (s *ChatStateService) Delete(ctx context.Context, chatID int64) error {
+	return s.repo.DeleteBotChatState(ctx, chatID)
+}
+
+// cleanup removes purchases abandoned in any chat, at most once per BotChatStateTTL
+func (s *ChatStateService) cleanup(ctx context.Context) {
+	s.mu.Lock()
+	if time.Since(s.cleanedAt) < config.BotChatStateTTL {
+		s.mu.Unlock()
+		return
+	}
+	s.cleanedAt = time.Now()
+	s.mu.Unlock()
+
+	if _, err := s.repo.DeleteBotChatStatesBefore(ctx, time.Now().Add(-config.BotChatStateTTL)); err != nil {
+		log.Printf("WARNING: Failed to delete abandoned bot chat states: %v", err)
+	}
+}
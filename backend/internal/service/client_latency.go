@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/config"
+	"github.com/zyvpn/backend/internal/i18n"
+	"github.com/zyvpn/backend/internal/model"
+)
+
+var (
+	ErrInvalidLatencyReport = errors.New("invalid latency report")
+	ErrLatencyReportTooSoon = i18n.NewError("too_many_requests")
+)
+
+// ReportLatency records latencies the mini app measured from the user's device.
+// They are averaged per user and per network of the client IP, and used to pick
+// servers for the user and for other users of the same network.
+func (s *ServerService) ReportLatency(ctx context.Context, userID int64, clientIP string, reports []model.LatencyReport) error {
+	if len(reports) == 0 || len(reports) > config.ClientLatencyMaxReports {
+		return fmt.Errorf("%w: between 1 and %d servers must be reported", ErrInvalidLatencyReport, config.ClientLatencyMaxReports)
+	}
+	for _, report := range reports {
+		if report.LatencyMs != nil && (*report.LatencyMs <= 0 || *report.LatencyMs > config.ClientLatencyTimeoutMs) {
+			return fmt.Errorf("%w: latency must be between 1 and %d ms", ErrInvalidLatencyReport, config.ClientLatencyTimeoutMs)
+		}
+	}
+	if !s.allowLatencyReport(userID) {
+		return ErrLatencyReportTooSoon
+	}
+
+	servers, err := s.repo.GetActiveServers(ctx)
+	if err != nil {
+		return err
+	}
+	active := make(map[uuid.UUID]bool, len(servers))
+	for _, server := range servers {
+		active[server.ID] = true
+	}
+
+	// Servers removed since the mini app loaded the list are ignored
+	latencies := make(map[uuid.UUID]float64, len(reports))
+	for _, report := range reports {
+		if !active[report.ServerID] {
+			continue
+		}
+		latency := config.ClientLatencyTimeoutMs
+		if report.LatencyMs != nil {
+			latency = *report.LatencyMs
+		}
+		latencies[report.ServerID] = float64(latency)
+	}
+	if len(latencies) == 0 {
+		return nil
+	}
+
+	asn := s.asn.Lookup(ctx, clientIP)
+	staleBefore := time.Now().Add(-config.ClientLatencyMaxAge)
+	return s.repo.RecordClientLatency(ctx, userID, asn, latencies, config.ClientLatencySmoothing, staleBefore)
+}
+
+// allowLatencyReport limits how often a user's reports are recorded
+func (s *ServerService) allowLatencyReport(userID int64) bool {
+	s.latencyMu.Lock()
+	defer s.latencyMu.Unlock()
+
+	now := time.Now()
+	if last, ok := s.latencyReportedAt[userID]; ok && now.Sub(last) < config.ClientLatencyReportInterval {
+		return false
+	}
+	if len(s.latencyReportedAt) >= 10000 {
+		for id, at := range s.latencyReportedAt {
+			if now.Sub(at) >= config.ClientLatencyReportInterval {
+				delete(s.latencyReportedAt, id)
+			}
+		}
+	}
+	s.latencyReportedAt[userID] = now
+	return true
+}
+
+// clientLatencies returns the latency the user can expect to each server: the
+// user's own measurements, or for servers without them the measurements of
+// other users of the network the user last reported from
+func (s *ServerService) clientLatencies(ctx context.Context, userID int64) map[uuid.UUID]model.LatencyEstimate {
+	since := time.Now().Add(-config.ClientLatencyMaxAge)
+	estimates := make(map[uuid.UUID]model.LatencyEstimate)
+
+	asn, err := s.repo.GetUserASN(ctx, userID)
+	if err != nil {
+		log.Printf("WARNING: Failed to get network of user %d: %v", userID, err)
+	}
+	if asn != 0 {
+		network, err := s.repo.GetNetworkLatencies(ctx, asn, since, config.ClientLatencyMinNetworkUsers)
+		if err != nil {
+			log.Printf("WARNING: Failed to get latencies of network AS%d: %v", asn, err)
+		}
+		for _, l := range network {
+			estimates[l.ServerID] = model.LatencyEstimate{LatencyMs: int(math.Round(l.LatencyMs)), Source: model.LatencySourceNetwork}
+		}
+	}
+
+	own, err := s.repo.GetUserLatencies(ctx, userID, since)
+	if err != nil {
+		log.Printf("WARNING: Failed to get latencies of user %d: %v", userID, err)
+	}
+	for _, l := range own {
+		estimates[l.ServerID] = model.LatencyEstimate{LatencyMs: int(math.Round(l.LatencyMs)), Source: model.LatencySourceUser}
+	}
+
+	return estimates
+}
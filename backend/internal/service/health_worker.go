@@ -2,30 +2,65 @@ package service
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/config"
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/panel"
 	"github.com/zyvpn/backend/internal/repository"
 )
 
 const (
 	HealthCheckInterval = 10 * time.Second
 	PingTimeout         = 5 * time.Second
+
+	// The panel is probed less often than the VPN port: it is slower and rate limited
+	PanelCheckInterval = time.Minute
+	PanelCheckTimeout  = 10 * time.Second
+
+	// Health checks older than this are deleted; incidents are kept
+	HealthHistoryRetention = 30 * 24 * time.Hour
+	healthCleanupInterval  = time.Hour
 )
 
 type HealthWorker struct {
 	repo      *repository.Repository
 	serverSvc *ServerService
+	alerts    *AlertService
+
+	mu             sync.Mutex
+	panelProbes    map[uuid.UUID]*panelProbe
+	offlineStreaks map[uuid.UUID]int // consecutive offline checks
+	endpointDown   map[uuid.UUID]int // consecutive failed checks by endpoint
+	panelFailures  map[uuid.UUID]int // consecutive failed panel checks
+	lastCleanup    time.Time
 }
 
-func NewHealthWorker(repo *repository.Repository, serverSvc *ServerService) *HealthWorker {
+// panelProbe is the latest panel check of a server, reused between panel checks
+type panelProbe struct {
+	at     time.Time
+	dur    time.Duration
+	err    error
+	system *panel.SystemStatus
+}
+
+func NewHealthWorker(repo *repository.Repository, serverSvc *ServerService, alerts *AlertService) *HealthWorker {
 	return &HealthWorker{
-		repo:      repo,
-		serverSvc: serverSvc,
+		repo:           repo,
+		serverSvc:      serverSvc,
+		alerts:         alerts,
+		panelProbes:    make(map[uuid.UUID]*panelProbe),
+		offlineStreaks: make(map[uuid.UUID]int),
+		endpointDown:   make(map[uuid.UUID]int),
+		panelFailures:  make(map[uuid.UUID]int),
 	}
 }
 
@@ -35,11 +70,6 @@ func (w *HealthWorker) Start(ctx context.Context) {
 	// Initial check
 	w.checkAllServers(ctx)
 
-	// Sync server loads on startup
-	if err := w.repo.SyncAllServerLoads(ctx); err != nil {
-		log.Printf("[Health Worker] Failed to sync server loads: %v", err)
-	}
-
 	ticker := time.NewTicker(HealthCheckInterval)
 	defer ticker.Stop()
 
@@ -50,6 +80,7 @@ func (w *HealthWorker) Start(ctx context.Context) {
 			return
 		case <-ticker.C:
 			w.checkAllServers(ctx)
+			w.cleanupHistory(ctx)
 		}
 	}
 }
@@ -66,43 +97,337 @@ func (w *HealthWorker) checkAllServers(ctx context.Context) {
 	}
 
 	var wg sync.WaitGroup
-	for _, server := range servers {
+	for i := range servers {
+		server := &servers[i]
 		if !server.IsActive {
 			continue
 		}
 
 		wg.Add(1)
-		go func(serverID, address string, port int) {
+		go func() {
 			defer wg.Done()
-			w.checkServer(ctx, serverID, address, port)
-		}(server.ID.String(), server.ServerAddress, server.ServerPort)
+			w.checkServer(ctx, server)
+			w.checkEndpoints(ctx, server)
+		}()
 	}
 	wg.Wait()
 }
 
-func (w *HealthWorker) checkServer(ctx context.Context, serverID, address string, port int) {
-	id, err := uuid.Parse(serverID)
+// checkServer probes the VPN port (TCP, then a TLS/Reality handshake with the
+// configured SNI) and, every PanelCheckInterval, the panel API and host status.
+// A server whose port or handshake fails is offline; one whose panel fails or
+// reports Xray as not running is degraded.
+func (w *HealthWorker) checkServer(ctx context.Context, server *model.Server) {
+	check := &model.HealthCheck{ServerID: server.ID, Status: model.ServerStatusOnline}
+	var problems []string
+
+	address := w.primaryAddress(ctx, server)
+
+	// Measure TCP connection time as ping
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", address, PingTimeout)
 	if err != nil {
-		log.Printf("[Health Worker] Invalid server ID %s: %v", serverID, err)
+		check.Status = model.ServerStatusOffline
+		problems = append(problems, "tcp: "+err.Error())
+	} else {
+		conn.Close()
+		check.TCPMs = msSince(start)
+
+		if server.ServerName != "" {
+			ok, ms, err := probeTLS(address, server.ServerName)
+			check.TLSOK = &ok
+			check.TLSMs = ms
+			if !ok {
+				check.Status = model.ServerStatusOffline
+				problems = append(problems, "tls: "+err.Error())
+			}
+		}
+	}
+
+	probe, fresh := w.probePanel(ctx, server)
+	if fresh {
+		ok := probe.err == nil
+		check.PanelOK = &ok
+		check.PanelMs = probe.ms()
+	}
+	if probe.err != nil {
+		problems = append(problems, "panel: "+probe.err.Error())
+		if check.Status == model.ServerStatusOnline {
+			check.Status = model.ServerStatusDegraded
+		}
+	}
+	if probe.system != nil {
+		if fresh {
+			check.CPUPercent = &probe.system.CPUPercent
+			check.MemPercent = &probe.system.MemPercent
+			check.XrayState = &probe.system.XrayState
+		}
+		if probe.system.XrayState != "" && probe.system.XrayState != "running" {
+			problems = append(problems, "xray: "+strings.TrimSpace(probe.system.XrayState+" "+probe.system.XrayError))
+			if check.Status == model.ServerStatusOnline {
+				check.Status = model.ServerStatusDegraded
+			}
+		}
+	}
+	check.Error = strings.Join(problems, "; ")
+
+	if err := w.repo.UpdateServerHealth(ctx, server.ID, check.TCPMs, check.Status); err != nil {
+		log.Printf("[Health Worker] Failed to update server %s health: %v", server.ID, err)
+	}
+	if err := w.repo.CreateHealthCheck(ctx, check); err != nil {
+		log.Printf("[Health Worker] Failed to save health check of server %s: %v", server.ID, err)
+	}
+	if check.Status != server.Status {
+		w.recordTransition(ctx, server, check)
+	}
+	w.raiseAlerts(ctx, server, check, probe, fresh)
+}
+
+// primaryAddress returns the address users connect to first: the first endpoint
+// offered in keys, or the server address when no endpoint is offered
+func (w *HealthWorker) primaryAddress(ctx context.Context, server *model.Server) string {
+	endpoints, err := w.repo.GetServerEndpoints(ctx, server.ID)
+	if err != nil {
+		log.Printf("[Health Worker] Failed to get endpoints of server %s: %v", server.Name, err)
+	}
+	if output := model.OutputEndpoints(endpoints); len(output) > 0 {
+		return output[0].HostPort(server.ServerPort)
+	}
+	return net.JoinHostPort(server.ServerAddress, fmt.Sprint(server.ServerPort))
+}
+
+// checkEndpoints probes every endpoint of a server that is not retired the same
+// way as the server's own address, so a blocked or misrouted address shows up
+// even while the server itself is reachable
+func (w *HealthWorker) checkEndpoints(ctx context.Context, server *model.Server) {
+	endpoints, err := w.repo.GetActiveServerEndpoints(ctx, server.ID)
+	if err != nil {
+		log.Printf("[Health Worker] Failed to get endpoints of server %s: %v", server.Name, err)
 		return
 	}
 
-	// Measure TCP connection time as ping
+	for i := range endpoints {
+		endpoint := &endpoints[i]
+		address := net.JoinHostPort(endpoint.Address, fmt.Sprint(endpoint.PortOr(server.ServerPort)))
+
+		status := model.ServerStatusOnline
+		var problem *string
+		start := time.Now()
+		conn, err := net.DialTimeout("tcp", address, PingTimeout)
+		pingMs := msSince(start)
+		if err == nil {
+			conn.Close()
+			if server.ServerName != "" {
+				if ok, _, tlsErr := probeTLS(address, server.ServerName); !ok {
+					err = fmt.Errorf("tls: %w", tlsErr)
+				}
+			}
+		} else {
+			err = fmt.Errorf("tcp: %w", err)
+		}
+		if err != nil {
+			status = model.ServerStatusOffline
+			pingMs = nil
+			msg := err.Error()
+			problem = &msg
+		}
+
+		if err := w.repo.UpdateServerEndpointHealth(ctx, endpoint.ID, status, pingMs, problem); err != nil {
+			log.Printf("[Health Worker] Failed to update endpoint %s health: %v", endpoint.Address, err)
+		}
+		if status != endpoint.Status && endpoint.Status != model.ServerStatusUnknown {
+			log.Printf("[Health Worker] Endpoint %s of server %s is %s", address, server.Name, status)
+		}
+		// The server's own address is already covered by the server down alert
+		if endpoint.Address != server.ServerAddress || endpoint.PortOr(server.ServerPort) != server.ServerPort {
+			w.raiseEndpointAlert(ctx, server, endpoint, address, problem)
+		}
+	}
+}
+
+// raiseEndpointAlert reports an endpoint that keeps failing and resolves the alert once it recovers
+func (w *HealthWorker) raiseEndpointAlert(ctx context.Context, server *model.Server, endpoint *model.ServerEndpoint, address string, problem *string) {
+	w.mu.Lock()
+	if problem != nil {
+		w.endpointDown[endpoint.ID]++
+	} else {
+		delete(w.endpointDown, endpoint.ID)
+	}
+	failures := w.endpointDown[endpoint.ID]
+	w.mu.Unlock()
+
+	subject := endpoint.ID.String()
+	if failures >= config.ServerDownAlertAfter {
+		name := strings.TrimSpace(server.FlagEmoji + " " + server.Name)
+		w.alerts.Fire(ctx, model.AlertKindEndpointDown, subject, model.AlertSeverityWarning,
+			fmt.Sprintf("Адрес %s сервера %s недоступен: %s", address, name, *problem))
+	} else if failures == 0 && endpoint.Status == model.ServerStatusOffline {
+		w.alerts.Resolve(ctx, model.AlertKindEndpointDown, subject)
+	}
+}
+
+// probeTLS performs a TLS handshake with the given SNI. Reality forwards
+// unauthenticated handshakes to its destination, so a completed handshake
+// means Xray is up and its destination is reachable.
+func probeTLS(address, serverName string) (bool, *int, error) {
 	start := time.Now()
-	conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", address, port), PingTimeout)
-	pingMs := int(time.Since(start).Milliseconds())
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: PingTimeout}, "tcp", address, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true, // Only reachability is checked
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	conn.Close()
+	return true, msSince(start), nil
+}
 
+// probePanel returns the panel check of a server, running a new one if the last
+// is older than PanelCheckInterval. fresh reports whether the check was run now.
+func (w *HealthWorker) probePanel(ctx context.Context, server *model.Server) (probe *panelProbe, fresh bool) {
+	w.mu.Lock()
+	last := w.panelProbes[server.ID]
+	w.mu.Unlock()
+	if last != nil && time.Since(last.at) < PanelCheckInterval {
+		return last, false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, PanelCheckTimeout)
+	defer cancel()
+
+	probe = &panelProbe{at: time.Now()}
+	client, err := w.serverSvc.GetPanelForInbound(server, server.XUIInboundID)
 	if err != nil {
-		// Server is offline
-		if updateErr := w.repo.UpdateServerHealth(ctx, id, nil, "offline"); updateErr != nil {
-			log.Printf("[Health Worker] Failed to update server %s health: %v", serverID, updateErr)
+		probe.err = err
+	} else if _, err := client.GetInboundInfo(ctx); err != nil {
+		probe.err = err
+	} else if reporter, ok := client.(panel.StatusReporter); ok {
+		status, err := reporter.SystemStatus(ctx)
+		switch {
+		case err == nil:
+			probe.system = status
+		case !errors.Is(err, panel.ErrNotSupported):
+			log.Printf("[Health Worker] Failed to get system status of server %s: %v", server.Name, err)
 		}
+	}
+	probe.dur = time.Since(probe.at)
+
+	w.mu.Lock()
+	w.panelProbes[server.ID] = probe
+	w.mu.Unlock()
+	return probe, true
+}
+
+func (p *panelProbe) ms() *int {
+	ms := int(p.dur.Milliseconds())
+	return &ms
+}
+
+// recordTransition opens or closes incidents when a server's status changes
+func (w *HealthWorker) recordTransition(ctx context.Context, server *model.Server, check *model.HealthCheck) {
+	open, err := w.repo.GetOpenServerIncident(ctx, server.ID)
+	if err != nil {
+		log.Printf("[Health Worker] Failed to get open incident of server %s: %v", server.ID, err)
 		return
 	}
-	conn.Close()
 
-	// Server is online
-	if updateErr := w.repo.UpdateServerHealth(ctx, id, &pingMs, "online"); updateErr != nil {
-		log.Printf("[Health Worker] Failed to update server %s health: %v", serverID, updateErr)
+	if open != nil {
+		if open.Status == check.Status {
+			return
+		}
+		if err := w.repo.CloseServerIncident(ctx, open.ID); err != nil {
+			log.Printf("[Health Worker] Failed to close incident of server %s: %v", server.ID, err)
+			return
+		}
+		if check.Status == model.ServerStatusOnline {
+			log.Printf("[Health Worker] Server %s recovered after %v", server.Name, time.Since(open.StartedAt).Round(time.Second))
+		}
+	}
+
+	if check.Status == model.ServerStatusOnline {
+		return
+	}
+
+	incident := &model.ServerIncident{ServerID: server.ID, Status: check.Status, Cause: check.Error}
+	if err := w.repo.CreateServerIncident(ctx, incident); err != nil {
+		log.Printf("[Health Worker] Failed to open incident of server %s: %v", server.ID, err)
+		return
+	}
+	log.Printf("[Health Worker] Server %s is %s: %s", server.Name, check.Status, check.Error)
+}
+
+// raiseAlerts reports servers that stay offline, panels that keep failing and
+// Xray not running, and resolves these alerts once the server recovers
+func (w *HealthWorker) raiseAlerts(ctx context.Context, server *model.Server, check *model.HealthCheck, probe *panelProbe, fresh bool) {
+	subject := server.ID.String()
+	name := strings.TrimSpace(server.FlagEmoji + " " + server.Name)
+
+	w.mu.Lock()
+	if check.Status == model.ServerStatusOffline {
+		w.offlineStreaks[server.ID]++
+	} else {
+		delete(w.offlineStreaks, server.ID)
 	}
+	offline := w.offlineStreaks[server.ID]
+	if fresh {
+		if probe.err != nil {
+			w.panelFailures[server.ID]++
+		} else {
+			delete(w.panelFailures, server.ID)
+		}
+	}
+	panelFailures := w.panelFailures[server.ID]
+	w.mu.Unlock()
+
+	if offline >= config.ServerDownAlertAfter {
+		w.alerts.Fire(ctx, model.AlertKindServerDown, subject, model.AlertSeverityCritical,
+			fmt.Sprintf("Сервер %s недоступен: %s", name, check.Error))
+	} else if offline == 0 && server.Status == model.ServerStatusOffline {
+		w.alerts.Resolve(ctx, model.AlertKindServerDown, subject)
+	}
+
+	if !fresh {
+		return
+	}
+
+	if probe.err != nil {
+		if panelFailures >= config.PanelErrorAlertAfter {
+			w.alerts.Fire(ctx, model.AlertKindPanelErrors, subject, model.AlertSeverityWarning,
+				fmt.Sprintf("Панель сервера %s не отвечает (%d проверок подряд): %v", name, panelFailures, probe.err))
+		}
+		return
+	}
+	w.alerts.Resolve(ctx, model.AlertKindPanelErrors, subject)
+
+	if probe.system == nil {
+		return
+	}
+	if state := probe.system.XrayState; state != "" && state != "running" {
+		w.alerts.Fire(ctx, model.AlertKindServerDegraded, subject, model.AlertSeverityCritical,
+			fmt.Sprintf("Xray на сервере %s не запущен: %s", name, strings.TrimSpace(state+" "+probe.system.XrayError)))
+	} else {
+		w.alerts.Resolve(ctx, model.AlertKindServerDegraded, subject)
+	}
+}
+
+// cleanupHistory deletes health checks past the retention period, at most once per healthCleanupInterval
+func (w *HealthWorker) cleanupHistory(ctx context.Context) {
+	if time.Since(w.lastCleanup) < healthCleanupInterval {
+		return
+	}
+	w.lastCleanup = time.Now()
+
+	deleted, err := w.repo.DeleteHealthChecksBefore(ctx, time.Now().Add(-HealthHistoryRetention))
+	if err != nil {
+		log.Printf("[Health Worker] Failed to delete old health checks: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("[Health Worker] Deleted %d old health checks", deleted)
+	}
+}
+
+func msSince(start time.Time) *int {
+	ms := int(time.Since(start).Milliseconds())
+	return &ms
 }
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/config"
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/panel"
+	"github.com/zyvpn/backend/internal/repository"
+)
+
+const (
+	LoadSampleInterval = time.Minute
+	LoadSampleTimeout  = 10 * time.Second
+
+	// Load samples older than this are deleted
+	LoadHistoryRetention = 30 * 24 * time.Hour
+)
+
+// LoadWorker periodically records the load panels report (online clients,
+// connections and throughput) together with the active subscription count
+// of every server. The latest sample is used by load balancing.
+type LoadWorker struct {
+	repo      *repository.Repository
+	serverSvc *ServerService
+
+	mu          sync.Mutex
+	counters    map[uuid.UUID]trafficCounter
+	lastCleanup time.Time
+}
+
+// trafficCounter is the cumulative host traffic of a server at the previous sample
+type trafficCounter struct {
+	at   time.Time
+	up   int64
+	down int64
+}
+
+func NewLoadWorker(repo *repository.Repository, serverSvc *ServerService) *LoadWorker {
+	return &LoadWorker{
+		repo:      repo,
+		serverSvc: serverSvc,
+		counters:  make(map[uuid.UUID]trafficCounter),
+	}
+}
+
+func (w *LoadWorker) Start(ctx context.Context) {
+	log.Printf("[Load Worker] Started, sampling every %v", LoadSampleInterval)
+
+	w.sampleAll(ctx)
+
+	ticker := time.NewTicker(LoadSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("[Load Worker] Stopped")
+			return
+		case <-ticker.C:
+			w.sampleAll(ctx)
+			w.cleanupHistory(ctx)
+		}
+	}
+}
+
+func (w *LoadWorker) sampleAll(ctx context.Context) {
+	// current_load is only written here: active subscriptions are recounted every sample
+	if err := w.repo.SyncAllServerLoads(ctx); err != nil {
+		log.Printf("[Load Worker] Failed to sync server loads: %v", err)
+	}
+
+	servers, err := w.repo.GetActiveServers(ctx)
+	if err != nil {
+		log.Printf("[Load Worker] Failed to get servers: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := range servers {
+		server := &servers[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.sampleServer(ctx, server)
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *LoadWorker) sampleServer(ctx context.Context, server *model.Server) {
+	client, err := w.serverSvc.GetPanelForInbound(server, server.XUIInboundID)
+	if err != nil {
+		log.Printf("[Load Worker] Failed to get panel of server %s: %v", server.Name, err)
+		return
+	}
+	reporter, ok := client.(panel.LoadReporter)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, LoadSampleTimeout)
+	defer cancel()
+
+	load, err := reporter.Load(ctx)
+	if err != nil {
+		// Unsupported panels have no live load; unavailable ones are reported by the health worker
+		if !errors.Is(err, panel.ErrNotSupported) && !errors.Is(err, panel.ErrUnavailable) {
+			log.Printf("[Load Worker] Failed to get load of server %s: %v", server.Name, err)
+		}
+		return
+	}
+
+	sample := &model.ServerLoadSample{
+		ServerID:      server.ID,
+		OnlineClients: load.OnlineClients,
+		Connections:   load.Connections,
+		Subscriptions: server.CurrentLoad,
+	}
+	now := time.Now()
+	if up, down, elapsed, ok := w.trafficDelta(server.ID, load, now); ok {
+		upBps := int64(float64(up) / elapsed.Seconds())
+		downBps := int64(float64(down) / elapsed.Seconds())
+		sample.UploadBps, sample.DownloadBps = &upBps, &downBps
+
+		if err := w.repo.AddServerMonthlyTraffic(ctx, server.ID, now, up, down); err != nil {
+			log.Printf("[Load Worker] Failed to save traffic of server %s: %v", server.Name, err)
+		}
+	}
+
+	if err := w.repo.CreateServerLoadSample(ctx, sample); err != nil {
+		log.Printf("[Load Worker] Failed to save load of server %s: %v", server.Name, err)
+	}
+}
+
+// trafficDelta returns the host traffic since the previous sample, from which
+// throughput and monthly traffic are derived. Nothing is returned for the first
+// sample or after the counters were reset by a restart.
+func (w *LoadWorker) trafficDelta(serverID uuid.UUID, load *panel.Load, now time.Time) (up, down int64, elapsed time.Duration, ok bool) {
+	w.mu.Lock()
+	prev, found := w.counters[serverID]
+	w.counters[serverID] = trafficCounter{at: now, up: load.TrafficUp, down: load.TrafficDown}
+	w.mu.Unlock()
+
+	elapsed = now.Sub(prev.at)
+	if !found || elapsed <= 0 || load.TrafficUp < prev.up || load.TrafficDown < prev.down {
+		return 0, 0, 0, false
+	}
+	return load.TrafficUp - prev.up, load.TrafficDown - prev.down, elapsed, true
+}
+
+// cleanupHistory deletes load samples past the retention period and client
+// latencies no longer used, at most once per healthCleanupInterval
+func (w *LoadWorker) cleanupHistory(ctx context.Context) {
+	if time.Since(w.lastCleanup) < healthCleanupInterval {
+		return
+	}
+	w.lastCleanup = time.Now()
+
+	deleted, err := w.repo.DeleteServerLoadSamplesBefore(ctx, time.Now().Add(-LoadHistoryRetention))
+	if err != nil {
+		log.Printf("[Load Worker] Failed to delete old load samples: %v", err)
+	} else if deleted > 0 {
+		log.Printf("[Load Worker] Deleted %d old load samples", deleted)
+	}
+
+	deleted, err = w.repo.DeleteClientLatenciesBefore(ctx, time.Now().Add(-config.ClientLatencyMaxAge))
+	if err != nil {
+		log.Printf("[Load Worker] Failed to delete old client latencies: %v", err)
+	} else if deleted > 0 {
+		log.Printf("[Load Worker] Deleted %d old client latencies", deleted)
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"github.com/zyvpn/backend/internal/model"
+)
+
+// panelRemovalBatch is how many queued removals one retry run takes
+const panelRemovalBatch = 200
+
+// removePanelClient deletes a client from a server's panel. When the panel is
+// unavailable or the deletion fails, the removal is queued for RetryPanelRemovals.
+func (s *SubscriptionService) removePanelClient(ctx context.Context, server *model.Server, inboundID int, clientID, email string) {
+	err := s.serverSvc.CheckPanel(server.ID)
+	if err == nil {
+		panelClient, perr := s.serverSvc.GetPanelForInbound(server, inboundID)
+		if perr != nil {
+			err = perr
+		} else {
+			err = panelClient.DeleteClient(ctx, clientID, email)
+		}
+	}
+	if err == nil {
+		return
+	}
+
+	log.Printf("WARNING: Failed to delete client %s from server %s, queued for retry: %v", email, server.Name, err)
+	lastError := err.Error()
+	if err := s.repo.QueuePanelClientRemoval(ctx, &model.PanelClientRemoval{
+		ServerID:     server.ID,
+		XUIInboundID: inboundID,
+		XUIClientID:  clientID,
+		XUIEmail:     email,
+		LastError:    &lastError,
+	}); err != nil {
+		log.Printf("ERROR: Failed to queue removal of client %s: %v", email, err)
+	}
+}
+
+// RetryPanelRemovals deletes queued panel clients from panels that are reachable again
+func (s *SubscriptionService) RetryPanelRemovals(ctx context.Context) error {
+	removals, err := s.repo.GetPanelClientRemovals(ctx, panelRemovalBatch)
+	if err != nil {
+		return err
+	}
+
+	removed := 0
+	for _, removal := range removals {
+		// Still down: wait for the breaker instead of counting an attempt
+		if err := s.serverSvc.CheckPanel(removal.ServerID); err != nil {
+			continue
+		}
+		server, err := s.serverSvc.GetServer(ctx, removal.ServerID)
+		if err != nil {
+			continue
+		}
+
+		panelClient, err := s.serverSvc.GetPanelForInbound(server, removal.XUIInboundID)
+		if err == nil {
+			err = panelClient.DeleteClient(ctx, removal.XUIClientID, removal.XUIEmail)
+		}
+		if err != nil {
+			log.Printf("WARNING: Retry of client %s removal on server %s failed: %v", removal.XUIEmail, server.Name, err)
+			if err := s.repo.FailPanelClientRemoval(ctx, removal.ID, err.Error()); err != nil {
+				log.Printf("WARNING: Failed to record removal attempt of client %s: %v", removal.XUIEmail, err)
+			}
+			continue
+		}
+
+		if err := s.repo.DeletePanelClientRemoval(ctx, removal.ID); err != nil {
+			log.Printf("WARNING: Failed to dequeue removal of client %s: %v", removal.XUIEmail, err)
+			continue
+		}
+		removed++
+	}
+
+	if removed > 0 {
+		log.Printf("Removed %d of %d queued panel clients", removed, len(removals))
+	}
+	return nil
+}
@@ -4,24 +4,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 
 	"github.com/google/uuid"
 	"github.com/zyvpn/backend/internal/config"
+	"github.com/zyvpn/backend/internal/i18n"
 	"github.com/zyvpn/backend/internal/model"
 	"github.com/zyvpn/backend/internal/repository"
 	"github.com/zyvpn/backend/internal/ton"
 )
 
 var (
-	ErrInvalidPaymentProvider = errors.New("Неверный способ оплаты")
-	ErrPaymentAlreadyComplete = errors.New("Платёж уже завершён")
-	ErrPaymentNotPending      = errors.New("Платёж не ожидает оплаты")
+	ErrInvalidPaymentProvider = i18n.NewError("invalid_payment_provider")
+	ErrPaymentAlreadyComplete = i18n.NewError("payment_already_complete")
+	ErrPaymentNotPending      = i18n.NewError("payment_not_pending")
+	ErrTONTransactionUsed     = errors.New("transaction already belongs to another payment")
 )
 
 // Notifier interface for sending notifications (implemented by telegram.Bot)
 type Notifier interface {
 	SendReferralBonus(chatID int64, bonusTON float64, bonusDays int) error
 	SendBalanceTopUp(chatID int64, amount float64, newBalance float64) error
+	SendPaymentResult(payment *model.Payment) error
 }
 
 type PaymentService struct {
@@ -72,6 +76,17 @@ func (s *PaymentService) CreatePaymentWithServer(ctx context.Context, userID int
 		return nil, err
 	}
 
+	// Refuse a chosen server that cannot take new subscriptions, or a plan the
+	// active subscription cannot move to, before the user pays
+	if serverID != nil {
+		if err := s.subscriptionSvc.CheckServerAvailable(ctx, *serverID, plan); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.subscriptionSvc.CheckPlanChange(ctx, userID, plan, serverID); err != nil {
+		return nil, err
+	}
+
 	var amount float64
 	var currency string
 
@@ -104,6 +119,48 @@ func (s *PaymentService) CreatePaymentWithServer(ctx context.Context, userID int
 	return payment, nil
 }
 
+// PayFromBalance buys a plan with the user's balance: the price is debited and
+// the subscription created at once, on the chosen server or an automatically
+// picked one when serverID is nil. The balance is refunded if the subscription
+// cannot be created. It returns the completed payment and the new balance.
+func (s *PaymentService) PayFromBalance(ctx context.Context, userID int64, planID uuid.UUID, serverID *uuid.UUID) (*model.Payment, float64, error) {
+	if s.balanceSvc == nil {
+		return nil, 0, errors.New("balance service not configured")
+	}
+
+	plan, err := s.repo.GetPlan(ctx, planID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	canAfford, err := s.balanceSvc.CanAfford(ctx, userID, plan.PriceTON)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !canAfford {
+		return nil, 0, ErrInsufficientBalance
+	}
+
+	payment, err := s.CreatePaymentWithServer(ctx, userID, plan.ID, serverID, model.PaymentProviderBalance)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	newBalance, err := s.balanceSvc.DebitForSubscription(ctx, userID, plan.PriceTON, payment.ID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to debit balance: %w", err)
+	}
+
+	if err := s.CompletePayment(ctx, payment.ID); err != nil {
+		if _, refundErr := s.balanceSvc.CreditRefund(ctx, userID, plan.PriceTON, payment.ID); refundErr != nil {
+			fmt.Printf("Failed to refund balance payment %s: %v\n", payment.ID, refundErr)
+		}
+		return nil, 0, fmt.Errorf("failed to complete payment: %w", err)
+	}
+
+	return payment, newBalance, nil
+}
+
 func (s *PaymentService) GetPayment(ctx context.Context, id uuid.UUID) (*model.Payment, error) {
 	return s.repo.GetPayment(ctx, id)
 }
@@ -125,7 +182,7 @@ func (s *PaymentService) GetTONPaymentInfo(ctx context.Context, paymentID uuid.U
 
 	// Format amount for TON (9 decimals)
 	amountNano := fmt.Sprintf("%.0f", plan.PriceTON*1e9)
-	comment := payment.ID.String()
+	comment := tonComment(payment)
 
 	deepLink := fmt.Sprintf("ton://transfer/%s?amount=%s&text=%s",
 		s.cfg.TON.WalletAddress,
@@ -161,8 +218,7 @@ func (s *PaymentService) VerifyTONPayment(ctx context.Context, paymentID uuid.UU
 	}
 
 	// Try immediate verification (optional - worker will also check)
-	expectedAmountNano := int64(payment.Amount * 1e9)
-	txInfo, err := s.tonVerifier.VerifyTransaction(boc, expectedAmountNano, "")
+	txInfo, err := findTONTransaction(ctx, s.repo, s.tonVerifier, payment, boc)
 	if err != nil {
 		// Not found yet - that's ok, worker will keep checking
 		fmt.Printf("[TON] Payment %s: transaction not confirmed yet, worker will retry\n", paymentID)
@@ -177,7 +233,77 @@ func (s *PaymentService) VerifyTONPayment(ctx context.Context, paymentID uuid.UU
 		return err
 	}
 
-	return s.CompletePayment(ctx, paymentID)
+	if err := s.CompletePayment(ctx, paymentID); err != nil {
+		return err
+	}
+	s.notifyPaymentResult(ctx, paymentID)
+	return nil
+}
+
+// RequireTONComment makes a TON payment match only transfers carrying its comment.
+// The bot sets it before showing the payment details, which include the comment.
+func (s *PaymentService) RequireTONComment(ctx context.Context, paymentID uuid.UUID) error {
+	return s.repo.SetPaymentCommentRequired(ctx, paymentID)
+}
+
+// AwaitTONPayment hands a TON payment the user reported paying in the bot chat
+// to the TON worker, which matches the transfer by its comment and tells the user
+// in the chat how the payment ends
+func (s *PaymentService) AwaitTONPayment(ctx context.Context, paymentID uuid.UUID) error {
+	awaiting, err := s.repo.AwaitPaymentTx(ctx, paymentID)
+	if err != nil {
+		return err
+	}
+	if !awaiting {
+		return ErrPaymentNotPending
+	}
+	return nil
+}
+
+// notifyPaymentResult tells the user how a TON payment they reported in the bot
+// chat ended
+func (s *PaymentService) notifyPaymentResult(ctx context.Context, paymentID uuid.UUID) {
+	if s.notifier == nil {
+		return
+	}
+	payment, err := s.repo.GetPayment(ctx, paymentID)
+	if err != nil || !payment.NotifyChat {
+		return
+	}
+	if err := s.notifier.SendPaymentResult(payment); err != nil {
+		log.Printf("WARNING: Failed to send result of payment %s to user %d: %v", paymentID, payment.UserID, err)
+	}
+}
+
+// tonComment returns the comment a TON transfer paying for the payment carries
+func tonComment(payment *model.Payment) string {
+	if payment.PaymentType == model.PaymentTypeTopUp {
+		return "topup_" + payment.ID.String()
+	}
+	return payment.ID.String()
+}
+
+// findTONTransaction looks for the transfer paying a TON payment: one to the
+// wallet of at least its amount, carrying the payment's comment when the payment
+// requires one, and not already recorded on another payment
+func findTONTransaction(ctx context.Context, repo *repository.Repository, verifier *ton.Verifier, payment *model.Payment, boc string) (*ton.TransactionInfo, error) {
+	var comment string
+	if payment.CommentRequired {
+		comment = tonComment(payment)
+	}
+	txInfo, err := verifier.VerifyTransaction(boc, int64(payment.Amount*1e9), comment)
+	if err != nil {
+		return nil, err
+	}
+
+	other, err := repo.GetPaymentByExternalID(ctx, txInfo.Hash)
+	switch {
+	case err == nil && other.ID != payment.ID:
+		return nil, fmt.Errorf("%w: %s", ErrTONTransactionUsed, other.ID)
+	case err != nil && !errors.Is(err, repository.ErrPaymentNotFound):
+		return nil, err
+	}
+	return txInfo, nil
 }
 
 // GetPaymentStatus returns payment status for polling
@@ -423,7 +549,7 @@ func (s *PaymentService) GetTONTopUpInfo(ctx context.Context, paymentID uuid.UUI
 
 	// Format amount for TON (9 decimals)
 	amountNano := fmt.Sprintf("%.0f", payment.Amount*1e9)
-	comment := "topup_" + payment.ID.String()
+	comment := tonComment(payment)
 
 	deepLink := fmt.Sprintf("ton://transfer/%s?amount=%s&text=%s",
 		s.cfg.TON.WalletAddress,
@@ -474,8 +600,7 @@ func (s *PaymentService) CompleteTopUpPayment(ctx context.Context, paymentID uui
 		}
 
 		// Try immediate verification
-		expectedAmountNano := int64(payment.Amount * 1e9)
-		txInfo, err := s.tonVerifier.VerifyTransaction(boc, expectedAmountNano, "")
+		txInfo, err := findTONTransaction(ctx, s.repo, s.tonVerifier, payment, boc)
 		if err != nil {
 			// Not found yet - worker will keep checking
 			fmt.Printf("[TON] Top-up %s: transaction not confirmed yet, worker will retry\n", paymentID)
@@ -509,5 +634,6 @@ func (s *PaymentService) CompleteTopUpPayment(ctx context.Context, paymentID uui
 		return err
 	}
 
+	s.notifyPaymentResult(ctx, paymentID)
 	return nil
 }
@@ -5,17 +5,18 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/zyvpn/backend/internal/i18n"
 	"github.com/zyvpn/backend/internal/model"
 	"github.com/zyvpn/backend/internal/repository"
 )
 
 var (
-	ErrPromoCodeNotFound          = errors.New("Промокод не найден")
-	ErrPromoCodeExpired           = errors.New("Срок действия промокода истёк")
-	ErrPromoCodeUsageLimitReached = errors.New("Лимит использований промокода исчерпан")
-	ErrPromoCodeAlreadyUsed       = errors.New("Вы уже использовали этот промокод")
-	ErrPromoCodeInactive          = errors.New("Промокод неактивен")
-	ErrNoActiveSubscription       = errors.New("Нет активной подписки для продления")
+	ErrPromoCodeNotFound          = i18n.NewError("promo_code_not_found")
+	ErrPromoCodeExpired           = i18n.NewError("promo_code_expired")
+	ErrPromoCodeUsageLimitReached = i18n.NewError("promo_code_usage_limit")
+	ErrPromoCodeAlreadyUsed       = i18n.NewError("promo_code_already_used")
+	ErrPromoCodeInactive          = i18n.NewError("promo_code_inactive")
+	ErrNoActiveSubscription       = i18n.NewError("no_subscription_to_extend")
 )
 
 type PromoCodeService struct {
@@ -77,6 +78,23 @@ type ApplyResult struct {
 	Value      float64             `json:"value"`
 	NewBalance *float64            `json:"new_balance,omitempty"`
 	Message    string              `json:"message"`
+
+	messageKey  string
+	messageArgs []any
+}
+
+// setMessage sets the result message from the catalog, in the default language
+func (r *ApplyResult) setMessage(key string, args ...any) {
+	r.messageKey, r.messageArgs = key, args
+	r.Message = i18n.T(i18n.DefaultLang, key, args...)
+}
+
+// LocalizedMessage returns the result message in lang
+func (r *ApplyResult) LocalizedMessage(lang string) string {
+	if r.messageKey == "" {
+		return r.Message
+	}
+	return i18n.T(lang, r.messageKey, r.messageArgs...)
 }
 
 // ApplyPromoCode applies a promo code to a user
@@ -101,7 +119,7 @@ func (s *PromoCodeService) ApplyPromoCode(ctx context.Context, code string, user
 			return nil, fmt.Errorf("failed to credit balance: %w", err)
 		}
 		result.NewBalance = &newBalance
-		result.Message = fmt.Sprintf("На ваш баланс зачислено %.4f TON", promo.Value)
+		result.setMessage("promo_applied_balance", promo.Value)
 
 	case model.PromoCodeTypeDays:
 		if s.subscriptionSvc == nil {
@@ -114,7 +132,7 @@ func (s *PromoCodeService) ApplyPromoCode(ctx context.Context, code string, user
 			if err := s.subscriptionSvc.ExtendSubscription(ctx, sub.ID, int(promo.Value)); err != nil {
 				return nil, fmt.Errorf("failed to extend subscription: %w", err)
 			}
-			result.Message = fmt.Sprintf("Ваша подписка продлена на %d дней", int(promo.Value))
+			result.setMessage("promo_applied_extended", int(promo.Value))
 		} else {
 			// No active subscription - create new one with trial plan + promo days
 			days := int(promo.Value)
@@ -126,7 +144,7 @@ func (s *PromoCodeService) ApplyPromoCode(ctx context.Context, code string, user
 				return nil, fmt.Errorf("failed to activate subscription: %w", err)
 			}
 			_ = sub
-			result.Message = fmt.Sprintf("Вам активирована подписка на %d дней", days)
+			result.setMessage("promo_applied_activated", days)
 		}
 
 	case model.PromoCodeTypeRegionSwitch:
@@ -139,9 +157,9 @@ func (s *PromoCodeService) ApplyPromoCode(ctx context.Context, code string, user
 			return nil, fmt.Errorf("failed to add free region switches: %w", err)
 		}
 		if count == 1 {
-			result.Message = "Вам начислена 1 бесплатная смена региона"
+			result.setMessage("promo_applied_region_switch")
 		} else {
-			result.Message = fmt.Sprintf("Вам начислено %d бесплатных смен региона", count)
+			result.setMessage("promo_applied_region_switches", count)
 		}
 	}
 
@@ -2,16 +2,16 @@ package service
 
 import (
 	"context"
-	"errors"
 
 	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/i18n"
 	"github.com/zyvpn/backend/internal/model"
 	"github.com/zyvpn/backend/internal/repository"
 )
 
 var (
-	ErrReferralAlreadyExists = errors.New("Реферал уже существует")
-	ErrSelfReferral          = errors.New("Нельзя пригласить самого себя")
+	ErrReferralAlreadyExists = i18n.NewError("referral_exists")
+	ErrSelfReferral          = i18n.NewError("self_referral")
 )
 
 type ReferralService struct {
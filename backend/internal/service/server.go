@@ -2,42 +2,140 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/config"
 	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/panel"
 	"github.com/zyvpn/backend/internal/repository"
-	"github.com/zyvpn/backend/internal/xui"
 )
 
+var (
+	ErrInvalidInbound = errors.New("invalid inbound")
+	ErrInboundInUse   = errors.New("inbound has subscription clients")
+)
+
+// clientKey identifies a cached panel client by server and inbound
+type clientKey struct {
+	serverID  uuid.UUID
+	inboundID int
+}
+
 type ServerService struct {
-	repo    *repository.Repository
-	clients map[uuid.UUID]*xui.Client
-	mu      sync.RWMutex
+	repo     *repository.Repository
+	ratesSvc *RatesService
+	clients  map[clientKey]panel.Panel
+	breakers map[uuid.UUID]*panel.Breaker
+	mu       sync.RWMutex
+
+	asn               *asnResolver
+	latencyMu         sync.Mutex
+	latencyReportedAt map[int64]time.Time
 }
 
 func NewServerService(repo *repository.Repository) *ServerService {
 	return &ServerService{
-		repo:    repo,
-		clients: make(map[uuid.UUID]*xui.Client),
+		repo:              repo,
+		clients:           make(map[clientKey]panel.Panel),
+		breakers:          make(map[uuid.UUID]*panel.Breaker),
+		asn:               newASNResolver(),
+		latencyReportedAt: make(map[int64]time.Time),
 	}
 }
 
-// GetActiveServers returns all active servers for users
-func (s *ServerService) GetActiveServers(ctx context.Context) ([]model.ServerPublic, error) {
+// SetRatesService sets the exchange rates used to compare revenue with server costs
+func (s *ServerService) SetRatesService(ratesSvc *RatesService) {
+	s.ratesSvc = ratesSvc
+}
+
+// GetActiveServers returns all active servers for users. Servers outside the
+// plan's groups are marked locked and list the purchasable plans that include them.
+// A nil plan locks nothing.
+func (s *ServerService) GetActiveServers(ctx context.Context, plan *model.Plan) ([]model.ServerPublic, error) {
 	servers, err := s.repo.GetActiveServers(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	result := make([]model.ServerPublic, len(servers))
+	var plans []model.Plan
+	if plan != nil && len(plan.ServerGroups) > 0 {
+		if plans, err = s.repo.GetActivePlans(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	result := s.publicServers(ctx, servers)
 	for i, srv := range servers {
-		result[i] = srv.ToPublic()
+		if plan.AllowsServerGroup(srv.Group) {
+			continue
+		}
+		result[i].Locked = true
+		for _, p := range plans {
+			if p.AllowsServerGroup(srv.Group) {
+				result[i].UnlockPlans = append(result[i].UnlockPlans, model.PlanRef{ID: p.ID, Name: p.Name})
+			}
+		}
 	}
 	return result, nil
 }
 
+// GetServersForUser returns active servers locked by the given plan, or by the
+// plan of the user's active subscription when planID is nil. Servers carry the
+// latency measured by the user or the user's network, and the server that would
+// be picked for the user is marked recommended.
+func (s *ServerService) GetServersForUser(ctx context.Context, userID int64, planID *uuid.UUID) ([]model.ServerPublic, error) {
+	var plan *model.Plan
+	var err error
+	if planID != nil {
+		plan, err = s.repo.GetPlan(ctx, *planID)
+	} else {
+		plan, err = s.GetUserPlan(ctx, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	servers, err := s.GetActiveServers(ctx, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	latencies := s.clientLatencies(ctx, userID)
+	best, err := s.GetBestServer(ctx, userID, plan)
+	if err != nil && !errors.Is(err, ErrNoServersAvailable) {
+		log.Printf("WARNING: Failed to pick a server for user %d: %v", userID, err)
+	}
+	for i := range servers {
+		if latency, ok := latencies[servers[i].ID]; ok {
+			servers[i].LatencyMs = &latency.LatencyMs
+			servers[i].LatencySource = latency.Source
+		}
+		servers[i].Recommended = best != nil && best.ID == servers[i].ID
+	}
+	return servers, nil
+}
+
+// GetUserPlan returns the plan of the user's active subscription, or nil without one
+func (s *ServerService) GetUserPlan(ctx context.Context, userID int64) (*model.Plan, error) {
+	sub, err := s.repo.GetActiveSubscription(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrSubscriptionNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !sub.IsActive() {
+		return nil, nil
+	}
+	return s.repo.GetPlan(ctx, sub.PlanID)
+}
+
 // GetAllServers returns all servers for admin
 func (s *ServerService) GetAllServers(ctx context.Context) ([]model.ServerAdmin, error) {
 	servers, err := s.repo.GetAllServers(ctx)
@@ -47,16 +145,33 @@ func (s *ServerService) GetAllServers(ctx context.Context) ([]model.ServerAdmin,
 
 	result := make([]model.ServerAdmin, len(servers))
 	for i, srv := range servers {
-		result[i] = srv.ToAdmin()
+		result[i] = s.ToAdmin(&srv)
 	}
 	return result, nil
 }
 
+// ToAdmin converts a server to the admin view including its panel availability
+func (s *ServerService) ToAdmin(server *model.Server) model.ServerAdmin {
+	result := server.ToAdmin()
+
+	st := s.breaker(server.ID).Status()
+	result.PanelState = st.State
+	result.PanelError = st.LastError
+	result.PanelRetryAt = st.RetryAt
+	return result
+}
+
 // GetServer returns a server by ID
 func (s *ServerService) GetServer(ctx context.Context, id uuid.UUID) (*model.Server, error) {
 	return s.repo.GetServer(ctx, id)
 }
 
+// GetServerForAdmin returns a server to edit; one whose password cannot be
+// decrypted is returned with the password cleared instead of failing
+func (s *ServerService) GetServerForAdmin(ctx context.Context, id uuid.UUID) (*model.Server, error) {
+	return s.repo.GetServerForAdmin(ctx, id)
+}
+
 // GetDefaultServer returns the default server
 func (s *ServerService) GetDefaultServer(ctx context.Context) (*model.Server, error) {
 	return s.repo.GetDefaultServer(ctx)
@@ -64,111 +179,350 @@ func (s *ServerService) GetDefaultServer(ctx context.Context) (*model.Server, er
 
 // CreateServer creates a new server
 func (s *ServerService) CreateServer(ctx context.Context, server *model.Server) error {
-	return s.repo.CreateServer(ctx, server)
+	if err := s.repo.CreateServer(ctx, server); err != nil {
+		return err
+	}
+	if server.IsActive {
+		if err := s.repo.OpenServerCost(ctx, server); err != nil {
+			log.Printf("WARNING: Failed to record cost of server %s: %v", server.Name, err)
+		}
+	}
+
+	// The server address is its first endpoint
+	endpoint := &model.ServerEndpoint{ServerID: server.ID, Address: server.ServerAddress}
+	if err := s.repo.CreateServerEndpoint(ctx, endpoint); err != nil {
+		log.Printf("WARNING: Failed to add endpoint of server %s: %v", server.Name, err)
+	}
+	return nil
 }
 
-// UpdateServer updates a server
-func (s *ServerService) UpdateServer(ctx context.Context, server *model.Server) error {
-	// Invalidate cached client
-	s.mu.Lock()
-	delete(s.clients, server.ID)
-	s.mu.Unlock()
+// UpdateServer updates a server and reports whether connection parameters changed,
+// i.e. whether stored connection keys must be regenerated. The panel breaker is
+// reset, since the change may have fixed the panel settings.
+func (s *ServerService) UpdateServer(ctx context.Context, server *model.Server) (bool, error) {
+	old, err := s.repo.GetServerForAdmin(ctx, server.ID)
+	if err != nil {
+		return false, err
+	}
+
+	s.invalidateClients(server.ID)
+	s.breaker(server.ID).Reset()
+	if err := s.repo.UpdateServer(ctx, server); err != nil {
+		return false, err
+	}
+
+	// Endpoints at the old address follow the server to the new one
+	if old.ServerAddress != server.ServerAddress {
+		if err := s.repo.UpdateServerEndpointAddress(ctx, server.ID, old.ServerAddress, server.ServerAddress); err != nil {
+			log.Printf("WARNING: Failed to move endpoints of server %s to %s: %v", server.Name, server.ServerAddress, err)
+		}
+	}
+	s.updateServerCost(ctx, old, server)
+	return !old.SameConnectionParams(server), nil
+}
+
+// updateServerCost records a changed cost or activity of a server in its cost
+// history, so past months keep the cost they were charged
+func (s *ServerService) updateServerCost(ctx context.Context, old, server *model.Server) {
+	if old.IsActive == server.IsActive && old.MonthlyCost == server.MonthlyCost && old.CostCurrency == server.CostCurrency {
+		return
+	}
+	if err := s.repo.CloseServerCost(ctx, server.ID); err != nil {
+		log.Printf("WARNING: Failed to close cost of server %s: %v", server.Name, err)
+		return
+	}
+	if server.IsActive {
+		if err := s.repo.OpenServerCost(ctx, server); err != nil {
+			log.Printf("WARNING: Failed to record cost of server %s: %v", server.Name, err)
+		}
+	}
+}
+
+// RevealCredentials returns the panel credentials of a server to a superadmin and records it in the admin log
+func (s *ServerService) RevealCredentials(ctx context.Context, adminID int64, serverID uuid.UUID) (*model.ServerCredentials, error) {
+	admin, err := s.repo.GetAdmin(ctx, adminID)
+	if err != nil || admin.Role != model.AdminRoleSuperAdmin {
+		return nil, ErrInsufficientPerms
+	}
+
+	server, err := s.repo.GetServer(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.LogAdminAction(ctx, adminID, model.AdminActionRevealServerCred, nil, map[string]interface{}{
+		"server_id":   server.ID,
+		"server_name": server.Name,
+	}); err != nil {
+		// Never reveal without an audit record
+		return nil, fmt.Errorf("failed to record admin action: %w", err)
+	}
 
-	return s.repo.UpdateServer(ctx, server)
+	return &model.ServerCredentials{
+		ServerID:    server.ID,
+		XUIUsername: server.XUIUsername,
+		XUIPassword: server.XUIPassword,
+	}, nil
 }
 
 // DeleteServer deletes a server
 func (s *ServerService) DeleteServer(ctx context.Context, id uuid.UUID) error {
-	// Invalidate cached client
+	s.invalidateClients(id)
+
 	s.mu.Lock()
-	delete(s.clients, id)
+	delete(s.breakers, id)
 	s.mu.Unlock()
 
-	return s.repo.DeleteServer(ctx, id)
+	if err := s.repo.DeleteServer(ctx, id); err != nil {
+		return err
+	}
+	// The cost history is kept for the profitability report
+	if err := s.repo.CloseServerCost(ctx, id); err != nil {
+		log.Printf("WARNING: Failed to close cost of server %s: %v", id, err)
+	}
+	return nil
 }
 
-// GetXUIClient returns an XUI client for a specific server (with caching)
-func (s *ServerService) GetXUIClient(ctx context.Context, serverID uuid.UUID) (*xui.Client, *model.Server, error) {
-	// Check cache first
+// breaker returns the circuit breaker shared by all panel clients of a server
+func (s *ServerService) breaker(serverID uuid.UUID) *panel.Breaker {
 	s.mu.RLock()
-	client, exists := s.clients[serverID]
+	b, exists := s.breakers[serverID]
 	s.mu.RUnlock()
+	if exists {
+		return b
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, exists := s.breakers[serverID]; exists {
+		return b
+	}
+	b = panel.NewBreaker(panel.BreakerConfig{
+		FailureThreshold: config.PanelFailureThreshold,
+		OpenTimeout:      config.PanelOpenTimeout,
+		MaxInFlight:      config.PanelMaxInFlight,
+	})
+	s.breakers[serverID] = b
+	return b
+}
+
+// CheckPanel returns a panel.UnavailableError while the server's panel breaker is open
+func (s *ServerService) CheckPanel(serverID uuid.UUID) error {
+	return s.breaker(serverID).Check()
+}
+
+// ResetPanel closes the server's panel breaker so the next request is sent to the panel
+func (s *ServerService) ResetPanel(serverID uuid.UUID) {
+	s.breaker(serverID).Reset()
+}
+
+// invalidateClients drops cached panel clients for all inbounds of a server
+func (s *ServerService) invalidateClients(serverID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, client := range s.clients {
+		if key.serverID == serverID {
+			// Release long-lived connections (xray gRPC)
+			if closer, ok := client.(io.Closer); ok {
+				_ = closer.Close()
+			}
+			delete(s.clients, key)
+		}
+	}
+}
 
+// GetPanel returns the panel client for the primary inbound of a server (with caching)
+func (s *ServerService) GetPanel(ctx context.Context, serverID uuid.UUID) (panel.Panel, *model.Server, error) {
 	server, err := s.repo.GetServer(ctx, serverID)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	client, err := s.GetPanelForInbound(server, server.XUIInboundID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return client, server, nil
+}
+
+// GetPanelForInbound returns a panel client bound to a specific inbound of a server (with caching)
+func (s *ServerService) GetPanelForInbound(server *model.Server, inboundID int) (panel.Panel, error) {
+	key := clientKey{serverID: server.ID, inboundID: inboundID}
+
+	// Check cache first
+	s.mu.RLock()
+	client, exists := s.clients[key]
+	s.mu.RUnlock()
+
 	if exists && client != nil {
-		return client, server, nil
+		return client, nil
 	}
 
-	// Create new client
-	client, err = xui.NewClient(server.XUIBaseURL, server.XUIUsername, server.XUIPassword, server.XUIInboundID)
+	// Create new client for the server's panel type, guarded by the server's breaker
+	client, err := panel.New(server, inboundID)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create XUI client: %w", err)
+		return nil, fmt.Errorf("failed to create panel client: %w", err)
 	}
+	client = panel.Guard(client, s.breaker(server.ID))
 
 	// Cache it
 	s.mu.Lock()
-	s.clients[serverID] = client
+	s.clients[key] = client
 	s.mu.Unlock()
 
-	return client, server, nil
+	return client, nil
 }
 
-// GetXUIClientForDefault returns XUI client for default server
-func (s *ServerService) GetXUIClientForDefault(ctx context.Context) (*xui.Client, *model.Server, error) {
+// GetPanelForDefault returns the panel client for the default server
+func (s *ServerService) GetPanelForDefault(ctx context.Context) (panel.Panel, *model.Server, error) {
 	server, err := s.repo.GetDefaultServer(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
-	return s.GetXUIClient(ctx, server.ID)
-}
-
-// GenerateConnectionKey generates a VLESS connection key for a subscription
-func (s *ServerService) GenerateConnectionKey(server *model.Server, clientID, email string) string {
-	return fmt.Sprintf("vless://%s@%s:%d?type=tcp&security=reality&pbk=%s&fp=chrome&sni=%s&sid=%s&spx=%%2F&flow=xtls-rprx-vision#%s",
-		clientID,
-		server.ServerAddress,
-		server.ServerPort,
-		server.PublicKey,
-		server.ServerName,
-		server.ShortID,
-		email,
-	)
+	return s.GetPanel(ctx, server.ID)
 }
 
-// GetBestServer returns the best available server based on load balancing
-func (s *ServerService) GetBestServer(ctx context.Context) (*model.Server, error) {
-	return s.repo.GetBestServer(ctx)
+// GenerateConnectionKey generates a share link for a client on the server's primary inbound.
+// Address, port and Reality parameters configured on the server take precedence over the panel's.
+func (s *ServerService) GenerateConnectionKey(ctx context.Context, p panel.Panel, server *model.Server, account *panel.Account) (string, error) {
+	return p.ConnectionKey(ctx, account, panel.Endpoint{
+		Address:    server.ServerAddress,
+		Port:       server.ServerPort,
+		PublicKey:  server.PublicKey,
+		ShortID:    server.ShortID,
+		ServerName: server.ServerName,
+	})
 }
 
-// GetOnlineServers returns all online active servers
+// GetOnlineServers returns all active servers that serve traffic. Degraded
+// servers are included; provisioning on them fails while their panel is down.
 func (s *ServerService) GetOnlineServers(ctx context.Context) ([]model.ServerPublic, error) {
 	servers, err := s.repo.GetOnlineServers(ctx)
 	if err != nil {
 		return nil, err
 	}
+	return s.publicServers(ctx, servers), nil
+}
 
-	result := make([]model.ServerPublic, len(servers))
-	for i, srv := range servers {
-		result[i] = srv.ToPublic()
+// UpdateServerHealth updates server health status
+func (s *ServerService) UpdateServerHealth(ctx context.Context, serverID uuid.UUID, pingMs *int, status string) error {
+	return s.repo.UpdateServerHealth(ctx, serverID, pingMs, status)
+}
+
+// --- Additional inbounds ---
+
+// GetServerInbounds returns all additional inbounds of a server
+func (s *ServerService) GetServerInbounds(ctx context.Context, serverID uuid.UUID) ([]model.ServerInbound, error) {
+	return s.repo.GetServerInbounds(ctx, serverID)
+}
+
+// GetServerInbound returns an additional inbound by ID
+func (s *ServerService) GetServerInbound(ctx context.Context, id uuid.UUID) (*model.ServerInbound, error) {
+	return s.repo.GetServerInbound(ctx, id)
+}
+
+// GetPlanInbounds returns active additional inbounds of a server that the plan allows
+func (s *ServerService) GetPlanInbounds(ctx context.Context, server *model.Server, plan *model.Plan) ([]model.ServerInbound, error) {
+	// Only 3x-ui servers have selectable inbounds
+	if server.PanelType != "" && server.PanelType != model.PanelTypeXUI {
+		return nil, nil
+	}
+
+	inbounds, err := s.repo.GetActiveServerInbounds(ctx, server.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []model.ServerInbound
+	for _, inbound := range inbounds {
+		if plan == nil || plan.AllowsInboundTag(inbound.Tag) {
+			result = append(result, inbound)
+		}
 	}
 	return result, nil
 }
 
-// IncrementLoad increments server load when subscription is created
-func (s *ServerService) IncrementLoad(ctx context.Context, serverID uuid.UUID) error {
-	return s.repo.IncrementServerLoad(ctx, serverID)
+// CreateServerInbound adds an additional inbound to a server
+func (s *ServerService) CreateServerInbound(ctx context.Context, inbound *model.ServerInbound) error {
+	return s.repo.CreateServerInbound(ctx, inbound)
 }
 
-// DecrementLoad decrements server load when subscription is cancelled/expired
-func (s *ServerService) DecrementLoad(ctx context.Context, serverID uuid.UUID) error {
-	return s.repo.DecrementServerLoad(ctx, serverID)
+// UpdateServerInbound updates an additional inbound. A changed panel inbound must exist
+// on the panel, and cannot be changed while subscriptions have clients on the old one.
+func (s *ServerService) UpdateServerInbound(ctx context.Context, inbound *model.ServerInbound) error {
+	current, err := s.repo.GetServerInbound(ctx, inbound.ID)
+	if err != nil {
+		return err
+	}
+
+	if inbound.XUIInboundID != current.XUIInboundID {
+		clients, err := s.repo.GetSubscriptionInboundsByServerInbound(ctx, inbound.ID)
+		if err != nil {
+			return err
+		}
+		if len(clients) > 0 {
+			return fmt.Errorf("%w: %d subscriptions have clients on inbound %d", ErrInboundInUse, len(clients), current.XUIInboundID)
+		}
+
+		server, err := s.repo.GetServer(ctx, inbound.ServerID)
+		if err != nil {
+			return err
+		}
+		if inbound.XUIInboundID == server.XUIInboundID {
+			return fmt.Errorf("%w: inbound %d is the primary inbound of the server", ErrInvalidInbound, inbound.XUIInboundID)
+		}
+		client, err := s.GetPanelForInbound(server, inbound.XUIInboundID)
+		if err == nil {
+			_, err = client.GetInboundInfo(ctx)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: failed to load inbound %d from panel: %v", ErrInvalidInbound, inbound.XUIInboundID, err)
+		}
+	}
+
+	s.invalidateClients(inbound.ServerID)
+	return s.repo.UpdateServerInbound(ctx, inbound)
 }
 
-// UpdateServerHealth updates server health status
-func (s *ServerService) UpdateServerHealth(ctx context.Context, serverID uuid.UUID, pingMs *int, status string) error {
-	return s.repo.UpdateServerHealth(ctx, serverID, pingMs, status)
+// DeleteServerInbound deletes an additional inbound and the subscribers' clients on it.
+// Clients that cannot be removed from the panel are logged; the rows go either way.
+func (s *ServerService) DeleteServerInbound(ctx context.Context, inbound *model.ServerInbound) error {
+	clients, err := s.repo.GetSubscriptionInboundsByServerInbound(ctx, inbound.ID)
+	if err != nil {
+		return err
+	}
+
+	if len(clients) > 0 {
+		server, err := s.repo.GetServer(ctx, inbound.ServerID)
+		if err != nil {
+			return err
+		}
+		panelClient, err := s.GetPanelForInbound(server, inbound.XUIInboundID)
+		if err != nil {
+			return fmt.Errorf("failed to get panel client for inbound %d: %w", inbound.XUIInboundID, err)
+		}
+
+		failed := 0
+		for _, client := range clients {
+			if err := panelClient.DeleteClient(ctx, client.XUIClientID, client.XUIEmail); err != nil {
+				// A down panel would leave every client behind, so keep the inbound for a retry
+				if errors.Is(err, panel.ErrUnavailable) {
+					return err
+				}
+				log.Printf("WARNING: Failed to delete client %s from inbound %d: %v", client.XUIEmail, inbound.XUIInboundID, err)
+				failed++
+			}
+		}
+		log.Printf("Removed %d of %d clients from inbound %d on server %s", len(clients)-failed, len(clients), inbound.XUIInboundID, server.Name)
+	}
+
+	s.invalidateClients(inbound.ServerID)
+	return s.repo.DeleteServerInbound(ctx, inbound.ID)
+}
+
+// GenerateInboundConnectionKey generates a connection key for a client on an additional inbound.
+// Unlike the primary inbound, the port and Reality parameters come from the inbound itself.
+func (s *ServerService) GenerateInboundConnectionKey(ctx context.Context, p panel.Panel, server *model.Server, account *panel.Account) (string, error) {
+	return p.ConnectionKey(ctx, account, panel.Endpoint{Address: server.ServerAddress})
 }
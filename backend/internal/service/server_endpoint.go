@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/i18n"
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/panel"
+	"github.com/zyvpn/backend/internal/repository"
+)
+
+var (
+	ErrServerEndpointNotFound = repository.ErrServerEndpointNotFound
+	ErrServerEndpointExists   = repository.ErrServerEndpointExists
+	ErrInvalidServerEndpoint  = errors.New("invalid server endpoint")
+	ErrNoActiveEndpoints      = i18n.NewError("no_active_endpoints")
+)
+
+// GetServerEndpoints returns all endpoints of a server, retired ones included
+func (s *ServerService) GetServerEndpoints(ctx context.Context, serverID uuid.UUID) ([]model.ServerEndpoint, error) {
+	return s.repo.GetServerEndpoints(ctx, serverID)
+}
+
+// GetServerEndpoint returns an endpoint by ID
+func (s *ServerService) GetServerEndpoint(ctx context.Context, id uuid.UUID) (*model.ServerEndpoint, error) {
+	return s.repo.GetServerEndpoint(ctx, id)
+}
+
+// CreateServerEndpoint adds an endpoint to a server
+func (s *ServerService) CreateServerEndpoint(ctx context.Context, endpoint *model.ServerEndpoint) error {
+	if err := validateServerEndpoint(endpoint); err != nil {
+		return err
+	}
+	return s.repo.CreateServerEndpoint(ctx, endpoint)
+}
+
+// UpdateServerEndpoint updates an endpoint's address, port, label and order
+func (s *ServerService) UpdateServerEndpoint(ctx context.Context, endpoint *model.ServerEndpoint) error {
+	if err := validateServerEndpoint(endpoint); err != nil {
+		return err
+	}
+	return s.repo.UpdateServerEndpoint(ctx, endpoint)
+}
+
+// RetireServerEndpoint stops offering an endpoint, e.g. after its IP was blocked.
+// Subscriptions are not touched: their keys simply no longer list the endpoint.
+func (s *ServerService) RetireServerEndpoint(ctx context.Context, endpoint *model.ServerEndpoint) error {
+	if endpoint.IsRetired() {
+		return nil
+	}
+	now := time.Now()
+	endpoint.RetiredAt = &now
+	return s.repo.UpdateServerEndpoint(ctx, endpoint)
+}
+
+// RestoreServerEndpoint offers a retired endpoint again
+func (s *ServerService) RestoreServerEndpoint(ctx context.Context, endpoint *model.ServerEndpoint) error {
+	endpoint.RetiredAt = nil
+	return s.repo.UpdateServerEndpoint(ctx, endpoint)
+}
+
+// DeleteServerEndpoint deletes an endpoint
+func (s *ServerService) DeleteServerEndpoint(ctx context.Context, endpoint *model.ServerEndpoint) error {
+	return s.repo.DeleteServerEndpoint(ctx, endpoint.ID)
+}
+
+func validateServerEndpoint(endpoint *model.ServerEndpoint) error {
+	endpoint.Address = strings.Trim(strings.TrimSpace(endpoint.Address), "[]")
+	endpoint.Label = strings.TrimSpace(endpoint.Label)
+	if endpoint.Address == "" {
+		return fmt.Errorf("%w: address is required", ErrInvalidServerEndpoint)
+	}
+	if strings.ContainsAny(endpoint.Address, "/?#@ ") {
+		return fmt.Errorf("%w: address must be a host name or IP address", ErrInvalidServerEndpoint)
+	}
+	if endpoint.Port < 0 || endpoint.Port > 65535 {
+		return fmt.Errorf("%w: port must be between 0 and 65535", ErrInvalidServerEndpoint)
+	}
+	return nil
+}
+
+// outputEndpoints returns the endpoints offered in a server's subscription keys
+// and whether the server has any endpoint, retired ones included
+func (s *ServerService) outputEndpoints(ctx context.Context, serverID uuid.UUID) ([]model.ServerEndpoint, bool) {
+	endpoints, err := s.repo.GetServerEndpoints(ctx, serverID)
+	if err != nil {
+		log.Printf("WARNING: Failed to get endpoints of server %s: %v", serverID, err)
+		return nil, false
+	}
+	return model.OutputEndpoints(endpoints), len(endpoints) > 0
+}
+
+// EndpointKeys expands a stored key into one key per output endpoint of the server.
+// Only keys of the primary inbound take the endpoint's port; additional inbounds
+// keep their own. A server without endpoints keeps the stored key as is; one whose
+// endpoints are all retired has no keys, since the stored key points at the
+// server's original address.
+func (s *ServerService) EndpointKeys(ctx context.Context, serverID uuid.UUID, key model.ConnectionKey, primary bool) []model.ConnectionKey {
+	if key.Key == "" {
+		return []model.ConnectionKey{key}
+	}
+	endpoints, configured := s.outputEndpoints(ctx, serverID)
+	if !configured {
+		return []model.ConnectionKey{key}
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	keys := make([]model.ConnectionKey, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		label := endpoint.Label
+		if label == "" && len(endpoints) > 1 {
+			label = endpoint.Address
+		}
+		port := 0
+		if primary {
+			port = endpoint.Port
+		}
+
+		link, err := panel.RewriteShareLink(key.Key, endpoint.Address, port, label)
+		if err != nil {
+			log.Printf("WARNING: Failed to build %s key for endpoint %s: %v", key.Tag, endpoint.Address, err)
+			continue
+		}
+
+		name := key.Name
+		if label != "" {
+			name += " · " + label
+		}
+		keys = append(keys, model.ConnectionKey{Tag: key.Tag, Name: name, Key: link, Endpoint: &endpoint.ID})
+	}
+	if len(keys) == 0 {
+		return []model.ConnectionKey{key}
+	}
+	return keys
+}
+
+// publicServers converts servers for users. Users ping the first endpoint offered
+// in keys rather than the server address, which may have been retired; a server
+// whose endpoints are all retired has no ping host.
+func (s *ServerService) publicServers(ctx context.Context, servers []model.Server) []model.ServerPublic {
+	endpoints, err := s.repo.GetAllServerEndpoints(ctx)
+	if err != nil {
+		log.Printf("WARNING: Failed to get server endpoints: %v", err)
+	}
+	byServer := make(map[uuid.UUID][]model.ServerEndpoint)
+	for _, endpoint := range endpoints {
+		byServer[endpoint.ServerID] = append(byServer[endpoint.ServerID], endpoint)
+	}
+
+	result := make([]model.ServerPublic, len(servers))
+	for i := range servers {
+		server := &servers[i]
+		result[i] = server.ToPublic()
+		if endpoints, ok := byServer[server.ID]; ok {
+			result[i].PingHost = ""
+			if output := model.OutputEndpoints(endpoints); len(output) > 0 && server.ServerPort != 0 {
+				result[i].PingHost = output[0].HostPort(server.ServerPort)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/repository"
+)
+
+// recentHealthChecks is the number of raw checks included in a server health report
+const recentHealthChecks = 60
+
+var (
+	ErrInvalidHealthPeriod = errors.New("invalid period, expected 24h, 7d or 30d")
+	ErrServerNotFound      = repository.ErrServerNotFound
+)
+
+// healthPeriods are the reporting periods of the health API
+var healthPeriods = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": HealthHistoryRetention,
+}
+
+// ParseHealthPeriod returns the duration of a reporting period; empty means 24h
+func ParseHealthPeriod(period string) (string, time.Duration, error) {
+	if period == "" {
+		period = "24h"
+	}
+	d, ok := healthPeriods[period]
+	if !ok {
+		return "", 0, ErrInvalidHealthPeriod
+	}
+	return period, d, nil
+}
+
+// GetHealthSummary returns the uptime and latency of every server over a period
+func (s *ServerService) GetHealthSummary(ctx context.Context, period string) ([]model.ServerHealthReport, error) {
+	period, d, err := ParseHealthPeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	servers, err := s.repo.GetAllServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := s.repo.GetAllServerHealthStats(ctx, time.Now().Add(-d))
+	if err != nil {
+		return nil, err
+	}
+
+	byServer := make(map[uuid.UUID]model.ServerHealthStats, len(stats))
+	for _, st := range stats {
+		byServer[st.ServerID] = st
+	}
+
+	result := make([]model.ServerHealthReport, len(servers))
+	for i, srv := range servers {
+		st, ok := byServer[srv.ID]
+		if !ok {
+			st = model.ServerHealthStats{ServerID: srv.ID}
+		}
+		result[i] = model.ServerHealthReport{
+			ServerID:    srv.ID,
+			Name:        srv.Name,
+			Status:      srv.Status,
+			LastCheckAt: srv.LastCheckAt,
+			Period:      period,
+			Stats:       st,
+		}
+	}
+	return result, nil
+}
+
+// GetServerHealth returns the uptime, latency, incidents and latest checks of a server over a period
+func (s *ServerService) GetServerHealth(ctx context.Context, serverID uuid.UUID, period string) (*model.ServerHealthReport, error) {
+	period, d, err := ParseHealthPeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	server, err := s.repo.GetServer(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	since := time.Now().Add(-d)
+	stats, err := s.repo.GetServerHealthStats(ctx, serverID, since)
+	if err != nil {
+		return nil, err
+	}
+	incidents, err := s.repo.GetServerIncidents(ctx, serverID, since)
+	if err != nil {
+		return nil, err
+	}
+	checks, err := s.repo.GetRecentHealthChecks(ctx, serverID, recentHealthChecks)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ServerHealthReport{
+		ServerID:     server.ID,
+		Name:         server.Name,
+		Status:       server.Status,
+		LastCheckAt:  server.LastCheckAt,
+		Period:       period,
+		Stats:        *stats,
+		Incidents:    incidents,
+		RecentChecks: checks,
+	}, nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/model"
+)
+
+// GetServerLoad returns the live load and subscription count of a server with
+// its load samples over a period (24h, 7d or 30d)
+func (s *ServerService) GetServerLoad(ctx context.Context, serverID uuid.UUID, period string) (*model.ServerLoadReport, error) {
+	period, d, err := ParseHealthPeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	server, err := s.repo.GetServer(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	samples, err := s.repo.GetServerLoadSamples(ctx, serverID, time.Now().Add(-d))
+	if err != nil {
+		return nil, err
+	}
+	if samples == nil {
+		samples = []model.ServerLoadSample{}
+	}
+
+	return &model.ServerLoadReport{
+		ServerID:      server.ID,
+		Name:          server.Name,
+		Capacity:      server.Capacity,
+		Subscriptions: server.CurrentLoad,
+		MaxOnline:     server.MaxOnline,
+		OnlineClients: server.OnlineClients,
+		Connections:   server.Connections,
+		ThroughputBps: server.ThroughputBps,
+		LoadPercent:   server.LoadPercent(),
+		SampledAt:     server.LoadSampledAt,
+		Period:        period,
+		Samples:       samples,
+	}, nil
+}
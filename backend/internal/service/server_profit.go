@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/model"
+)
+
+const (
+	// starUSD is what one Telegram Star is worth when withdrawn
+	starUSD = 0.013
+
+	// profitReportMonths is the number of months reported when no period is given
+	profitReportMonths = 3
+	// maxProfitReportMonths is the longest period of a profitability report
+	maxProfitReportMonths = 24
+
+	reportMonthLayout = "2006-01"
+)
+
+var ErrInvalidReportPeriod = errors.New("invalid report period")
+
+// GetProfitabilityReport compares revenue with cost for every server and month
+// from..to (YYYY-MM, inclusive). Empty bounds default to the last three months.
+// Servers are charged from their cost history, for the part of each month they
+// were active at the cost then in effect. Deleted servers keep their rows.
+func (s *ServerService) GetProfitabilityReport(ctx context.Context, from, to string) (*model.ProfitabilityReport, error) {
+	start, end, err := parseReportPeriod(from, to, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if s.ratesSvc == nil {
+		return nil, errors.New("rates service not configured")
+	}
+	exchange, err := s.ratesSvc.GetRates()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange rates: %w", err)
+	}
+	rates := model.ProfitRates{TONUSD: exchange.TONUSD, USDRUB: exchange.USDRUB, StarUSD: starUSD}
+
+	servers, err := s.repo.GetAllServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	costs, err := s.repo.GetServerCosts(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	revenue, err := s.repo.GetServerRevenue(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	users, err := s.repo.GetServerMonthlyUsers(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	traffic, err := s.repo.GetServerMonthlyTraffic(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.ProfitabilityReport{
+		From:    start.Format(reportMonthLayout),
+		To:      end.AddDate(0, -1, 0).Format(reportMonthLayout),
+		Rates:   rates,
+		Servers: []model.ServerProfit{},
+	}
+
+	// Rows describe existing servers as they are now and deleted ones by their cost history
+	known := make(map[uuid.UUID]model.ServerProfit)
+	for _, c := range costs {
+		known[c.ServerID] = model.ServerProfit{ServerID: c.ServerID, Name: c.Name, Country: c.Country, FlagEmoji: c.FlagEmoji}
+	}
+	for _, server := range servers {
+		known[server.ID] = model.ServerProfit{
+			ServerID:  server.ID,
+			Name:      server.Name,
+			Country:   server.Country,
+			FlagEmoji: server.FlagEmoji,
+			IsActive:  server.IsActive,
+		}
+	}
+
+	type rowKey struct {
+		server uuid.UUID
+		month  string
+	}
+	rows := make(map[rowKey]*model.ServerProfit)
+	var keys []rowKey
+	rowFor := func(serverID uuid.UUID, month time.Time) *model.ServerProfit {
+		key := rowKey{serverID, month.Format(reportMonthLayout)}
+		if r, ok := rows[key]; ok {
+			return r
+		}
+		info, ok := known[serverID]
+		if !ok {
+			return nil
+		}
+		info.Month = key.month
+		rows[key] = &info
+		keys = append(keys, key)
+		return &info
+	}
+
+	for month := start; month.Before(end); month = month.AddDate(0, 1, 0) {
+		monthEnd := month.AddDate(0, 1, 0)
+		for _, c := range costs {
+			active := activeDuration(c, month, monthEnd)
+			if active <= 0 {
+				continue
+			}
+			costUSD, ok := rates.ToUSD(c.MonthlyCost, c.CostCurrency)
+			if !ok {
+				log.Printf("WARNING: Server %s has cost in unknown currency %q", c.Name, c.CostCurrency)
+			}
+			r := rowFor(c.ServerID, month)
+			r.Cost = c.MonthlyCost
+			r.CostCurrency = c.CostCurrency
+			r.CostUSD += costUSD * float64(active) / float64(monthEnd.Sub(month))
+		}
+	}
+
+	for _, rev := range revenue {
+		amountUSD, ok := rates.ToUSD(rev.Amount, rev.Currency)
+		if !ok {
+			log.Printf("WARNING: Revenue in unknown currency %q left out of the profitability report", rev.Currency)
+			continue
+		}
+		var r *model.ServerProfit
+		if rev.ServerID != nil {
+			r = rowFor(*rev.ServerID, rev.Month)
+		}
+		if r == nil {
+			report.UnattributedUSD += amountUSD
+			continue
+		}
+		r.Payments += rev.Payments
+		r.RevenueUSD += amountUSD
+		switch rev.Currency {
+		case "TON":
+			r.RevenueTON += rev.Amount
+		case "XTR":
+			r.RevenueStars += rev.Amount
+		}
+	}
+
+	for _, u := range users {
+		if r := rows[rowKey{u.ServerID, u.Month.Format(reportMonthLayout)}]; r != nil {
+			r.Users = u.Users
+		}
+	}
+
+	for _, t := range traffic {
+		if r := rows[rowKey{t.ServerID, t.Month.Format(reportMonthLayout)}]; r != nil {
+			r.UploadBytes = t.UploadBytes
+			r.DownloadBytes = t.DownloadBytes
+		}
+	}
+
+	// Rows were added by cost, then by revenue; list them month by month
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].month < keys[j].month })
+	for _, key := range keys {
+		row := rows[key]
+		row.RevenueUSD = roundMoney(row.RevenueUSD)
+		row.CostUSD = roundMoney(row.CostUSD)
+		row.MarginUSD = roundMoney(row.RevenueUSD - row.CostUSD)
+		if row.RevenueUSD > 0 {
+			percent := math.Round(row.MarginUSD/row.RevenueUSD*1000) / 10
+			row.MarginPercent = &percent
+		}
+
+		report.RevenueUSD += row.RevenueUSD
+		report.CostUSD += row.CostUSD
+		report.Servers = append(report.Servers, *row)
+	}
+	report.RevenueUSD = roundMoney(report.RevenueUSD)
+	report.CostUSD = roundMoney(report.CostUSD)
+	report.MarginUSD = roundMoney(report.RevenueUSD - report.CostUSD)
+	report.UnattributedUSD = roundMoney(report.UnattributedUSD)
+
+	return report, nil
+}
+
+// activeDuration returns how long a cost period overlaps [from, to)
+func activeDuration(c model.ServerCost, from, to time.Time) time.Duration {
+	start := c.EffectiveFrom
+	if start.Before(from) {
+		start = from
+	}
+	stop := to
+	if c.EffectiveTo != nil && c.EffectiveTo.Before(stop) {
+		stop = *c.EffectiveTo
+	}
+	return stop.Sub(start)
+}
+
+// parseReportPeriod returns the start of the first month and the end of the last
+// month (exclusive) of a report, in UTC
+func parseReportPeriod(from, to string, now time.Time) (time.Time, time.Time, error) {
+	now = now.UTC()
+	last := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if to != "" {
+		t, err := time.Parse(reportMonthLayout, to)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: to must be YYYY-MM", ErrInvalidReportPeriod)
+		}
+		last = t
+	}
+
+	first := last.AddDate(0, 1-profitReportMonths, 0)
+	if from != "" {
+		t, err := time.Parse(reportMonthLayout, from)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: from must be YYYY-MM", ErrInvalidReportPeriod)
+		}
+		first = t
+	}
+
+	if first.After(last) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from is after to", ErrInvalidReportPeriod)
+	}
+	if first.AddDate(0, maxProfitReportMonths, 0).Before(last.AddDate(0, 1, 0)) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: at most %d months", ErrInvalidReportPeriod, maxProfitReportMonths)
+	}
+
+	return first, last.AddDate(0, 1, 0), nil
+}
+
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
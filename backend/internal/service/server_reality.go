@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/panel"
+	"github.com/zyvpn/backend/internal/xui"
+)
+
+// RealityChange reports that the Reality parameters of a server's inbound changed on the panel
+type RealityChange struct {
+	Server     model.Server
+	Changes    []string // what changed on the panel
+	Followed   []string // configured values updated to follow the panel
+	Mismatches []string // configured values the panel no longer accepts
+}
+
+// supportsRealityDiscovery reports whether connection parameters can be read from the server's panel
+func supportsRealityDiscovery(server *model.Server) bool {
+	return server.PanelType == "" || server.PanelType == model.PanelTypeXUI
+}
+
+// DiscoverReality reads the primary inbound from the panel, fills connection parameters
+// the admin left empty and records the panel's values. It returns the configured values
+// that the inbound would reject. The server is not saved.
+func (s *ServerService) DiscoverReality(ctx context.Context, server *model.Server) ([]string, error) {
+	if !supportsRealityDiscovery(server) {
+		return nil, nil
+	}
+
+	// The server may not be saved yet, so use an uncached client
+	client, err := panel.New(server, server.XUIInboundID)
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := client.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	info, err := client.GetInboundInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inbound from panel: %w", err)
+	}
+
+	if server.ServerPort == 0 {
+		server.ServerPort = info.Port
+	}
+	if info.Security == xui.SecurityReality {
+		if server.PublicKey == "" {
+			server.PublicKey = info.PublicKey
+		}
+		if server.ShortID == "" {
+			server.ShortID = info.ShortID
+		}
+		if server.ServerName == "" {
+			server.ServerName = info.ServerName
+		}
+	}
+	recordPanelReality(server, info)
+
+	return server.RealityMismatches(), nil
+}
+
+// recordPanelReality stores the inbound's values on the server
+func recordPanelReality(server *model.Server, info *panel.InboundInfo) {
+	now := time.Now()
+	server.PanelPort = info.Port
+	server.PanelPublicKey = info.PublicKey
+	server.PanelShortIDs = info.ShortIDs
+	server.PanelServerNames = info.ServerNames
+	server.RealityCheckedAt = &now
+}
+
+// CheckRealityParams re-reads the primary inbound of every active 3x-ui server and
+// reports servers whose Reality keys, short IDs, SNI or port changed on the panel.
+// Configured values that matched the old panel values follow the panel; deliberate
+// overrides are left alone and reported as mismatches.
+func (s *ServerService) CheckRealityParams(ctx context.Context) ([]RealityChange, error) {
+	servers, err := s.repo.GetAllServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []RealityChange
+	for i := range servers {
+		server := &servers[i]
+		if !server.IsActive || !supportsRealityDiscovery(server) {
+			continue
+		}
+
+		client, err := s.GetPanelForInbound(server, server.XUIInboundID)
+		if err != nil {
+			log.Printf("[Reality] Failed to get panel client for server %s: %v", server.Name, err)
+			continue
+		}
+		info, err := client.GetInboundInfo(ctx)
+		if err != nil {
+			log.Printf("[Reality] Failed to read inbound of server %s: %v", server.Name, err)
+			continue
+		}
+
+		firstCheck := server.RealityCheckedAt == nil
+		change := RealityChange{Changes: realityChanges(server, info)}
+
+		if !firstCheck && len(change.Changes) > 0 {
+			change.Followed = followPanelReality(server, info)
+		}
+		recordPanelReality(server, info)
+
+		if err := s.repo.UpdateServerReality(ctx, server); err != nil {
+			log.Printf("[Reality] Failed to save inbound parameters of server %s: %v", server.Name, err)
+			continue
+		}
+
+		if firstCheck || len(change.Changes) == 0 {
+			continue
+		}
+
+		if len(change.Followed) > 0 {
+			// Links are built from the server record, drop clients holding the old one
+			s.invalidateClients(server.ID)
+		}
+		change.Server = *server
+		change.Mismatches = server.RealityMismatches()
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// realityChanges compares the inbound with the values recorded on the previous check
+func realityChanges(server *model.Server, info *panel.InboundInfo) []string {
+	var changes []string
+	if info.Port != server.PanelPort {
+		changes = append(changes, fmt.Sprintf("port %d → %d", server.PanelPort, info.Port))
+	}
+	if info.PublicKey != server.PanelPublicKey {
+		changes = append(changes, "public key")
+	}
+	if !slices.Equal(info.ShortIDs, server.PanelShortIDs) {
+		changes = append(changes, "short IDs")
+	}
+	if !slices.Equal(info.ServerNames, server.PanelServerNames) {
+		changes = append(changes, "server names (SNI)")
+	}
+	return changes
+}
+
+// followPanelReality updates configured values that were taken from the panel
+// and are no longer accepted by it. It returns the names of the updated fields.
+func followPanelReality(server *model.Server, info *panel.InboundInfo) []string {
+	var followed []string
+	if server.ServerPort == server.PanelPort && info.Port != server.PanelPort {
+		server.ServerPort = info.Port
+		followed = append(followed, "server_port")
+	}
+	if server.PublicKey == server.PanelPublicKey && info.PublicKey != server.PanelPublicKey {
+		server.PublicKey = info.PublicKey
+		followed = append(followed, "public_key")
+	}
+	if slices.Contains(server.PanelShortIDs, server.ShortID) && !slices.Contains(info.ShortIDs, server.ShortID) {
+		server.ShortID = info.ShortID
+		followed = append(followed, "short_id")
+	}
+	if slices.Contains(server.PanelServerNames, server.ServerName) && !slices.Contains(info.ServerNames, server.ServerName) {
+		server.ServerName = info.ServerName
+		followed = append(followed, "server_name")
+	}
+	return followed
+}
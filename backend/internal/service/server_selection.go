@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/i18n"
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/repository"
+)
+
+// serverSelectionSetting is the settings key holding the JSON ServerSelectionConfig
+const serverSelectionSetting = "server_selection"
+
+var (
+	ErrServerFull             = i18n.NewError("server_full")
+	ErrServerLocked           = i18n.NewError("server_locked")
+	ErrInvalidServerSelection = errors.New("invalid server selection settings")
+	ErrPlanNotFound           = repository.ErrPlanNotFound
+)
+
+// SelectionContext is what strategies know about the user and the servers
+type SelectionContext struct {
+	PreferredCountries []string                            // country codes, most preferred first
+	Uptime             map[uuid.UUID]*float64              // uptime percentage over the last 24h
+	Latency            map[uuid.UUID]model.LatencyEstimate // measured from the user's device or network
+}
+
+// SelectionStrategy scores a candidate server; the highest score wins
+type SelectionStrategy interface {
+	Score(server *model.Server, sc *SelectionContext, cfg *model.ServerSelectionConfig) float64
+}
+
+var selectionStrategies = map[string]SelectionStrategy{
+	model.SelectionLeastLoaded: leastLoadedStrategy{},
+	model.SelectionWeighted:    weightedStrategy{},
+}
+
+// leastLoadedStrategy prefers the lowest load/capacity ratio
+type leastLoadedStrategy struct{}
+
+func (leastLoadedStrategy) Score(server *model.Server, _ *SelectionContext, _ *model.ServerSelectionConfig) float64 {
+	return -server.LoadRatio()
+}
+
+// weightedStrategy combines free capacity, ping, uptime and country preference.
+// The latency measured by the user, or by the user's network, is used as ping
+// when known; otherwise the backend's own ping to the server.
+type weightedStrategy struct{}
+
+func (weightedStrategy) Score(server *model.Server, sc *SelectionContext, cfg *model.ServerSelectionConfig) float64 {
+	load := 1 - math.Min(server.LoadRatio(), 1)
+
+	ping := 0.5 // not measured yet
+	if latency, ok := sc.Latency[server.ID]; ok {
+		ping = 1 - math.Min(float64(latency.LatencyMs), float64(cfg.MaxPingMs))/float64(cfg.MaxPingMs)
+	} else if server.PingMs != nil {
+		ping = 1 - math.Min(float64(*server.PingMs), float64(cfg.MaxPingMs))/float64(cfg.MaxPingMs)
+	}
+
+	health := 0.5 // no checks yet
+	if uptime := sc.Uptime[server.ID]; uptime != nil {
+		health = *uptime / 100
+	}
+	if server.Status == model.ServerStatusDegraded {
+		health /= 2
+	}
+
+	country := 0.0
+	if i := slices.Index(sc.PreferredCountries, server.CountryCode()); i >= 0 {
+		country = 1 - float64(i)/float64(len(sc.PreferredCountries))
+	}
+
+	return cfg.LoadWeight*load + cfg.PingWeight*ping + cfg.HealthWeight*health + cfg.CountryWeight*country
+}
+
+// GetBestServer picks a server for a new subscription of the user on the plan
+// with the configured strategy. Full servers, servers outside the plan's groups
+// and servers whose panel is down are never picked. Online servers are preferred; when none is left, degraded and not yet
+// checked servers are used. Offline servers are not used at all.
+func (s *ServerService) GetBestServer(ctx context.Context, userID int64, plan *model.Plan) (*model.Server, error) {
+	cfg := s.GetSelectionConfig(ctx)
+	strategy := selectionStrategies[cfg.Strategy]
+
+	servers, err := s.repo.GetActiveServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var online, fallback []*model.Server
+	for i := range servers {
+		server := &servers[i]
+		if server.IsFull() || !plan.AllowsServerGroup(server.Group) || s.CheckPanel(server.ID) != nil {
+			continue
+		}
+		switch server.Status {
+		case model.ServerStatusOnline:
+			online = append(online, server)
+		case model.ServerStatusOffline:
+		default:
+			fallback = append(fallback, server)
+		}
+	}
+	candidates := online
+	if len(candidates) == 0 {
+		candidates = fallback
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoServersAvailable
+	}
+
+	sc := &SelectionContext{PreferredCountries: s.preferredCountries(ctx, userID, cfg)}
+	if cfg.Strategy == model.SelectionWeighted && cfg.HealthWeight > 0 {
+		sc.Uptime, err = s.uptimeSince(ctx, time.Now().Add(-24*time.Hour))
+		if err != nil {
+			log.Printf("WARNING: Failed to get server uptime for selection: %v", err)
+		}
+	}
+	if cfg.Strategy == model.SelectionWeighted && cfg.PingWeight > 0 {
+		sc.Latency = s.clientLatencies(ctx, userID)
+	}
+
+	scores := make(map[uuid.UUID]float64, len(candidates))
+	for _, server := range candidates {
+		scores[server.ID] = strategy.Score(server, sc, cfg)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if scores[a.ID] != scores[b.ID] {
+			return scores[a.ID] > scores[b.ID]
+		}
+		if a.Capacity != b.Capacity {
+			return a.Capacity > b.Capacity
+		}
+		return a.SortOrder < b.SortOrder
+	})
+
+	return candidates[0], nil
+}
+
+// preferredCountries returns the country of the user's last server followed by
+// the countries preferred for the user's language
+func (s *ServerService) preferredCountries(ctx context.Context, userID int64, cfg *model.ServerSelectionConfig) []string {
+	var countries []string
+
+	subs, err := s.repo.GetUserSubscriptions(ctx, userID)
+	if err == nil {
+		for _, sub := range subs {
+			if sub.ServerID == nil {
+				continue
+			}
+			if server, err := s.repo.GetServer(ctx, *sub.ServerID); err == nil {
+				countries = append(countries, server.CountryCode())
+			}
+			break
+		}
+	}
+
+	user, err := s.repo.GetUser(ctx, userID)
+	if err == nil && user.LanguageCode != nil {
+		for _, code := range cfg.PreferredCountries(*user.LanguageCode) {
+			if !slices.Contains(countries, code) {
+				countries = append(countries, code)
+			}
+		}
+	}
+
+	return countries
+}
+
+func (s *ServerService) uptimeSince(ctx context.Context, since time.Time) (map[uuid.UUID]*float64, error) {
+	stats, err := s.repo.GetAllServerHealthStats(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	uptime := make(map[uuid.UUID]*float64, len(stats))
+	for _, st := range stats {
+		uptime[st.ServerID] = st.UptimePercent
+	}
+	return uptime, nil
+}
+
+// CheckAccepting returns ErrServerLocked when the plan does not include the
+// server's group and ErrServerFull when the server reached its capacity. The
+// active subscriptions are counted now rather than taken from the last load sample.
+func (s *ServerService) CheckAccepting(ctx context.Context, serverID uuid.UUID, plan *model.Plan) error {
+	server, err := s.repo.GetServer(ctx, serverID)
+	if err != nil {
+		return err
+	}
+	if !plan.AllowsServerGroup(server.Group) {
+		return ErrServerLocked
+	}
+	if count, err := s.repo.CountActiveSubscriptionsByServer(ctx, serverID); err == nil {
+		server.CurrentLoad = count
+	}
+	if server.IsFull() {
+		return ErrServerFull
+	}
+	return nil
+}
+
+// GetSelectionConfig returns the server selection settings, or the defaults
+// when they are not set or cannot be read
+func (s *ServerService) GetSelectionConfig(ctx context.Context) *model.ServerSelectionConfig {
+	return loadSelectionConfig(ctx, s.repo)
+}
+
+func loadSelectionConfig(ctx context.Context, repo *repository.Repository) *model.ServerSelectionConfig {
+	value, err := repo.GetSetting(ctx, serverSelectionSetting)
+	if err != nil {
+		if !errors.Is(err, repository.ErrSettingNotFound) {
+			log.Printf("WARNING: Failed to read server selection settings: %v", err)
+		}
+		return model.DefaultServerSelectionConfig()
+	}
+
+	cfg, err := ParseSelectionConfig([]byte(value))
+	if err != nil {
+		log.Printf("WARNING: %v, using defaults", err)
+		return model.DefaultServerSelectionConfig()
+	}
+	return cfg
+}
+
+// ParseSelectionConfig decodes and validates server selection settings.
+// Omitted fields keep their default values.
+func ParseSelectionConfig(data []byte) (*model.ServerSelectionConfig, error) {
+	cfg := model.DefaultServerSelectionConfig()
+	cfg.CountryPreferences = nil // decoding merges into a non-nil map
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidServerSelection, err)
+	}
+	if cfg.CountryPreferences == nil {
+		cfg.CountryPreferences = model.DefaultServerSelectionConfig().CountryPreferences
+	}
+	if err := validateSelectionConfig(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// validateSelectionConfig checks the strategy and weights and normalizes country preferences
+func validateSelectionConfig(cfg *model.ServerSelectionConfig) error {
+	if _, ok := selectionStrategies[cfg.Strategy]; !ok {
+		names := make([]string, 0, len(selectionStrategies))
+		for name := range selectionStrategies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("%w: strategy must be one of %s", ErrInvalidServerSelection, strings.Join(names, ", "))
+	}
+	for _, w := range []float64{cfg.LoadWeight, cfg.PingWeight, cfg.HealthWeight, cfg.CountryWeight} {
+		if w < 0 || w > 1 {
+			return fmt.Errorf("%w: weights must be between 0 and 1", ErrInvalidServerSelection)
+		}
+	}
+	if cfg.Strategy == model.SelectionWeighted && cfg.LoadWeight+cfg.PingWeight+cfg.HealthWeight+cfg.CountryWeight == 0 {
+		return fmt.Errorf("%w: at least one weight must be positive", ErrInvalidServerSelection)
+	}
+	if cfg.MaxPingMs <= 0 {
+		return fmt.Errorf("%w: max_ping_ms must be positive", ErrInvalidServerSelection)
+	}
+
+	prefs := make(map[string][]string, len(cfg.CountryPreferences))
+	for lang, countries := range cfg.CountryPreferences {
+		codes := make([]string, 0, len(countries))
+		for _, c := range countries {
+			code := strings.ToUpper(strings.TrimSpace(c))
+			if len(code) != 2 {
+				return fmt.Errorf("%w: invalid country code %q for language %q", ErrInvalidServerSelection, c, lang)
+			}
+			codes = append(codes, code)
+		}
+		prefs[strings.ToLower(strings.TrimSpace(lang))] = codes
+	}
+	cfg.CountryPreferences = prefs
+	return nil
+}
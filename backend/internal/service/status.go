@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/repository"
+)
+
+// statusCacheTTL limits how often the unauthenticated status page hits the database
+const statusCacheTTL = 30 * time.Second
+
+// adminNoticesLimit is the number of notices returned to admins
+const adminNoticesLimit = 100
+
+var (
+	ErrStatusNoticeNotFound = repository.ErrStatusNoticeNotFound
+	ErrInvalidStatusNotice  = errors.New("invalid status notice")
+)
+
+// StatusService builds the public status page and manages admin notices
+type StatusService struct {
+	repo *repository.Repository
+
+	mu       sync.Mutex
+	page     *model.StatusPage
+	cachedAt time.Time
+}
+
+func NewStatusService(repo *repository.Repository) *StatusService {
+	return &StatusService{repo: repo}
+}
+
+// GetStatusPage returns the public status of the service, cached for statusCacheTTL
+func (s *StatusService) GetStatusPage(ctx context.Context) (*model.StatusPage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.page != nil && time.Since(s.cachedAt) < statusCacheTTL {
+		return s.page, nil
+	}
+
+	page, err := s.buildStatusPage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.page = page
+	s.cachedAt = time.Now()
+	return page, nil
+}
+
+func (s *StatusService) buildStatusPage(ctx context.Context) (*model.StatusPage, error) {
+	now := time.Now()
+
+	servers, err := s.repo.GetActiveServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	uptime24h, err := s.uptimeSince(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	uptime7d, err := s.uptimeSince(ctx, now.Add(-7*24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	incidents, err := s.repo.GetOpenServerIncidents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	notices, err := s.repo.GetCurrentStatusNotices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[uuid.UUID]string, len(servers))
+	for _, srv := range servers {
+		names[srv.ID] = srv.Name
+	}
+
+	// Servers under announced maintenance, or all of them for a service-wide one
+	maintenance := make(map[uuid.UUID]bool)
+	allMaintenance := false
+	page := &model.StatusPage{
+		Locations: make([]model.LocationStatus, 0, len(servers)),
+		Incidents: []model.StatusIncident{},
+		Notices:   make([]model.PublicNotice, 0, len(notices)),
+		UpdatedAt: now,
+	}
+
+	for _, n := range notices {
+		location := ""
+		if n.ServerID != nil {
+			name, ok := names[*n.ServerID]
+			if !ok {
+				continue // inactive server
+			}
+			location = name
+		}
+		if n.Kind == model.NoticeKindMaintenance && n.IsActiveAt(now) {
+			if n.ServerID == nil {
+				allMaintenance = true
+			} else {
+				maintenance[*n.ServerID] = true
+			}
+		}
+		page.Notices = append(page.Notices, model.PublicNotice{
+			Kind:     n.Kind,
+			Title:    n.Title,
+			Message:  n.Message,
+			Location: location,
+			StartsAt: n.StartsAt,
+			EndsAt:   n.EndsAt,
+		})
+	}
+
+	for _, inc := range incidents {
+		name, ok := names[inc.ServerID]
+		if !ok || allMaintenance || maintenance[inc.ServerID] {
+			continue
+		}
+		page.Incidents = append(page.Incidents, model.StatusIncident{
+			Location:  name,
+			Status:    inc.Status,
+			StartedAt: inc.StartedAt,
+		})
+	}
+
+	var offline, degraded int
+	for _, srv := range servers {
+		status := srv.Status
+		switch {
+		case allMaintenance || maintenance[srv.ID]:
+			status = model.LocationStatusMaintenance
+		case status == model.ServerStatusOffline:
+			offline++
+		case status == model.ServerStatusDegraded:
+			degraded++
+		}
+
+		page.Locations = append(page.Locations, model.LocationStatus{
+			Name:      srv.Name,
+			Country:   srv.Country,
+			City:      srv.City,
+			FlagEmoji: srv.FlagEmoji,
+			Status:    status,
+			Uptime24h: uptime24h[srv.ID],
+			Uptime7d:  uptime7d[srv.ID],
+		})
+	}
+
+	switch {
+	case len(servers) > 0 && offline == len(servers):
+		page.Status = model.StatusMajorOutage
+	case offline > 0:
+		page.Status = model.StatusPartialOutage
+	case degraded > 0:
+		page.Status = model.StatusDegraded
+	default:
+		page.Status = model.StatusOperational
+	}
+
+	return page, nil
+}
+
+// uptimeSince returns the uptime percentage of every server with health checks since the given time
+func (s *StatusService) uptimeSince(ctx context.Context, since time.Time) (map[uuid.UUID]*float64, error) {
+	stats, err := s.repo.GetAllServerHealthStats(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	uptime := make(map[uuid.UUID]*float64, len(stats))
+	for _, st := range stats {
+		uptime[st.ServerID] = st.UptimePercent
+	}
+	return uptime, nil
+}
+
+// invalidate drops the cached status page so notice changes show up immediately
+func (s *StatusService) invalidate() {
+	s.mu.Lock()
+	s.page = nil
+	s.mu.Unlock()
+}
+
+// --- Admin notices ---
+
+// StatusNoticeParams holds the fields of a notice; nil fields are left unchanged on update
+type StatusNoticeParams struct {
+	Kind     *string
+	Title    *string
+	Message  *string
+	ServerID *uuid.UUID
+	StartsAt *time.Time
+	EndsAt   *time.Time
+
+	ClearServer bool // make the notice service-wide
+}
+
+// GetNotices returns the latest notices for admins
+func (s *StatusService) GetNotices(ctx context.Context) ([]model.StatusNotice, error) {
+	return s.repo.GetStatusNotices(ctx, adminNoticesLimit)
+}
+
+// CreateNotice posts an incident or maintenance notice
+func (s *StatusService) CreateNotice(ctx context.Context, adminID int64, params StatusNoticeParams) (*model.StatusNotice, error) {
+	notice := &model.StatusNotice{StartsAt: time.Now(), CreatedBy: adminID}
+	applyNoticeParams(notice, params)
+	if err := s.validateNotice(ctx, notice); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateStatusNotice(ctx, notice); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+
+	_ = s.repo.LogAdminAction(ctx, adminID, model.AdminActionCreateNotice, nil, map[string]interface{}{
+		"notice_id": notice.ID,
+		"kind":      notice.Kind,
+		"title":     notice.Title,
+	})
+
+	return notice, nil
+}
+
+// UpdateNotice changes a notice, e.g. to post an update or set its end time
+func (s *StatusService) UpdateNotice(ctx context.Context, adminID int64, id uuid.UUID, params StatusNoticeParams) (*model.StatusNotice, error) {
+	notice, err := s.repo.GetStatusNotice(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	applyNoticeParams(notice, params)
+	if err := s.validateNotice(ctx, notice); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateStatusNotice(ctx, notice); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+
+	_ = s.repo.LogAdminAction(ctx, adminID, model.AdminActionUpdateNotice, nil, map[string]interface{}{
+		"notice_id": notice.ID,
+	})
+
+	return notice, nil
+}
+
+// ResolveNotice ends a notice now. A scheduled notice that has not started is cancelled.
+func (s *StatusService) ResolveNotice(ctx context.Context, adminID int64, id uuid.UUID) (*model.StatusNotice, error) {
+	notice, err := s.repo.GetStatusNotice(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	params := StatusNoticeParams{EndsAt: &now}
+	if notice.StartsAt.After(now) {
+		params.StartsAt = &now
+	}
+	return s.UpdateNotice(ctx, adminID, id, params)
+}
+
+// DeleteNotice removes a notice
+func (s *StatusService) DeleteNotice(ctx context.Context, adminID int64, id uuid.UUID) error {
+	if err := s.repo.DeleteStatusNotice(ctx, id); err != nil {
+		return err
+	}
+	s.invalidate()
+
+	_ = s.repo.LogAdminAction(ctx, adminID, model.AdminActionDeleteNotice, nil, map[string]interface{}{
+		"notice_id": id,
+	})
+
+	return nil
+}
+
+func applyNoticeParams(notice *model.StatusNotice, params StatusNoticeParams) {
+	if params.Kind != nil {
+		notice.Kind = *params.Kind
+	}
+	if params.Title != nil {
+		notice.Title = strings.TrimSpace(*params.Title)
+	}
+	if params.Message != nil {
+		notice.Message = strings.TrimSpace(*params.Message)
+	}
+	if params.ServerID != nil {
+		notice.ServerID = params.ServerID
+	}
+	if params.ClearServer {
+		notice.ServerID = nil
+	}
+	if params.StartsAt != nil {
+		notice.StartsAt = *params.StartsAt
+	}
+	if params.EndsAt != nil {
+		notice.EndsAt = params.EndsAt
+	}
+}
+
+func (s *StatusService) validateNotice(ctx context.Context, notice *model.StatusNotice) error {
+	if notice.Kind != model.NoticeKindIncident && notice.Kind != model.NoticeKindMaintenance {
+		return fmt.Errorf("%w: kind must be %q or %q", ErrInvalidStatusNotice, model.NoticeKindIncident, model.NoticeKindMaintenance)
+	}
+	if notice.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidStatusNotice)
+	}
+	if notice.EndsAt != nil && notice.EndsAt.Before(notice.StartsAt) {
+		return fmt.Errorf("%w: ends_at is before starts_at", ErrInvalidStatusNotice)
+	}
+	if notice.ServerID != nil {
+		if _, err := s.repo.GetServer(ctx, *notice.ServerID); err != nil {
+			return fmt.Errorf("%w: server not found", ErrInvalidStatusNotice)
+		}
+	}
+	return nil
+}
@@ -9,16 +9,17 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/zyvpn/backend/internal/config"
+	"github.com/zyvpn/backend/internal/i18n"
 	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/panel"
 	"github.com/zyvpn/backend/internal/repository"
-	"github.com/zyvpn/backend/internal/xui"
 )
 
 var (
-	ErrSubscriptionActive    = errors.New("У пользователя уже есть активная подписка")
-	ErrSubscriptionNotActive = errors.New("Подписка неактивна")
-	ErrTrialAlreadyUsed      = errors.New("Пробный период уже использован")
-	ErrNoServersAvailable    = errors.New("Нет доступных серверов")
+	ErrSubscriptionActive    = i18n.NewError("subscription_active")
+	ErrSubscriptionNotActive = i18n.NewError("subscription_not_active")
+	ErrTrialAlreadyUsed      = i18n.NewError("trial_already_used")
+	ErrNoServersAvailable    = i18n.NewError("no_servers_available")
 )
 
 type SubscriptionService struct {
@@ -40,19 +41,124 @@ func (s *SubscriptionService) SetServerService(serverSvc *ServerService) {
 	s.serverSvc = serverSvc
 }
 
-// getXUIClientForSubscription returns the appropriate XUI client for a subscription
-func (s *SubscriptionService) getXUIClientForSubscription(ctx context.Context, sub *model.Subscription) (*xui.Client, *model.Server, error) {
+// getPanelForSubscription returns the panel client for a subscription
+func (s *SubscriptionService) getPanelForSubscription(ctx context.Context, sub *model.Subscription) (panel.Panel, *model.Server, error) {
 	if s.serverSvc == nil {
 		return nil, nil, fmt.Errorf("server service not available")
 	}
 
 	// If subscription has a server_id, use it
 	if sub.ServerID != nil {
-		return s.serverSvc.GetXUIClient(ctx, *sub.ServerID)
+		return s.serverSvc.GetPanel(ctx, *sub.ServerID)
 	}
 
 	// Fall back to default server for old subscriptions without server_id
-	return s.serverSvc.GetXUIClientForDefault(ctx)
+	return s.serverSvc.GetPanelForDefault(ctx)
+}
+
+// CheckServerAvailable returns a panel.UnavailableError while the server's panel
+// is known to be down, ErrServerLocked when the plan does not include the server
+// and ErrServerFull when the server reached its capacity
+func (s *SubscriptionService) CheckServerAvailable(ctx context.Context, serverID uuid.UUID, plan *model.Plan) error {
+	if s.serverSvc == nil {
+		return nil
+	}
+	if err := s.serverSvc.CheckPanel(serverID); err != nil {
+		return err
+	}
+	return s.serverSvc.CheckAccepting(ctx, serverID, plan)
+}
+
+// CheckSwitchAvailable checks that the user's active subscription can be switched to the server
+func (s *SubscriptionService) CheckSwitchAvailable(ctx context.Context, userID int64, serverID uuid.UUID) error {
+	if s.serverSvc == nil {
+		return nil
+	}
+	plan, err := s.serverSvc.GetUserPlan(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.CheckServerAvailable(ctx, serverID, plan)
+}
+
+// CheckPlanChange checks that the user's active subscription, if any, can move to
+// the plan. It returns ErrServerLocked when the plan does not include the
+// subscription's server and no server of the plan can take it.
+func (s *SubscriptionService) CheckPlanChange(ctx context.Context, userID int64, plan *model.Plan, serverID *uuid.UUID) error {
+	sub, err := s.repo.GetActiveSubscription(ctx, userID)
+	if err != nil || !sub.IsActive() || sub.PlanID == plan.ID {
+		return nil
+	}
+	_, err = s.planChangeTarget(ctx, sub, plan, serverID)
+	return err
+}
+
+// planChangeTarget returns the server a subscription moves to when it changes to
+// the plan: nil when the plan includes its current server, otherwise the chosen
+// server or the best one the plan allows
+func (s *SubscriptionService) planChangeTarget(ctx context.Context, sub *model.Subscription, plan *model.Plan, serverID *uuid.UUID) (*model.Server, error) {
+	if s.serverSvc == nil {
+		return nil, nil
+	}
+	server, err := s.subscriptionServer(ctx, sub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription server: %w", err)
+	}
+	if plan.AllowsServerGroup(server.Group) {
+		return nil, nil
+	}
+
+	if serverID != nil && s.CheckServerAvailable(ctx, *serverID, plan) == nil {
+		return s.serverSvc.GetServer(ctx, *serverID)
+	}
+	target, err := s.serverSvc.GetBestServer(ctx, sub.UserID, plan)
+	if err != nil {
+		return nil, ErrServerLocked
+	}
+	return target, nil
+}
+
+// changePlan moves an active subscription to the plan. With a target server the
+// subscription is switched to it, since the plan does not include its server;
+// otherwise its additional inbounds are reconciled with the plan. If the switch
+// fails the subscription stays on its previous plan.
+func (s *SubscriptionService) changePlan(ctx context.Context, sub *model.Subscription, plan *model.Plan, target *model.Server) {
+	if err := s.repo.UpdateSubscriptionPlan(ctx, sub.ID, plan.ID); err != nil {
+		log.Printf("WARNING: Failed to move subscription %s to plan %s: %v", sub.ID, plan.ID, err)
+		return
+	}
+
+	if target != nil {
+		if _, err := s.SwitchServer(ctx, sub.UserID, target.ID); err != nil {
+			log.Printf("WARNING: Failed to move subscription %s to server %s of plan %s: %v", sub.ID, target.ID, plan.ID, err)
+			if err := s.repo.UpdateSubscriptionPlan(ctx, sub.ID, sub.PlanID); err != nil {
+				log.Printf("WARNING: Failed to restore plan of subscription %s: %v", sub.ID, err)
+			}
+		}
+		return
+	}
+
+	updated, err := s.repo.GetSubscription(ctx, sub.ID)
+	if err != nil {
+		log.Printf("WARNING: Failed to get subscription %s: %v", sub.ID, err)
+		return
+	}
+	server, err := s.subscriptionServer(ctx, updated)
+	if err != nil {
+		log.Printf("WARNING: Failed to get server of subscription %s: %v", sub.ID, err)
+		return
+	}
+	s.reconcileExtraInbounds(ctx, updated, server, plan)
+}
+
+// remainingLimits returns the traffic in GB and days left on a subscription, at least one of each
+func remainingLimits(sub *model.Subscription) (int64, int) {
+	days := 1
+	if sub.ExpiresAt != nil {
+		days = max(int(time.Until(*sub.ExpiresAt).Hours()/24), 1)
+	}
+	trafficGB := max((sub.TrafficLimit-sub.TrafficUsed)/(1024*1024*1024), 1)
+	return trafficGB, days
 }
 
 func min(a, b int) int {
@@ -78,11 +184,24 @@ func (s *SubscriptionService) CreateSubscriptionWithServer(ctx context.Context,
 	// Check for existing active subscription - extend it instead of creating new
 	existing, err := s.repo.GetActiveSubscription(ctx, userID)
 	if err == nil && existing.IsActive() {
+		// A plan that does not include the current server needs a server to move to
+		var target *model.Server
+		if existing.PlanID != plan.ID {
+			if target, err = s.planChangeTarget(ctx, existing, plan, serverID); err != nil {
+				return nil, err
+			}
+		}
+
 		// Extend existing subscription with new plan's days and traffic
 		log.Printf("Extending existing subscription %s for user %d by %d days and %d GB traffic", existing.ID, userID, plan.DurationDays, plan.TrafficGB)
 		if err := s.ExtendSubscriptionWithTraffic(ctx, existing.ID, plan.DurationDays, plan.TrafficBytes()); err != nil {
 			return nil, fmt.Errorf("failed to extend subscription: %w", err)
 		}
+		// The subscription moves to the purchased plan, so an upgrade unlocks its
+		// server groups and a downgrade gives up the ones it does not include
+		if existing.PlanID != plan.ID {
+			s.changePlan(ctx, existing, plan, target)
+		}
 		// Return updated subscription
 		return s.repo.GetSubscription(ctx, existing.ID)
 	}
@@ -91,28 +210,37 @@ func (s *SubscriptionService) CreateSubscriptionWithServer(ctx context.Context,
 		return nil, ErrNoServersAvailable
 	}
 
-	// Get XUI client for the selected server (or best available)
-	var xuiClientAPI *xui.Client
+	// Get panel client for the selected server (or best available)
+	var panelClient panel.Panel
 	var server *model.Server
 
 	if serverID != nil {
-		xuiClientAPI, server, err = s.serverSvc.GetXUIClient(ctx, *serverID)
+		// The server was chosen when paying; if it filled up or went down since,
+		// the subscription is provisioned on the best other server instead
+		if err := s.CheckServerAvailable(ctx, *serverID, plan); err != nil {
+			log.Printf("WARNING: Chosen server %s unavailable for user %d, selecting another: %v", *serverID, userID, err)
+			serverID = nil
+		}
+	}
+
+	if serverID != nil {
+		panelClient, server, err = s.serverSvc.GetPanel(ctx, *serverID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get server: %w", err)
 		}
 	} else {
-		// Auto-select best server based on load balancing
-		server, err = s.serverSvc.GetBestServer(ctx)
+		// Auto-select best server with the configured selection strategy
+		server, err = s.serverSvc.GetBestServer(ctx, userID, plan)
 		if err != nil {
 			return nil, ErrNoServersAvailable
 		}
-		xuiClientAPI, server, err = s.serverSvc.GetXUIClient(ctx, server.ID)
+		panelClient, server, err = s.serverSvc.GetPanel(ctx, server.ID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get server client: %w", err)
 		}
 	}
 
-	// Generate unique email for 3x-ui client
+	// Generate unique email for panel client
 	email := fmt.Sprintf("user_%d_%d", userID, time.Now().Unix())
 
 	maxDevices := plan.MaxDevices
@@ -122,27 +250,31 @@ func (s *SubscriptionService) CreateSubscriptionWithServer(ctx context.Context,
 
 	log.Printf("Creating VPN client for user %d, email: %s, traffic: %d GB, days: %d, devices: %d", userID, email, plan.TrafficGB, plan.DurationDays, maxDevices)
 
-	// Create client in 3x-ui
-	xuiClient, err := xuiClientAPI.AddClient(email, int64(plan.TrafficGB), plan.DurationDays, maxDevices)
+	// Create client on the panel
+	account, err := panelClient.AddClient(ctx, email, int64(plan.TrafficGB), plan.DurationDays, maxDevices)
 	if err != nil {
 		log.Printf("ERROR: Failed to create VPN client for user %d: %v", userID, err)
 		return nil, fmt.Errorf("failed to create VPN client: %w", err)
 	}
 
-	log.Printf("VPN client created successfully: ID=%s, Email=%s", xuiClient.ID, xuiClient.Email)
+	log.Printf("VPN client created successfully: ID=%s, Email=%s", account.ID, account.Email)
 
 	now := time.Now()
 	expiresAt := now.Add(time.Duration(plan.DurationDays) * 24 * time.Hour)
 
 	// Generate connection key
-	connectionKey := s.serverSvc.GenerateConnectionKey(server, xuiClient.ID, email)
+	connectionKey, err := s.serverSvc.GenerateConnectionKey(ctx, panelClient, server, account)
+	if err != nil {
+		_ = panelClient.DeleteClient(ctx, account.ID, account.Email)
+		return nil, fmt.Errorf("failed to generate connection key: %w", err)
+	}
 
 	sub := &model.Subscription{
 		UserID:        userID,
 		PlanID:        plan.ID,
 		ServerID:      &server.ID,
 		Status:        model.SubscriptionStatusActive,
-		XUIClientID:   xuiClient.ID,
+		XUIClientID:   account.ID,
 		XUIEmail:      email,
 		ConnectionKey: connectionKey,
 		StartedAt:     &now,
@@ -153,15 +285,12 @@ func (s *SubscriptionService) CreateSubscriptionWithServer(ctx context.Context,
 	}
 
 	if err := s.repo.CreateSubscription(ctx, sub); err != nil {
-		// Try to cleanup 3x-ui client
-		_ = xuiClientAPI.DeleteClient(xuiClient.ID)
+		// Try to cleanup panel client
+		_ = panelClient.DeleteClient(ctx, account.ID, account.Email)
 		return nil, err
 	}
 
-	// Increment server load
-	if err := s.serverSvc.IncrementLoad(ctx, server.ID); err != nil {
-		log.Printf("WARNING: Failed to increment server load: %v", err)
-	}
+	s.provisionExtraInbounds(ctx, sub, server, plan, int64(plan.TrafficGB), plan.DurationDays)
 
 	return sub, nil
 }
@@ -180,30 +309,61 @@ func (s *SubscriptionService) ExtendSubscriptionWithTraffic(ctx context.Context,
 		return ErrSubscriptionNotActive
 	}
 
-	// Get appropriate XUI client for this subscription
-	xuiClientAPI, _, err := s.getXUIClientForSubscription(ctx, sub)
+	// Update on the panel FIRST (before database, so we can fail early)
+	newTrafficLimit := sub.TrafficLimit + additionalTrafficBytes
+	newExpiry := sub.ExpiresAt.Add(time.Duration(days) * 24 * time.Hour)
+	if err := s.updatePanelLimits(ctx, sub, newTrafficLimit, newExpiry); err != nil {
+		return err
+	}
+
+	// Only extend in database after the panel succeeded
+	if err := s.repo.ExtendSubscription(ctx, subID, days, additionalTrafficBytes); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ExtendSubscriptionTo moves the expiry of an active subscription to expiresAt.
+// A subscription that already expires later is left as is, so repeating the
+// call after a failure does not extend it twice.
+func (s *SubscriptionService) ExtendSubscriptionTo(ctx context.Context, subID uuid.UUID, expiresAt time.Time) error {
+	sub, err := s.repo.GetSubscription(ctx, subID)
 	if err != nil {
-		return fmt.Errorf("failed to get XUI client: %w", err)
+		return err
 	}
 
-	// Calculate new traffic limit
-	newTrafficLimit := sub.TrafficLimit + additionalTrafficBytes
+	if sub.Status != model.SubscriptionStatusActive || sub.ExpiresAt == nil {
+		return ErrSubscriptionNotActive
+	}
+	if !sub.ExpiresAt.Before(expiresAt) {
+		return nil
+	}
+
+	// The database is only updated after the panel, so an applied expiry is on both
+	if err := s.updatePanelLimits(ctx, sub, sub.TrafficLimit, expiresAt); err != nil {
+		return err
+	}
+	return s.repo.ExtendSubscriptionTo(ctx, subID, expiresAt)
+}
+
+// updatePanelLimits applies a traffic limit in bytes and an expiry to the panel clients of a subscription
+func (s *SubscriptionService) updatePanelLimits(ctx context.Context, sub *model.Subscription, trafficLimit int64, expiry time.Time) error {
+	panelClient, _, err := s.getPanelForSubscription(ctx, sub)
+	if err != nil {
+		return fmt.Errorf("failed to get panel client: %w", err)
+	}
 
-	// Update in 3x-ui FIRST (before database, so we can fail early)
-	newExpiry := sub.ExpiresAt.Add(time.Duration(days) * 24 * time.Hour)
 	maxDevices := sub.MaxDevices
 	if maxDevices <= 0 {
 		maxDevices = 3
 	}
-	if err := xuiClientAPI.UpdateClientTraffic(sub.XUIClientID, sub.XUIEmail, newTrafficLimit/(1024*1024*1024), newExpiry.UnixMilli(), maxDevices); err != nil {
+	if err := panelClient.UpdateClient(ctx, sub.XUIClientID, sub.XUIEmail, trafficLimit/(1024*1024*1024), expiry.UnixMilli(), maxDevices); err != nil {
 		return fmt.Errorf("failed to update VPN client: %w", err)
 	}
-
-	// Only extend in database after 3x-ui succeeded
-	if err := s.repo.ExtendSubscription(ctx, subID, days, additionalTrafficBytes); err != nil {
-		return err
+	if err := s.updateExtraInbounds(ctx, sub, trafficLimit/(1024*1024*1024), expiry.UnixMilli(), maxDevices); err != nil {
+		log.Printf("WARNING: Failed to extend additional inbound clients for subscription %s: %v", sub.ID, err)
 	}
-
 	return nil
 }
 
@@ -213,75 +373,149 @@ func (s *SubscriptionService) CancelSubscription(ctx context.Context, subID uuid
 		return err
 	}
 
-	// Delete from 3x-ui
+	// Delete from the panel
+	s.removeExtraInbounds(ctx, sub)
 	if sub.XUIClientID != "" {
-		xuiClientAPI, _, err := s.getXUIClientForSubscription(ctx, sub)
+		panelClient, _, err := s.getPanelForSubscription(ctx, sub)
 		if err != nil {
-			log.Printf("WARNING: Failed to get XUI client for subscription %s: %v", subID, err)
+			log.Printf("WARNING: Failed to get panel client for subscription %s: %v", subID, err)
 		} else {
-			if err := xuiClientAPI.DeleteClient(sub.XUIClientID); err != nil {
+			if err := panelClient.DeleteClient(ctx, sub.XUIClientID, sub.XUIEmail); err != nil {
 				return fmt.Errorf("failed to delete VPN client: %w", err)
 			}
 		}
 	}
 
-	// Decrement server load
-	if sub.ServerID != nil && s.serverSvc != nil {
-		if err := s.serverSvc.DecrementLoad(ctx, *sub.ServerID); err != nil {
-			log.Printf("WARNING: Failed to decrement server load: %v", err)
-		}
-	}
-
 	return s.repo.UpdateSubscriptionStatus(ctx, subID, model.SubscriptionStatusCancelled)
 }
 
+// ExpireSubscription marks a subscription expired and removes its panel clients.
+// Access ends in the database right away; clients that cannot be removed while the
+// panel is down are queued for RetryPanelRemovals.
 func (s *SubscriptionService) ExpireSubscription(ctx context.Context, subID uuid.UUID) error {
 	sub, err := s.repo.GetSubscription(ctx, subID)
 	if err != nil {
 		return err
 	}
 
-	// Delete from 3x-ui
-	if sub.XUIClientID != "" {
-		xuiClientAPI, _, err := s.getXUIClientForSubscription(ctx, sub)
+	if err := s.repo.UpdateSubscriptionStatus(ctx, subID, model.SubscriptionStatusExpired); err != nil {
+		return err
+	}
+
+	// Delete from the panel
+	s.removeExtraInbounds(ctx, sub)
+	if sub.XUIClientID != "" && s.serverSvc != nil {
+		server, err := s.subscriptionServer(ctx, sub)
 		if err != nil {
-			log.Printf("WARNING: Failed to get XUI client for subscription %s: %v", subID, err)
+			log.Printf("WARNING: Failed to get server of subscription %s, client %s left on the panel: %v", subID, sub.XUIEmail, err)
 		} else {
-			if err := xuiClientAPI.DeleteClient(sub.XUIClientID); err != nil {
-				// Log error but continue with expiration
-				log.Printf("Failed to delete VPN client %s: %v", sub.XUIClientID, err)
-			}
+			s.removePanelClient(ctx, server, server.XUIInboundID, sub.XUIClientID, sub.XUIEmail)
 		}
 	}
 
-	// Decrement server load
-	if sub.ServerID != nil && s.serverSvc != nil {
-		if err := s.serverSvc.DecrementLoad(ctx, *sub.ServerID); err != nil {
-			log.Printf("WARNING: Failed to decrement server load: %v", err)
-		}
-	}
+	return nil
+}
 
-	return s.repo.UpdateSubscriptionStatus(ctx, subID, model.SubscriptionStatusExpired)
+// subscriptionServer returns the server of a subscription, the default server for old ones without server_id
+func (s *SubscriptionService) subscriptionServer(ctx context.Context, sub *model.Subscription) (*model.Server, error) {
+	if sub.ServerID != nil {
+		return s.serverSvc.GetServer(ctx, *sub.ServerID)
+	}
+	return s.serverSvc.GetDefaultServer(ctx)
 }
 
-func (s *SubscriptionService) SyncTraffic(ctx context.Context, subID uuid.UUID) error {
-	sub, err := s.repo.GetSubscription(ctx, subID)
+// syncTraffic stores the traffic a subscription used and expires it once its quota
+// is used up. Only SyncAllTraffic calls it, so a single worker owns the panel reads.
+func (s *SubscriptionService) syncTraffic(ctx context.Context, sub *model.Subscription) error {
+	panelClient, server, err := s.getPanelForSubscription(ctx, sub)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to get panel client: %w", err)
 	}
 
-	xuiClientAPI, _, err := s.getXUIClientForSubscription(ctx, sub)
+	traffic, err := panelClient.GetClientTraffic(ctx, sub.XUIEmail)
 	if err != nil {
-		return fmt.Errorf("failed to get XUI client: %w", err)
+		return fmt.Errorf("failed to get traffic: %w", err)
 	}
 
-	traffic, err := xuiClientAPI.GetClientTraffic(sub.XUIEmail)
+	if _, ok := panelClient.(panel.Stateless); ok || traffic.Rolling {
+		// Stateless backends report raw counters and rolling ones a recent window,
+		// the usage is the difference between syncs
+		counter := traffic.Total()
+		applied, err := s.repo.AddSubscriptionTrafficCounter(ctx, sub.ID, sub.TrafficCounter, counter, traffic.Rolling)
+		if err != nil {
+			return err
+		}
+		if !applied {
+			// Another sync stored a newer counter in the meantime
+			return nil
+		}
+		if counter >= sub.TrafficCounter {
+			sub.TrafficUsed += counter - sub.TrafficCounter
+		} else if !traffic.Rolling {
+			sub.TrafficUsed += counter
+		}
+		sub.TrafficCounter = counter
+	} else {
+		// Each client on the panel is limited to the whole quota, so with additional
+		// inbounds the shared quota is only enforced here, on their sum
+		sub.TrafficUsed = traffic.Total() + s.extraInboundsTraffic(ctx, sub, server)
+		if err := s.repo.UpdateSubscriptionTraffic(ctx, sub.ID, sub.TrafficUsed); err != nil {
+			return err
+		}
+	}
+
+	if sub.Status == model.SubscriptionStatusActive && sub.TrafficLimit > 0 && sub.TrafficUsed >= sub.TrafficLimit {
+		log.Printf("Subscription %s used its traffic quota, expiring", sub.ID)
+		return s.ExpireSubscription(ctx, sub.ID)
+	}
+	return nil
+}
+
+// SyncAllTraffic syncs the traffic of every active subscription and enforces limits:
+// subscriptions past their expiry or quota are expired, and on backends that do not
+// keep clients themselves (plain Xray) valid clients are re-added in case the node restarted.
+func (s *SubscriptionService) SyncAllTraffic(ctx context.Context) error {
+	subs, err := s.repo.GetActiveSubscriptions(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get traffic: %w", err)
+		return err
+	}
+
+	failed := 0
+	for _, sub := range subs {
+		if sub.ExpiresAt != nil && time.Now().After(*sub.ExpiresAt) {
+			if err := s.ExpireSubscription(ctx, sub.ID); err != nil {
+				log.Printf("Failed to expire subscription %s: %v", sub.ID, err)
+			}
+			continue
+		}
+		if sub.XUIClientID == "" {
+			continue
+		}
+
+		if err := s.syncTraffic(ctx, &sub); err != nil {
+			log.Printf("WARNING: Failed to sync traffic for subscription %s: %v", sub.ID, err)
+			failed++
+			continue
+		}
+		if sub.TrafficLimit > 0 && sub.TrafficUsed >= sub.TrafficLimit {
+			continue
+		}
+
+		panelClient, _, err := s.getPanelForSubscription(ctx, &sub)
+		if err != nil {
+			continue
+		}
+		if stateless, ok := panelClient.(panel.Stateless); ok {
+			if err := stateless.EnsureClient(ctx, sub.XUIClientID, sub.XUIEmail); err != nil {
+				log.Printf("WARNING: Failed to restore client %s: %v", sub.XUIEmail, err)
+			}
+		}
 	}
 
-	totalUsed := traffic.Up + traffic.Down
-	return s.repo.UpdateSubscriptionTraffic(ctx, subID, totalUsed)
+	if failed > 0 {
+		log.Printf("Traffic sync failed for %d of %d subscriptions", failed, len(subs))
+	}
+	return nil
 }
 
 func (s *SubscriptionService) GetConnectionKey(ctx context.Context, userID int64) (string, error) {
@@ -293,8 +527,16 @@ func (s *SubscriptionService) GetConnectionKey(ctx context.Context, userID int64
 	if !sub.IsActive() {
 		return "", ErrSubscriptionNotActive
 	}
+	if sub.ServerID == nil {
+		return sub.ConnectionKey, nil
+	}
 
-	return sub.ConnectionKey, nil
+	// The stored key may point at a retired endpoint
+	keys := s.serverSvc.EndpointKeys(ctx, *sub.ServerID, model.ConnectionKey{Key: sub.ConnectionKey}, true)
+	if len(keys) == 0 {
+		return "", ErrNoActiveEndpoints
+	}
+	return keys[0].Key, nil
 }
 
 func (s *SubscriptionService) ProcessExpiredSubscriptions(ctx context.Context) error {
@@ -335,7 +577,7 @@ func (s *SubscriptionService) SwitchServer(ctx context.Context, userID int64, ne
 	}
 
 	// Get the new server
-	newXUIClient, newServer, err := s.serverSvc.GetXUIClient(ctx, newServerID)
+	newPanel, newServer, err := s.serverSvc.GetPanel(ctx, newServerID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get new server: %w", err)
 	}
@@ -343,37 +585,34 @@ func (s *SubscriptionService) SwitchServer(ctx context.Context, userID int64, ne
 	if !newServer.IsOnline() {
 		return nil, fmt.Errorf("selected server is not available")
 	}
+	plan, err := s.repo.GetPlan(ctx, sub.PlanID)
+	if err != nil {
+		plan = nil
+	}
+	if !plan.AllowsServerGroup(newServer.Group) {
+		return nil, ErrServerLocked
+	}
+	if newServer.IsFull() {
+		return nil, ErrServerFull
+	}
 
-	// Delete client from old server
+	// Delete clients from old server
+	s.removeExtraInbounds(ctx, sub)
 	if sub.XUIClientID != "" {
-		oldXUIClient, _, err := s.getXUIClientForSubscription(ctx, sub)
+		oldPanel, _, err := s.getPanelForSubscription(ctx, sub)
 		if err != nil {
-			log.Printf("WARNING: Failed to get old XUI client: %v", err)
+			log.Printf("WARNING: Failed to get old panel client: %v", err)
 		} else {
-			if err := oldXUIClient.DeleteClient(sub.XUIClientID); err != nil {
+			if err := oldPanel.DeleteClient(ctx, sub.XUIClientID, sub.XUIEmail); err != nil {
 				log.Printf("WARNING: Failed to delete client from old server: %v", err)
 			}
 		}
 	}
 
-	// Decrement old server load
-	if sub.ServerID != nil {
-		if err := s.serverSvc.DecrementLoad(ctx, *sub.ServerID); err != nil {
-			log.Printf("WARNING: Failed to decrement old server load: %v", err)
-		}
-	}
-
 	// Calculate remaining time and traffic
-	remainingDays := int(time.Until(*sub.ExpiresAt).Hours() / 24)
-	if remainingDays < 1 {
-		remainingDays = 1
-	}
-	remainingTrafficGB := int((sub.TrafficLimit - sub.TrafficUsed) / (1024 * 1024 * 1024))
-	if remainingTrafficGB < 1 {
-		remainingTrafficGB = 1
-	}
+	remainingTrafficGB, remainingDays := remainingLimits(sub)
 
-	// Generate new email for XUI client
+	// Generate new email for panel client
 	email := fmt.Sprintf("user_%d_%d", userID, time.Now().Unix())
 
 	maxDevices := sub.MaxDevices
@@ -384,33 +623,34 @@ func (s *SubscriptionService) SwitchServer(ctx context.Context, userID int64, ne
 	log.Printf("Switching user %d to server %s, email: %s, traffic: %d GB, days: %d", userID, newServer.Name, email, remainingTrafficGB, remainingDays)
 
 	// Create client on new server
-	newClient, err := newXUIClient.AddClient(email, int64(remainingTrafficGB), remainingDays, maxDevices)
+	account, err := newPanel.AddClient(ctx, email, remainingTrafficGB, remainingDays, maxDevices)
 	if err != nil {
 		log.Printf("ERROR: Failed to create VPN client on new server: %v", err)
 		return nil, fmt.Errorf("failed to create VPN client on new server: %w", err)
 	}
 
-	log.Printf("VPN client created on new server: ID=%s, Email=%s", newClient.ID, newClient.Email)
+	log.Printf("VPN client created on new server: ID=%s, Email=%s", account.ID, account.Email)
 
 	// Generate new connection key
-	connectionKey := s.serverSvc.GenerateConnectionKey(newServer, newClient.ID, email)
+	connectionKey, err := s.serverSvc.GenerateConnectionKey(ctx, newPanel, newServer, account)
+	if err != nil {
+		_ = newPanel.DeleteClient(ctx, account.ID, account.Email)
+		return nil, fmt.Errorf("failed to generate connection key: %w", err)
+	}
 
 	// Update subscription in database
 	sub.ServerID = &newServer.ID
-	sub.XUIClientID = newClient.ID
+	sub.XUIClientID = account.ID
 	sub.XUIEmail = email
 	sub.ConnectionKey = connectionKey
 
-	if err := s.repo.UpdateSubscriptionServer(ctx, sub.ID, newServer.ID, newClient.ID, email, connectionKey); err != nil {
+	if err := s.repo.UpdateSubscriptionServer(ctx, sub.ID, newServer.ID, account.ID, email, connectionKey); err != nil {
 		// Try to cleanup new client
-		_ = newXUIClient.DeleteClient(newClient.ID)
+		_ = newPanel.DeleteClient(ctx, account.ID, account.Email)
 		return nil, fmt.Errorf("failed to update subscription: %w", err)
 	}
 
-	// Increment new server load
-	if err := s.serverSvc.IncrementLoad(ctx, newServer.ID); err != nil {
-		log.Printf("WARNING: Failed to increment new server load: %v", err)
-	}
+	s.provisionExtraInbounds(ctx, sub, newServer, plan, remainingTrafficGB, remainingDays)
 
 	return sub, nil
 }
@@ -467,3 +707,58 @@ func (s *SubscriptionService) ActivateTrialWithDays(ctx context.Context, userID
 	// Create subscription
 	return s.CreateSubscription(ctx, userID, &customPlan)
 }
+
+// RegenerateConnectionKeys rebuilds the stored connection keys of all active
+// subscriptions on a server after its address, port or Reality parameters changed.
+// It returns the subscriptions whose primary key changed, with the new key set;
+// keys that could not be rebuilt are counted in the returned error.
+func (s *SubscriptionService) RegenerateConnectionKeys(ctx context.Context, serverID uuid.UUID) ([]model.Subscription, error) {
+	panelClient, server, err := s.serverSvc.GetPanel(ctx, serverID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get panel client: %w", err)
+	}
+
+	subs, err := s.repo.GetActiveSubscriptionsByServer(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []model.Subscription
+	failed, extraFailed := 0, 0
+	for _, sub := range subs {
+		if sub.XUIClientID == "" {
+			continue
+		}
+
+		account := &panel.Account{ID: sub.XUIClientID, Email: sub.XUIEmail}
+		key, err := s.serverSvc.GenerateConnectionKey(ctx, panelClient, server, account)
+		if err != nil {
+			if errors.Is(err, panel.ErrUnavailable) {
+				return changed, err
+			}
+			log.Printf("WARNING: Failed to regenerate connection key for subscription %s: %v", sub.ID, err)
+			failed++
+			continue
+		}
+
+		extraFailed += s.regenerateExtraInboundKeys(ctx, &sub, server)
+
+		if key == sub.ConnectionKey {
+			continue
+		}
+		if err := s.repo.UpdateSubscriptionConnectionKey(ctx, sub.ID, key); err != nil {
+			log.Printf("WARNING: Failed to save connection key for subscription %s: %v", sub.ID, err)
+			failed++
+			continue
+		}
+		sub.ConnectionKey = key
+		changed = append(changed, sub)
+	}
+
+	log.Printf("Regenerated connection keys on server %s: %d of %d subscriptions changed, %d keys and %d additional inbound keys failed",
+		server.Name, len(changed), len(subs), failed, extraFailed)
+	if failed > 0 || extraFailed > 0 {
+		return changed, fmt.Errorf("%d connection keys and %d additional inbound keys could not be regenerated", failed, extraFailed)
+	}
+	return changed, nil
+}
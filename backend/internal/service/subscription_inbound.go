@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/i18n"
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/panel"
+)
+
+// provisionExtraInbounds creates clients for a subscription on the additional inbounds
+// of its server that the plan allows and it has no client on yet. Failures are
+// logged and skipped: the primary inbound key is always enough to connect.
+func (s *SubscriptionService) provisionExtraInbounds(ctx context.Context, sub *model.Subscription, server *model.Server, plan *model.Plan, trafficGB int64, days int) {
+	inbounds, err := s.serverSvc.GetPlanInbounds(ctx, server, plan)
+	if err != nil {
+		log.Printf("WARNING: Failed to get additional inbounds for server %s: %v", server.ID, err)
+		return
+	}
+	extras, err := s.repo.GetSubscriptionInbounds(ctx, sub.ID)
+	if err != nil {
+		log.Printf("WARNING: Failed to get inbound clients for subscription %s: %v", sub.ID, err)
+		return
+	}
+	provisioned := make(map[uuid.UUID]bool, len(extras))
+	for _, extra := range extras {
+		provisioned[extra.ServerInboundID] = true
+	}
+
+	maxDevices := sub.MaxDevices
+	if maxDevices <= 0 {
+		maxDevices = 3
+	}
+
+	for _, inbound := range inbounds {
+		if provisioned[inbound.ID] {
+			continue
+		}
+		panelClient, err := s.serverSvc.GetPanelForInbound(server, inbound.XUIInboundID)
+		if err != nil {
+			log.Printf("WARNING: Failed to get panel client for inbound %d: %v", inbound.XUIInboundID, err)
+			continue
+		}
+
+		email := fmt.Sprintf("%s_in%d", sub.XUIEmail, inbound.XUIInboundID)
+		account, err := panelClient.AddClient(ctx, email, trafficGB, days, maxDevices)
+		if err != nil {
+			log.Printf("WARNING: Failed to create client on inbound %d for subscription %s: %v", inbound.XUIInboundID, sub.ID, err)
+			continue
+		}
+
+		connectionKey, err := s.serverSvc.GenerateInboundConnectionKey(ctx, panelClient, server, account)
+		if err != nil {
+			log.Printf("WARNING: Failed to generate key for inbound %d: %v", inbound.XUIInboundID, err)
+			_ = panelClient.DeleteClient(ctx, account.ID, account.Email)
+			continue
+		}
+
+		si := &model.SubscriptionInbound{
+			SubscriptionID:  sub.ID,
+			ServerInboundID: inbound.ID,
+			XUIClientID:     account.ID,
+			XUIEmail:        email,
+			ConnectionKey:   connectionKey,
+		}
+		if err := s.repo.CreateSubscriptionInbound(ctx, si); err != nil {
+			log.Printf("WARNING: Failed to save inbound client for subscription %s: %v", sub.ID, err)
+			_ = panelClient.DeleteClient(ctx, account.ID, account.Email)
+		}
+	}
+}
+
+// removeExtraInbounds deletes the additional inbound clients of a subscription from the panel and database
+func (s *SubscriptionService) removeExtraInbounds(ctx context.Context, sub *model.Subscription) {
+	if sub.ServerID == nil {
+		return
+	}
+
+	extras, err := s.repo.GetSubscriptionInbounds(ctx, sub.ID)
+	if err != nil || len(extras) == 0 {
+		return
+	}
+
+	server, err := s.serverSvc.GetServer(ctx, *sub.ServerID)
+	if err == nil {
+		for _, extra := range extras {
+			inbound, err := s.serverSvc.GetServerInbound(ctx, extra.ServerInboundID)
+			if err != nil {
+				continue
+			}
+			s.removePanelClient(ctx, server, inbound.XUIInboundID, extra.XUIClientID, extra.XUIEmail)
+		}
+	}
+
+	if err := s.repo.DeleteSubscriptionInbounds(ctx, sub.ID); err != nil {
+		log.Printf("WARNING: Failed to delete inbound clients for subscription %s: %v", sub.ID, err)
+	}
+}
+
+// reconcileExtraInbounds makes the additional inbound clients of a subscription
+// match the plan after a plan change: clients on inbounds the plan no longer
+// allows are removed and the newly allowed inbounds are provisioned
+func (s *SubscriptionService) reconcileExtraInbounds(ctx context.Context, sub *model.Subscription, server *model.Server, plan *model.Plan) {
+	inbounds, err := s.serverSvc.GetPlanInbounds(ctx, server, plan)
+	if err != nil {
+		log.Printf("WARNING: Failed to get additional inbounds for server %s: %v", server.ID, err)
+		return
+	}
+	allowed := make(map[uuid.UUID]bool, len(inbounds))
+	for _, inbound := range inbounds {
+		allowed[inbound.ID] = true
+	}
+
+	extras, err := s.repo.GetSubscriptionInbounds(ctx, sub.ID)
+	if err != nil {
+		log.Printf("WARNING: Failed to get inbound clients for subscription %s: %v", sub.ID, err)
+		return
+	}
+	for _, extra := range extras {
+		if allowed[extra.ServerInboundID] {
+			continue
+		}
+		inbound, err := s.serverSvc.GetServerInbound(ctx, extra.ServerInboundID)
+		if err != nil {
+			log.Printf("WARNING: Failed to get inbound %s: %v", extra.ServerInboundID, err)
+			continue
+		}
+		s.removePanelClient(ctx, server, inbound.XUIInboundID, extra.XUIClientID, extra.XUIEmail)
+		if err := s.repo.DeleteSubscriptionInbound(ctx, extra.ID); err != nil {
+			log.Printf("WARNING: Failed to delete inbound client %s of subscription %s: %v", extra.ID, sub.ID, err)
+		}
+	}
+
+	trafficGB, days := remainingLimits(sub)
+	s.provisionExtraInbounds(ctx, sub, server, plan, trafficGB, days)
+}
+
+// updateExtraInbounds applies new traffic/expiry limits to the additional inbound clients of a subscription
+func (s *SubscriptionService) updateExtraInbounds(ctx context.Context, sub *model.Subscription, totalGB int64, expiryTime int64, maxDevices int) error {
+	if sub.ServerID == nil {
+		return nil
+	}
+
+	extras, err := s.repo.GetSubscriptionInbounds(ctx, sub.ID)
+	if err != nil || len(extras) == 0 {
+		return err
+	}
+
+	server, err := s.serverSvc.GetServer(ctx, *sub.ServerID)
+	if err != nil {
+		return err
+	}
+
+	for _, extra := range extras {
+		inbound, err := s.serverSvc.GetServerInbound(ctx, extra.ServerInboundID)
+		if err != nil {
+			return err
+		}
+		panelClient, err := s.serverSvc.GetPanelForInbound(server, inbound.XUIInboundID)
+		if err != nil {
+			return err
+		}
+		if err := panelClient.UpdateClient(ctx, extra.XUIClientID, extra.XUIEmail, totalGB, expiryTime, maxDevices); err != nil {
+			return fmt.Errorf("inbound %d: %w", inbound.XUIInboundID, err)
+		}
+	}
+
+	return nil
+}
+
+// extraInboundsTraffic returns the traffic used across the additional inbound clients of a subscription
+func (s *SubscriptionService) extraInboundsTraffic(ctx context.Context, sub *model.Subscription, server *model.Server) int64 {
+	extras, err := s.repo.GetSubscriptionInbounds(ctx, sub.ID)
+	if err != nil {
+		return 0
+	}
+
+	var total int64
+	for _, extra := range extras {
+		inbound, err := s.serverSvc.GetServerInbound(ctx, extra.ServerInboundID)
+		if err != nil {
+			continue
+		}
+		panelClient, err := s.serverSvc.GetPanelForInbound(server, inbound.XUIInboundID)
+		if err != nil {
+			continue
+		}
+		traffic, err := panelClient.GetClientTraffic(ctx, extra.XUIEmail)
+		if err != nil {
+			continue
+		}
+		total += traffic.Total()
+	}
+	return total
+}
+
+// GetConnectionKeys returns all connection keys of the user's active subscription,
+// the primary inbound first followed by additional inbounds, each once per server
+// endpoint. Key names are in lang.
+func (s *SubscriptionService) GetConnectionKeys(ctx context.Context, userID int64, lang string) ([]model.ConnectionKey, error) {
+	sub, err := s.repo.GetActiveSubscription(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !sub.IsActive() {
+		return nil, ErrSubscriptionNotActive
+	}
+
+	primary := model.ConnectionKey{
+		Tag:  model.PrimaryInboundTag,
+		Name: i18n.T(lang, "key_name_primary"),
+		Key:  sub.ConnectionKey,
+	}
+	if sub.ServerID == nil {
+		return []model.ConnectionKey{primary}, nil
+	}
+	keys := s.serverSvc.EndpointKeys(ctx, *sub.ServerID, primary, true)
+	if len(keys) == 0 {
+		return nil, ErrNoActiveEndpoints
+	}
+
+	extras, err := s.repo.GetSubscriptionInbounds(ctx, sub.ID)
+	if err != nil {
+		return keys, nil
+	}
+
+	for _, extra := range extras {
+		inbound, err := s.serverSvc.GetServerInbound(ctx, extra.ServerInboundID)
+		if err != nil {
+			continue
+		}
+		name := inbound.Name
+		if name == "" {
+			name = inbound.Tag
+		}
+		keys = append(keys, s.serverSvc.EndpointKeys(ctx, *sub.ServerID, model.ConnectionKey{
+			Tag:  inbound.Tag,
+			Name: name,
+			Key:  extra.ConnectionKey,
+		}, false)...)
+	}
+
+	return keys, nil
+}
+
+// GetSubscriptionURL returns the panel subscription URL of the user's active subscription,
+// or "" when its panel does not serve one
+func (s *SubscriptionService) GetSubscriptionURL(ctx context.Context, userID int64) (string, error) {
+	sub, err := s.repo.GetActiveSubscription(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if !sub.IsActive() {
+		return "", ErrSubscriptionNotActive
+	}
+	if sub.ServerID == nil {
+		return "", nil
+	}
+
+	panelClient, _, err := s.serverSvc.GetPanel(ctx, *sub.ServerID)
+	if err != nil {
+		return "", err
+	}
+	linker, ok := panelClient.(panel.SubscriptionLinker)
+	if !ok {
+		return "", nil
+	}
+	url, err := linker.SubscriptionURL(ctx, &panel.Account{ID: sub.XUIClientID, Email: sub.XUIEmail})
+	if errors.Is(err, panel.ErrNotSupported) {
+		return "", nil
+	}
+	return url, err
+}
+
+// regenerateExtraInboundKeys rebuilds the stored keys of a subscription's additional
+// inbound clients and returns how many of them could not be rebuilt
+func (s *SubscriptionService) regenerateExtraInboundKeys(ctx context.Context, sub *model.Subscription, server *model.Server) int {
+	extras, err := s.repo.GetSubscriptionInbounds(ctx, sub.ID)
+	if err != nil {
+		log.Printf("WARNING: Failed to get additional inbound clients of subscription %s: %v", sub.ID, err)
+		return 1
+	}
+
+	failed := 0
+	for _, extra := range extras {
+		inbound, err := s.serverSvc.GetServerInbound(ctx, extra.ServerInboundID)
+		if err != nil {
+			log.Printf("WARNING: Failed to get inbound %s of client %s: %v", extra.ServerInboundID, extra.XUIEmail, err)
+			failed++
+			continue
+		}
+		panelClient, err := s.serverSvc.GetPanelForInbound(server, inbound.XUIInboundID)
+		if err != nil {
+			log.Printf("WARNING: Failed to get panel client for inbound %d: %v", inbound.XUIInboundID, err)
+			failed++
+			continue
+		}
+
+		account := &panel.Account{ID: extra.XUIClientID, Email: extra.XUIEmail}
+		key, err := s.serverSvc.GenerateInboundConnectionKey(ctx, panelClient, server, account)
+		if err != nil {
+			log.Printf("WARNING: Failed to regenerate key for %s on inbound %d: %v", extra.XUIEmail, inbound.XUIInboundID, err)
+			failed++
+			continue
+		}
+		if key == extra.ConnectionKey {
+			continue
+		}
+		if err := s.repo.UpdateSubscriptionInboundKey(ctx, extra.ID, key); err != nil {
+			log.Printf("WARNING: Failed to save key for %s on inbound %d: %v", extra.XUIEmail, inbound.XUIInboundID, err)
+			failed++
+		}
+	}
+	return failed
+}
@@ -2,9 +2,11 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/zyvpn/backend/internal/config"
 	"github.com/zyvpn/backend/internal/model"
 	"github.com/zyvpn/backend/internal/repository"
 	"github.com/zyvpn/backend/internal/ton"
@@ -20,6 +22,11 @@ type TonWorker struct {
 	verifier    *ton.Verifier
 	balanceSvc  *BalanceService
 	paymentSvc  *PaymentService
+	alerts      *AlertService
+
+	failures    int  // consecutive runs with errors
+	failing     bool // a worker alert may be firing
+	stuck       bool // a stuck payments alert may be firing
 }
 
 func NewTonWorker(
@@ -27,12 +34,17 @@ func NewTonWorker(
 	verifier *ton.Verifier,
 	balanceSvc *BalanceService,
 	paymentSvc *PaymentService,
+	alerts *AlertService,
 ) *TonWorker {
 	return &TonWorker{
 		repo:       repo,
 		verifier:   verifier,
 		balanceSvc: balanceSvc,
 		paymentSvc: paymentSvc,
+		alerts:     alerts,
+		// Alerts of a previous run may still be firing, resolve them on the first clean run
+		failing: true,
+		stuck:   true,
 	}
 }
 
@@ -49,48 +61,99 @@ func (w *TonWorker) Start(ctx context.Context) {
 			fmt.Println("[TON Worker] Stopped")
 			return
 		case <-ticker.C:
-			w.processAwaitingPayments(ctx)
+			w.recordRun(ctx, w.processAwaitingPayments(ctx))
+			w.checkStuckPayments(ctx)
 		}
 	}
 }
 
-// processAwaitingPayments checks all payments waiting for tx confirmation
-func (w *TonWorker) processAwaitingPayments(ctx context.Context) {
+// processAwaitingPayments checks all payments waiting for tx confirmation.
+// It returns the last error of the run, if any.
+func (w *TonWorker) processAwaitingPayments(ctx context.Context) error {
 	payments, err := w.repo.GetAwaitingTxPayments(ctx)
 	if err != nil {
 		fmt.Printf("[TON Worker] Error getting awaiting payments: %v\n", err)
-		return
+		return err
 	}
 
 	if len(payments) == 0 {
-		return
+		return nil
 	}
 
 	fmt.Printf("[TON Worker] Processing %d awaiting payments\n", len(payments))
 
+	var lastErr error
 	for _, payment := range payments {
-		w.processPayment(ctx, &payment)
+		if err := w.processPayment(ctx, &payment); err != nil {
+			lastErr = err
+		}
 	}
+	return lastErr
 }
 
-// processPayment tries to verify a single payment
-func (w *TonWorker) processPayment(ctx context.Context, payment *model.Payment) {
+// recordRun raises an alert when TonWorkerAlertAfter runs in a row fail
+func (w *TonWorker) recordRun(ctx context.Context, err error) {
+	if err == nil {
+		if w.failing {
+			w.alerts.Resolve(ctx, model.AlertKindTonWorker, "")
+			w.failing = false
+		}
+		w.failures = 0
+		return
+	}
+
+	w.failures++
+	if w.failures >= config.TonWorkerAlertAfter {
+		w.alerts.Fire(ctx, model.AlertKindTonWorker, "", model.AlertSeverityWarning,
+			fmt.Sprintf("TON worker: %d запусков подряд с ошибками, последняя: %v", w.failures, err))
+		w.failing = true
+	}
+}
+
+// checkStuckPayments reports payments left in awaiting_tx long after they should have been completed or failed
+func (w *TonWorker) checkStuckPayments(ctx context.Context) {
+	count, err := w.repo.GetStuckPaymentsCount(ctx, time.Now().Add(-config.PaymentStuckAfter))
+	if err != nil {
+		fmt.Printf("[TON Worker] Error counting stuck payments: %v\n", err)
+		return
+	}
+
+	if count == 0 {
+		if w.stuck {
+			w.alerts.Resolve(ctx, model.AlertKindPaymentsStuck, "")
+			w.stuck = false
+		}
+		return
+	}
+
+	w.alerts.Fire(ctx, model.AlertKindPaymentsStuck, "", model.AlertSeverityCritical,
+		fmt.Sprintf("%d платежей в статусе awaiting_tx дольше %v", count, config.PaymentStuckAfter))
+	w.stuck = true
+}
+
+// processPayment tries to verify a single payment. It returns an error when a
+// payment could not be updated, not while its transaction is still pending.
+func (w *TonWorker) processPayment(ctx context.Context, payment *model.Payment) error {
 	// Check if payment is too old
 	if time.Since(payment.CreatedAt) > TonPaymentTimeout {
 		fmt.Printf("[TON Worker] Payment %s timed out, marking as failed\n", payment.ID)
-		w.repo.UpdatePaymentStatus(ctx, payment.ID, model.PaymentStatusFailed)
-		return
+		if err := w.repo.UpdatePaymentStatus(ctx, payment.ID, model.PaymentStatusFailed); err != nil {
+			return err
+		}
+		w.paymentSvc.notifyPaymentResult(ctx, payment.ID)
+		return nil
 	}
 
-	// Get expected amount in nanoTON
-	expectedAmountNano := int64(payment.Amount * 1e9)
-
-	// Try to find the transaction
-	txInfo, err := w.verifier.VerifyTransaction("", expectedAmountNano, "")
+	// Try to find the transaction carrying the payment's comment
+	txInfo, err := findTONTransaction(ctx, w.repo, w.verifier, payment, "")
+	if errors.Is(err, ErrTONTransactionUsed) {
+		fmt.Printf("[TON Worker] Payment %s: %v\n", payment.ID, err)
+		return nil
+	}
 	if err != nil {
 		// Transaction not found yet - keep waiting
 		fmt.Printf("[TON Worker] Payment %s: transaction not found yet\n", payment.ID)
-		return
+		return nil
 	}
 
 	fmt.Printf("[TON Worker] Payment %s: found transaction hash=%s, amount=%d\n",
@@ -99,7 +162,7 @@ func (w *TonWorker) processPayment(ctx context.Context, payment *model.Payment)
 	// Update external ID with transaction hash
 	if err := w.repo.UpdatePaymentExternalID(ctx, payment.ID, txInfo.Hash); err != nil {
 		fmt.Printf("[TON Worker] Error updating external ID: %v\n", err)
-		return
+		return err
 	}
 
 	// Complete the payment based on type
@@ -109,20 +172,33 @@ func (w *TonWorker) processPayment(ctx context.Context, payment *model.Payment)
 		_, err = w.balanceSvc.CreditTopUp(ctx, payment.UserID, tonAmount, payment.ID)
 		if err != nil {
 			fmt.Printf("[TON Worker] Error crediting balance: %v\n", err)
-			return
+			w.paymentNotCredited(ctx, payment, txInfo.Hash, err)
+			return err
 		}
 		// Update status
 		if err := w.repo.UpdatePaymentStatus(ctx, payment.ID, model.PaymentStatusCompleted); err != nil {
 			fmt.Printf("[TON Worker] Error updating status: %v\n", err)
-			return
+			w.paymentNotCredited(ctx, payment, txInfo.Hash, err)
+			return err
 		}
 		fmt.Printf("[TON Worker] Payment %s completed (top-up %.4f TON)\n", payment.ID, tonAmount)
 	} else {
 		// Subscription payment - use payment service
 		if err := w.paymentSvc.CompletePayment(ctx, payment.ID); err != nil {
 			fmt.Printf("[TON Worker] Error completing payment: %v\n", err)
-			return
+			w.paymentNotCredited(ctx, payment, txInfo.Hash, err)
+			return err
 		}
 		fmt.Printf("[TON Worker] Payment %s completed (subscription)\n", payment.ID)
 	}
+
+	w.alerts.Resolve(ctx, model.AlertKindReconciliation, "payment:"+payment.ID.String())
+	w.paymentSvc.notifyPaymentResult(ctx, payment.ID)
+	return nil
+}
+
+// paymentNotCredited alerts admins that a payment was received on chain but not applied
+func (w *TonWorker) paymentNotCredited(ctx context.Context, payment *model.Payment, txHash string, err error) {
+	w.alerts.Fire(ctx, model.AlertKindReconciliation, "payment:"+payment.ID.String(), model.AlertSeverityCritical,
+		fmt.Sprintf("Платёж %s пользователя %d: транзакция %s найдена, но платёж не зачислен: %v", payment.ID, payment.UserID, txHash, err))
 }
@@ -4,18 +4,41 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base32"
+	"errors"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/zyvpn/backend/internal/i18n"
 	"github.com/zyvpn/backend/internal/model"
 	"github.com/zyvpn/backend/internal/repository"
 )
 
+const (
+	languageCacheTTL  = time.Minute
+	languageCacheSize = 10000
+)
+
 type UserService struct {
 	repo *repository.Repository
+
+	// Language runs for every API request and bot update, so the stored language
+	// settings are cached. Other replicas see a change within languageCacheTTL.
+	languageMu sync.Mutex
+	languages  map[int64]languageCacheEntry
+}
+
+type languageCacheEntry struct {
+	preference   *string
+	languageCode *string
+	expires      time.Time
 }
 
 func NewUserService(repo *repository.Repository) *UserService {
-	return &UserService{repo: repo}
+	return &UserService{
+		repo:      repo,
+		languages: make(map[int64]languageCacheEntry),
+	}
 }
 
 func (s *UserService) GetOrCreateUser(ctx context.Context, telegramUser TelegramUser) (*model.User, bool, error) {
@@ -29,6 +52,7 @@ func (s *UserService) GetOrCreateUser(ctx context.Context, telegramUser Telegram
 		if err := s.repo.UpdateUser(ctx, existingUser); err != nil {
 			return nil, false, err
 		}
+		s.forgetLanguage(existingUser.ID)
 		return existingUser, false, nil
 	}
 
@@ -76,6 +100,79 @@ func (s *UserService) UseFreeRegionSwitch(ctx context.Context, userID int64) (bo
 	return s.repo.UseFreeRegionSwitch(ctx, userID)
 }
 
+var ErrUnsupportedLanguage = i18n.NewError("unsupported_language")
+
+// SetLanguage sets the language of bot messages and API errors for the user.
+// An empty language clears the choice so the Telegram client language is used.
+func (s *UserService) SetLanguage(ctx context.Context, userID int64, language string) error {
+	var preference *string
+	if language != "" {
+		if !i18n.Supported(language) {
+			return ErrUnsupportedLanguage
+		}
+		preference = &language
+	}
+	err := s.repo.SetUserLanguage(ctx, userID, preference)
+	s.forgetLanguage(userID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+// Language returns the language to talk to the user in: the language they
+// chose, else their Telegram client language. languageCode is the client
+// language of the current request and is used for users not stored yet.
+func (s *UserService) Language(ctx context.Context, userID int64, languageCode string) string {
+	s.languageMu.Lock()
+	entry, ok := s.languages[userID]
+	s.languageMu.Unlock()
+
+	if !ok || time.Now().After(entry.expires) {
+		user, err := s.repo.GetUser(ctx, userID)
+		if err != nil {
+			return i18n.Lang(nil, languageCode)
+		}
+		entry = languageCacheEntry{
+			preference:   user.Language,
+			languageCode: user.LanguageCode,
+			expires:      time.Now().Add(languageCacheTTL),
+		}
+		s.cacheLanguage(userID, entry)
+	}
+
+	if languageCode == "" && entry.languageCode != nil {
+		languageCode = *entry.languageCode
+	}
+	return i18n.Lang(entry.preference, languageCode)
+}
+
+// cacheLanguage stores the language settings of a user, dropping expired
+// entries when the cache is full
+func (s *UserService) cacheLanguage(userID int64, entry languageCacheEntry) {
+	s.languageMu.Lock()
+	defer s.languageMu.Unlock()
+
+	if len(s.languages) >= languageCacheSize {
+		now := time.Now()
+		for id, e := range s.languages {
+			if now.After(e.expires) {
+				delete(s.languages, id)
+			}
+		}
+	}
+	if len(s.languages) < languageCacheSize {
+		s.languages[userID] = entry
+	}
+}
+
+// forgetLanguage drops the cached language settings of a user after they change
+func (s *UserService) forgetLanguage(userID int64) {
+	s.languageMu.Lock()
+	delete(s.languages, userID)
+	s.languageMu.Unlock()
+}
+
 type TelegramUser struct {
 	ID           int64
 	Username     *string
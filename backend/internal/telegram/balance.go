@@ -0,0 +1,225 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/zyvpn/backend/internal/config"
+	"github.com/zyvpn/backend/internal/i18n"
+	"github.com/zyvpn/backend/internal/model"
+	tele "gopkg.in/telebot.v3"
+)
+
+// topUpAmounts are the top-up amounts in TON offered by /topup, as in the mini app
+var topUpAmounts = []float64{0.5, 1, 2, 5}
+
+// handleBalance shows the balance with the first page of transactions
+func (b *Bot) handleBalance(c tele.Context) error {
+	if b.balanceSvc == nil {
+		return c.Send(errorText(b.lang(c), fmt.Errorf("balance service not configured")))
+	}
+	return b.sendBalancePage(c, 0)
+}
+
+// sendBalancePage shows the balance with a page of transactions, newest first
+func (b *Bot) sendBalancePage(c tele.Context, page int) error {
+	ctx := context.Background()
+	lang := b.lang(c)
+	userID := c.Sender().ID
+
+	balance, err := b.balanceSvc.GetBalance(ctx, userID)
+	if err != nil {
+		log.Printf("[Bot] Failed to get balance of user %d: %v", userID, err)
+		return c.Send(errorText(lang, err))
+	}
+
+	// One more than a page tells whether there is a next page
+	pageSize := config.BotTransactionsPageSize
+	transactions, err := b.balanceSvc.GetTransactions(ctx, userID, lang, pageSize+1, page*pageSize)
+	if err != nil {
+		log.Printf("[Bot] Failed to get transactions of user %d: %v", userID, err)
+		return c.Send(errorText(lang, err))
+	}
+	hasNext := len(transactions) > pageSize
+	if hasNext {
+		transactions = transactions[:pageSize]
+	}
+
+	var sb strings.Builder
+	sb.WriteString(i18n.T(lang, "bot_balance", balance))
+	if len(transactions) == 0 && page == 0 {
+		sb.WriteString(i18n.T(lang, "bot_balance_empty"))
+	} else {
+		sb.WriteString(i18n.T(lang, "bot_balance_history", page+1))
+		for _, tx := range transactions {
+			fmt.Fprintf(&sb, "\n%s · <b>%+.4f TON</b>\n%s\n",
+				tx.CreatedAt.Format("02.01.2006 15:04"), tx.Amount, html.EscapeString(transactionLabel(lang, tx)))
+		}
+	}
+
+	keyboard := &tele.ReplyMarkup{}
+	var pager []tele.Btn
+	if page > 0 {
+		pager = append(pager, keyboard.Data("◀️", "balance_page", strconv.Itoa(page-1)))
+	}
+	if hasNext {
+		pager = append(pager, keyboard.Data("▶️", "balance_page", strconv.Itoa(page+1)))
+	}
+	var rows []tele.Row
+	if len(pager) > 0 {
+		rows = append(rows, keyboard.Row(pager...))
+	}
+	actions := []tele.Btn{keyboard.Data(i18n.T(lang, "btn_top_up"), "topup")}
+	if b.promoCodeSvc != nil {
+		actions = append(actions, keyboard.Data(i18n.T(lang, "btn_promo_code"), "buy_promo"))
+	}
+	rows = append(rows, keyboard.Row(actions...))
+	keyboard.Inline(rows...)
+
+	return b.sendOrEdit(c, sb.String(), keyboard)
+}
+
+// transactionLabel describes a balance transaction by its type
+func transactionLabel(lang string, tx model.BalanceTransaction) string {
+	key := "tx_" + string(tx.Type)
+	if label := i18n.T(lang, key); label != key {
+		return label
+	}
+	if tx.Description != nil {
+		return *tx.Description
+	}
+	return string(tx.Type)
+}
+
+// handleBalancePageCallback switches the page of transactions
+func (b *Bot) handleBalancePageCallback(c tele.Context, payload string) error {
+	_ = c.Respond()
+	page, err := strconv.Atoi(payload)
+	if err != nil || page < 0 || b.balanceSvc == nil {
+		return nil
+	}
+	return b.sendBalancePage(c, page)
+}
+
+// handleTopUp offers the top-up amounts
+func (b *Bot) handleTopUp(c tele.Context) error {
+	lang := b.lang(c)
+	if b.paymentSvc == nil {
+		return c.Send(i18n.T(lang, "payment_unavailable"))
+	}
+
+	keyboard := &tele.ReplyMarkup{}
+	var buttons []tele.Btn
+	for _, amount := range topUpAmounts {
+		buttons = append(buttons, keyboard.Data(i18n.T(lang, "btn_top_up_amount", amount), "topup_amount", formatAmount(amount)))
+	}
+	keyboard.Inline(
+		keyboard.Row(buttons...),
+		keyboard.Row(keyboard.Data(i18n.T(lang, "btn_cancel"), "buy_cancel")),
+	)
+
+	return b.sendOrEdit(c, i18n.T(lang, "bot_top_up_amounts"), keyboard)
+}
+
+// handleTopUpCallback handles the amount and payment method buttons of a top-up
+func (b *Bot) handleTopUpCallback(c tele.Context, action, payload string) error {
+	_ = c.Respond()
+	lang := b.lang(c)
+	if b.paymentSvc == nil {
+		return c.Send(i18n.T(lang, "payment_unavailable"))
+	}
+
+	if action == "topup_amount" {
+		amount, err := parseAmount(payload)
+		if err != nil {
+			return nil
+		}
+		keyboard := &tele.ReplyMarkup{}
+		keyboard.Inline(
+			keyboard.Row(
+				keyboard.Data(i18n.T(lang, "btn_method_stars"), "topup_pay", string(model.PaymentProviderStars), payload),
+				keyboard.Data(i18n.T(lang, "btn_method_ton"), "topup_pay", string(model.PaymentProviderTON), payload),
+			),
+			keyboard.Row(keyboard.Data(i18n.T(lang, "btn_cancel"), "buy_cancel")),
+		)
+		return b.sendOrEdit(c, i18n.T(lang, "bot_top_up_methods", amount), keyboard)
+	}
+
+	provider, amountText, _ := strings.Cut(payload, "|")
+	amount, err := parseAmount(amountText)
+	if err != nil {
+		return nil
+	}
+	return b.topUpWith(c, lang, amount, model.PaymentProvider(provider))
+}
+
+// topUpWith creates a top-up payment and sends a Stars invoice or TON payment details
+func (b *Bot) topUpWith(c tele.Context, lang string, amount float64, provider model.PaymentProvider) error {
+	ctx := context.Background()
+	userID := c.Sender().ID
+
+	payment, err := b.paymentSvc.CreateTopUpPayment(ctx, userID, amount, provider)
+	if err != nil {
+		log.Printf("[Bot] Failed to create top-up payment for user %d: %v", userID, err)
+		return c.Send(errorText(lang, err))
+	}
+
+	if provider == model.PaymentProviderStars {
+		link, err := b.CreateStarsInvoice(userID,
+			i18n.T(lang, "invoice_top_up_title"),
+			i18n.T(lang, "invoice_top_up_description", amount),
+			int(payment.Amount),
+			payment.ID.String(),
+		)
+		if err != nil {
+			log.Printf("[Bot] Failed to create Stars top-up invoice for user %d: %v", userID, err)
+			return c.Send(errorText(lang, err))
+		}
+
+		keyboard := &tele.ReplyMarkup{}
+		keyboard.Inline(keyboard.Row(keyboard.URL(i18n.T(lang, "btn_pay_invoice", int(payment.Amount)), link)))
+		return c.Send(i18n.T(lang, "bot_top_up_stars", amount, int(payment.Amount)), keyboard, tele.ModeHTML)
+	}
+
+	info, err := b.paymentSvc.GetTONTopUpInfo(ctx, payment.ID)
+	if err != nil {
+		log.Printf("[Bot] Failed to get TON top-up info for %s: %v", payment.ID, err)
+		return c.Send(errorText(lang, err))
+	}
+	return b.sendTONPayment(c, lang, info)
+}
+
+// handlePromo applies the promo code given with /promo, or asks for one
+func (b *Bot) handlePromo(c tele.Context) error {
+	if b.promoCodeSvc == nil {
+		return nil
+	}
+	code := strings.TrimSpace(c.Message().Payload)
+	if code == "" {
+		return b.handlePromoPrompt(c)
+	}
+	return b.applyPromoCode(c, code)
+}
+
+// formatAmount formats a TON amount for button data
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+// parseAmount parses a TON amount from button data, accepting only the offered amounts
+func parseAmount(s string) (float64, error) {
+	amount, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	for _, offered := range topUpAmounts {
+		if amount == offered {
+			return amount, nil
+		}
+	}
+	return 0, fmt.Errorf("amount %s is not offered", s)
+}
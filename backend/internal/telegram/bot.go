@@ -1,15 +1,23 @@
 package telegram
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"html"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	tele "gopkg.in/telebot.v3"
 	"github.com/zyvpn/backend/internal/config"
+	"github.com/zyvpn/backend/internal/i18n"
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/panel"
+	"github.com/zyvpn/backend/internal/qr"
 	"github.com/zyvpn/backend/internal/service"
 )
 
@@ -20,6 +28,13 @@ type Bot struct {
 	subscriptionSvc *service.SubscriptionService
 	referralSvc     *service.ReferralService
 	paymentSvc      *service.PaymentService
+	statusSvc       *service.StatusService
+	alertSvc        *service.AlertService
+	planSvc         *service.PlanService
+	serverSvc       *service.ServerService
+	balanceSvc      *service.BalanceService
+	promoCodeSvc    *service.PromoCodeService
+	chatStates      *service.ChatStateService
 }
 
 func NewBot(
@@ -59,8 +74,15 @@ func (b *Bot) registerHandlers() {
 	b.bot.Handle("/support", b.handleSupport)
 	b.bot.Handle("/referral", b.handleReferral)
 	b.bot.Handle("/trial", b.handleTrial)
+	b.bot.Handle("/status_servers", b.handleServersStatus)
+	b.bot.Handle("/language", b.handleLanguage)
+	b.bot.Handle("/buy", b.handleBuy)
+	b.bot.Handle("/balance", b.handleBalance)
+	b.bot.Handle("/topup", b.handleTopUp)
+	b.bot.Handle("/promo", b.handlePromo)
 
 	b.bot.Handle(tele.OnCallback, b.handleCallback)
+	b.bot.Handle(tele.OnText, b.handleText)
 	b.bot.Handle(tele.OnCheckout, b.handlePreCheckout)
 	b.bot.Handle(tele.OnPayment, b.handleSuccessfulPayment)
 }
@@ -69,7 +91,29 @@ func (b *Bot) SetPaymentService(svc *service.PaymentService) {
 	b.paymentSvc = svc
 }
 
+func (b *Bot) SetStatusService(svc *service.StatusService) {
+	b.statusSvc = svc
+}
+
+func (b *Bot) SetAlertService(svc *service.AlertService) {
+	b.alertSvc = svc
+}
+
+// SetShopServices sets the services used to buy subscriptions in the chat
+func (b *Bot) SetShopServices(planSvc *service.PlanService, serverSvc *service.ServerService, balanceSvc *service.BalanceService, promoCodeSvc *service.PromoCodeService, chatStates *service.ChatStateService) {
+	b.planSvc = planSvc
+	b.serverSvc = serverSvc
+	b.balanceSvc = balanceSvc
+	b.promoCodeSvc = promoCodeSvc
+	b.chatStates = chatStates
+}
+
 func (b *Bot) StartPolling(ctx context.Context) {
+	// Long polling fails while a webhook is set, e.g. one kept from webhook mode
+	if err := b.bot.RemoveWebhook(); err != nil {
+		log.Printf("Failed to delete Telegram webhook: %v", err)
+	}
+
 	go func() {
 		<-ctx.Done()
 		b.bot.Stop()
@@ -77,6 +121,20 @@ func (b *Bot) StartPolling(ctx context.Context) {
 	b.bot.Start()
 }
 
+// lang returns the language to answer the sender of an update in
+func (b *Bot) lang(c tele.Context) string {
+	sender := c.Sender()
+	if sender == nil {
+		return i18n.DefaultLang
+	}
+	return b.userService.Language(context.Background(), sender.ID, sender.LanguageCode)
+}
+
+// userLang returns the language of notifications sent to a user
+func (b *Bot) userLang(userID int64) string {
+	return b.userService.Language(context.Background(), userID, "")
+}
+
 func (b *Bot) handlePreCheckout(c tele.Context) error {
 	// Accept all pre-checkout queries
 	return c.Accept()
@@ -112,7 +170,13 @@ func (b *Bot) handleSuccessfulPayment(c tele.Context) error {
 	// Complete the payment
 	if err := b.paymentSvc.CompletePayment(context.Background(), paymentID); err != nil {
 		log.Printf("Failed to complete payment %s: %v", paymentID, err)
-		return c.Send("Ошибка при обработке платежа. Обратитесь в поддержку.")
+		return c.Send(i18n.T(b.lang(c), "bot_payment_failed"))
+	}
+
+	// Top-ups report the new balance instead of a subscription
+	if p, err := b.paymentSvc.GetPayment(context.Background(), paymentID); err == nil && p.PaymentType == model.PaymentTypeTopUp {
+		b.sendPaymentCompleted(c.Sender().ID, p)
+		return nil
 	}
 
 	// Get subscription for notification
@@ -121,7 +185,7 @@ func (b *Bot) handleSuccessfulPayment(c tele.Context) error {
 		return b.SendSubscriptionActivated(c.Sender().ID, sub.ExpiresAt.Format("02.01.2006"))
 	}
 
-	return c.Send("✅ Оплата прошла успешно! Используйте /key для получения ключа.")
+	return c.Send(i18n.T(b.lang(c), "bot_payment_done"))
 }
 
 func (b *Bot) GetBotUsername() string {
@@ -164,19 +228,11 @@ func (b *Bot) handleStart(c tele.Context) error {
 		_ = b.referralSvc.CreateReferral(context.Background(), *referredBy, user.ID)
 	}
 
-	text := fmt.Sprintf(`Привет, %s! 👋
-
-🔐 <b>ZyVPN</b> — быстрый и безопасный VPN
-
-✅ Протокол VLESS + Reality
-✅ Высокая скорость
-✅ Безлимитные тарифы
-✅ Простое подключение
-
-Нажми кнопку ниже, чтобы выбрать тариф и оплатить подписку.`, user.FirstName)
+	lang := b.lang(c)
+	text := i18n.T(lang, "bot_start", user.FirstName)
 
 	if isNew && referredBy != nil {
-		text += "\n\n🎁 Тебя пригласил друг! Твой друг получит бонус при твоей первой оплате."
+		text += i18n.T(lang, "bot_start_referred")
 	}
 
 	// Check if user has used trial
@@ -188,17 +244,20 @@ func (b *Bot) handleStart(c tele.Context) error {
 	// Only show trial button if user hasn't used it yet
 	if !hasUsedTrial {
 		rows = append(rows, keyboard.Row(
-			keyboard.Data("🎁 Попробовать бесплатно (3 дня)", "trial"),
+			keyboard.Data(i18n.T(lang, "btn_trial"), "trial"),
 		))
 	}
 
 	rows = append(rows,
 		keyboard.Row(
-			keyboard.WebApp("📱 Открыть магазин", &tele.WebApp{URL: b.cfg.Telegram.WebAppURL}),
+			keyboard.WebApp(i18n.T(lang, "btn_open_shop"), &tele.WebApp{URL: b.cfg.Telegram.WebAppURL}),
+		),
+		keyboard.Row(
+			keyboard.Data(i18n.T(lang, "btn_buy"), "buy"),
 		),
 		keyboard.Row(
-			keyboard.Data("📊 Статус подписки", "status"),
-			keyboard.Data("🔑 Получить ключ", "key"),
+			keyboard.Data(i18n.T(lang, "btn_status"), "status"),
+			keyboard.Data(i18n.T(lang, "btn_get_key"), "key"),
 		),
 	)
 	keyboard.Inline(rows...)
@@ -208,40 +267,32 @@ func (b *Bot) handleStart(c tele.Context) error {
 
 func (b *Bot) handleStatus(c tele.Context) error {
 	user := c.Sender()
+	lang := b.lang(c)
 	sub, err := b.subscriptionSvc.GetActiveSubscription(context.Background(), user.ID)
 	if err != nil {
-		text := `❌ <b>У вас нет активной подписки</b>
-
-Нажмите кнопку ниже, чтобы выбрать тариф.`
+		text := i18n.T(lang, "bot_status_none")
 
 		keyboard := &tele.ReplyMarkup{}
 		keyboard.Inline(
 			keyboard.Row(
-				keyboard.WebApp("📱 Выбрать тариф", &tele.WebApp{URL: b.cfg.Telegram.WebAppURL}),
+				keyboard.WebApp(i18n.T(lang, "btn_choose_plan"), &tele.WebApp{URL: b.cfg.Telegram.WebAppURL}),
 			),
 		)
 
 		return c.Send(text, keyboard, tele.ModeHTML)
 	}
 
-	_ = b.subscriptionSvc.SyncTraffic(context.Background(), sub.ID)
-	sub, _ = b.subscriptionSvc.GetSubscription(context.Background(), sub.ID)
-
 	var trafficText string
 	if sub.TrafficLimit > 0 {
 		trafficGB := float64(sub.TrafficUsed) / (1024 * 1024 * 1024)
 		limitGB := float64(sub.TrafficLimit) / (1024 * 1024 * 1024)
-		trafficText = fmt.Sprintf("%.2f / %.0f ГБ", trafficGB, limitGB)
+		trafficText = i18n.T(lang, "bot_traffic_limited", trafficGB, limitGB)
 	} else {
 		trafficGB := float64(sub.TrafficUsed) / (1024 * 1024 * 1024)
-		trafficText = fmt.Sprintf("%.2f ГБ (безлимит)", trafficGB)
+		trafficText = i18n.T(lang, "bot_traffic_unlimited", trafficGB)
 	}
 
-	text := fmt.Sprintf(`✅ <b>Подписка активна</b>
-
-📅 Действует до: %s
-📊 Трафик: %s
-⏳ Осталось: %d дней`,
+	text := i18n.T(lang, "bot_status_active",
 		sub.ExpiresAt.Format("02.01.2006"),
 		trafficText,
 		sub.DaysRemaining(),
@@ -250,10 +301,10 @@ func (b *Bot) handleStatus(c tele.Context) error {
 	keyboard := &tele.ReplyMarkup{}
 	keyboard.Inline(
 		keyboard.Row(
-			keyboard.Data("🔑 Получить ключ", "key"),
+			keyboard.Data(i18n.T(lang, "btn_get_key"), "key"),
 		),
 		keyboard.Row(
-			keyboard.WebApp("📱 Продлить подписку", &tele.WebApp{URL: b.cfg.Telegram.WebAppURL}),
+			keyboard.WebApp(i18n.T(lang, "btn_extend"), &tele.WebApp{URL: b.cfg.Telegram.WebAppURL}),
 		),
 	)
 
@@ -262,76 +313,85 @@ func (b *Bot) handleStatus(c tele.Context) error {
 
 func (b *Bot) handleKey(c tele.Context) error {
 	user := c.Sender()
-	key, err := b.subscriptionSvc.GetConnectionKey(context.Background(), user.ID)
-	if err != nil {
-		text := `❌ <b>Ключ недоступен</b>
-
-У вас нет активной подписки. Оформите подписку, чтобы получить ключ подключения.`
+	lang := b.lang(c)
+	keys, err := b.subscriptionSvc.GetConnectionKeys(context.Background(), user.ID, lang)
+	if errors.Is(err, service.ErrNoActiveEndpoints) {
+		return c.Send(errorText(lang, err))
+	}
+	if err != nil || len(keys) == 0 {
+		text := i18n.T(lang, "bot_key_none")
 
 		keyboard := &tele.ReplyMarkup{}
 		keyboard.Inline(
 			keyboard.Row(
-				keyboard.WebApp("📱 Выбрать тариф", &tele.WebApp{URL: b.cfg.Telegram.WebAppURL}),
+				keyboard.WebApp(i18n.T(lang, "btn_choose_plan"), &tele.WebApp{URL: b.cfg.Telegram.WebAppURL}),
 			),
 		)
 
 		return c.Send(text, keyboard, tele.ModeHTML)
 	}
 
-	text := fmt.Sprintf(`🔑 <b>Ваш ключ подключения:</b>
-
-<code>%s</code>
-
-📱 Скопируйте ключ и вставьте в приложение:
-• iOS: Streisand, V2Box
-• Android: V2rayNG, NekoBox
-• Windows/Mac: Nekoray, V2rayN`, key)
+	key := keys[0].Key
+	text := i18n.T(lang, "bot_key", key)
 
 	keyboard := &tele.ReplyMarkup{}
 	keyboard.Inline(
 		keyboard.Row(
-			keyboard.WebApp("📱 QR-код", &tele.WebApp{URL: b.cfg.Telegram.WebAppURL + "/key"}),
+			keyboard.WebApp(i18n.T(lang, "btn_open_miniapp"), &tele.WebApp{URL: b.cfg.Telegram.WebAppURL + "/key"}),
 		),
 	)
 
-	return c.Send(text, keyboard, tele.ModeHTML)
-}
-
-func (b *Bot) handleHelp(c tele.Context) error {
-	text := `📖 <b>Помощь по ZyVPN</b>
-
-<b>🔧 Настройка VPN:</b>
-
-1️⃣ <b>Выберите приложение:</b>
-• iOS: Streisand, V2Box
-• Android: V2rayNG, NekoBox
-• Windows/Mac: Nekoray, V2rayN
-
-2️⃣ Установите приложение
-
-3️⃣ Получите ключ: /key или Mini App
-
-4️⃣ Вставьте ключ в приложение
-
-5️⃣ Подключитесь!
+	// Send the key as a QR photo so it can be scanned from a TV or a second phone.
+	// The key text goes in its own message: captions are capped at 1024 characters,
+	// which long multi-inbound keys exceed.
+	if png, err := qr.PNG(key, qr.DefaultSize); err != nil {
+		log.Printf("Failed to render QR code for user %d: %v", user.ID, err)
+	} else {
+		photo := &tele.Photo{
+			File:    tele.FromReader(bytes.NewReader(png)),
+			Caption: i18n.T(lang, "bot_key_qr"),
+		}
+		if err := c.Send(photo); err != nil {
+			log.Printf("Failed to send QR code to user %d: %v", user.ID, err)
+		}
+	}
+	if err := c.Send(text, keyboard, tele.ModeHTML); err != nil {
+		return err
+	}
 
-<b>🎁 Промокоды:</b>
-Откройте Mini App → Баланс → Введите промокод
+	// Subscription URL, where the server's panel serves one
+	if url, err := b.subscriptionSvc.GetSubscriptionURL(context.Background(), user.ID); err == nil && url != "" {
+		caption := i18n.T(lang, "bot_key_subscription_url", url)
+		if png, err := qr.PNG(url, qr.DefaultSize); err == nil {
+			err = c.Send(&tele.Photo{File: tele.FromReader(bytes.NewReader(png)), Caption: caption}, tele.ModeHTML)
+		} else {
+			err = c.Send(caption, tele.ModeHTML)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(keys) < 2 {
+		return nil
+	}
 
-<b>📱 Команды:</b>
-/start — Главное меню
-/status — Статус подписки
-/key — Получить ключ
-/trial — Бесплатный период
-/referral — Реферальная программа
-/support — Связаться с поддержкой
+	// Fallback keys for additional inbounds (e.g. when Reality is blocked)
+	var sb strings.Builder
+	sb.WriteString(i18n.T(lang, "bot_key_fallback"))
+	for _, k := range keys[1:] {
+		sb.WriteString(fmt.Sprintf("\n<b>%s</b>\n<code>%s</code>\n", k.Name, k.Key))
+	}
+	return c.Send(sb.String(), tele.ModeHTML)
+}
 
-❓ Вопросы? /support`
+func (b *Bot) handleHelp(c tele.Context) error {
+	lang := b.lang(c)
+	text := i18n.T(lang, "bot_help")
 
 	keyboard := &tele.ReplyMarkup{}
 	keyboard.Inline(
 		keyboard.Row(
-			keyboard.WebApp("📱 Открыть Mini App", &tele.WebApp{URL: b.cfg.Telegram.WebAppURL}),
+			keyboard.WebApp(i18n.T(lang, "btn_open_miniapp"), &tele.WebApp{URL: b.cfg.Telegram.WebAppURL}),
 		),
 	)
 
@@ -339,14 +399,7 @@ func (b *Bot) handleHelp(c tele.Context) error {
 }
 
 func (b *Bot) handleSupport(c tele.Context) error {
-	text := `💬 <b>Поддержка</b>
-
-Если у вас возникли вопросы или проблемы, напишите нам:
-
-📧 support@zyvpn.com
-💬 @zyvpn_support
-
-Мы ответим в течение 24 часов.`
+	text := i18n.T(b.lang(c), "bot_support")
 
 	return c.Send(text, tele.ModeHTML)
 }
@@ -363,20 +416,7 @@ func (b *Bot) handleReferral(c tele.Context) error {
 		return err
 	}
 
-	text := fmt.Sprintf(`🎁 <b>Реферальная программа</b>
-
-Приглашай друзей и получай TON на баланс!
-
-Когда друг оплатит первую подписку:
-• Ты получишь +0.1 TON на баланс
-
-📊 <b>Твоя статистика:</b>
-👥 Приглашено: %d
-⏳ Ожидают оплаты: %d
-💎 Получено TON: %.4f
-
-🔗 <b>Твоя ссылка:</b>
-<code>%s</code>`,
+	text := i18n.T(b.lang(c), "bot_referral",
 		stats.TotalReferrals,
 		stats.PendingReferrals,
 		stats.CreditedBonusTON,
@@ -386,10 +426,119 @@ func (b *Bot) handleReferral(c tele.Context) error {
 	return c.Send(text, tele.ModeHTML)
 }
 
+// statusEmoji returns the indicator of a location status on the status page
+func statusEmoji(status string) string {
+	switch status {
+	case model.ServerStatusOnline:
+		return "🟢"
+	case model.ServerStatusDegraded:
+		return "🟡"
+	case model.ServerStatusOffline:
+		return "🔴"
+	case model.LocationStatusMaintenance:
+		return "🛠"
+	default:
+		return "⚪️"
+	}
+}
+
+// formatUptime formats an uptime percentage, or a dash when there is no history yet
+func formatUptime(uptime *float64) string {
+	if uptime == nil {
+		return "—"
+	}
+	return fmt.Sprintf("%.1f%%", *uptime)
+}
+
+func (b *Bot) handleServersStatus(c tele.Context) error {
+	lang := b.lang(c)
+	if b.statusSvc == nil {
+		return c.Send(i18n.T(lang, "bot_servers_unavailable"))
+	}
+
+	page, err := b.statusSvc.GetStatusPage(context.Background())
+	if err != nil {
+		log.Printf("[Bot] Failed to get status page: %v", err)
+		return c.Send(i18n.T(lang, "bot_servers_unavailable"))
+	}
+
+	var sb strings.Builder
+	switch page.Status {
+	case model.StatusOperational:
+		sb.WriteString(i18n.T(lang, "bot_servers_operational"))
+	case model.StatusDegraded:
+		sb.WriteString(i18n.T(lang, "bot_servers_degraded"))
+	case model.StatusPartialOutage:
+		sb.WriteString(i18n.T(lang, "bot_servers_partial_outage"))
+	default:
+		sb.WriteString(i18n.T(lang, "bot_servers_outage"))
+	}
+
+	sb.WriteString(i18n.T(lang, "bot_servers_uptime"))
+	for _, loc := range page.Locations {
+		sb.WriteString(fmt.Sprintf("%s %s %s — %s / %s\n",
+			statusEmoji(loc.Status), loc.FlagEmoji, html.EscapeString(loc.Name),
+			formatUptime(loc.Uptime24h), formatUptime(loc.Uptime7d)))
+	}
+
+	if len(page.Incidents) > 0 {
+		sb.WriteString(i18n.T(lang, "bot_servers_incidents"))
+		for _, inc := range page.Incidents {
+			sb.WriteString(fmt.Sprintf("%s %s — %s\n",
+				statusEmoji(inc.Status), html.EscapeString(inc.Location), i18n.T(lang, "bot_since", inc.StartedAt.Format("02.01 15:04"))))
+		}
+	}
+
+	if len(page.Notices) > 0 {
+		sb.WriteString(i18n.T(lang, "bot_servers_notices"))
+		for _, n := range page.Notices {
+			icon := "⚠️"
+			if n.Kind == model.NoticeKindMaintenance {
+				icon = "🛠"
+			}
+			sb.WriteString(fmt.Sprintf("%s <b>%s</b>", icon, html.EscapeString(n.Title)))
+			if n.Location != "" {
+				sb.WriteString(" (" + html.EscapeString(n.Location) + ")")
+			}
+			sb.WriteString("\n")
+			if n.Kind == model.NoticeKindMaintenance {
+				period := i18n.T(lang, "bot_since", n.StartsAt.Format("02.01 15:04"))
+				if n.EndsAt != nil {
+					period += i18n.T(lang, "bot_until", n.EndsAt.Format("02.01 15:04"))
+				}
+				sb.WriteString(period + "\n")
+			}
+			if n.Message != "" {
+				sb.WriteString(html.EscapeString(n.Message) + "\n")
+			}
+		}
+	}
+
+	return c.Send(sb.String(), tele.ModeHTML)
+}
+
 func (b *Bot) handleCallback(c tele.Context) error {
 	data := c.Callback().Data
 	fmt.Printf("[Bot] Callback received: %q from user %d\n", data, c.Sender().ID)
 
+	// Buttons with a payload: \funique|data
+	if unique, payload, ok := strings.Cut(strings.TrimPrefix(data, "\f"), "|"); ok {
+		switch unique {
+		case "alert_ack", "alert_mute":
+			return b.handleAlertCallback(c, unique, payload)
+		case "lang":
+			return b.handleLanguageCallback(c, payload)
+		case "buy_plan", "buy_server", "buy_pay":
+			return b.handleBuyCallback(c, unique, payload)
+		case "ton_paid":
+			return b.handleTONPaidCallback(c, payload)
+		case "balance_page":
+			return b.handleBalancePageCallback(c, payload)
+		case "topup_amount", "topup_pay":
+			return b.handleTopUpCallback(c, unique, payload)
+		}
+	}
+
 	// Acknowledge callback to remove loading state
 	defer c.Respond()
 
@@ -402,6 +551,12 @@ func (b *Bot) handleCallback(c tele.Context) error {
 		return b.handleKey(c)
 	case "trial":
 		return b.handleTrial(c)
+	case "buy":
+		return b.handleBuy(c)
+	case "topup":
+		return b.handleTopUp(c)
+	case "buy_promo", "buy_cancel":
+		return b.handleBuyCallback(c, strings.TrimPrefix(data, "\f"), "")
 	default:
 		fmt.Printf("[Bot] Unknown callback data: %q\n", data)
 	}
@@ -410,51 +565,194 @@ func (b *Bot) handleCallback(c tele.Context) error {
 
 func (b *Bot) handleTrial(c tele.Context) error {
 	user := c.Sender()
+	lang := b.lang(c)
 	fmt.Printf("[Bot] handleTrial called for user %d\n", user.ID)
 
 	sub, err := b.subscriptionSvc.ActivateTrial(context.Background(), user.ID)
 	if err != nil {
 		fmt.Printf("[Bot] Trial activation error for user %d: %v\n", user.ID, err)
 		var text string
-		if err.Error() == "trial subscription already used" {
-			text = `❌ <b>Trial уже использован</b>
-
-Вы уже использовали бесплатный пробный период. Выберите тариф для продления.`
-		} else if err.Error() == "user already has an active subscription" {
-			text = `❌ <b>Подписка уже активна</b>
-
-У вас уже есть активная подписка. Используйте /status для проверки статуса.`
-		} else {
-			text = fmt.Sprintf("❌ Ошибка: %s", err.Error())
+		switch {
+		case errors.Is(err, service.ErrTrialAlreadyUsed):
+			text = i18n.T(lang, "bot_trial_used")
+		case errors.Is(err, service.ErrSubscriptionActive):
+			text = i18n.T(lang, "bot_trial_subscription_active")
+		case errors.Is(err, panel.ErrUnavailable):
+			text = i18n.T(lang, "bot_trial_server_unavailable")
+		default:
+			text = i18n.T(lang, "bot_error", i18n.Message(lang, err))
 		}
 
 		keyboard := &tele.ReplyMarkup{}
 		keyboard.Inline(
 			keyboard.Row(
-				keyboard.WebApp("📱 Выбрать тариф", &tele.WebApp{URL: b.cfg.Telegram.WebAppURL}),
+				keyboard.WebApp(i18n.T(lang, "btn_choose_plan"), &tele.WebApp{URL: b.cfg.Telegram.WebAppURL}),
 			),
 		)
 
 		return c.Send(text, keyboard, tele.ModeHTML)
 	}
 
-	text := fmt.Sprintf(`✅ <b>Trial подписка активирована!</b>
+	text := i18n.T(lang, "bot_trial_activated", sub.ExpiresAt.Format("02.01.2006 15:04"))
 
-🎁 Вам предоставлен бесплатный доступ на 3 дня.
+	keyboard := &tele.ReplyMarkup{}
+	keyboard.Inline(
+		keyboard.Row(
+			keyboard.Data(i18n.T(lang, "btn_get_key"), "key"),
+		),
+	)
 
-📅 Действует до: %s
-📊 Трафик: 10 ГБ
+	return c.Send(text, keyboard, tele.ModeHTML)
+}
 
-Используйте команду /key чтобы получить ключ подключения.`, sub.ExpiresAt.Format("02.01.2006 15:04"))
+// languageNames are the names of supported languages on the language buttons
+var languageNames = map[string]string{
+	i18n.LangRU: "🇷🇺 Русский",
+	i18n.LangEN: "🇬🇧 English",
+}
 
+// handleLanguage lets the user choose the language of the bot and the mini app
+func (b *Bot) handleLanguage(c tele.Context) error {
+	keyboard := &tele.ReplyMarkup{}
+	var buttons []tele.Btn
+	for _, lang := range i18n.Languages {
+		buttons = append(buttons, keyboard.Data(languageNames[lang], "lang", lang))
+	}
+	keyboard.Inline(keyboard.Row(buttons...))
+
+	return c.Send(i18n.T(b.lang(c), "bot_language"), keyboard)
+}
+
+// handleLanguageCallback stores the language chosen with a language button
+func (b *Bot) handleLanguageCallback(c tele.Context, lang string) error {
+	if err := b.userService.SetLanguage(context.Background(), c.Sender().ID, lang); err != nil {
+		log.Printf("[Bot] Failed to set language %q for user %d: %v", lang, c.Sender().ID, err)
+		return c.Respond(&tele.CallbackResponse{Text: i18n.Message(b.lang(c), err), ShowAlert: true})
+	}
+
+	text := i18n.T(lang, "bot_language_set")
+	if msg := c.Message(); msg != nil {
+		_, _ = b.bot.Edit(msg, text)
+	}
+	return c.Respond(&tele.CallbackResponse{Text: text})
+}
+
+// alertKindNames are the titles of admin alert kinds
+var alertKindNames = map[string]string{
+	model.AlertKindServerDown:     "Сервер недоступен",
+	model.AlertKindServerDegraded: "Xray не запущен",
+	model.AlertKindPanelErrors:    "Ошибки панели",
+	model.AlertKindTonWorker:      "Ошибки TON worker",
+	model.AlertKindPaymentsStuck:  "Зависшие платежи",
+	model.AlertKindReconciliation: "Ошибка синхронизации",
+}
+
+// alertTitle returns the title of an alert kind, or the kind itself when it has none
+func alertTitle(kind string) string {
+	if title := alertKindNames[kind]; title != "" {
+		return title
+	}
+	return kind
+}
+
+// SendAlert sends an admin alert, with acknowledge and mute buttons while it is firing
+func (b *Bot) SendAlert(chatID int64, alert *model.Alert) error {
+	if alert.Status == model.AlertStatusResolved {
+		text := fmt.Sprintf(`✅ <b>Восстановлено: %s</b>
+
+%s
+
+Длительность: %s`,
+			alertTitle(alert.Kind),
+			html.EscapeString(alert.Message),
+			time.Since(alert.FirstFiredAt).Round(time.Second),
+		)
+		_, err := b.bot.Send(&tele.Chat{ID: chatID}, text, tele.ModeHTML)
+		return err
+	}
+
+	text := alertText(alert)
+	id := alert.ID.String()
 	keyboard := &tele.ReplyMarkup{}
 	keyboard.Inline(
 		keyboard.Row(
-			keyboard.Data("🔑 Получить ключ", "key"),
+			keyboard.Data("✅ Принято", "alert_ack", id),
+		),
+		keyboard.Row(
+			keyboard.Data("🔕 1 ч", "alert_mute", id, "60"),
+			keyboard.Data("🔕 24 ч", "alert_mute", id, "1440"),
 		),
 	)
 
-	return c.Send(text, keyboard, tele.ModeHTML)
+	_, err := b.bot.Send(&tele.Chat{ID: chatID}, text, keyboard, tele.ModeHTML)
+	return err
+}
+
+// alertText formats a firing alert for the admin chat
+func alertText(alert *model.Alert) string {
+	icon := "⚠️"
+	if alert.Severity == model.AlertSeverityCritical {
+		icon = "🚨"
+	}
+	return fmt.Sprintf(`%s <b>%s</b>
+
+%s
+
+С %s, повторений: %d`,
+		icon,
+		alertTitle(alert.Kind),
+		html.EscapeString(alert.Message),
+		alert.FirstFiredAt.Format("02.01 15:04"),
+		alert.Occurrences,
+	)
+}
+
+// handleAlertCallback acknowledges or mutes an alert from the admin chat
+func (b *Bot) handleAlertCallback(c tele.Context, action, payload string) error {
+	if b.alertSvc == nil {
+		return c.Respond()
+	}
+
+	parts := strings.Split(payload, "|")
+	alertID, err := uuid.Parse(parts[0])
+	if err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: "Неверный алерт"})
+	}
+
+	var alert *model.Alert
+	var reply string
+	if action == "alert_ack" {
+		alert, err = b.alertSvc.Acknowledge(context.Background(), c.Sender().ID, alertID)
+		reply = "Принято, повторов не будет"
+	} else {
+		minutes := 60
+		if len(parts) > 1 {
+			if m, convErr := strconv.Atoi(parts[1]); convErr == nil && m > 0 {
+				minutes = m
+			}
+		}
+		d := time.Duration(minutes) * time.Minute
+		alert, err = b.alertSvc.Mute(context.Background(), c.Sender().ID, alertID, d)
+		reply = fmt.Sprintf("Уведомления отключены на %v", d)
+	}
+	if err != nil {
+		if errors.Is(err, service.ErrNotAdmin) {
+			return c.Respond(&tele.CallbackResponse{Text: "Только для администраторов", ShowAlert: true})
+		}
+		log.Printf("[Bot] Failed to %s alert %s: %v", action, alertID, err)
+		return c.Respond(&tele.CallbackResponse{Text: "Ошибка: " + err.Error(), ShowAlert: true})
+	}
+
+	// Remove the buttons and note who handled the alert
+	who := c.Sender().Username
+	if who == "" {
+		who = c.Sender().FirstName
+	}
+	if msg := c.Message(); msg != nil {
+		text := alertText(alert) + "\n\n" + html.EscapeString(reply+" — "+who)
+		_, _ = b.bot.Edit(msg, text, tele.ModeHTML)
+	}
+	return c.Respond(&tele.CallbackResponse{Text: reply})
 }
 
 func (b *Bot) SendMessage(chatID int64, text string) error {
@@ -463,16 +761,13 @@ func (b *Bot) SendMessage(chatID int64, text string) error {
 }
 
 func (b *Bot) SendSubscriptionExpiring(chatID int64, daysLeft int) error {
-	text := fmt.Sprintf(`⏰ <b>Подписка скоро закончится!</b>
-
-Осталось дней: %d
-
-Продлите подписку, чтобы не потерять доступ к VPN.`, daysLeft)
+	lang := b.userLang(chatID)
+	text := i18n.T(lang, "notify_expiring", daysLeft)
 
 	keyboard := &tele.ReplyMarkup{}
 	keyboard.Inline(
 		keyboard.Row(
-			keyboard.WebApp("📱 Продлить подписку", &tele.WebApp{URL: b.cfg.Telegram.WebAppURL}),
+			keyboard.WebApp(i18n.T(lang, "btn_extend"), &tele.WebApp{URL: b.cfg.Telegram.WebAppURL}),
 		),
 	)
 
@@ -481,14 +776,13 @@ func (b *Bot) SendSubscriptionExpiring(chatID int64, daysLeft int) error {
 }
 
 func (b *Bot) SendSubscriptionExpired(chatID int64) error {
-	text := `❌ <b>Подписка закончилась</b>
-
-Ваша подписка на VPN истекла. Продлите подписку, чтобы восстановить доступ.`
+	lang := b.userLang(chatID)
+	text := i18n.T(lang, "notify_expired")
 
 	keyboard := &tele.ReplyMarkup{}
 	keyboard.Inline(
 		keyboard.Row(
-			keyboard.WebApp("📱 Продлить подписку", &tele.WebApp{URL: b.cfg.Telegram.WebAppURL}),
+			keyboard.WebApp(i18n.T(lang, "btn_extend"), &tele.WebApp{URL: b.cfg.Telegram.WebAppURL}),
 		),
 	)
 
@@ -497,16 +791,13 @@ func (b *Bot) SendSubscriptionExpired(chatID int64) error {
 }
 
 func (b *Bot) SendSubscriptionActivated(chatID int64, expiresAt string) error {
-	text := fmt.Sprintf(`✅ <b>Подписка активирована!</b>
-
-Спасибо за покупку! Ваша подписка активна до %s.
-
-Используйте команду /key чтобы получить ключ подключения.`, expiresAt)
+	lang := b.userLang(chatID)
+	text := i18n.T(lang, "notify_activated", expiresAt)
 
 	keyboard := &tele.ReplyMarkup{}
 	keyboard.Inline(
 		keyboard.Row(
-			keyboard.Data("🔑 Получить ключ", "key"),
+			keyboard.Data(i18n.T(lang, "btn_get_key"), "key"),
 		),
 	)
 
@@ -514,39 +805,34 @@ func (b *Bot) SendSubscriptionActivated(chatID int64, expiresAt string) error {
 	return err
 }
 
-// SendReferralBonus notifies referrer about received bonus
-func (b *Bot) SendReferralBonus(chatID int64, bonusTON float64, bonusDays int) error {
-	var text string
-	if bonusTON > 0 && bonusDays > 0 {
-		text = fmt.Sprintf(`🎁 <b>Реферальный бонус!</b>
+// SendSubscriptionExtended notifies a user that an admin added free days to the subscription
+func (b *Bot) SendSubscriptionExtended(chatID int64, days int, expiresAt string) error {
+	text := i18n.T(b.userLang(chatID), "notify_extended", days, expiresAt)
 
-Ваш друг оплатил подписку. Вы получили:
-💎 <b>+%.4f TON</b> на баланс
-📅 <b>+%d дней</b> к подписке
-
-Спасибо, что приглашаете друзей!`, bonusTON, bonusDays)
-	} else if bonusTON > 0 {
-		text = fmt.Sprintf(`🎁 <b>Реферальный бонус!</b>
-
-Ваш друг оплатил подписку. Вы получили:
-💎 <b>+%.4f TON</b> на баланс
-
-Спасибо, что приглашаете друзей!`, bonusTON)
-	} else if bonusDays > 0 {
-		text = fmt.Sprintf(`🎁 <b>Реферальный бонус!</b>
-
-Ваш друг оплатил подписку. Вы получили:
-📅 <b>+%d дней</b> к подписке
+	_, err := b.bot.Send(&tele.User{ID: chatID}, text, tele.ModeHTML)
+	return err
+}
 
-Спасибо, что приглашаете друзей!`, bonusDays)
-	} else {
+// SendReferralBonus notifies referrer about received bonus
+func (b *Bot) SendReferralBonus(chatID int64, bonusTON float64, bonusDays int) error {
+	if bonusTON <= 0 && bonusDays <= 0 {
 		return nil // No bonus to notify about
 	}
 
+	lang := b.userLang(chatID)
+	text := i18n.T(lang, "notify_referral_bonus")
+	if bonusTON > 0 {
+		text += i18n.T(lang, "notify_referral_bonus_ton", bonusTON)
+	}
+	if bonusDays > 0 {
+		text += i18n.T(lang, "notify_referral_bonus_days", bonusDays)
+	}
+	text += i18n.T(lang, "notify_referral_bonus_end")
+
 	keyboard := &tele.ReplyMarkup{}
 	keyboard.Inline(
 		keyboard.Row(
-			keyboard.WebApp("👥 Пригласить ещё", &tele.WebApp{URL: b.cfg.Telegram.WebAppURL + "/#/referral"}),
+			keyboard.WebApp(i18n.T(lang, "btn_invite_more"), &tele.WebApp{URL: b.cfg.Telegram.WebAppURL + "/#/referral"}),
 		),
 	)
 
@@ -554,12 +840,37 @@ func (b *Bot) SendReferralBonus(chatID int64, bonusTON float64, bonusDays int) e
 	return err
 }
 
+// SendConnectionKeyChanged sends a user the new key after their server's connection settings changed
+func (b *Bot) SendConnectionKeyChanged(chatID int64, key string) error {
+	text := i18n.T(b.userLang(chatID), "notify_key_changed", key)
+
+	_, err := b.bot.Send(&tele.User{ID: chatID}, text, tele.ModeHTML)
+	return err
+}
+
+// SendRealityChanged alerts an admin that a server's inbound parameters changed on the panel
+func (b *Bot) SendRealityChanged(chatID int64, change service.RealityChange) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "⚠️ <b>Параметры inbound изменились на панели</b>\n\nСервер: <b>%s</b>\nИзменено: %s",
+		change.Server.Name, strings.Join(change.Changes, ", "))
+
+	if len(change.Followed) > 0 {
+		fmt.Fprintf(&sb, "\n\n✅ Обновлено в настройках сервера: %s. Ключи подписок перегенерированы.", strings.Join(change.Followed, ", "))
+	}
+	if len(change.Mismatches) > 0 {
+		sb.WriteString("\n\n❌ <b>Не совпадает с панелью, ключи пользователей не работают:</b>")
+		for _, mismatch := range change.Mismatches {
+			sb.WriteString("\n• " + mismatch)
+		}
+	}
+
+	_, err := b.bot.Send(&tele.User{ID: chatID}, sb.String(), tele.ModeHTML)
+	return err
+}
+
 // SendBalanceTopUp notifies user about balance top-up
 func (b *Bot) SendBalanceTopUp(chatID int64, amount float64, newBalance float64) error {
-	text := fmt.Sprintf(`💰 <b>Баланс пополнен!</b>
-
-Зачислено: <b>+%.4f TON</b>
-Текущий баланс: <b>%.4f TON</b>`, amount, newBalance)
+	text := i18n.T(b.userLang(chatID), "notify_top_up", amount, newBalance)
 
 	_, err := b.bot.Send(&tele.User{ID: chatID}, text, tele.ModeHTML)
 	return err
@@ -0,0 +1,455 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/zyvpn/backend/internal/i18n"
+	"github.com/zyvpn/backend/internal/model"
+	"github.com/zyvpn/backend/internal/panel"
+	"github.com/zyvpn/backend/internal/service"
+	tele "gopkg.in/telebot.v3"
+)
+
+// awaitPromoCode marks a chat whose next text message is a promo code
+const awaitPromoCode = "promo_code"
+
+// chatState returns the purchase in progress in a chat, or nil without one
+func (b *Bot) chatState(chatID int64) *model.BotChatState {
+	if b.chatStates == nil {
+		return nil
+	}
+	state, err := b.chatStates.Get(context.Background(), chatID)
+	if err != nil {
+		log.Printf("[Bot] Failed to get purchase in chat %d: %v", chatID, err)
+		return nil
+	}
+	return state
+}
+
+// setChatState stores the purchase in progress in a chat; nil ends it
+func (b *Bot) setChatState(chatID int64, state *model.BotChatState) {
+	if b.chatStates == nil {
+		return
+	}
+	ctx := context.Background()
+	if state == nil {
+		if err := b.chatStates.Delete(ctx, chatID); err != nil {
+			log.Printf("[Bot] Failed to end purchase in chat %d: %v", chatID, err)
+		}
+		return
+	}
+	state.ChatID = chatID
+	if err := b.chatStates.Save(ctx, state); err != nil {
+		log.Printf("[Bot] Failed to save purchase in chat %d: %v", chatID, err)
+	}
+}
+
+// errorText returns the message shown for a failed action: the text of a
+// user-facing error, a generic one for anything else
+func errorText(lang string, err error) string {
+	switch {
+	case i18n.Code(err) != "":
+		return i18n.T(lang, "bot_error", i18n.Message(lang, err))
+	case errors.Is(err, panel.ErrUnavailable):
+		return i18n.T(lang, "bot_error", i18n.T(lang, "server_unavailable"))
+	}
+	return i18n.T(lang, "bot_error", i18n.T(lang, "internal_error"))
+}
+
+// handleBuy starts a purchase with the list of plans
+func (b *Bot) handleBuy(c tele.Context) error {
+	lang := b.lang(c)
+	if b.planSvc == nil || b.paymentSvc == nil {
+		return c.Send(i18n.T(lang, "bot_buy_no_plans"))
+	}
+
+	plans, err := b.planSvc.GetActivePlans(context.Background())
+	if err != nil {
+		log.Printf("[Bot] Failed to get plans: %v", err)
+		return c.Send(errorText(lang, err))
+	}
+	if len(plans) == 0 {
+		return c.Send(i18n.T(lang, "bot_buy_no_plans"))
+	}
+
+	b.setChatState(c.Chat().ID, &model.BotChatState{})
+
+	keyboard := &tele.ReplyMarkup{}
+	var rows []tele.Row
+	for _, plan := range plans {
+		label := i18n.T(lang, "bot_buy_plan", plan.Name, plan.PriceTON, plan.PriceStars)
+		rows = append(rows, keyboard.Row(keyboard.Data(label, "buy_plan", plan.ID.String())))
+	}
+	if b.promoCodeSvc != nil {
+		rows = append(rows, keyboard.Row(keyboard.Data(i18n.T(lang, "btn_promo_code"), "buy_promo")))
+	}
+	keyboard.Inline(rows...)
+
+	return c.Send(i18n.T(lang, "bot_buy_plans"), keyboard, tele.ModeHTML)
+}
+
+// handleBuyCallback routes the buttons of a purchase
+func (b *Bot) handleBuyCallback(c tele.Context, action, payload string) error {
+	lang := b.lang(c)
+	switch action {
+	case "buy_promo":
+		return b.handlePromoPrompt(c)
+	case "buy_cancel":
+		b.setChatState(c.Chat().ID, nil)
+		_ = c.Respond(&tele.CallbackResponse{Text: i18n.T(lang, "bot_buy_cancelled")})
+		if msg := c.Message(); msg != nil {
+			_ = b.bot.Delete(msg)
+		}
+		return nil
+	}
+
+	state := b.chatState(c.Chat().ID)
+	if state == nil && action != "buy_plan" {
+		_ = c.Respond()
+		return c.Send(i18n.T(lang, "bot_buy_expired"))
+	}
+	if state == nil {
+		state = &model.BotChatState{}
+	}
+
+	switch action {
+	case "buy_plan":
+		planID, err := uuid.Parse(payload)
+		if err != nil {
+			return c.Respond()
+		}
+		state.PlanID, state.ServerID, state.ServerChosen = planID, nil, false
+		b.setChatState(c.Chat().ID, state)
+		_ = c.Respond()
+		return b.sendBuyServers(c, lang, state)
+
+	case "buy_server":
+		state.ServerID, state.ServerChosen = nil, true
+		if payload != "auto" {
+			serverID, err := uuid.Parse(payload)
+			if err != nil {
+				return c.Respond()
+			}
+			state.ServerID = &serverID
+		}
+		b.setChatState(c.Chat().ID, state)
+		_ = c.Respond()
+		return b.sendBuyMethods(c, lang, state)
+
+	case "buy_pay":
+		_ = c.Respond()
+		if state.PlanID == uuid.Nil || !state.ServerChosen {
+			return c.Send(i18n.T(lang, "bot_buy_expired"))
+		}
+		return b.buyWith(c, lang, state, payload)
+	}
+	return c.Respond()
+}
+
+// sendBuyServers offers the online servers the chosen plan may use
+func (b *Bot) sendBuyServers(c tele.Context, lang string, state *model.BotChatState) error {
+	plan, err := b.planSvc.GetPlan(context.Background(), state.PlanID)
+	if err != nil || !plan.IsActive {
+		return c.Send(i18n.T(lang, "bot_error", i18n.T(lang, "plan_not_found")))
+	}
+
+	keyboard := &tele.ReplyMarkup{}
+	rows := []tele.Row{keyboard.Row(keyboard.Data(i18n.T(lang, "btn_server_auto"), "buy_server", "auto"))}
+	if b.serverSvc != nil {
+		servers, err := b.serverSvc.GetOnlineServers(context.Background())
+		if err != nil {
+			log.Printf("[Bot] Failed to get online servers: %v", err)
+		}
+		for _, srv := range servers {
+			if srv.IsFull || !plan.AllowsServerGroup(srv.Group) {
+				continue
+			}
+			rows = append(rows, keyboard.Row(keyboard.Data(serverLabel(srv), "buy_server", srv.ID.String())))
+		}
+	}
+	rows = append(rows, keyboard.Row(keyboard.Data(i18n.T(lang, "btn_cancel"), "buy_cancel")))
+	keyboard.Inline(rows...)
+
+	text := i18n.T(lang, "bot_buy_servers", html.EscapeString(plan.Name))
+	return b.sendOrEdit(c, text, keyboard)
+}
+
+// sendBuyMethods offers the payment methods for the chosen plan and server
+func (b *Bot) sendBuyMethods(c tele.Context, lang string, state *model.BotChatState) error {
+	ctx := context.Background()
+	plan, err := b.planSvc.GetPlan(ctx, state.PlanID)
+	if err != nil || !plan.IsActive {
+		return c.Send(i18n.T(lang, "bot_error", i18n.T(lang, "plan_not_found")))
+	}
+
+	serverName := i18n.T(lang, "bot_buy_server_auto")
+	if state.ServerID != nil && b.serverSvc != nil {
+		if srv, err := b.serverSvc.GetServer(ctx, *state.ServerID); err == nil {
+			serverName = serverLabel(srv.ToPublic())
+		}
+	}
+
+	keyboard := &tele.ReplyMarkup{}
+	var rows []tele.Row
+	if plan.PriceStars > 0 {
+		rows = append(rows, keyboard.Row(keyboard.Data(i18n.T(lang, "btn_pay_stars", plan.PriceStars), "buy_pay", string(model.PaymentProviderStars))))
+	}
+	if plan.PriceTON > 0 {
+		rows = append(rows, keyboard.Row(keyboard.Data(i18n.T(lang, "btn_pay_ton", plan.PriceTON), "buy_pay", string(model.PaymentProviderTON))))
+		if b.balanceSvc != nil {
+			rows = append(rows, keyboard.Row(keyboard.Data(i18n.T(lang, "btn_pay_balance", plan.PriceTON), "buy_pay", string(model.PaymentProviderBalance))))
+		}
+	}
+	if b.promoCodeSvc != nil {
+		rows = append(rows, keyboard.Row(keyboard.Data(i18n.T(lang, "btn_promo_code"), "buy_promo")))
+	}
+	rows = append(rows, keyboard.Row(keyboard.Data(i18n.T(lang, "btn_cancel"), "buy_cancel")))
+	keyboard.Inline(rows...)
+
+	text := i18n.T(lang, "bot_buy_methods", html.EscapeString(plan.Name), html.EscapeString(serverName))
+	return b.sendOrEdit(c, text, keyboard)
+}
+
+// buyWith pays for the chosen plan with the given provider
+func (b *Bot) buyWith(c tele.Context, lang string, state *model.BotChatState, provider string) error {
+	ctx := context.Background()
+	userID := c.Sender().ID
+
+	plan, err := b.planSvc.GetPlan(ctx, state.PlanID)
+	if err != nil {
+		return c.Send(i18n.T(lang, "bot_error", i18n.T(lang, "plan_not_found")))
+	}
+
+	switch model.PaymentProvider(provider) {
+	case model.PaymentProviderBalance:
+		if _, _, err := b.paymentSvc.PayFromBalance(ctx, userID, plan.ID, state.ServerID); err != nil {
+			if errors.Is(err, service.ErrInsufficientBalance) {
+				balance, _ := b.balanceSvc.GetBalance(ctx, userID)
+				return c.Send(i18n.T(lang, "bot_buy_insufficient_balance", balance, plan.PriceTON), tele.ModeHTML)
+			}
+			log.Printf("[Bot] Failed to pay plan %s from balance for user %d: %v", plan.ID, userID, err)
+			return c.Send(errorText(lang, err))
+		}
+		b.setChatState(c.Chat().ID, nil)
+		if sub, err := b.subscriptionSvc.GetActiveSubscription(ctx, userID); err == nil && sub != nil {
+			return b.SendSubscriptionActivated(userID, sub.ExpiresAt.Format("02.01.2006"))
+		}
+		return c.Send(i18n.T(lang, "bot_payment_done"))
+
+	case model.PaymentProviderStars:
+		payment, err := b.paymentSvc.CreatePaymentWithServer(ctx, userID, plan.ID, state.ServerID, model.PaymentProviderStars)
+		if err != nil {
+			log.Printf("[Bot] Failed to create Stars payment for user %d: %v", userID, err)
+			return c.Send(errorText(lang, err))
+		}
+		link, err := b.CreateStarsInvoice(userID,
+			i18n.T(lang, "invoice_subscription_title", plan.Name),
+			i18n.T(lang, "invoice_subscription_description", plan.DurationDays),
+			int(payment.Amount),
+			payment.ID.String(),
+		)
+		if err != nil {
+			log.Printf("[Bot] Failed to create Stars invoice for user %d: %v", userID, err)
+			return c.Send(errorText(lang, err))
+		}
+		b.setChatState(c.Chat().ID, nil)
+
+		keyboard := &tele.ReplyMarkup{}
+		keyboard.Inline(keyboard.Row(keyboard.URL(i18n.T(lang, "btn_pay_invoice", int(payment.Amount)), link)))
+		return c.Send(i18n.T(lang, "bot_buy_stars", html.EscapeString(plan.Name), int(payment.Amount)), keyboard, tele.ModeHTML)
+
+	case model.PaymentProviderTON:
+		payment, err := b.paymentSvc.CreatePaymentWithServer(ctx, userID, plan.ID, state.ServerID, model.PaymentProviderTON)
+		if err != nil {
+			log.Printf("[Bot] Failed to create TON payment for user %d: %v", userID, err)
+			return c.Send(errorText(lang, err))
+		}
+		info, err := b.paymentSvc.GetTONPaymentInfo(ctx, payment.ID)
+		if err != nil {
+			log.Printf("[Bot] Failed to get TON payment info for %s: %v", payment.ID, err)
+			return c.Send(errorText(lang, err))
+		}
+		b.setChatState(c.Chat().ID, nil)
+		return b.sendTONPayment(c, lang, info)
+	}
+	return nil
+}
+
+// sendTONPayment shows how to pay a TON payment, with a button to report the transfer.
+// The payment then only matches transfers carrying the comment shown.
+func (b *Bot) sendTONPayment(c tele.Context, lang string, info *model.TONPaymentInfo) error {
+	if err := b.paymentSvc.RequireTONComment(context.Background(), info.PaymentID); err != nil {
+		log.Printf("[Bot] Failed to require comment on TON payment %s: %v", info.PaymentID, err)
+		return c.Send(errorText(lang, err))
+	}
+
+	keyboard := &tele.ReplyMarkup{}
+	rows := []tele.Row{keyboard.Row(keyboard.Data(i18n.T(lang, "btn_ton_paid"), "ton_paid", info.PaymentID.String()))}
+	// Inline buttons only open http(s) links, so wallets are reached through Tonkeeper's universal link
+	if walletLink := strings.Replace(info.DeepLink, "ton://", "https://app.tonkeeper.com/", 1); walletLink != info.DeepLink {
+		rows = append([]tele.Row{keyboard.Row(keyboard.URL(i18n.T(lang, "btn_open_wallet"), walletLink))}, rows...)
+	}
+	keyboard.Inline(rows...)
+
+	text := i18n.T(lang, "bot_buy_ton", info.Amount, info.WalletAddress, info.Comment, html.EscapeString(info.DeepLink))
+	return c.Send(text, keyboard, tele.ModeHTML)
+}
+
+// handleTONPaidCallback hands a TON payment to the TON worker once the user
+// reports the transfer. The worker matches the transfer by the payment comment
+// and the user is told the outcome through SendPaymentResult.
+func (b *Bot) handleTONPaidCallback(c tele.Context, payload string) error {
+	lang := b.lang(c)
+	paymentID, err := uuid.Parse(payload)
+	if err != nil || b.paymentSvc == nil {
+		return c.Respond()
+	}
+
+	ctx := context.Background()
+	payment, err := b.paymentSvc.GetPayment(ctx, paymentID)
+	if err != nil || payment.UserID != c.Sender().ID {
+		return c.Respond(&tele.CallbackResponse{Text: i18n.T(lang, "payment_not_found"), ShowAlert: true})
+	}
+
+	switch payment.Status {
+	case model.PaymentStatusCompleted:
+		return c.Respond(&tele.CallbackResponse{Text: i18n.T(lang, "payment_already_complete"), ShowAlert: true})
+	case model.PaymentStatusPending, model.PaymentStatusAwaitingTx:
+		if err := b.paymentSvc.AwaitTONPayment(ctx, paymentID); err != nil {
+			log.Printf("[Bot] Failed to queue TON payment %s: %v", paymentID, err)
+			return c.Respond(&tele.CallbackResponse{Text: errorText(lang, err), ShowAlert: true})
+		}
+	default:
+		return c.Respond(&tele.CallbackResponse{Text: i18n.T(lang, "bot_ton_failed"), ShowAlert: true})
+	}
+
+	if msg := c.Message(); msg != nil {
+		_, _ = b.bot.EditReplyMarkup(msg, nil)
+	}
+	_ = c.Respond()
+	return c.Send(i18n.T(lang, "bot_ton_checking"))
+}
+
+// SendPaymentResult tells a user how a TON payment they reported in the chat ended
+func (b *Bot) SendPaymentResult(payment *model.Payment) error {
+	switch payment.Status {
+	case model.PaymentStatusCompleted:
+		b.sendPaymentCompleted(payment.UserID, payment)
+	case model.PaymentStatusFailed:
+		_, err := b.bot.Send(&tele.User{ID: payment.UserID}, i18n.T(b.userLang(payment.UserID), "bot_ton_failed"))
+		return err
+	}
+	return nil
+}
+
+// sendPaymentCompleted tells a user that a payment went through
+func (b *Bot) sendPaymentCompleted(userID int64, payment *model.Payment) {
+	ctx := context.Background()
+	if payment.PaymentType == model.PaymentTypeTopUp {
+		if b.balanceSvc == nil {
+			return
+		}
+		// Stars top-ups are credited at 1 TON = 100 Stars
+		amount := payment.Amount
+		if payment.Provider == model.PaymentProviderStars {
+			amount /= 100
+		}
+		balance, err := b.balanceSvc.GetBalance(ctx, userID)
+		if err == nil {
+			_ = b.SendBalanceTopUp(userID, amount, balance)
+		}
+		return
+	}
+
+	if sub, err := b.subscriptionSvc.GetActiveSubscription(ctx, userID); err == nil && sub != nil {
+		_ = b.SendSubscriptionActivated(userID, sub.ExpiresAt.Format("02.01.2006"))
+	}
+}
+
+// handlePromoPrompt asks for a promo code; the next text message in the chat is applied
+func (b *Bot) handlePromoPrompt(c tele.Context) error {
+	_ = c.Respond()
+	if b.promoCodeSvc == nil {
+		return nil
+	}
+
+	state := b.chatState(c.Chat().ID)
+	if state == nil {
+		state = &model.BotChatState{}
+	}
+	state.Awaiting = awaitPromoCode
+	b.setChatState(c.Chat().ID, state)
+
+	return c.Send(i18n.T(b.lang(c), "bot_promo_enter"))
+}
+
+// handleText takes the input a purchase in the chat is waiting for
+func (b *Bot) handleText(c tele.Context) error {
+	state := b.chatState(c.Chat().ID)
+	if state == nil || state.Awaiting == "" {
+		return nil
+	}
+
+	text := strings.TrimSpace(c.Text())
+	awaiting := state.Awaiting
+	state.Awaiting = ""
+	b.setChatState(c.Chat().ID, state)
+	if strings.HasPrefix(text, "/") {
+		return nil
+	}
+
+	if awaiting == awaitPromoCode {
+		if err := b.applyPromoCode(c, text); err != nil {
+			return err
+		}
+		// Carry on with the purchase: a credited balance may now cover the plan
+		lang := b.lang(c)
+		switch {
+		case state.PlanID == uuid.Nil:
+			return nil
+		case state.ServerChosen:
+			return b.sendBuyMethods(c, lang, state)
+		default:
+			return b.sendBuyServers(c, lang, state)
+		}
+	}
+	return nil
+}
+
+// applyPromoCode applies a promo code for the sender and reports the result
+func (b *Bot) applyPromoCode(c tele.Context, code string) error {
+	lang := b.lang(c)
+	result, err := b.promoCodeSvc.ApplyPromoCode(context.Background(), code, c.Sender().ID)
+	if err != nil {
+		if i18n.Code(err) == "" {
+			log.Printf("[Bot] Failed to apply promo code for user %d: %v", c.Sender().ID, err)
+		}
+		return c.Send(errorText(lang, err))
+	}
+	return c.Send(i18n.T(lang, "bot_promo_applied", result.LocalizedMessage(lang)))
+}
+
+// sendOrEdit replaces the message of the pressed button, or sends a new one
+func (b *Bot) sendOrEdit(c tele.Context, text string, keyboard *tele.ReplyMarkup) error {
+	if c.Callback() != nil && c.Message() != nil {
+		if err := c.Edit(text, keyboard, tele.ModeHTML); err == nil {
+			return nil
+		}
+	}
+	return c.Send(text, keyboard, tele.ModeHTML)
+}
+
+// serverLabel names a server on a button
+func serverLabel(srv model.ServerPublic) string {
+	label := strings.TrimSpace(srv.FlagEmoji + " " + srv.Name)
+	if srv.City != nil && *srv.City != "" && *srv.City != srv.Name {
+		label += fmt.Sprintf(" (%s)", *srv.City)
+	}
+	return label
+}
@@ -0,0 +1,74 @@
+package telegram
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// WebhookSecretHeader carries the secret_token Telegram sends with every webhook update
+const WebhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+var ErrInvalidWebhookSecret = errors.New("invalid webhook secret token")
+
+// WebhookEnabled reports whether updates arrive through the webhook instead of long polling
+func (b *Bot) WebhookEnabled() bool {
+	return b.cfg.Telegram.WebhookURL != ""
+}
+
+// webhookSecret returns the configured secret token, or one derived from the bot
+// token so that all replicas agree on it without extra configuration
+func (b *Bot) webhookSecret() string {
+	if b.cfg.Telegram.WebhookSecret != "" {
+		return b.cfg.Telegram.WebhookSecret
+	}
+	sum := sha256.Sum256([]byte("webhook:" + b.cfg.Telegram.BotToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// StartWebhook registers the webhook URL and secret token with Telegram.
+// Updates that arrived while no webhook was set are delivered to it.
+func (b *Bot) StartWebhook() error {
+	err := b.bot.SetWebhook(&tele.Webhook{
+		SecretToken: b.webhookSecret(),
+		Endpoint:    &tele.WebhookEndpoint{PublicURL: b.cfg.Telegram.WebhookURL},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set webhook: %w", err)
+	}
+	return nil
+}
+
+// StopWebhook removes the webhook on shutdown when configured to, keeping pending
+// updates for the next start. By default it is kept, since with several replicas
+// behind the webhook one shutting down must not stop updates for the others.
+func (b *Bot) StopWebhook() {
+	if !b.cfg.Telegram.WebhookDelete {
+		return
+	}
+	if err := b.bot.RemoveWebhook(); err != nil {
+		log.Printf("Failed to delete Telegram webhook: %v", err)
+	}
+}
+
+// HandleWebhookUpdate checks the secret token of a webhook request and passes
+// the update to the same handlers long polling uses
+func (b *Bot) HandleWebhookUpdate(secretToken string, body []byte) error {
+	if subtle.ConstantTimeCompare([]byte(secretToken), []byte(b.webhookSecret())) != 1 {
+		return ErrInvalidWebhookSecret
+	}
+
+	var update tele.Update
+	if err := json.Unmarshal(body, &update); err != nil {
+		return fmt.Errorf("invalid update: %w", err)
+	}
+
+	b.bot.ProcessUpdate(update)
+	return nil
+}
@@ -69,7 +69,8 @@ func (v *Verifier) connect(ctx context.Context) error {
 }
 
 // VerifyTransaction verifies a TON transaction from BOC
-// The BOC is the signed transaction result from TON Connect
+// The BOC is the signed transaction result from TON Connect. When expectedComment
+// is set, only transfers carrying it match, so one transfer cannot pay for another payment.
 func (v *Verifier) VerifyTransaction(boc string, expectedAmountNano int64, expectedComment string) (*TransactionInfo, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -103,8 +104,7 @@ func (v *Verifier) VerifyTransaction(boc string, expectedAmountNano int64, expec
 			continue
 		}
 
-		// Check amount
-		if int64(tx.Amount) < expectedAmountNano-1000000 { // 0.001 TON tolerance
+		if !matchesPayment(&tx, expectedAmountNano, expectedComment) {
 			continue
 		}
 
@@ -117,7 +117,7 @@ func (v *Verifier) VerifyTransaction(boc string, expectedAmountNano int64, expec
 	// to extract transaction hash and verify
 	if boc != "" {
 		txInfo, err := v.verifyFromBOC(ctx, boc, walletAddr, expectedAmountNano)
-		if err == nil {
+		if err == nil && matchesPayment(txInfo, expectedAmountNano, expectedComment) {
 			return txInfo, nil
 		}
 		fmt.Printf("[TON] BOC verification failed: %v\n", err)
@@ -126,6 +126,15 @@ func (v *Verifier) VerifyTransaction(boc string, expectedAmountNano int64, expec
 	return nil, ErrTransactionNotFound
 }
 
+// matchesPayment reports whether a transfer pays the expected amount and, when
+// expectedComment is set, carries that comment
+func matchesPayment(tx *TransactionInfo, expectedAmountNano int64, expectedComment string) bool {
+	if int64(tx.Amount) < expectedAmountNano-1000000 { // 0.001 TON tolerance
+		return false
+	}
+	return expectedComment == "" || tx.Comment == expectedComment
+}
+
 // getRecentTransactions fetches recent incoming transactions to the wallet
 func (v *Verifier) getRecentTransactions(ctx context.Context, addr *address.Address, limit int) ([]TransactionInfo, error) {
 	// Get account state
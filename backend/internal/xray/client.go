@@ -0,0 +1,140 @@
+// Package xray talks to Xray's own gRPC API (HandlerService and StatsService)
+// so nodes can be provisioned without a web panel.
+package xray
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	handlercmd "github.com/xtls/xray-core/app/proxyman/command"
+	statscmd "github.com/xtls/xray-core/app/stats/command"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/proxy/vless"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+const requestTimeout = 10 * time.Second
+
+// ErrUserExists is returned when adding a user whose email is already on the inbound
+var ErrUserExists = errors.New("xray: user already exists")
+
+// ErrUserNotFound is returned when removing a user that is not on the inbound
+var ErrUserNotFound = errors.New("xray: user not found")
+
+// Client is a connection to the Xray API inbound (the "api" tag in the Xray config).
+// The API is plaintext gRPC, so it should only be reachable over a private network or tunnel.
+type Client struct {
+	conn    *grpc.ClientConn
+	handler handlercmd.HandlerServiceClient
+	stats   statscmd.StatsServiceClient
+}
+
+// NewClient prepares a client for the API address (host:port). The connection
+// is established lazily on the first call.
+func NewClient(address string) (*Client, error) {
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client: %w", err)
+	}
+
+	return &Client{
+		conn:    conn,
+		handler: handlercmd.NewHandlerServiceClient(conn),
+		stats:   statscmd.NewStatsServiceClient(conn),
+	}, nil
+}
+
+// Close closes the underlying connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// AddVLESSUser adds a VLESS user to the inbound with the given tag
+func (c *Client) AddVLESSUser(ctx context.Context, tag, email, id, flow string) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	user := &protocol.User{
+		Email:   email,
+		Account: serial.ToTypedMessage(&vless.Account{Id: id, Flow: flow, Encryption: "none"}),
+	}
+
+	_, err := c.handler.AlterInbound(ctx, &handlercmd.AlterInboundRequest{
+		Tag:       tag,
+		Operation: serial.ToTypedMessage(&handlercmd.AddUserOperation{User: user}),
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			return ErrUserExists
+		}
+		return fmt.Errorf("add user failed: %w", err)
+	}
+	return nil
+}
+
+// RemoveUser removes a user by email from the inbound with the given tag
+func (c *Client) RemoveUser(ctx context.Context, tag, email string) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	_, err := c.handler.AlterInbound(ctx, &handlercmd.AlterInboundRequest{
+		Tag:       tag,
+		Operation: serial.ToTypedMessage(&handlercmd.RemoveUserOperation{Email: email}),
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("remove user failed: %w", err)
+	}
+	return nil
+}
+
+// GetUserTraffic returns the user's uplink and downlink counters. With reset the
+// counters are zeroed after reading, so repeated calls return usage deltas.
+// Counters only exist when statsUserUplink/statsUserDownlink are enabled in the policy.
+func (c *Client) GetUserTraffic(ctx context.Context, email string, reset bool) (up int64, down int64, err error) {
+	up, err = c.getStat(ctx, "user>>>"+email+">>>traffic>>>uplink", reset)
+	if err != nil {
+		return 0, 0, err
+	}
+	down, err = c.getStat(ctx, "user>>>"+email+">>>traffic>>>downlink", reset)
+	if err != nil {
+		return 0, 0, err
+	}
+	return up, down, nil
+}
+
+func (c *Client) getStat(ctx context.Context, name string, reset bool) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	resp, err := c.stats.GetStats(ctx, &statscmd.GetStatsRequest{Name: name, Reset_: reset})
+	if err != nil {
+		// A counter is only created once the user has sent traffic
+		if status.Code(err) == codes.NotFound {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("get stats failed: %w", err)
+	}
+	return resp.GetStat().GetValue(), nil
+}
+
+// Ping checks that the API is reachable and returns Xray's uptime in seconds
+func (c *Client) Ping(ctx context.Context) (uint32, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	resp, err := c.stats.GetSysStats(ctx, &statscmd.SysStatsRequest{})
+	if err != nil {
+		return 0, fmt.Errorf("xray api unreachable: %w", err)
+	}
+	return resp.GetUptime(), nil
+}
@@ -2,37 +2,59 @@ package xui
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/http/cookiejar"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
 )
 
+// Client is a 3x-ui API client bound to one inbound. It is safe for concurrent use:
+// the session cookie lives in a shared jar and expired sessions are renewed once per request.
+// Logins run outside mu and concurrent ones are merged, so a slow panel does not block cached reads.
 type Client struct {
 	baseURL   string
 	username  string
 	password  string
 	inboundID int
 	client    *http.Client
-	loggedIn  bool
+	logins    singleflight.Group
+
+	mu      sync.Mutex   // guards session and info
+	session int          // incremented on every successful login, 0 = not logged in
+	info    *InboundInfo // cached inbound protocol and stream settings
 }
 
 type ClientConfig struct {
-	ID         string `json:"id"`
+	ID         string `json:"id,omitempty"`       // VLESS/VMess UUID
+	Password   string `json:"password,omitempty"` // Trojan/Shadowsocks password
+	Method     string `json:"method,omitempty"`   // Shadowsocks cipher
+	Security   string `json:"security,omitempty"` // VMess cipher
 	Email      string `json:"email"`
 	Enable     bool   `json:"enable"`
-	Flow       string `json:"flow"`
+	Flow       string `json:"flow,omitempty"`
 	LimitIP    int    `json:"limitIp"`
 	TotalGB    int64  `json:"totalGB"`
 	ExpiryTime int64  `json:"expiryTime"` // milliseconds timestamp
 }
 
+// Credential returns the value that identifies the client on the inbound:
+// the UUID for VLESS/VMess or the password for Trojan/Shadowsocks
+func (cc *ClientConfig) Credential() string {
+	if cc.ID != "" {
+		return cc.ID
+	}
+	return cc.Password
+}
+
 type Traffic struct {
 	Email    string `json:"email"`
 	Enable   bool   `json:"enable"`
@@ -43,7 +65,9 @@ type Traffic struct {
 }
 
 type InboundSettings struct {
-	Clients []ClientConfig `json:"clients"`
+	Clients  []ClientConfig `json:"clients"`
+	Method   string         `json:"method,omitempty"`   // Shadowsocks inbound cipher
+	Password string         `json:"password,omitempty"` // Shadowsocks 2022 server key
 }
 
 type Inbound struct {
@@ -63,6 +87,16 @@ type Response struct {
 	Obj     interface{} `json:"obj"`
 }
 
+// errSessionExpired marks a response that indicates the session cookie is no longer valid
+var errSessionExpired = errors.New("session expired")
+
+// ErrNotFound is returned when the panel answers 404 with a valid session
+var ErrNotFound = errors.New("xui: not found")
+
+// sessionPathPrefix is where 3x-ui answers 404 instead of redirecting to the login page
+// when the session expired, so only 404s under it may mean an expired session
+const sessionPathPrefix = "/panel/api/"
+
 func NewClient(baseURL, username, password string, inboundID int) (*Client, error) {
 	jar, err := cookiejar.New(nil)
 	if err != nil {
@@ -77,11 +111,22 @@ func NewClient(baseURL, username, password string, inboundID int) (*Client, erro
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 			Jar:     jar,
+			// 3x-ui redirects unauthenticated requests to the login page;
+			// surface the redirect so it can be treated as an expired session
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
 		},
 	}, nil
 }
 
-func (c *Client) Login() error {
+// Login authenticates and stores the session cookie
+func (c *Client) Login(ctx context.Context) error {
+	return c.login(ctx)
+}
+
+// login performs the login request without holding c.mu and counts the new session
+func (c *Client) login(ctx context.Context) error {
 	data := map[string]string{
 		"username": c.username,
 		"password": c.password,
@@ -92,265 +137,287 @@ func (c *Client) Login() error {
 		return err
 	}
 
-	fmt.Printf("[XUI] Logging in to %s...\n", c.baseURL)
-	resp, err := c.client.Post(c.baseURL+"/login", "application/json", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/login", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	log.Printf("[XUI] Logging in to %s...", c.baseURL)
+	resp, err := c.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("login request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, _ := io.ReadAll(resp.Body)
-	fmt.Printf("[XUI] Login response status: %d, body: %s\n", resp.StatusCode, string(respBody))
 
 	var result Response
 	if err := json.Unmarshal(respBody, &result); err != nil {
-		return fmt.Errorf("failed to decode login response: %w", err)
+		return fmt.Errorf("failed to decode login response (status=%d, body=%s): %w", resp.StatusCode, string(respBody), err)
 	}
 
 	if !result.Success {
 		return fmt.Errorf("login failed: %s", result.Msg)
 	}
 
-	c.loggedIn = true
-	fmt.Println("[XUI] Login successful")
+	c.mu.Lock()
+	c.session++
+	c.mu.Unlock()
 	return nil
 }
 
-func (c *Client) ensureLoggedIn() error {
-	if !c.loggedIn {
-		return c.Login()
-	}
-	return nil
+// currentSession returns the current session number, 0 = not logged in
+func (c *Client) currentSession() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.session
 }
 
-func (c *Client) AddClient(email string, trafficLimitGB int64, expiryDays int, maxDevices int) (*ClientConfig, error) {
-	if err := c.ensureLoggedIn(); err != nil {
-		return nil, err
+// ensureSession logs in if there is no session yet and returns the current session number
+func (c *Client) ensureSession(ctx context.Context) (int, error) {
+	if session := c.currentSession(); session != 0 {
+		return session, nil
 	}
-
-	clientID := uuid.New().String()
-
-	var expiryTime int64 = 0
-	if expiryDays > 0 {
-		expiryTime = time.Now().Add(time.Duration(expiryDays) * 24 * time.Hour).UnixMilli()
+	if err := c.renewSession(ctx, 0); err != nil {
+		return 0, err
 	}
+	return c.currentSession(), nil
+}
 
-	var totalGB int64 = 0
-	if trafficLimitGB > 0 {
-		totalGB = trafficLimitGB * 1024 * 1024 * 1024
-	}
+// renewSession logs in again unless another goroutine already renewed the expired session.
+// Concurrent renewals share a single login request.
+func (c *Client) renewSession(ctx context.Context, expired int) error {
+	_, err, _ := c.logins.Do("login", func() (any, error) {
+		if c.currentSession() != expired {
+			return nil, nil
+		}
+		return nil, c.login(ctx)
+	})
+	return err
+}
 
-	if maxDevices <= 0 {
-		maxDevices = 3 // Default to 3 devices
+// call is the single request pipeline for the panel API. It sends a request with
+// an optional JSON payload and decodes the {success, msg, obj} envelope, storing obj
+// into out when it is non-nil. An expired session (redirect, 401, empty body, or 404
+// under the API prefix) triggers one re-login and retry; a 404 that persists with
+// the new session is returned as ErrNotFound.
+func (c *Client) call(ctx context.Context, method, path string, payload any, out any) error {
+	var body []byte
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return err
+		}
 	}
 
-	client := ClientConfig{
-		ID:         clientID,
-		Email:      email,
-		Enable:     true,
-		Flow:       "xtls-rprx-vision",
-		LimitIP:    maxDevices,
-		TotalGB:    totalGB,
-		ExpiryTime: expiryTime,
-	}
+	for attempt := 0; ; attempt++ {
+		session, err := c.ensureSession(ctx)
+		if err != nil {
+			return err
+		}
 
-	settings := map[string]interface{}{
-		"clients": []ClientConfig{client},
-	}
+		err = c.send(ctx, method, path, body, out)
+		if errors.Is(err, ErrNotFound) && attempt == 0 && strings.HasPrefix(path, sessionPathPrefix) {
+			err = fmt.Errorf("%w: %w", errSessionExpired, err)
+		}
+		if !errors.Is(err, errSessionExpired) || attempt > 0 {
+			return err
+		}
 
-	settingsJSON, err := json.Marshal(settings)
-	if err != nil {
-		return nil, err
+		log.Printf("[XUI] Session expired on %s %s, re-logging in...", method, path)
+		if err := c.renewSession(ctx, session); err != nil {
+			return fmt.Errorf("re-login failed: %w", err)
+		}
 	}
+}
 
-	data := map[string]interface{}{
-		"id":       c.inboundID,
-		"settings": string(settingsJSON),
+func (c *Client) send(ctx context.Context, method, path string, body []byte, out any) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
 	}
 
-	body, err := json.Marshal(data)
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
 
-	log.Printf("[XUI] AddClient URL: %s", c.baseURL+"/panel/api/inbounds/addClient")
-	log.Printf("[XUI] AddClient Body: %s", string(body))
-
-	resp, err := c.client.Post(c.baseURL+"/panel/api/inbounds/addClient", "application/json", bytes.NewReader(body))
+	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("add client request failed: %w", err)
+		return fmt.Errorf("%s %s request failed: %w", method, path, err)
 	}
 	defer resp.Body.Close()
 
-	// Read response body for logging
-	bodyBytes, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	log.Printf("[XUI] AddClient Response Status: %d", resp.StatusCode)
-	log.Printf("[XUI] AddClient Response: %s", string(bodyBytes))
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s %s", ErrNotFound, method, path)
+	}
+	if (resp.StatusCode >= 300 && resp.StatusCode < 400) ||
+		resp.StatusCode == http.StatusUnauthorized ||
+		len(bytes.TrimSpace(respBody)) == 0 {
+		return fmt.Errorf("%w: status=%d, body_len=%d", errSessionExpired, resp.StatusCode, len(respBody))
+	}
 
-	var result Response
-	if err := json.Unmarshal(bodyBytes, &result); err != nil {
-		return nil, fmt.Errorf("failed to decode response (status=%d, body=%s): %w", resp.StatusCode, string(bodyBytes), err)
+	var result struct {
+		Success bool            `json:"success"`
+		Msg     string          `json:"msg"`
+		Obj     json.RawMessage `json:"obj"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("failed to decode response (status=%d, body=%s): %w", resp.StatusCode, string(respBody), err)
 	}
 
 	if !result.Success {
-		return nil, fmt.Errorf("add client failed: %s", result.Msg)
+		return errors.New(result.Msg)
 	}
 
-	return &client, nil
+	if out != nil && len(result.Obj) > 0 {
+		if err := json.Unmarshal(result.Obj, out); err != nil {
+			return fmt.Errorf("failed to decode response object: %w", err)
+		}
+	}
+	return nil
 }
 
-func (c *Client) DeleteClient(clientID string) error {
-	if err := c.ensureLoggedIn(); err != nil {
-		return err
+// addClientRequest builds the addClient/updateClient payload for a single client
+func (c *Client) addClientRequest(client ClientConfig) (map[string]interface{}, error) {
+	settingsJSON, err := json.Marshal(map[string]interface{}{
+		"clients": []ClientConfig{client},
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	url := fmt.Sprintf("%s/panel/api/inbounds/%d/delClient/%s", c.baseURL, c.inboundID, clientID)
+	return map[string]interface{}{
+		"id":       c.inboundID,
+		"settings": string(settingsJSON),
+	}, nil
+}
 
-	req, err := http.NewRequest(http.MethodPost, url, nil)
+func (c *Client) AddClient(ctx context.Context, email string, trafficLimitGB int64, expiryDays int, maxDevices int) (*ClientConfig, error) {
+	info, err := c.CachedInboundInfo(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("delete client request failed: %w", err)
+	var expiryTime int64 = 0
+	if expiryDays > 0 {
+		expiryTime = time.Now().Add(time.Duration(expiryDays) * 24 * time.Hour).UnixMilli()
 	}
-	defer resp.Body.Close()
 
-	var result Response
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+	var totalGB int64 = 0
+	if trafficLimitGB > 0 {
+		totalGB = trafficLimitGB * 1024 * 1024 * 1024
 	}
 
-	if !result.Success {
-		return fmt.Errorf("delete client failed: %s", result.Msg)
+	if maxDevices <= 0 {
+		maxDevices = 3 // Default to 3 devices
 	}
 
-	return nil
+	client := newClientConfig(info, "", email, totalGB, expiryTime, maxDevices)
+
+	data, err := c.addClientRequest(client)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.call(ctx, http.MethodPost, "/panel/api/inbounds/addClient", data, nil); err != nil {
+		return nil, fmt.Errorf("add client failed: %w", err)
+	}
+
+	log.Printf("[XUI] Client %s added to inbound %d", email, c.inboundID)
+	return &client, nil
 }
 
-// DeleteClientByEmail finds a client by email and deletes it by UUID
-func (c *Client) DeleteClientByEmail(email string) error {
-	if err := c.ensureLoggedIn(); err != nil {
+// DeleteClient deletes a client by its credential (UUID or password)
+func (c *Client) DeleteClient(ctx context.Context, clientID string) error {
+	apiID, err := c.resolveAPIClientID(ctx, clientID)
+	if err != nil {
 		return err
 	}
 
+	path := fmt.Sprintf("/panel/api/inbounds/%d/delClient/%s", c.inboundID, apiID)
+	if err := c.call(ctx, http.MethodPost, path, nil, nil); err != nil {
+		return fmt.Errorf("delete client failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteClientByEmail finds a client by email and deletes it by UUID
+func (c *Client) DeleteClientByEmail(ctx context.Context, email string) error {
 	// First, get the inbound to find the client UUID by email
-	inbound, err := c.GetInbound()
+	inbound, err := c.GetInbound(ctx)
 	if err != nil {
-		fmt.Printf("[XUI] DeleteClientByEmail: failed to get inbound: %v\n", err)
 		return err
 	}
 
 	// Parse settings to get clients list
 	var settings InboundSettings
 	if err := json.Unmarshal([]byte(inbound.Settings), &settings); err != nil {
-		fmt.Printf("[XUI] DeleteClientByEmail: failed to parse settings: %v\n", err)
-		return err
+		return fmt.Errorf("failed to parse inbound settings: %w", err)
 	}
 
 	// Find client by email
 	var clientUUID string
 	for _, client := range settings.Clients {
 		if client.Email == email {
-			clientUUID = client.ID
+			clientUUID = apiClientID(inbound.Protocol, client)
 			break
 		}
 	}
 
 	if clientUUID == "" {
-		fmt.Printf("[XUI] DeleteClientByEmail: client with email %s not found\n", email)
 		return nil // Not an error - client doesn't exist
 	}
 
-	fmt.Printf("[XUI] DeleteClientByEmail: found client %s with UUID %s, deleting...\n", email, clientUUID)
-
-	// Delete by UUID
-	url := fmt.Sprintf("%s/panel/api/inbounds/%d/delClient/%s", c.baseURL, c.inboundID, clientUUID)
-	fmt.Printf("[XUI] DeleteClientByEmail URL: %s\n", url)
-
-	req, err := http.NewRequest(http.MethodPost, url, nil)
-	if err != nil {
-		return err
-	}
-
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("delete client request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-	fmt.Printf("[XUI] DeleteClientByEmail Response: %d - %s\n", resp.StatusCode, string(respBody))
-
-	var result Response
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	if !result.Success {
-		return fmt.Errorf("delete client failed: %s", result.Msg)
+	path := fmt.Sprintf("/panel/api/inbounds/%d/delClient/%s", c.inboundID, clientUUID)
+	if err := c.call(ctx, http.MethodPost, path, nil, nil); err != nil {
+		return fmt.Errorf("delete client failed: %w", err)
 	}
 
-	fmt.Printf("[XUI] DeleteClientByEmail: successfully deleted client %s\n", email)
+	log.Printf("[XUI] Deleted client %s", email)
 	return nil
 }
 
-func (c *Client) GetClientTraffic(email string) (*Traffic, error) {
-	if err := c.ensureLoggedIn(); err != nil {
-		return nil, err
+func (c *Client) GetClientTraffic(ctx context.Context, email string) (*Traffic, error) {
+	var traffic *Traffic
+	if err := c.call(ctx, http.MethodGet, "/panel/api/inbounds/getClientTraffics/"+email, nil, &traffic); err != nil {
+		return nil, fmt.Errorf("get traffic failed: %w", err)
 	}
-
-	url := fmt.Sprintf("%s/panel/api/inbounds/getClientTraffics/%s", c.baseURL, email)
-
-	resp, err := c.client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("get traffic request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	var result struct {
-		Success bool     `json:"success"`
-		Msg     string   `json:"msg"`
-		Obj     *Traffic `json:"obj"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	if !result.Success {
-		return nil, fmt.Errorf("get traffic failed: %s", result.Msg)
-	}
-
-	return result.Obj, nil
+	return traffic, nil
 }
 
-func (c *Client) UpdateClientTraffic(clientUUID string, email string, totalGB int64, expiryTime int64, maxDevices int) error {
+func (c *Client) UpdateClientTraffic(ctx context.Context, clientUUID string, email string, totalGB int64, expiryTime int64, maxDevices int) error {
 	if maxDevices <= 0 {
 		maxDevices = 3
 	}
-	err := c.updateClientTrafficWithRetry(clientUUID, email, totalGB, expiryTime, maxDevices, true)
+	err := c.updateClient(ctx, clientUUID, email, totalGB, expiryTime, maxDevices)
 
 	// If client not found (404), try to recreate it
-	if err != nil && (strings.Contains(err.Error(), "status=404") || strings.Contains(err.Error(), "not found")) {
-		fmt.Printf("[XUI] Client %s not found, recreating...\n", clientUUID)
+	if err != nil && (errors.Is(err, ErrNotFound) || strings.Contains(err.Error(), "not found")) {
+		log.Printf("[XUI] Client %s not found, recreating...", clientUUID)
 
 		// First try to delete any existing client with this email (cleanup)
-		_ = c.DeleteClientByEmail(email)
+		_ = c.DeleteClientByEmail(ctx, email)
 
 		// Try to create with original email first
-		createErr := c.addClientWithUUID(clientUUID, email, totalGB, expiryTime, maxDevices)
+		createErr := c.addClientWithUUID(ctx, clientUUID, email, totalGB, expiryTime, maxDevices)
 
 		// If duplicate email (exists in another inbound), use new unique email
 		if createErr != nil && strings.Contains(createErr.Error(), "duplicate") {
 			newEmail := fmt.Sprintf("%s_%d", email, time.Now().Unix())
-			fmt.Printf("[XUI] Duplicate email in another inbound, using new email: %s\n", newEmail)
-			return c.addClientWithUUID(clientUUID, newEmail, totalGB, expiryTime, maxDevices)
+			log.Printf("[XUI] Duplicate email in another inbound, using new email: %s", newEmail)
+			return c.addClientWithUUID(ctx, clientUUID, newEmail, totalGB, expiryTime, maxDevices)
 		}
 
 		return createErr
@@ -358,221 +425,116 @@ func (c *Client) UpdateClientTraffic(clientUUID string, email string, totalGB in
 
 	// If duplicate email error on update, try with new email
 	if err != nil && strings.Contains(err.Error(), "duplicate") {
-		fmt.Printf("[XUI] Duplicate email detected, trying with new email...\n")
-		_ = c.DeleteClientByEmail(email)
+		log.Printf("[XUI] Duplicate email detected, trying with new email...")
+		_ = c.DeleteClientByEmail(ctx, email)
 		newEmail := fmt.Sprintf("%s_%d", email, time.Now().Unix())
-		return c.addClientWithUUID(clientUUID, newEmail, totalGB, expiryTime, maxDevices)
+		return c.addClientWithUUID(ctx, clientUUID, newEmail, totalGB, expiryTime, maxDevices)
 	}
 
 	return err
 }
 
 // addClientWithUUID adds a client with a specific UUID (for recreating deleted clients)
-func (c *Client) addClientWithUUID(clientUUID string, email string, totalGB int64, expiryTime int64, maxDevices int) error {
-	if err := c.ensureLoggedIn(); err != nil {
-		return err
-	}
-
+func (c *Client) addClientWithUUID(ctx context.Context, clientUUID string, email string, totalGB int64, expiryTime int64, maxDevices int) error {
 	if maxDevices <= 0 {
 		maxDevices = 3
 	}
 
-	client := ClientConfig{
-		ID:         clientUUID,
-		Email:      email,
-		Enable:     true,
-		Flow:       "xtls-rprx-vision",
-		LimitIP:    maxDevices,
-		TotalGB:    totalGB * 1024 * 1024 * 1024,
-		ExpiryTime: expiryTime,
-	}
-
-	settings := map[string]interface{}{
-		"clients": []ClientConfig{client},
-	}
-
-	settingsJSON, err := json.Marshal(settings)
+	info, err := c.CachedInboundInfo(ctx)
 	if err != nil {
 		return err
 	}
 
-	data := map[string]interface{}{
-		"id":       c.inboundID,
-		"settings": string(settingsJSON),
-	}
+	client := newClientConfig(info, clientUUID, email, totalGB*1024*1024*1024, expiryTime, maxDevices)
 
-	body, err := json.Marshal(data)
+	data, err := c.addClientRequest(client)
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("[XUI] AddClient URL: %s/panel/api/inbounds/addClient\n", c.baseURL)
-	fmt.Printf("[XUI] AddClient Body: %s\n", string(body))
-
-	resp, err := c.client.Post(c.baseURL+"/panel/api/inbounds/addClient", "application/json", bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("add client request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-	fmt.Printf("[XUI] AddClient Response Status: %d\n", resp.StatusCode)
-	fmt.Printf("[XUI] AddClient Response: %s\n", string(respBody))
-
-	var result Response
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return fmt.Errorf("failed to decode response: %w, body: %s", err, string(respBody))
-	}
-
-	if !result.Success {
-		return fmt.Errorf("add client failed: %s", result.Msg)
+	if err := c.call(ctx, http.MethodPost, "/panel/api/inbounds/addClient", data, nil); err != nil {
+		return fmt.Errorf("add client failed: %w", err)
 	}
 
-	fmt.Printf("[XUI] Client %s created successfully\n", clientUUID)
+	log.Printf("[XUI] Client %s created successfully", clientUUID)
 	return nil
 }
 
-func (c *Client) updateClientTrafficWithRetry(clientUUID string, email string, totalGB int64, expiryTime int64, maxDevices int, canRetry bool) error {
-	if err := c.ensureLoggedIn(); err != nil {
-		return err
-	}
-
-	if maxDevices <= 0 {
-		maxDevices = 3
-	}
-
-	client := ClientConfig{
-		ID:         clientUUID,
-		Email:      email,
-		Enable:     true,
-		Flow:       "xtls-rprx-vision",
-		LimitIP:    maxDevices,
-		TotalGB:    totalGB * 1024 * 1024 * 1024,
-		ExpiryTime: expiryTime,
-	}
-
-	settings := map[string]interface{}{
-		"clients": []ClientConfig{client},
-	}
-
-	settingsJSON, err := json.Marshal(settings)
+func (c *Client) updateClient(ctx context.Context, clientUUID string, email string, totalGB int64, expiryTime int64, maxDevices int) error {
+	info, err := c.CachedInboundInfo(ctx)
 	if err != nil {
 		return err
 	}
 
-	data := map[string]interface{}{
-		"id":       c.inboundID,
-		"settings": string(settingsJSON),
-	}
+	client := newClientConfig(info, clientUUID, email, totalGB*1024*1024*1024, expiryTime, maxDevices)
 
-	body, err := json.Marshal(data)
+	data, err := c.addClientRequest(client)
 	if err != nil {
 		return err
 	}
 
-	url := fmt.Sprintf("%s/panel/api/inbounds/%d/updateClient/%s", c.baseURL, c.inboundID, clientUUID)
-	fmt.Printf("[XUI] UpdateClient URL: %s\n", url)
-	fmt.Printf("[XUI] UpdateClient Body: %s\n", string(body))
-
-	resp, err := c.client.Post(url, "application/json", bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("update client request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-	fmt.Printf("[XUI] UpdateClient Response Status: %d\n", resp.StatusCode)
-	fmt.Printf("[XUI] UpdateClient Response: %s\n", string(respBody))
-
-	// Check status code - 3xx or non-200 with empty body means auth issue
-	if resp.StatusCode != 200 || (len(respBody) == 0 && canRetry) {
-		fmt.Printf("[XUI] Bad response (status=%d, body_len=%d), attempting re-login...\n", resp.StatusCode, len(respBody))
-		c.loggedIn = false
-		// Force re-login
-		if err := c.Login(); err != nil {
-			return fmt.Errorf("re-login failed: %w", err)
-		}
-		if canRetry {
-			return c.updateClientTrafficWithRetry(clientUUID, email, totalGB, expiryTime, maxDevices, false)
-		}
-		return fmt.Errorf("update client failed after re-login: status=%d, body=%s", resp.StatusCode, string(respBody))
-	}
-
-	var result Response
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return fmt.Errorf("failed to decode response: %w, body: %s", err, string(respBody))
-	}
-
-	if !result.Success {
-		return fmt.Errorf("update client failed: %s", result.Msg)
+	path := fmt.Sprintf("/panel/api/inbounds/%d/updateClient/%s", c.inboundID, apiClientID(info.Protocol, client))
+	if err := c.call(ctx, http.MethodPost, path, data, nil); err != nil {
+		return fmt.Errorf("update client failed: %w", err)
 	}
-
 	return nil
 }
 
-func (c *Client) ResetClientTraffic(email string) error {
-	if err := c.ensureLoggedIn(); err != nil {
-		return err
+func (c *Client) ResetClientTraffic(ctx context.Context, email string) error {
+	path := fmt.Sprintf("/panel/api/inbounds/%d/resetClientTraffic/%s", c.inboundID, email)
+	if err := c.call(ctx, http.MethodPost, path, nil, nil); err != nil {
+		return fmt.Errorf("reset traffic failed: %w", err)
 	}
-
-	url := fmt.Sprintf("%s/panel/api/inbounds/%d/resetClientTraffic/%s", c.baseURL, c.inboundID, email)
-
-	req, err := http.NewRequest(http.MethodPost, url, nil)
-	if err != nil {
-		return err
-	}
-
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("reset traffic request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	var result Response
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	if !result.Success {
-		return fmt.Errorf("reset traffic failed: %s", result.Msg)
-	}
-
 	return nil
 }
 
-func (c *Client) GetInbound() (*Inbound, error) {
-	if err := c.ensureLoggedIn(); err != nil {
-		return nil, err
-	}
-
-	url := fmt.Sprintf("%s/panel/api/inbounds/get/%d", c.baseURL, c.inboundID)
-
-	resp, err := c.client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("get inbound request failed: %w", err)
-	}
-	defer resp.Body.Close()
+// ServerStatus is the subset of the panel's system status used for health checks
+type ServerStatus struct {
+	CPU float64 `json:"cpu"` // percent
+	Mem struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"mem"`
+	Xray struct {
+		State    string `json:"state"` // running, stop, error
+		ErrorMsg string `json:"errorMsg"`
+		Version  string `json:"version"`
+	} `json:"xray"`
+	Uptime     int64 `json:"uptime"` // seconds
+	TCPCount   int   `json:"tcpCount"`
+	NetTraffic struct {
+		Sent int64 `json:"sent"`
+		Recv int64 `json:"recv"`
+	} `json:"netTraffic"` // bytes since boot
+}
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+// GetServerStatus returns CPU, memory and Xray state of the panel host
+func (c *Client) GetServerStatus(ctx context.Context) (*ServerStatus, error) {
+	var status ServerStatus
+	if err := c.call(ctx, http.MethodPost, "/server/status", nil, &status); err != nil {
+		return nil, fmt.Errorf("get server status failed: %w", err)
 	}
+	return &status, nil
+}
 
-	var result struct {
-		Success bool     `json:"success"`
-		Msg     string   `json:"msg"`
-		Obj     *Inbound `json:"obj"`
+// GetOnlineClients returns the emails of clients connected to any inbound
+func (c *Client) GetOnlineClients(ctx context.Context) ([]string, error) {
+	var emails []string
+	if err := c.call(ctx, http.MethodPost, "/panel/api/inbounds/onlines", nil, &emails); err != nil {
+		return nil, fmt.Errorf("get online clients failed: %w", err)
 	}
+	return emails, nil
+}
 
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+func (c *Client) GetInbound(ctx context.Context) (*Inbound, error) {
+	var inbound *Inbound
+	if err := c.call(ctx, http.MethodGet, fmt.Sprintf("/panel/api/inbounds/get/%d", c.inboundID), nil, &inbound); err != nil {
+		return nil, fmt.Errorf("get inbound failed: %w", err)
 	}
-
-	if !result.Success {
-		return nil, fmt.Errorf("get inbound failed: %s", result.Msg)
+	if inbound == nil {
+		return nil, fmt.Errorf("get inbound failed: inbound %d not found", c.inboundID)
 	}
-
-	return result.Obj, nil
+	return inbound, nil
 }
 
 // GenerateVLESSLink generates a VLESS connection link for a client
@@ -605,42 +567,197 @@ type RealitySettings struct {
 	} `json:"settings"`
 }
 
+// TLSSettings represents TLS security settings
+type TLSSettings struct {
+	ServerName string   `json:"serverName"`
+	ALPN       []string `json:"alpn"`
+	Settings   struct {
+		Fingerprint string `json:"fingerprint"`
+	} `json:"settings"`
+}
+
+// WSSettings represents WebSocket and HTTPUpgrade transport settings
+type WSSettings struct {
+	Path    string            `json:"path"`
+	Host    string            `json:"host"`
+	Headers map[string]string `json:"headers"`
+}
+
+// GRPCSettings represents gRPC transport settings
+type GRPCSettings struct {
+	ServiceName string `json:"serviceName"`
+	MultiMode   bool   `json:"multiMode"`
+}
+
+// XHTTPSettings represents XHTTP (SplitHTTP) transport settings
+type XHTTPSettings struct {
+	Path string `json:"path"`
+	Host string `json:"host"`
+	Mode string `json:"mode"`
+}
+
 // StreamSettings represents inbound stream settings
 type StreamSettings struct {
-	Network         string          `json:"network"`
-	Security        string          `json:"security"`
-	RealitySettings RealitySettings `json:"realitySettings"`
+	Network             string          `json:"network"`
+	Security            string          `json:"security"`
+	RealitySettings     RealitySettings `json:"realitySettings"`
+	TLSSettings         TLSSettings     `json:"tlsSettings"`
+	WSSettings          WSSettings      `json:"wsSettings"`
+	HTTPUpgradeSettings WSSettings      `json:"httpupgradeSettings"`
+	GRPCSettings        GRPCSettings    `json:"grpcSettings"`
+	XHTTPSettings       XHTTPSettings   `json:"xhttpSettings"`
 }
 
 // InboundInfo contains parsed inbound information for generating keys
 type InboundInfo struct {
-	Port       int
-	PublicKey  string
-	ShortID    string
-	ServerName string
+	Protocol string
+	Network  string
+	Security string
+	Port     int
+
+	// Reality / TLS
+	PublicKey   string
+	ShortID     string
+	ServerName  string
+	Fingerprint string
+	SpiderX     string
+	ALPN        []string
+
+	// All Reality short IDs and server names accepted by the inbound;
+	// ShortID and ServerName are the first of each
+	ShortIDs    []string
+	ServerNames []string
+
+	// Transport
+	Path        string
+	Host        string
+	ServiceName string
+	MultiMode   bool
+	Mode        string
+
+	// Shadowsocks
+	SSMethod   string
+	SSPassword string
+}
+
+// CachedInboundInfo returns inbound info, fetching it from the panel only once
+func (c *Client) CachedInboundInfo(ctx context.Context) (*InboundInfo, error) {
+	c.mu.Lock()
+	info := c.info
+	c.mu.Unlock()
+
+	if info != nil {
+		return info, nil
+	}
+	return c.GetInboundInfo(ctx)
+}
+
+// resolveAPIClientID maps a stored credential to the identifier used in API paths.
+// Shadowsocks clients are addressed by email, so it is looked up on the inbound.
+func (c *Client) resolveAPIClientID(ctx context.Context, credential string) (string, error) {
+	info, err := c.CachedInboundInfo(ctx)
+	if err != nil {
+		return "", err
+	}
+	if info.Protocol != ProtocolShadowsocks {
+		return credential, nil
+	}
+
+	inbound, err := c.GetInbound(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var settings InboundSettings
+	if err := json.Unmarshal([]byte(inbound.Settings), &settings); err != nil {
+		return "", fmt.Errorf("failed to parse inbound settings: %w", err)
+	}
+
+	for _, client := range settings.Clients {
+		if client.Password == credential {
+			return client.Email, nil
+		}
+	}
+	return "", fmt.Errorf("client not found")
 }
 
 // GetInboundInfo retrieves and parses inbound settings
-func (c *Client) GetInboundInfo() (*InboundInfo, error) {
-	inbound, err := c.GetInbound()
+func (c *Client) GetInboundInfo(ctx context.Context) (*InboundInfo, error) {
+	inbound, err := c.GetInbound(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	var streamSettings StreamSettings
-	if err := json.Unmarshal([]byte(inbound.StreamSettings), &streamSettings); err != nil {
-		return nil, fmt.Errorf("failed to parse stream settings: %w", err)
+	if inbound.StreamSettings != "" {
+		if err := json.Unmarshal([]byte(inbound.StreamSettings), &streamSettings); err != nil {
+			return nil, fmt.Errorf("failed to parse stream settings: %w", err)
+		}
 	}
 
 	info := &InboundInfo{
-		Port: inbound.Port,
+		Protocol: inbound.Protocol,
+		Network:  streamSettings.Network,
+		Security: streamSettings.Security,
+		Port:     inbound.Port,
+	}
+	if info.Protocol == "" {
+		info.Protocol = ProtocolVLESS
+	}
+	if info.Security == "" {
+		info.Security = SecurityNone
+	}
+
+	switch info.Network {
+	case NetworkWS:
+		info.Path = streamSettings.WSSettings.Path
+		info.Host = streamSettings.WSSettings.Host
+		if info.Host == "" {
+			info.Host = streamSettings.WSSettings.Headers["Host"]
+		}
+	case NetworkHTTPUpgrade:
+		info.Path = streamSettings.HTTPUpgradeSettings.Path
+		info.Host = streamSettings.HTTPUpgradeSettings.Host
+	case NetworkGRPC:
+		info.ServiceName = streamSettings.GRPCSettings.ServiceName
+		info.MultiMode = streamSettings.GRPCSettings.MultiMode
+	case NetworkXHTTP:
+		info.Path = streamSettings.XHTTPSettings.Path
+		info.Host = streamSettings.XHTTPSettings.Host
+		info.Mode = streamSettings.XHTTPSettings.Mode
+	}
+
+	if info.Security == SecurityTLS {
+		info.ServerName = streamSettings.TLSSettings.ServerName
+		info.Fingerprint = streamSettings.TLSSettings.Settings.Fingerprint
+		info.ALPN = streamSettings.TLSSettings.ALPN
+	}
+
+	if info.Protocol == ProtocolShadowsocks {
+		var settings InboundSettings
+		if err := json.Unmarshal([]byte(inbound.Settings), &settings); err != nil {
+			return nil, fmt.Errorf("failed to parse inbound settings: %w", err)
+		}
+		info.SSMethod = settings.Method
+		info.SSPassword = settings.Password
+	}
+
+	if info.Security != SecurityReality {
+		c.setInfo(info)
+		return info, nil
 	}
 
+	info.Fingerprint = streamSettings.RealitySettings.Settings.Fingerprint
+	info.SpiderX = streamSettings.RealitySettings.Settings.SpiderX
+
 	// Get public key from reality settings
 	if streamSettings.RealitySettings.Settings.PublicKey != "" {
 		info.PublicKey = streamSettings.RealitySettings.Settings.PublicKey
 	}
 
+	info.ShortIDs = streamSettings.RealitySettings.ShortIds
+	info.ServerNames = streamSettings.RealitySettings.ServerNames
+
 	// Get server name
 	if len(streamSettings.RealitySettings.ServerNames) > 0 {
 		info.ServerName = streamSettings.RealitySettings.ServerNames[0]
@@ -653,5 +770,12 @@ func (c *Client) GetInboundInfo() (*InboundInfo, error) {
 		info.ShortID = streamSettings.RealitySettings.ShortIds[0]
 	}
 
+	c.setInfo(info)
 	return info, nil
 }
+
+func (c *Client) setInfo(info *InboundInfo) {
+	c.mu.Lock()
+	c.info = info
+	c.mu.Unlock()
+}
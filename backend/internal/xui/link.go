@@ -0,0 +1,183 @@
+package xui
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ShareLink builds a client share link matching the inbound protocol and transport
+func ShareLink(info *InboundInfo, client *ClientConfig, address string, port int, remark string) (string, error) {
+	if port <= 0 {
+		port = info.Port
+	}
+	hostPort := net.JoinHostPort(address, strconv.Itoa(port))
+
+	switch info.Protocol {
+	case ProtocolVLESS, "":
+		query := streamQuery(info)
+		query.Set("encryption", "none")
+		if client.Flow != "" {
+			query.Set("flow", client.Flow)
+		}
+		return fmt.Sprintf("vless://%s@%s?%s#%s", client.ID, hostPort, query.Encode(), url.PathEscape(remark)), nil
+
+	case ProtocolTrojan:
+		query := streamQuery(info)
+		return fmt.Sprintf("trojan://%s@%s?%s#%s", url.PathEscape(client.Password), hostPort, query.Encode(), url.PathEscape(remark)), nil
+
+	case ProtocolVMess:
+		return vmessLink(info, client, address, port, remark)
+
+	case ProtocolShadowsocks:
+		return shadowsocksLink(info, client, hostPort, remark), nil
+	}
+
+	return "", fmt.Errorf("unsupported inbound protocol: %s", info.Protocol)
+}
+
+// streamQuery encodes transport and security parameters shared by VLESS and Trojan links
+func streamQuery(info *InboundInfo) url.Values {
+	query := url.Values{}
+
+	network := info.Network
+	if network == "" {
+		network = NetworkTCP
+	}
+	query.Set("type", network)
+
+	switch network {
+	case NetworkWS, NetworkHTTPUpgrade:
+		query.Set("path", defaultPath(info.Path))
+		if info.Host != "" {
+			query.Set("host", info.Host)
+		}
+	case NetworkGRPC:
+		query.Set("serviceName", info.ServiceName)
+		if info.MultiMode {
+			query.Set("mode", "multi")
+		} else {
+			query.Set("mode", "gun")
+		}
+	case NetworkXHTTP:
+		query.Set("path", defaultPath(info.Path))
+		if info.Host != "" {
+			query.Set("host", info.Host)
+		}
+		if info.Mode != "" {
+			query.Set("mode", info.Mode)
+		}
+	}
+
+	switch info.Security {
+	case SecurityReality:
+		query.Set("security", SecurityReality)
+		query.Set("pbk", info.PublicKey)
+		query.Set("fp", defaultFingerprint(info.Fingerprint))
+		query.Set("sni", info.ServerName)
+		query.Set("sid", info.ShortID)
+		query.Set("spx", defaultPath(info.SpiderX))
+	case SecurityTLS:
+		query.Set("security", SecurityTLS)
+		query.Set("fp", defaultFingerprint(info.Fingerprint))
+		if info.ServerName != "" {
+			query.Set("sni", info.ServerName)
+		}
+		if len(info.ALPN) > 0 {
+			query.Set("alpn", strings.Join(info.ALPN, ","))
+		}
+	default:
+		query.Set("security", SecurityNone)
+	}
+
+	return query
+}
+
+// vmessLink builds the base64 JSON share format used by v2rayN-compatible clients
+func vmessLink(info *InboundInfo, client *ClientConfig, address string, port int, remark string) (string, error) {
+	network := info.Network
+	if network == "" {
+		network = NetworkTCP
+	}
+
+	payload := map[string]string{
+		"v":    "2",
+		"ps":   remark,
+		"add":  address,
+		"port": strconv.Itoa(port),
+		"id":   client.ID,
+		"aid":  "0",
+		"scy":  "auto",
+		"net":  network,
+		"type": "none",
+		"host": info.Host,
+		"path": info.Path,
+		"tls":  "",
+	}
+
+	switch network {
+	case NetworkWS, NetworkHTTPUpgrade, NetworkXHTTP:
+		payload["path"] = defaultPath(info.Path)
+	case NetworkGRPC:
+		payload["path"] = info.ServiceName
+		if info.MultiMode {
+			payload["type"] = "multi"
+		} else {
+			payload["type"] = "gun"
+		}
+	}
+
+	if info.Security == SecurityTLS {
+		payload["tls"] = SecurityTLS
+		payload["sni"] = info.ServerName
+		payload["fp"] = defaultFingerprint(info.Fingerprint)
+		payload["alpn"] = strings.Join(info.ALPN, ",")
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return "vmess://" + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// shadowsocksLink builds a SIP002 ss:// link
+func shadowsocksLink(info *InboundInfo, client *ClientConfig, hostPort, remark string) string {
+	method := info.SSMethod
+	if method == "" {
+		method = client.Method
+	}
+
+	password := client.Password
+	// Multi-user SIP022 inbounds expect "serverKey:userKey"
+	if isShadowsocks2022(method) && info.SSPassword != "" {
+		password = info.SSPassword + ":" + client.Password
+	}
+
+	var userInfo string
+	if isShadowsocks2022(method) {
+		userInfo = url.PathEscape(method) + ":" + url.PathEscape(password)
+	} else {
+		userInfo = base64.RawURLEncoding.EncodeToString([]byte(method + ":" + password))
+	}
+
+	return fmt.Sprintf("ss://%s@%s#%s", userInfo, hostPort, url.PathEscape(remark))
+}
+
+func defaultPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+func defaultFingerprint(fp string) string {
+	if fp == "" {
+		return "chrome"
+	}
+	return fp
+}
@@ -0,0 +1,134 @@
+package xui
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Inbound protocols supported by 3x-ui
+const (
+	ProtocolVLESS       = "vless"
+	ProtocolVMess       = "vmess"
+	ProtocolTrojan      = "trojan"
+	ProtocolShadowsocks = "shadowsocks"
+)
+
+// Transport networks
+const (
+	NetworkTCP         = "tcp"
+	NetworkWS          = "ws"
+	NetworkGRPC        = "grpc"
+	NetworkXHTTP       = "xhttp"
+	NetworkHTTPUpgrade = "httpupgrade"
+)
+
+// Stream security modes
+const (
+	SecurityNone    = "none"
+	SecurityTLS     = "tls"
+	SecurityReality = "reality"
+)
+
+const flowVision = "xtls-rprx-vision"
+
+// supportsVision reports whether the inbound can use the XTLS Vision flow.
+// Vision only works for VLESS over raw TCP with TLS or Reality.
+func (i *InboundInfo) supportsVision() bool {
+	if i.Protocol != ProtocolVLESS {
+		return false
+	}
+	if i.Network != "" && i.Network != NetworkTCP {
+		return false
+	}
+	return i.Security == SecurityReality || i.Security == SecurityTLS
+}
+
+// isShadowsocks2022 reports whether method is one of the SIP022 ciphers
+func isShadowsocks2022(method string) bool {
+	return strings.HasPrefix(method, "2022-")
+}
+
+// newClientConfig builds a protocol-correct client entry for the inbound.
+// credential is a UUID for VLESS/VMess, the password for Trojan and Shadowsocks;
+// an empty credential generates a new one.
+func newClientConfig(info *InboundInfo, credential, email string, totalBytes, expiryTime int64, maxDevices int) ClientConfig {
+	client := ClientConfig{
+		Email:      email,
+		Enable:     true,
+		LimitIP:    maxDevices,
+		TotalGB:    totalBytes,
+		ExpiryTime: expiryTime,
+	}
+
+	switch info.Protocol {
+	case ProtocolTrojan:
+		if credential == "" {
+			credential = uuid.New().String()
+		}
+		client.Password = credential
+	case ProtocolShadowsocks:
+		if credential == "" {
+			credential = generateShadowsocksPassword(info.SSMethod)
+		}
+		client.Password = credential
+		client.Method = info.SSMethod
+	case ProtocolVMess:
+		if credential == "" {
+			credential = uuid.New().String()
+		}
+		client.ID = credential
+		client.Security = "auto"
+	default:
+		if credential == "" {
+			credential = uuid.New().String()
+		}
+		client.ID = credential
+		if info.supportsVision() {
+			client.Flow = flowVision
+		}
+	}
+
+	return client
+}
+
+// ExistingClientConfig rebuilds the link-relevant settings of a client created on
+// the inbound from its stored credential (UUID or password) and email
+func ExistingClientConfig(info *InboundInfo, credential, email string) *ClientConfig {
+	client := newClientConfig(info, credential, email, 0, 0, 0)
+	return &client
+}
+
+// apiClientID returns the identifier 3x-ui expects in updateClient/delClient paths
+func apiClientID(protocol string, client ClientConfig) string {
+	switch protocol {
+	case ProtocolTrojan:
+		return client.Password
+	case ProtocolShadowsocks:
+		return client.Email
+	default:
+		return client.ID
+	}
+}
+
+// generateShadowsocksPassword returns a password suitable for the cipher.
+// SIP022 ciphers require a base64 key of the exact cipher key length.
+func generateShadowsocksPassword(method string) string {
+	keyLen := 0
+	switch method {
+	case "2022-blake3-aes-128-gcm":
+		keyLen = 16
+	case "2022-blake3-aes-256-gcm", "2022-blake3-chacha20-poly1305":
+		keyLen = 32
+	}
+
+	if keyLen == 0 {
+		return strings.ReplaceAll(uuid.New().String(), "-", "")
+	}
+
+	key := make([]byte, keyLen)
+	_, _ = rand.Read(key)
+	return base64.StdEncoding.EncodeToString(key)
+}
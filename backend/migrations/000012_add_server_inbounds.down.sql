@@ -0,0 +1,3 @@
+ALTER TABLE plans DROP COLUMN IF EXISTS inbound_tags;
+DROP TABLE IF EXISTS subscription_inbounds;
+DROP TABLE IF EXISTS server_inbounds;
@@ -0,0 +1,32 @@
+-- Additional inbounds per server (the primary inbound stays in servers.xui_inbound_id)
+CREATE TABLE IF NOT EXISTS server_inbounds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    xui_inbound_id INT NOT NULL,
+    tag VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    sort_order INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (server_id, xui_inbound_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_server_inbounds_server ON server_inbounds(server_id, is_active, sort_order);
+
+-- Clients provisioned for a subscription on additional inbounds
+CREATE TABLE IF NOT EXISTS subscription_inbounds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    server_inbound_id UUID NOT NULL REFERENCES server_inbounds(id) ON DELETE CASCADE,
+    xui_client_id VARCHAR(255) NOT NULL,
+    xui_email VARCHAR(255) NOT NULL,
+    connection_key TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_inbounds_sub ON subscription_inbounds(subscription_id);
+
+-- Plans can restrict which additional inbounds are provisioned (empty = all)
+ALTER TABLE plans ADD COLUMN IF NOT EXISTS inbound_tags TEXT[] NOT NULL DEFAULT '{}';
+
+COMMENT ON COLUMN server_inbounds.tag IS 'Transport tag matched against plans.inbound_tags, e.g. reality, ws-tls';
@@ -0,0 +1,5 @@
+-- Control panel implementation used to provision clients on the server.
+-- xui_base_url/xui_username/xui_password hold the credentials for any panel type.
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS panel_type VARCHAR(20) NOT NULL DEFAULT 'xui';
+
+COMMENT ON COLUMN servers.panel_type IS 'xui (3x-ui) or marzban';
@@ -0,0 +1 @@
+COMMENT ON COLUMN servers.panel_type IS 'xui (3x-ui) or marzban';
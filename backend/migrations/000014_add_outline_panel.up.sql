@@ -0,0 +1,3 @@
+-- Outline servers store the Management API URL in xui_base_url and the
+-- optional certificate SHA-256 in xui_password
+COMMENT ON COLUMN servers.panel_type IS 'xui (3x-ui), marzban or outline';
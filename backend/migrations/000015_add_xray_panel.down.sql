@@ -0,0 +1,3 @@
+ALTER TABLE servers DROP COLUMN IF EXISTS inbound_tag;
+
+COMMENT ON COLUMN servers.panel_type IS 'xui (3x-ui), marzban or outline';
@@ -0,0 +1,5 @@
+-- Plain Xray nodes are provisioned through the gRPC API on an inbound addressed by tag;
+-- xui_base_url holds the API address (host:port)
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS inbound_tag VARCHAR(100) NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN servers.panel_type IS 'xui (3x-ui), marzban, outline or xray';
@@ -0,0 +1,5 @@
+ALTER TABLE servers DROP COLUMN IF EXISTS reality_checked_at;
+ALTER TABLE servers DROP COLUMN IF EXISTS panel_port;
+ALTER TABLE servers DROP COLUMN IF EXISTS panel_server_names;
+ALTER TABLE servers DROP COLUMN IF EXISTS panel_short_ids;
+ALTER TABLE servers DROP COLUMN IF EXISTS panel_public_key;
@@ -0,0 +1,7 @@
+-- Reality parameters last read from the panel inbound, used to validate the
+-- configured public_key/short_id/server_name/server_port and to detect changes on the panel
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS panel_public_key VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS panel_short_ids TEXT[] DEFAULT '{}';
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS panel_server_names TEXT[] DEFAULT '{}';
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS panel_port INT NOT NULL DEFAULT 0;
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS reality_checked_at TIMESTAMP;
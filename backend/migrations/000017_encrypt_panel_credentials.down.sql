@@ -0,0 +1,2 @@
+-- Encrypted values must be decrypted by the backend before rolling back
+ALTER TABLE servers ALTER COLUMN xui_password TYPE VARCHAR(255);
@@ -0,0 +1,3 @@
+-- Panel passwords are stored encrypted (enc:v1:<key id>:<ciphertext>) once CREDENTIALS_KEY
+-- is configured; existing plaintext rows are encrypted by the backend on startup
+ALTER TABLE servers ALTER COLUMN xui_password TYPE TEXT;
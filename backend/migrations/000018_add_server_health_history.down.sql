@@ -0,0 +1,3 @@
+COMMENT ON COLUMN servers.status IS 'online, offline, or unknown';
+DROP TABLE IF EXISTS server_incidents;
+DROP TABLE IF EXISTS server_health_checks;
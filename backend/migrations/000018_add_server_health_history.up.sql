@@ -0,0 +1,34 @@
+-- Result of every health check, used for uptime and latency percentiles
+CREATE TABLE IF NOT EXISTS server_health_checks (
+    id BIGSERIAL PRIMARY KEY,
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    checked_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    status VARCHAR(20) NOT NULL,
+    tcp_ms INT,
+    tls_ok BOOLEAN,
+    tls_ms INT,
+    panel_ok BOOLEAN,
+    panel_ms INT,
+    cpu_percent DOUBLE PRECISION,
+    mem_percent DOUBLE PRECISION,
+    xray_state VARCHAR(20),
+    error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_server_health_checks_server ON server_health_checks(server_id, checked_at DESC);
+CREATE INDEX IF NOT EXISTS idx_server_health_checks_checked_at ON server_health_checks(checked_at);
+
+-- Periods during which a server was offline or degraded
+CREATE TABLE IF NOT EXISTS server_incidents (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    cause TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    ended_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_server_incidents_server ON server_incidents(server_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_server_incidents_open ON server_incidents(server_id) WHERE ended_at IS NULL;
+
+COMMENT ON COLUMN servers.status IS 'online, degraded, offline, or unknown';
@@ -0,0 +1,19 @@
+-- Incident and maintenance notices posted by admins on the public status page
+CREATE TABLE IF NOT EXISTS status_notices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind VARCHAR(20) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    server_id UUID REFERENCES servers(id) ON DELETE CASCADE,
+    starts_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    ends_at TIMESTAMP,
+    created_by BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_status_notices_ends_at ON status_notices(ends_at);
+
+COMMENT ON COLUMN status_notices.kind IS 'incident or maintenance';
+COMMENT ON COLUMN status_notices.server_id IS 'Affected server, NULL for the whole service';
+COMMENT ON COLUMN status_notices.ends_at IS 'NULL while unresolved';
@@ -0,0 +1,23 @@
+-- Admin alerts, one row per deduplication key (e.g. server_down:<server id>)
+CREATE TABLE IF NOT EXISTS alerts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    key VARCHAR(255) NOT NULL UNIQUE,
+    kind VARCHAR(50) NOT NULL,
+    severity VARCHAR(20) NOT NULL,
+    message TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    occurrences INT NOT NULL DEFAULT 1,
+    first_fired_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_fired_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_notified_at TIMESTAMP,
+    resolved_at TIMESTAMP,
+    acked_by BIGINT,
+    acked_at TIMESTAMP,
+    muted_until TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_alerts_status ON alerts(status, last_fired_at DESC);
+
+COMMENT ON COLUMN alerts.status IS 'firing or resolved';
+COMMENT ON COLUMN alerts.muted_until IS 'No notifications for this key until then, kept across resolve and re-fire';
@@ -0,0 +1,2 @@
+ALTER TABLE plans DROP COLUMN IF EXISTS server_groups;
+ALTER TABLE servers DROP COLUMN IF EXISTS server_group;
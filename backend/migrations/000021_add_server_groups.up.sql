@@ -0,0 +1,7 @@
+-- Server groups (tiers) such as standard and premium streaming locations
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS server_group VARCHAR(50) NOT NULL DEFAULT 'standard';
+
+-- Plans can restrict which server groups their subscriptions may use (empty = all)
+ALTER TABLE plans ADD COLUMN IF NOT EXISTS server_groups TEXT[] NOT NULL DEFAULT '{}';
+
+COMMENT ON COLUMN servers.server_group IS 'Server tier matched against plans.server_groups, e.g. standard, premium';
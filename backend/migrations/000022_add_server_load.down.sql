@@ -0,0 +1,7 @@
+COMMENT ON COLUMN servers.current_load IS 'Current number of active clients';
+ALTER TABLE servers DROP COLUMN IF EXISTS bandwidth_mbps;
+ALTER TABLE servers DROP COLUMN IF EXISTS load_sampled_at;
+ALTER TABLE servers DROP COLUMN IF EXISTS throughput_bps;
+ALTER TABLE servers DROP COLUMN IF EXISTS connections;
+ALTER TABLE servers DROP COLUMN IF EXISTS online_clients;
+DROP TABLE IF EXISTS server_load_samples;
@@ -0,0 +1,25 @@
+-- Live load reported by the panel, sampled periodically
+CREATE TABLE IF NOT EXISTS server_load_samples (
+    id BIGSERIAL PRIMARY KEY,
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    sampled_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    online_clients INT NOT NULL,
+    connections INT,
+    upload_bps BIGINT,
+    download_bps BIGINT,
+    subscriptions INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_server_load_samples_server ON server_load_samples(server_id, sampled_at DESC);
+CREATE INDEX IF NOT EXISTS idx_server_load_samples_sampled_at ON server_load_samples(sampled_at);
+
+-- Latest sample, used by load balancing
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS online_clients INT;
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS connections INT;
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS throughput_bps BIGINT;
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS load_sampled_at TIMESTAMP;
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS bandwidth_mbps INT NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN servers.current_load IS 'Number of active subscriptions on the server';
+COMMENT ON COLUMN servers.online_clients IS 'Clients online at load_sampled_at, as reported by the panel';
+COMMENT ON COLUMN servers.bandwidth_mbps IS 'Usable uplink bandwidth compared with throughput for load balancing (0 = unknown)';
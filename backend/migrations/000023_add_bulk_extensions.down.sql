@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS bulk_extension_items;
+DROP TABLE IF EXISTS bulk_extension_jobs;
@@ -0,0 +1,32 @@
+-- Bulk subscription extensions run by admins, e.g. to compensate users after an outage
+CREATE TABLE IF NOT EXISTS bulk_extension_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    admin_id BIGINT NOT NULL,
+    days INT NOT NULL,
+    server_id UUID REFERENCES servers(id) ON DELETE SET NULL,
+    plan_id UUID REFERENCES plans(id) ON DELETE SET NULL,
+    created_before TIMESTAMP WITH TIME ZONE,
+    notify BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL DEFAULT 'running',
+    total INT NOT NULL DEFAULT 0,
+    succeeded INT NOT NULL DEFAULT 0,
+    failed INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_bulk_extension_jobs_status ON bulk_extension_jobs(status);
+
+-- Subscriptions matched by a job when it was created, one row per subscription
+CREATE TABLE IF NOT EXISTS bulk_extension_items (
+    job_id UUID NOT NULL REFERENCES bulk_extension_jobs(id) ON DELETE CASCADE,
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    error TEXT,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (job_id, subscription_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bulk_extension_items_status ON bulk_extension_items(job_id, status);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_payments_completed_at;
+DROP TABLE IF EXISTS server_monthly_traffic;
+ALTER TABLE servers DROP COLUMN IF EXISTS cost_currency;
+ALTER TABLE servers DROP COLUMN IF EXISTS monthly_cost;
@@ -0,0 +1,14 @@
+-- What a server costs us per month, used by the profitability report
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS monthly_cost DECIMAL(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS cost_currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+
+-- Host traffic per server and calendar month (UTC), accumulated from load samples
+CREATE TABLE IF NOT EXISTS server_monthly_traffic (
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    month DATE NOT NULL,
+    upload_bytes BIGINT NOT NULL DEFAULT 0,
+    download_bytes BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (server_id, month)
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_completed_at ON payments(completed_at);
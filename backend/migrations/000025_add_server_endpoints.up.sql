@@ -0,0 +1,27 @@
+-- Addresses a server is reachable at (IPv4, IPv6, domain, CDN), in the order they
+-- are offered to users. Each one becomes a separate entry in subscription outputs.
+CREATE TABLE IF NOT EXISTS server_endpoints (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    address VARCHAR(255) NOT NULL,
+    port INT NOT NULL DEFAULT 0,
+    label VARCHAR(100) NOT NULL DEFAULT '',
+    sort_order INT NOT NULL DEFAULT 0,
+    retired_at TIMESTAMP,
+    status VARCHAR(20) NOT NULL DEFAULT 'unknown',
+    ping_ms INT,
+    last_error TEXT,
+    last_check_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (server_id, address, port)
+);
+
+CREATE INDEX IF NOT EXISTS idx_server_endpoints_server ON server_endpoints(server_id, sort_order);
+
+COMMENT ON COLUMN server_endpoints.port IS 'Port advertised for the primary inbound, 0 = the port of the stored key';
+COMMENT ON COLUMN server_endpoints.retired_at IS 'Set when the endpoint is no longer offered, e.g. because it is blocked';
+
+-- Every existing server starts with its current address as the only endpoint
+INSERT INTO server_endpoints (server_id, address)
+SELECT id, server_address FROM servers WHERE server_address <> ''
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_networks;
+DROP TABLE IF EXISTS asn_server_latency;
+DROP TABLE IF EXISTS user_server_latency;
@@ -0,0 +1,32 @@
+-- Latency to each server measured by the mini app on users' devices, smoothed
+-- per user and per network (autonomous system) of the user
+CREATE TABLE IF NOT EXISTS user_server_latency (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    latency_ms DOUBLE PRECISION NOT NULL,
+    samples INT NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, server_id)
+);
+
+CREATE TABLE IF NOT EXISTS asn_server_latency (
+    asn BIGINT NOT NULL,
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    latency_ms DOUBLE PRECISION NOT NULL,
+    samples INT NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (asn, server_id)
+);
+
+-- Network the user last reported latencies from
+CREATE TABLE IF NOT EXISTS user_networks (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    asn BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_server_latency_updated_at ON user_server_latency(updated_at);
+CREATE INDEX IF NOT EXISTS idx_asn_server_latency_updated_at ON asn_server_latency(updated_at);
+
+COMMENT ON COLUMN user_server_latency.latency_ms IS 'Exponential moving average of reported round-trip times; unreachable counts as the timeout';
+COMMENT ON COLUMN asn_server_latency.latency_ms IS 'Exponential moving average of round-trip times reported from the network';
@@ -0,0 +1,2 @@
+-- Language chosen by the user, overrides the Telegram client language
+ALTER TABLE users ADD COLUMN IF NOT EXISTS language VARCHAR(8);
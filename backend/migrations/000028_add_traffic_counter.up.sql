@@ -0,0 +1,3 @@
+-- Raw traffic counter of the subscription's client at the last sync, for stateless
+-- nodes (plain Xray) whose counters are read without reset and diffed by the backend
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS traffic_counter BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,13 @@
+-- Panel clients of expired subscriptions that could not be removed yet (panel down
+-- or the deletion failed); the traffic worker retries them until the panel answers
+CREATE TABLE IF NOT EXISTS panel_client_removals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    xui_inbound_id INT NOT NULL,
+    xui_client_id VARCHAR(255) NOT NULL,
+    xui_email VARCHAR(255) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (server_id, xui_inbound_id, xui_email)
+);
@@ -0,0 +1,5 @@
+-- capacity limits active subscriptions; max_online limits clients online at once
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS max_online INT NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN servers.capacity IS 'Maximum number of active subscriptions on the server (0 = unlimited)';
+COMMENT ON COLUMN servers.max_online IS 'Clients online at once compared with online_clients for load balancing (0 = unknown)';
@@ -0,0 +1 @@
+ALTER TABLE bulk_extension_items DROP COLUMN IF EXISTS expires_from;
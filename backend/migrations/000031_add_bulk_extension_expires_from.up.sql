@@ -0,0 +1,3 @@
+-- Expiry of the subscription when its item was claimed. A resumed or retried item
+-- extends the subscription to expires_from + days instead of by days once more.
+ALTER TABLE bulk_extension_items ADD COLUMN IF NOT EXISTS expires_from TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,5 @@
+DELETE FROM server_monthly_traffic WHERE server_id NOT IN (SELECT id FROM servers);
+ALTER TABLE server_monthly_traffic ADD CONSTRAINT server_monthly_traffic_server_id_fkey
+    FOREIGN KEY (server_id) REFERENCES servers(id) ON DELETE CASCADE;
+
+DROP TABLE IF EXISTS server_costs;
@@ -0,0 +1,28 @@
+-- Monthly cost of a server over time, charged by the profitability report. A
+-- period is open (effective_to NULL) while the server is active; changing the
+-- cost closes it and opens a new one. Periods outlive deleted servers, so
+-- server_id has no foreign key and the server's name is kept with them.
+CREATE TABLE IF NOT EXISTS server_costs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    server_id UUID NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    country VARCHAR(50) NOT NULL DEFAULT '',
+    flag_emoji VARCHAR(10) NOT NULL DEFAULT '',
+    monthly_cost DECIMAL(12,2) NOT NULL,
+    cost_currency VARCHAR(3) NOT NULL,
+    effective_from TIMESTAMP NOT NULL DEFAULT NOW(),
+    effective_to TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_server_costs_period ON server_costs(effective_from, effective_to);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_server_costs_open ON server_costs(server_id) WHERE effective_to IS NULL;
+
+-- Existing servers are charged their current cost since they were created,
+-- inactive ones until they were last updated
+INSERT INTO server_costs (server_id, name, country, flag_emoji, monthly_cost, cost_currency, effective_from, effective_to)
+SELECT id, name, country, COALESCE(flag_emoji, ''), monthly_cost, cost_currency,
+    COALESCE(created_at, NOW()), CASE WHEN is_active THEN NULL ELSE COALESCE(updated_at, NOW()) END
+FROM servers;
+
+-- Traffic of deleted servers stays in the report
+ALTER TABLE server_monthly_traffic DROP CONSTRAINT IF EXISTS server_monthly_traffic_server_id_fkey;
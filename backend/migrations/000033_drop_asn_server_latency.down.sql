@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS asn_server_latency (
+    asn BIGINT NOT NULL,
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    latency_ms DOUBLE PRECISION NOT NULL,
+    samples INT NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (asn, server_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_asn_server_latency_updated_at ON asn_server_latency(updated_at);
@@ -0,0 +1,3 @@
+-- Network latencies are now computed from per-user averages, so each user of
+-- a network counts once however many reports they send
+DROP TABLE IF EXISTS asn_server_latency;
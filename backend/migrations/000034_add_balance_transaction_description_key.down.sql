@@ -0,0 +1,15 @@
+UPDATE balance_transactions SET description = format(m.template, VARIADIC description_args)
+FROM (VALUES
+    ('tx_desc_referral_bonus', 'Реферальный бонус: +%s TON'),
+    ('tx_desc_subscription_payment', 'Оплата подписки: -%s TON'),
+    ('tx_desc_refund', 'Возврат средств: +%s TON'),
+    ('tx_desc_top_up', 'Пополнение баланса: +%s TON'),
+    ('tx_desc_top_up_bonus', 'Пополнение баланса: +%s TON (+%s%% бонус = %s)'),
+    ('tx_desc_promo_code', 'Промокод %s: +%s TON'),
+    ('tx_desc_region_switch', 'Смена региона: -%s TON')
+) AS m(key, template)
+WHERE balance_transactions.description_key = m.key;
+
+ALTER TABLE balance_transactions
+    DROP COLUMN IF EXISTS description_args,
+    DROP COLUMN IF EXISTS description_key;
@@ -0,0 +1,20 @@
+-- Descriptions of balance transactions are rendered in the user's language from
+-- a catalog key and its arguments; description keeps free text entered by admins
+ALTER TABLE balance_transactions
+    ADD COLUMN IF NOT EXISTS description_key VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS description_args TEXT[];
+
+-- Move the Russian descriptions written so far to keys and arguments
+UPDATE balance_transactions SET description_key = m.key, description_args = regexp_match(description, m.pattern), description = NULL
+FROM (VALUES
+    ('referral_bonus', 'tx_desc_referral_bonus', '^Реферальный бонус: \+([0-9.]+) TON$'),
+    ('subscription_payment', 'tx_desc_subscription_payment', '^Оплата подписки: -([0-9.]+) TON$'),
+    ('refund', 'tx_desc_refund', '^Возврат средств: \+([0-9.]+) TON$'),
+    ('top_up', 'tx_desc_top_up', '^Пополнение баланса: \+([0-9.]+) TON$'),
+    ('top_up', 'tx_desc_top_up_bonus', '^Пополнение баланса: \+([0-9.]+) TON \(\+([0-9.]+)% бонус = ([0-9.]+)\)$'),
+    ('promo_code', 'tx_desc_promo_code', '^Промокод (.+): \+([0-9.]+) TON$'),
+    ('region_switch', 'tx_desc_region_switch', '^Смена региона: -([0-9.]+) TON$')
+) AS m(type, key, pattern)
+WHERE balance_transactions.type = m.type AND balance_transactions.description ~ m.pattern;
+
+COMMENT ON COLUMN balance_transactions.description_key IS 'Message catalog key the description is rendered from; NULL for free text';
@@ -0,0 +1,2 @@
+ALTER TABLE payments DROP COLUMN IF EXISTS notify_chat;
+DROP TABLE IF EXISTS bot_chat_states;
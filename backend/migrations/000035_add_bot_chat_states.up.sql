@@ -0,0 +1,17 @@
+-- Purchases in progress in bot chats, shared by all replicas
+CREATE TABLE IF NOT EXISTS bot_chat_states (
+    chat_id BIGINT PRIMARY KEY,
+    plan_id UUID,
+    server_id UUID,
+    server_chosen BOOLEAN NOT NULL DEFAULT FALSE,
+    awaiting VARCHAR(50) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_bot_chat_states_updated_at ON bot_chat_states(updated_at);
+
+COMMENT ON COLUMN bot_chat_states.server_id IS 'Chosen server; NULL picks one automatically';
+COMMENT ON COLUMN bot_chat_states.awaiting IS 'What the next text message in the chat is, e.g. promo_code';
+
+-- TON payments reported as paid in the bot chat; the TON worker tells the user how they end
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS notify_chat BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+-- TON payments made in the bot chat show a comment and match only transfers carrying it;
+-- other payments keep matching by amount
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS comment_required BOOLEAN NOT NULL DEFAULT FALSE;
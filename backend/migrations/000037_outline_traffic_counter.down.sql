@@ -0,0 +1,5 @@
+UPDATE subscriptions SET traffic_counter = 0
+WHERE server_id IN (SELECT id FROM servers WHERE panel_type = 'outline')
+   OR (server_id IS NULL AND (
+        SELECT panel_type FROM servers WHERE is_active = true ORDER BY sort_order, name LIMIT 1
+   ) = 'outline');
@@ -0,0 +1,8 @@
+-- Outline reports traffic over the last 30 days, which is now added up between
+-- syncs like the counters of stateless nodes. Start the counters of subscriptions
+-- on Outline servers from the window stored so far, so it is not counted twice.
+UPDATE subscriptions SET traffic_counter = traffic_used
+WHERE server_id IN (SELECT id FROM servers WHERE panel_type = 'outline')
+   OR (server_id IS NULL AND (
+        SELECT panel_type FROM servers WHERE is_active = true ORDER BY sort_order, name LIMIT 1
+   ) = 'outline');
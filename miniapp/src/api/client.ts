@@ -4,6 +4,13 @@ function getInitData(): string {
   return window.Telegram?.WebApp?.initData || ''
 }
 
+// ApiError carries the machine-readable error code returned by the API next to the localized message
+export class ApiError extends Error {
+  constructor(message: string, public code?: string, public status?: number) {
+    super(message)
+  }
+}
+
 async function request<T>(endpoint: string, options: RequestInit = {}): Promise<T> {
   const initData = getInitData()
 
@@ -18,7 +25,7 @@ async function request<T>(endpoint: string, options: RequestInit = {}): Promise<
 
   if (!response.ok) {
     const error = await response.json().catch(() => ({ error: 'Unknown error' }))
-    throw new Error(error.error || `HTTP error ${response.status}`)
+    throw new ApiError(error.error || `HTTP error ${response.status}`, error.code, response.status)
   }
 
   return response.json()
@@ -31,6 +38,13 @@ export const api = {
   // User
   getMe: () => request<{ id: number; subscription?: any }>('/api/user/me'),
 
+  // Language of bot messages and API errors; an empty language follows the Telegram client
+  setLanguage: (language: 'ru' | 'en' | '') =>
+    request<{ success: boolean; language: string }>('/api/user/language', {
+      method: 'PUT',
+      body: JSON.stringify({ language }),
+    }),
+
   // Plans
   getPlans: () => request<{ plans: any[] }>('/api/plans'),
 
@@ -344,6 +358,8 @@ export const api = {
       is_active: boolean
       sort_order: number
       capacity: number
+      monthly_cost?: number
+      cost_currency?: string
     }) =>
       request<any>('/api/admin/servers', {
         method: 'POST',
@@ -367,6 +383,8 @@ export const api = {
       is_active?: boolean
       sort_order?: number
       capacity?: number
+      monthly_cost?: number
+      cost_currency?: string
     }) =>
       request<any>(`/api/admin/servers/${serverId}`, {
         method: 'PUT',
@@ -388,6 +406,31 @@ export const api = {
   // Servers (for users)
   getServers: () =>
     request<{ servers: ServerPublic[] }>('/api/servers'),
+
+  reportLatency: (results: { server_id: string; latency_ms: number | null }[]) =>
+    request<{ success: boolean }>('/api/servers/latency', {
+      method: 'POST',
+      body: JSON.stringify({ results }),
+    }),
+}
+
+const LATENCY_TIMEOUT_MS = 3000
+
+// measureLatency times a connection to host:port from the user's device. The
+// request is expected to fail (the server does not speak plain HTTPS to us), so
+// the time until it settles approximates the round trip; null = unreachable.
+export async function measureLatency(host: string): Promise<number | null> {
+  const controller = new AbortController()
+  const timer = setTimeout(() => controller.abort(), LATENCY_TIMEOUT_MS)
+  const start = performance.now()
+  try {
+    await fetch(`https://${host}/`, { mode: 'no-cors', cache: 'no-store', signal: controller.signal })
+  } catch {
+    if (controller.signal.aborted) return null
+  } finally {
+    clearTimeout(timer)
+  }
+  return Math.max(1, Math.round(performance.now() - start))
 }
 
 export interface ServerPublic {
@@ -400,4 +443,16 @@ export interface ServerPublic {
   ping_ms?: number
   status: 'online' | 'offline' | 'unknown'
   load_percent: number
+  is_full: boolean
+  group: string
+  // address:port the mini app measures latency to
+  ping_host?: string
+  // Latency measured by the user, or by users of the same network when latency_source is 'network'
+  latency_ms?: number
+  latency_source?: 'user' | 'network'
+  // The server that would be picked for the user
+  recommended: boolean
+  // Set when the current plan does not include the server; unlock_plans can be bought to use it
+  locked: boolean
+  unlock_plans?: { id: string; name: string }[]
 }
@@ -1,6 +1,6 @@
 import { useEffect, useState } from 'react'
 import { useNavigate } from 'react-router-dom'
-import { api } from '../api/client'
+import { api, ApiError } from '../api/client'
 
 type Tab = 'stats' | 'users' | 'bans' | 'promo' | 'plans' | 'servers' | 'settings'
 
@@ -76,6 +76,12 @@ interface ServerItem {
   sort_order: number
   capacity: number
   current_load: number
+  monthly_cost: number
+  cost_currency: string
+  online_clients?: number
+  connections?: number
+  throughput_bps?: number
+  load_percent: number
   status: string
   ping_ms?: number
 }
@@ -140,6 +146,8 @@ export default function AdminPage() {
   const [serverAddress, setServerAddress] = useState('')
   const [serverSortOrder, setServerSortOrder] = useState('0')
   const [serverCapacity, setServerCapacity] = useState('100')
+  const [serverMonthlyCost, setServerMonthlyCost] = useState('0')
+  const [serverCostCurrency, setServerCostCurrency] = useState('USD')
   const [serverIsActive, setServerIsActive] = useState(true)
   const [serverSaving, setServerSaving] = useState(false)
 
@@ -183,7 +191,7 @@ export default function AdminPage() {
         setRegionSwitchPrice(regionSwitchData.region_switch_price || 0.1)
       }
     } catch (e) {
-      setError((e as Error).message)
+      setError(e instanceof ApiError && e.code === 'access_denied' ? 'access denied' : (e as Error).message)
     } finally {
       setLoading(false)
     }
@@ -387,6 +395,8 @@ export default function AdminPage() {
     setServerAddress('')
     setServerSortOrder('0')
     setServerCapacity('100')
+    setServerMonthlyCost('0')
+    setServerCostCurrency('USD')
     setServerIsActive(true)
     setServerSaving(false)
   }
@@ -405,6 +415,8 @@ export default function AdminPage() {
       setServerAddress(server.server_address)
       setServerSortOrder(server.sort_order.toString())
       setServerCapacity(server.capacity?.toString() || '100')
+      setServerMonthlyCost(server.monthly_cost?.toString() || '0')
+      setServerCostCurrency(server.cost_currency || 'USD')
       setServerIsActive(server.is_active)
     } else {
       resetServerForm()
@@ -435,6 +447,8 @@ export default function AdminPage() {
         server_name: 'www.google.com',
         sort_order: parseInt(serverSortOrder) || 0,
         capacity: parseInt(serverCapacity) || 100,
+        monthly_cost: parseFloat(serverMonthlyCost) || 0,
+        cost_currency: serverCostCurrency,
         is_active: serverIsActive,
       }
 
@@ -847,15 +861,22 @@ export default function AdminPage() {
                     </p>
                     <p className="text-xs mt-1">
                       <span className={`font-medium ${
-                        server.current_load / server.capacity * 100 > 80 ? 'text-red-500' :
-                        server.current_load / server.capacity * 100 > 50 ? 'text-yellow-500' :
+                        server.load_percent > 80 ? 'text-red-500' :
+                        server.load_percent > 50 ? 'text-yellow-500' :
                         'text-green-500'
                       }`}>
                         {server.current_load}/{server.capacity}
                       </span>
-                      <span className="text-hint"> клиентов</span>
+                      <span className="text-hint"> подписок</span>
                       {server.ping_ms && <span className="text-hint ml-2">• {server.ping_ms}ms</span>}
                     </p>
+                    {server.online_clients != null && (
+                      <p className="text-hint text-xs">
+                        {server.online_clients} онлайн
+                        {server.connections != null && ` • ${server.connections} соединений`}
+                        {server.throughput_bps != null && ` • ${(server.throughput_bps * 8 / 1_000_000).toFixed(1)} Мбит/с`}
+                      </p>
+                    )}
                   </div>
                   <div className="flex flex-col gap-1">
                     <button
@@ -1412,6 +1433,32 @@ export default function AdminPage() {
                   />
                 </div>
               </div>
+
+              {/* Monthly cost */}
+              <div className="grid grid-cols-2 gap-2">
+                <div>
+                  <label className="text-hint text-xs">Стоимость в месяц</label>
+                  <input
+                    type="number"
+                    value={serverMonthlyCost}
+                    onChange={(e) => setServerMonthlyCost(e.target.value)}
+                    placeholder="0"
+                    className="input w-full"
+                  />
+                </div>
+                <div>
+                  <label className="text-hint text-xs">Валюта</label>
+                  <select
+                    value={serverCostCurrency}
+                    onChange={(e) => setServerCostCurrency(e.target.value)}
+                    className="input w-full"
+                  >
+                    <option value="USD">USD</option>
+                    <option value="RUB">RUB</option>
+                    <option value="TON">TON</option>
+                  </select>
+                </div>
+              </div>
               <label className="flex items-center gap-2 cursor-pointer pt-2">
                 <input
                   type="checkbox"
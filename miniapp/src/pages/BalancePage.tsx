@@ -4,6 +4,7 @@ import { useTonConnectUI, useTonAddress } from '@tonconnect/ui-react'
 import { useStore } from '../store'
 import { useTelegram } from '../hooks/useTelegram'
 import { api } from '../api/client'
+import { commentPayload } from '../utils/ton'
 import type { BalanceTransaction } from '../types'
 
 const TOP_UP_AMOUNTS = [0.5, 1, 2, 5]
@@ -108,7 +109,7 @@ export default function BalancePage() {
         // Get payment info
         const tonInfo = await api.getTopUpTONInfo(payment_id)
 
-        // Send TON transaction (without payload - simple transfer)
+        // Send TON transaction with the payment comment the backend matches it by
         const amountNano = (topUpAmount * 1e9).toString()
 
         const transaction = {
@@ -117,6 +118,7 @@ export default function BalancePage() {
             {
               address: tonInfo.wallet_address,
               amount: amountNano,
+              payload: commentPayload(tonInfo.comment),
             },
           ],
         }
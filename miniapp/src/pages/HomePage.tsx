@@ -2,11 +2,34 @@ import { useEffect, useState } from 'react'
 import { useNavigate } from 'react-router-dom'
 import { useStore } from '../store'
 import { useTelegram } from '../hooks/useTelegram'
-import { api, ServerPublic } from '../api/client'
+import { api, measureLatency, ServerPublic } from '../api/client'
 import PlanCard from '../components/PlanCard'
 import SubscriptionCard from '../components/SubscriptionCard'
 
 const ONBOARDING_KEY = 'zyvpn_onboarding_seen'
+const LATENCY_MEASURED_KEY = 'zyvpn_latency_measured_at'
+const LATENCY_MEASURE_INTERVAL = 10 * 60 * 1000
+
+// Latency measured from the user's device or network, else the backend's ping
+const serverPing = (server: ServerPublic) => server.latency_ms ?? server.ping_ms
+
+// Measures latency to every online server one at a time and reports it, at most
+// once per LATENCY_MEASURE_INTERVAL. Resolves to true when a report was sent.
+async function measureServers(servers: ServerPublic[]): Promise<boolean> {
+  const last = Number(localStorage.getItem(LATENCY_MEASURED_KEY) || 0)
+  if (Date.now() - last < LATENCY_MEASURE_INTERVAL) return false
+
+  const results: { server_id: string; latency_ms: number | null }[] = []
+  for (const server of servers) {
+    if (server.status !== 'online' || !server.ping_host) continue
+    results.push({ server_id: server.id, latency_ms: await measureLatency(server.ping_host) })
+  }
+  if (results.length === 0) return false
+
+  localStorage.setItem(LATENCY_MEASURED_KEY, String(Date.now()))
+  await api.reportLatency(results)
+  return true
+}
 
 export default function HomePage() {
   const navigate = useNavigate()
@@ -25,13 +48,21 @@ export default function HomePage() {
     // Load servers
     api.getServers().then(data => {
       setServers(data.servers || [])
-      // Auto-select first online server if none selected
+      // Auto-select the recommended server, or the first online one, if none selected
       if (!selectedServerId) {
-        const onlineServer = data.servers?.find(s => s.status === 'online')
+        const onlineServer = data.servers?.find(s => s.recommended) ||
+          data.servers?.find(s => s.status === 'online' && !s.is_full)
         if (onlineServer) {
           setSelectedServerId(onlineServer.id)
         }
       }
+
+      // Measure latency from this device so locations are recommended by it
+      measureServers(data.servers || []).then(reported => {
+        if (reported) {
+          api.getServers().then(updated => setServers(updated.servers || []))
+        }
+      }).catch(() => {})
     }).catch(() => {})
 
     // Show onboarding only on first visit
@@ -101,13 +132,16 @@ export default function HomePage() {
                   selectedServerId === server.id
                     ? 'border-tg-button ring-1 ring-tg-button'
                     : ''
-                } ${server.status !== 'online' ? 'opacity-50' : ''}`}
-                disabled={server.status !== 'online'}
+                } ${server.status !== 'online' || server.is_full ? 'opacity-50' : ''}`}
+                disabled={server.status !== 'online' || server.is_full}
               >
                 <div className="flex items-center gap-3">
                   <span className="text-2xl">{server.flag_emoji}</span>
                   <div className="text-left">
-                    <p className="font-medium">{server.name}</p>
+                    <p className="font-medium">
+                      {server.name}
+                      {server.recommended && <span className="ml-2 text-xs text-green-500">Рекомендуем</span>}
+                    </p>
                     <p className="text-xs text-hint">{server.country}{server.city ? `, ${server.city}` : ''}</p>
                   </div>
                 </div>
@@ -115,15 +149,20 @@ export default function HomePage() {
                   {server.status === 'online' ? (
                     <>
                       <p className={`font-medium ${
-                        server.ping_ms && server.ping_ms < 100 ? 'text-green-500' :
-                        server.ping_ms && server.ping_ms < 200 ? 'text-yellow-500' :
+                        (serverPing(server) ?? Infinity) < 100 ? 'text-green-500' :
+                        (serverPing(server) ?? Infinity) < 200 ? 'text-yellow-500' :
                         'text-red-500'
                       }`}>
-                        {server.ping_ms ? `${server.ping_ms} ms` : '...'}
+                        {serverPing(server) ? `${serverPing(server)} ms` : '...'}
                       </p>
                       <p className="text-xs text-hint">
-                        {server.load_percent > 80 ? 'Загружен' : server.load_percent > 50 ? 'Средняя' : 'Низкая'} нагрузка
+                        {server.is_full ? 'Нет мест' : `${server.load_percent > 80 ? 'Загружен' : server.load_percent > 50 ? 'Средняя' : 'Низкая'} нагрузка`}
                       </p>
+                      {server.locked && (
+                        <p className="text-xs text-yellow-500">
+                          🔒 {server.unlock_plans?.length ? `В тарифе ${server.unlock_plans.map(p => p.name).join(', ')}` : 'Недоступно на вашем тарифе'}
+                        </p>
+                      )}
                     </>
                   ) : (
                     <p className="text-xs text-red-500">Оффлайн</p>
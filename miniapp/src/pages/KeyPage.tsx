@@ -57,7 +57,7 @@ export default function KeyPage() {
     } catch (err: any) {
       webApp?.HapticFeedback.notificationOccurred('error')
       // Check if it's insufficient balance error
-      if (err.message?.includes('Недостаточно средств')) {
+      if (err.code === 'insufficient_balance') {
         webApp?.showAlert(`Недостаточно средств. Пополните баланс на ${switchPrice.toFixed(2)} TON`)
       } else {
         webApp?.showAlert(err.message || 'Не удалось сменить сервер')
@@ -237,7 +237,7 @@ export default function KeyPage() {
                 <button
                   key={server.id}
                   onClick={() => handleSwitchServer(server.id)}
-                  disabled={switching || server.id === currentServerId}
+                  disabled={switching || server.id === currentServerId || server.is_full || server.locked}
                   className={`w-full card flex items-center justify-between p-3 transition-all ${
                     server.id === currentServerId
                       ? 'border-tg-button ring-1 ring-tg-button'
@@ -262,8 +262,13 @@ export default function KeyPage() {
                       {server.ping_ms ? `${server.ping_ms} ms` : '...'}
                     </p>
                     <p className="text-xs text-hint">
-                      {server.load_percent > 80 ? 'Загружен' : server.load_percent > 50 ? 'Средняя' : 'Низкая'} нагрузка
+                      {server.is_full ? 'Нет мест' : `${server.load_percent > 80 ? 'Загружен' : server.load_percent > 50 ? 'Средняя' : 'Низкая'} нагрузка`}
                     </p>
+                    {server.locked && (
+                      <p className="text-xs text-yellow-500">
+                        🔒 {server.unlock_plans?.length ? `Улучшите тариф до ${server.unlock_plans.map(p => p.name).join(', ')}` : 'Недоступно на вашем тарифе'}
+                      </p>
+                    )}
                   </div>
                 </button>
               ))}
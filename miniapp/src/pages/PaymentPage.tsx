@@ -4,6 +4,7 @@ import { useTonConnectUI, useTonAddress } from '@tonconnect/ui-react'
 import { useStore } from '../store'
 import { useTelegram } from '../hooks/useTelegram'
 import { api } from '../api/client'
+import { commentPayload } from '../utils/ton'
 import type { TONPaymentInfo } from '../types'
 
 export default function PaymentPage() {
@@ -143,13 +144,14 @@ export default function PaymentPage() {
       const amountNano = (parseFloat(info.amount) * 1e9).toString()
       console.log('Amount in nanotons:', amountNano)
 
-      // Simple transfer without payload - payment identified by amount and sender
+      // Transfer with the payment comment - the backend matches the payment by it
       const transaction = {
         validUntil: Math.floor(Date.now() / 1000) + 600, // 10 minutes
         messages: [
           {
             address: info.wallet_address,
             amount: amountNano,
+            payload: commentPayload(info.comment),
           },
         ],
       }
@@ -0,0 +1,6 @@
+import { beginCell } from '@ton/core'
+
+// Payload of a TON transfer carrying a text comment, as a base64 BOC for TON Connect
+export function commentPayload(comment: string): string {
+  return beginCell().storeUint(0, 32).storeStringTail(comment).endCell().toBoc().toString('base64')
+}